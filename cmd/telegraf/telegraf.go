@@ -207,6 +207,21 @@ func runAgent(ctx context.Context,
 	return ag.Run(ctx)
 }
 
+// checkConfig loads the configuration and checks the plugins without
+// starting them.  All problems are printed and the exit code is returned.
+func checkConfig(inputFilters, outputFilters []string) int {
+	errs := config.CheckFiles(*fConfig, *fConfigDirectory, inputFilters, outputFilters)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "Found %d problems in the configuration\n", len(errs))
+		return 1
+	}
+	fmt.Println("Configuration is valid")
+	return 0
+}
+
 func usageExit(rc int) {
 	fmt.Println(internal.Usage)
 	os.Exit(rc)
@@ -292,6 +307,9 @@ func main() {
 			fmt.Println(formatFullVersion())
 			return
 		case "config":
+			if len(args) > 1 && args[1] == "check" {
+				os.Exit(checkConfig(inputFilters, outputFilters))
+			}
 			config.PrintSampleConfig(
 				sectionFilters,
 				inputFilters,
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Checker is implemented by plugins with settings that can only be checked
+// against the system they run on, such as local directories.  Check is run
+// after Init by `telegraf config check` and must not change the system.
+type Checker interface {
+	Check() error
+}
+
+// CheckFiles loads the configuration file and the files of the directory, each
+// on its own, and checks their plugins without starting them.  All problems
+// found are returned.
+func CheckFiles(path, directory string, inputFilters, outputFilters []string) []error {
+	if path == "" {
+		var err error
+		if path, err = getDefaultConfigPath(); err != nil {
+			return []error{err}
+		}
+	}
+	files := []string{path}
+	if directory != "" {
+		dirFiles, err := directoryFiles(directory)
+		if err != nil {
+			return []error{err}
+		}
+		files = append(files, dirFiles...)
+	}
+
+	var errs []error
+	var inputs, outputs int
+	for _, file := range files {
+		c := NewConfig()
+		c.InputFilters = inputFilters
+		c.OutputFilters = outputFilters
+		if err := c.LoadConfig(file); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		inputs += len(c.Inputs)
+		outputs += len(c.Outputs)
+
+		for _, err := range c.Check() {
+			errs = append(errs, fmt.Errorf("%s: %w", file, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		if outputs == 0 {
+			errs = append(errs, errors.New("no outputs found"))
+		}
+		if inputs == 0 {
+			errs = append(errs, errors.New("no inputs found"))
+		}
+	}
+	return errs
+}
+
+// Check runs Init of every plugin and Check of the plugins implementing
+// Checker, and returns all problems found.  Init runs as on startup, with
+// any side effects of the plugins, but no plugin is started or connected.
+func (c *Config) Check() []error {
+	var errs []error
+	if c.Agent.Interval.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("agent: interval must be positive, found %s", c.Agent.Interval.Duration))
+	}
+	if c.Agent.FlushInterval.Duration <= 0 {
+		errs = append(errs, fmt.Errorf("agent: flush_interval must be positive, found %s", c.Agent.FlushInterval.Duration))
+	}
+
+	for _, input := range c.Inputs {
+		errs = appendCheck(errs, input.LogName(), input.Input)
+	}
+	for _, processor := range c.Processors {
+		plugin := interface{}(processor.Processor)
+		if p, ok := processor.Processor.(unwrappable); ok {
+			plugin = p.Unwrap()
+		}
+		errs = appendCheck(errs, processor.LogName(), plugin)
+	}
+	for _, aggregator := range c.Aggregators {
+		errs = appendCheck(errs, aggregator.LogName(), aggregator.Aggregator)
+	}
+	for _, output := range c.Outputs {
+		if output.Config.SpoolDirectory != "" {
+			if err := internal.CheckCreatableDir(output.Config.SpoolDirectory); err != nil {
+				errs = append(errs, fmt.Errorf("%s: spool_directory: %w", output.LogName(), err))
+			}
+		}
+		errs = appendCheck(errs, output.LogName(), output.Output)
+	}
+	return errs
+}
+
+func appendCheck(errs []error, name string, plugin interface{}) []error {
+	if p, ok := plugin.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			// Skip the system checks, they rely on Init.
+			return append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if p, ok := plugin.(Checker); ok {
+		if err := p.Check(); err != nil {
+			return append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errs
+}
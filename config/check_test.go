@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/stretchr/testify/require"
+)
+
+type checkMock struct {
+	Fail      bool   `toml:"fail"`
+	Directory string `toml:"directory"`
+}
+
+func (m *checkMock) SampleConfig() string                  { return "" }
+func (m *checkMock) Description() string                   { return "" }
+func (m *checkMock) Gather(acc telegraf.Accumulator) error { return nil }
+
+func (m *checkMock) Init() error {
+	if m.Fail {
+		return errors.New("init failed")
+	}
+	return nil
+}
+
+func (m *checkMock) Check() error {
+	if m.Directory == "" {
+		return nil
+	}
+	return internal.CheckWritableDir(m.Directory)
+}
+
+func init() {
+	inputs.Add("check_mock", func() telegraf.Input { return &checkMock{} })
+}
+
+func TestCheckFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	main := filepath.Join(dir, "telegraf.conf")
+	require.NoError(t, ioutil.WriteFile(main, []byte(`
+[[inputs.check_mock]]
+  fail = true
+
+[[inputs.check_mock]]
+  directory = "`+filepath.ToSlash(filepath.Join(dir, "missing"))+`"
+
+[[outputs.http]]
+  url = "http://localhost"
+`), 0644))
+
+	confDir := filepath.Join(dir, "telegraf.d")
+	require.NoError(t, os.Mkdir(confDir, 0755))
+	bad := filepath.Join(confDir, "bad.conf")
+	require.NoError(t, ioutil.WriteFile(bad, []byte(`
+[[inputs.check_mock]]
+  not_a_field = true
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, "good.conf"), []byte(`
+[[inputs.check_mock]]
+  directory = "`+filepath.ToSlash(dir)+`"
+`), 0644))
+
+	errs := CheckFiles(main, confDir, nil, nil)
+	require.Len(t, errs, 3)
+	require.EqualError(t, errs[0], main+": inputs.check_mock: init failed")
+	require.Contains(t, errs[1].Error(), main+": inputs.check_mock: stat ")
+	require.EqualError(t, errs[2], "Error loading config file "+bad+`: plugin inputs.check_mock: line 3: configuration specified the fields ["not_a_field"], but they weren't used`)
+}
+
+func TestCheckFilesMultipleFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	main := filepath.Join(dir, "telegraf.conf")
+	require.NoError(t, ioutil.WriteFile(main, []byte(`
+[agent]
+  interval = "10s"
+`), 0644))
+
+	// The inputs and outputs are counted across the files.
+	confDir := filepath.Join(dir, "telegraf.d")
+	require.NoError(t, os.Mkdir(confDir, 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, "inputs.conf"), []byte(`
+[[inputs.check_mock]]
+  directory = "`+filepath.ToSlash(dir)+`"
+`), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, "outputs.conf"), []byte(`
+[[outputs.http]]
+  url = "http://localhost"
+`), 0644))
+
+	require.Empty(t, CheckFiles(main, confDir, nil, nil))
+}
+
+func TestCheckFilesWithoutOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	main := filepath.Join(dir, "telegraf.conf")
+	require.NoError(t, ioutil.WriteFile(main, []byte(`
+[[inputs.check_mock]]
+`), 0644))
+
+	errs := CheckFiles(main, "", nil, nil)
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], "no outputs found")
+}
@@ -657,6 +657,22 @@ func PrintOutputConfig(name string) error {
 
 // LoadDirectory loads all toml config files found in the specified path, recursively.
 func (c *Config) LoadDirectory(path string) error {
+	files, err := directoryFiles(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := c.LoadConfig(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// directoryFiles returns the configuration files of the directory and its
+// subdirectories.
+func directoryFiles(path string) ([]string, error) {
+	var files []string
 	walkfn := func(thispath string, info os.FileInfo, _ error) error {
 		if info == nil {
 			log.Printf("W! Telegraf is not permitted to read %s", thispath)
@@ -675,13 +691,11 @@ func (c *Config) LoadDirectory(path string) error {
 		if len(name) < 6 || name[len(name)-5:] != ".conf" {
 			return nil
 		}
-		err := c.LoadConfig(thispath)
-		if err != nil {
-			return err
-		}
+		files = append(files, thispath)
 		return nil
 	}
-	return filepath.Walk(path, walkfn)
+	err := filepath.Walk(path, walkfn)
+	return files, err
 }
 
 // Try to find a default config file at these locations (in order):
@@ -760,6 +774,9 @@ func (c *Config) LoadConfigData(data []byte) error {
 		if err = c.toml.UnmarshalTable(subTable, c.Agent); err != nil {
 			return fmt.Errorf("error parsing [agent]: %w", err)
 		}
+		if err := c.unusedFieldsError(subTable); err != nil {
+			return err
+		}
 	}
 
 	if !c.Agent.OmitHostname {
@@ -775,10 +792,6 @@ func (c *Config) LoadConfigData(data []byte) error {
 		c.Tags["host"] = c.Agent.Hostname
 	}
 
-	if len(c.UnusedFields) > 0 {
-		return fmt.Errorf("line %d: configuration specified the fields %q, but they weren't used", tbl.Line, keys(c.UnusedFields))
-	}
-
 	// Parse all the rest of the plugins:
 	for name, val := range tbl.Fields {
 		subTable, ok := val.(*ast.Table)
@@ -796,19 +809,22 @@ func (c *Config) LoadConfigData(data []byte) error {
 					if err = c.addOutput(pluginName, pluginSubTable); err != nil {
 						return fmt.Errorf("error parsing %s, %w", pluginName, err)
 					}
+					if err = c.unusedFieldsError(pluginSubTable); err != nil {
+						return fmt.Errorf("plugin %s.%s: %w", name, pluginName, err)
+					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addOutput(pluginName, t); err != nil {
 							return fmt.Errorf("error parsing %s array, %w", pluginName, err)
 						}
+						if err = c.unusedFieldsError(t); err != nil {
+							return fmt.Errorf("plugin %s.%s: %w", name, pluginName, err)
+						}
 					}
 				default:
 					return fmt.Errorf("unsupported config format: %s",
 						pluginName)
 				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
-				}
 			}
 		case "inputs", "plugins":
 			for pluginName, pluginVal := range subTable.Fields {
@@ -818,19 +834,22 @@ func (c *Config) LoadConfigData(data []byte) error {
 					if err = c.addInput(pluginName, pluginSubTable); err != nil {
 						return fmt.Errorf("error parsing %s, %w", pluginName, err)
 					}
+					if err = c.unusedFieldsError(pluginSubTable); err != nil {
+						return fmt.Errorf("plugin %s.%s: %w", name, pluginName, err)
+					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addInput(pluginName, t); err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
+						if err = c.unusedFieldsError(t); err != nil {
+							return fmt.Errorf("plugin %s.%s: %w", name, pluginName, err)
+						}
 					}
 				default:
 					return fmt.Errorf("Unsupported config format: %s",
 						pluginName)
 				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
-				}
 			}
 		case "processors":
 			for pluginName, pluginVal := range subTable.Fields {
@@ -840,14 +859,14 @@ func (c *Config) LoadConfigData(data []byte) error {
 						if err = c.addProcessor(pluginName, t); err != nil {
 							return fmt.Errorf("error parsing %s, %w", pluginName, err)
 						}
+						if err = c.unusedFieldsError(t); err != nil {
+							return fmt.Errorf("plugin %s.%s: %w", name, pluginName, err)
+						}
 					}
 				default:
 					return fmt.Errorf("Unsupported config format: %s",
 						pluginName)
 				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
-				}
 			}
 		case "aggregators":
 			for pluginName, pluginVal := range subTable.Fields {
@@ -857,14 +876,14 @@ func (c *Config) LoadConfigData(data []byte) error {
 						if err = c.addAggregator(pluginName, t); err != nil {
 							return fmt.Errorf("Error parsing %s, %s", pluginName, err)
 						}
+						if err = c.unusedFieldsError(t); err != nil {
+							return fmt.Errorf("plugin %s.%s: %w", name, pluginName, err)
+						}
 					}
 				default:
 					return fmt.Errorf("Unsupported config format: %s",
 						pluginName)
 				}
-				if len(c.UnusedFields) > 0 {
-					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
-				}
 			}
 		// Assume it's an input input for legacy config file support if no other
 		// identifiers are present
@@ -872,6 +891,9 @@ func (c *Config) LoadConfigData(data []byte) error {
 			if err = c.addInput(name, subTable); err != nil {
 				return fmt.Errorf("Error parsing %s, %s", name, err)
 			}
+			if err = c.unusedFieldsError(subTable); err != nil {
+				return fmt.Errorf("plugin %s: %w", name, err)
+			}
 		}
 	}
 
@@ -980,6 +1002,9 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, aggregator); err != nil {
 		return err
 	}
+	if err := checkRequired(table, aggregator); err != nil {
+		return err
+	}
 
 	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
 	return nil
@@ -1024,10 +1049,16 @@ func (c *Config) newRunningProcessor(
 		if err := c.toml.UnmarshalTable(table, p.Unwrap()); err != nil {
 			return nil, err
 		}
+		if err := checkRequired(table, p.Unwrap()); err != nil {
+			return nil, err
+		}
 	} else {
 		if err := c.toml.UnmarshalTable(table, processor); err != nil {
 			return nil, err
 		}
+		if err := checkRequired(table, processor); err != nil {
+			return nil, err
+		}
 	}
 
 	rf := models.NewRunningProcessor(processor, processorConfig)
@@ -1063,6 +1094,9 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, output); err != nil {
 		return err
 	}
+	if err := checkRequired(table, output); err != nil {
+		return err
+	}
 
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
@@ -1100,8 +1134,13 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		if err != nil {
 			return err
 		}
+		tags := parserStatsTags(name, config.DataFormat, table)
 		t.SetParserFunc(func() (parsers.Parser, error) {
-			return parsers.NewParser(config)
+			parser, err := parsers.NewParser(config)
+			if err != nil {
+				return nil, err
+			}
+			return parsers.NewStatsParser(parser, tags), nil
 		})
 	}
 
@@ -1113,6 +1152,9 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, input); err != nil {
 		return err
 	}
+	if err := checkRequired(table, input); err != nil {
+		return err
+	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
 	rp.SetDefaultTags(c.Tags)
@@ -1120,6 +1162,20 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	return nil
 }
 
+// parserStatsTags returns the tags identifying the parser of an input in the
+// selfstats.
+func parserStatsTags(name, dataFormat string, tbl *ast.Table) map[string]string {
+	tags := map[string]string{"input": name, "data_format": dataFormat}
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				tags["alias"] = str.Value
+			}
+		}
+	}
+	return tags
+}
+
 // buildAggregator parses Aggregator specific items from the ast.Table,
 // builds the filter and returns a
 // models.AggregatorConfig to be inserted into models.RunningAggregator
@@ -1251,13 +1307,17 @@ func (c *Config) buildInput(name string, tbl *ast.Table) (*models.InputConfig, e
 
 // buildParser grabs the necessary entries from the ast.Table for creating
 // a parsers.Parser object, and creates it, which can then be added onto
-// an Input object.
+// an Input object.  The parser records its selfstats.
 func (c *Config) buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
 	config, err := c.getParserConfig(name, tbl)
 	if err != nil {
 		return nil, err
 	}
-	return parsers.NewParser(config)
+	parser, err := parsers.NewParser(config)
+	if err != nil {
+		return nil, err
+	}
+	return parsers.NewStatsParser(parser, parserStatsTags(name, config.DataFormat, tbl)), nil
 }
 
 func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
@@ -1274,6 +1334,23 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 		pc.DataFormat = "influx"
 	}
 
+	if node, ok := tbl.Fields["data_transform"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			pc.DataTransforms = make([]parsers.DataTransform, len(subtbls))
+			for i, subtbl := range subtbls {
+				subcfg := &pc.DataTransforms[i]
+				c.getFieldString(subtbl, "type", &subcfg.Type)
+				c.getFieldString(subtbl, "encoding", &subcfg.Encoding)
+				c.getFieldString(subtbl, "path", &subcfg.Path)
+			}
+		}
+	}
+
+	c.getFieldMap(tbl, "data_default_fields", &pc.DataDefaultFields)
+	c.getFieldStringMap(tbl, "data_default_tags", &pc.DataDefaultTags)
+	c.getFieldMap(tbl, "data_constant_fields", &pc.DataConstantFields)
+	c.getFieldStringMap(tbl, "data_constant_tags", &pc.DataConstantTags)
+
 	c.getFieldString(tbl, "separator", &pc.Separator)
 
 	c.getFieldStringSlice(tbl, "templates", &pc.Templates)
@@ -1281,11 +1358,16 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 	c.getFieldStringSlice(tbl, "json_string_fields", &pc.JSONStringFields)
 	c.getFieldString(tbl, "json_name_key", &pc.JSONNameKey)
 	c.getFieldString(tbl, "json_query", &pc.JSONQuery)
+	c.getFieldString(tbl, "json_path", &pc.JSONPath)
 	c.getFieldString(tbl, "json_time_key", &pc.JSONTimeKey)
+	c.getFieldString(tbl, "json_time_path", &pc.JSONTimePath)
 	c.getFieldString(tbl, "json_time_format", &pc.JSONTimeFormat)
 	c.getFieldString(tbl, "json_timezone", &pc.JSONTimezone)
 	c.getFieldBool(tbl, "json_strict", &pc.JSONStrict)
+	c.getFieldStringSlice(tbl, "json_expand_arrays", &pc.JSONExpandArrays)
 	c.getFieldString(tbl, "data_type", &pc.DataType)
+	c.getFieldStringSlice(tbl, "value_field_names", &pc.ValueFieldNames)
+	c.getFieldString(tbl, "value_separator", &pc.ValueSeparator)
 	c.getFieldString(tbl, "collectd_auth_file", &pc.CollectdAuthFile)
 	c.getFieldString(tbl, "collectd_security_level", &pc.CollectdSecurityLevel)
 	c.getFieldString(tbl, "collectd_parse_multivalue", &pc.CollectdSplit)
@@ -1304,6 +1386,8 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 	c.getFieldString(tbl, "grok_custom_patterns", &pc.GrokCustomPatterns)
 	c.getFieldStringSlice(tbl, "grok_custom_pattern_files", &pc.GrokCustomPatternFiles)
 	c.getFieldString(tbl, "grok_timezone", &pc.GrokTimezone)
+	c.getFieldStringMap(tbl, "grok_timezones", &pc.GrokTimezones)
+	c.getFieldStringSlice(tbl, "grok_tag_keys", &pc.GrokTagKeys)
 	c.getFieldString(tbl, "grok_unique_timestamp", &pc.GrokUniqueTimestamp)
 
 	//for csv parser
@@ -1313,17 +1397,237 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 	c.getFieldString(tbl, "csv_timezone", &pc.CSVTimezone)
 	c.getFieldString(tbl, "csv_delimiter", &pc.CSVDelimiter)
 	c.getFieldString(tbl, "csv_comment", &pc.CSVComment)
+	c.getFieldString(tbl, "csv_comment_prefix", &pc.CSVCommentPrefix)
+	c.getFieldString(tbl, "csv_quote", &pc.CSVQuote)
+	c.getFieldString(tbl, "csv_escape", &pc.CSVEscape)
 	c.getFieldString(tbl, "csv_measurement_column", &pc.CSVMeasurementColumn)
 	c.getFieldString(tbl, "csv_timestamp_column", &pc.CSVTimestampColumn)
 	c.getFieldString(tbl, "csv_timestamp_format", &pc.CSVTimestampFormat)
 	c.getFieldInt(tbl, "csv_header_row_count", &pc.CSVHeaderRowCount)
 	c.getFieldInt(tbl, "csv_skip_rows", &pc.CSVSkipRows)
 	c.getFieldInt(tbl, "csv_skip_columns", &pc.CSVSkipColumns)
+	c.getFieldInt(tbl, "csv_skip_footer_rows", &pc.CSVSkipFooterRows)
 	c.getFieldBool(tbl, "csv_trim_space", &pc.CSVTrimSpace)
 	c.getFieldStringSlice(tbl, "csv_skip_values", &pc.CSVSkipValues)
+	c.getFieldStringMap(tbl, "csv_column_type_map", &pc.CSVColumnTypeMap)
+	c.getFieldStringSlice(tbl, "csv_null_values", &pc.CSVNullValues)
 
 	c.getFieldStringSlice(tbl, "form_urlencoded_tag_keys", &pc.FormUrlencodedTagKeys)
 
+	//for avro parser
+	c.getFieldString(tbl, "avro_schema", &pc.AvroSchema)
+	c.getFieldString(tbl, "avro_schema_file", &pc.AvroSchemaFile)
+	c.getFieldString(tbl, "avro_schema_registry", &pc.AvroSchemaRegistry)
+	c.getFieldString(tbl, "avro_measurement_field", &pc.AvroMeasurementField)
+	c.getFieldStringSlice(tbl, "avro_tags", &pc.AvroTags)
+	c.getFieldStringSlice(tbl, "avro_fields", &pc.AvroFields)
+	c.getFieldString(tbl, "avro_timestamp_field", &pc.AvroTimestampField)
+	c.getFieldString(tbl, "avro_timestamp_format", &pc.AvroTimestampFormat)
+	c.getFieldString(tbl, "avro_timezone", &pc.AvroTimezone)
+	c.getFieldString(tbl, "avro_field_separator", &pc.AvroFieldSeparator)
+
+	//for parquet parser
+	c.getFieldStringSlice(tbl, "parquet_columns", &pc.ParquetColumns)
+	c.getFieldStringSlice(tbl, "parquet_tag_columns", &pc.ParquetTagColumns)
+	c.getFieldString(tbl, "parquet_measurement_column", &pc.ParquetMeasurementColumn)
+	c.getFieldString(tbl, "parquet_timestamp_column", &pc.ParquetTimestampColumn)
+	c.getFieldString(tbl, "parquet_timestamp_format", &pc.ParquetTimestampFormat)
+	c.getFieldString(tbl, "parquet_timezone", &pc.ParquetTimezone)
+
+	//for orc parser
+	c.getFieldStringSlice(tbl, "orc_columns", &pc.OrcColumns)
+	c.getFieldStringSlice(tbl, "orc_tag_columns", &pc.OrcTagColumns)
+	c.getFieldString(tbl, "orc_measurement_column", &pc.OrcMeasurementColumn)
+	c.getFieldString(tbl, "orc_timestamp_column", &pc.OrcTimestampColumn)
+	c.getFieldString(tbl, "orc_timestamp_format", &pc.OrcTimestampFormat)
+	c.getFieldString(tbl, "orc_timezone", &pc.OrcTimezone)
+
+	//for xlsx parser
+	c.getFieldStringSlice(tbl, "xlsx_sheets", &pc.XLSXSheets)
+	c.getFieldString(tbl, "xlsx_sheet_tag", &pc.XLSXSheetTag)
+	c.getFieldInt(tbl, "xlsx_header_row", &pc.XLSXHeaderRow)
+	c.getFieldInt(tbl, "xlsx_skip_rows", &pc.XLSXSkipRows)
+	c.getFieldStringSlice(tbl, "xlsx_column_names", &pc.XLSXColumnNames)
+	c.getFieldStringSlice(tbl, "xlsx_column_types", &pc.XLSXColumnTypes)
+	c.getFieldStringSlice(tbl, "xlsx_tag_columns", &pc.XLSXTagColumns)
+	c.getFieldString(tbl, "xlsx_measurement_column", &pc.XLSXMeasurementColumn)
+	c.getFieldString(tbl, "xlsx_timestamp_column", &pc.XLSXTimestampColumn)
+	c.getFieldString(tbl, "xlsx_timestamp_format", &pc.XLSXTimestampFormat)
+	c.getFieldString(tbl, "xlsx_timezone", &pc.XLSXTimezone)
+
+	//for protobuf parser
+	c.getFieldString(tbl, "protobuf_descriptor_set", &pc.ProtobufDescriptorSet)
+	c.getFieldStringSlice(tbl, "protobuf_files", &pc.ProtobufFiles)
+	c.getFieldStringSlice(tbl, "protobuf_import_paths", &pc.ProtobufImportPaths)
+	c.getFieldString(tbl, "protobuf_message_type", &pc.ProtobufMessageType)
+	c.getFieldString(tbl, "protobuf_framing", &pc.ProtobufFraming)
+	c.getFieldString(tbl, "protobuf_measurement_field", &pc.ProtobufMeasurementField)
+	c.getFieldStringSlice(tbl, "protobuf_tags", &pc.ProtobufTags)
+	c.getFieldStringSlice(tbl, "protobuf_fields", &pc.ProtobufFields)
+	c.getFieldString(tbl, "protobuf_timestamp_field", &pc.ProtobufTimestampField)
+	c.getFieldString(tbl, "protobuf_timestamp_format", &pc.ProtobufTimestampFormat)
+	c.getFieldString(tbl, "protobuf_timezone", &pc.ProtobufTimezone)
+	c.getFieldString(tbl, "protobuf_field_separator", &pc.ProtobufFieldSeparator)
+
+	//for xml parser
+	if node, ok := tbl.Fields["xml"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			pc.XMLConfig = make([]parsers.XMLConfig, len(subtbls))
+			for i, subtbl := range subtbls {
+				subcfg := &pc.XMLConfig[i]
+				c.getFieldString(subtbl, "metric_name", &subcfg.MetricName)
+				c.getFieldString(subtbl, "metric_selection", &subcfg.Selection)
+				c.getFieldString(subtbl, "timestamp", &subcfg.Timestamp)
+				c.getFieldString(subtbl, "timestamp_format", &subcfg.TimestampFormat)
+				c.getFieldString(subtbl, "timezone", &subcfg.Timezone)
+				c.getFieldStringMap(subtbl, "tags", &subcfg.Tags)
+				c.getFieldStringMap(subtbl, "fields", &subcfg.Fields)
+				c.getFieldStringMap(subtbl, "fields_int", &subcfg.FieldsInt)
+				c.getFieldString(subtbl, "field_selection", &subcfg.FieldSelection)
+				c.getFieldString(subtbl, "field_name", &subcfg.FieldName)
+				c.getFieldString(subtbl, "field_value", &subcfg.FieldValue)
+				c.getFieldBool(subtbl, "field_name_expansion", &subcfg.FieldNameExpand)
+			}
+		}
+	}
+
+	//for msgpack parser
+	c.getFieldStringSlice(tbl, "msgpack_tag_keys", &pc.MsgpackTagKeys)
+	c.getFieldString(tbl, "msgpack_name_key", &pc.MsgpackNameKey)
+	c.getFieldStringSlice(tbl, "msgpack_string_fields", &pc.MsgpackStringFields)
+	c.getFieldString(tbl, "msgpack_time_key", &pc.MsgpackTimeKey)
+	c.getFieldString(tbl, "msgpack_time_format", &pc.MsgpackTimeFormat)
+	c.getFieldString(tbl, "msgpack_timezone", &pc.MsgpackTimezone)
+	c.getFieldBool(tbl, "msgpack_strict", &pc.MsgpackStrict)
+
+	//for cbor parser
+	c.getFieldStringSlice(tbl, "cbor_tag_keys", &pc.CBORTagKeys)
+	c.getFieldString(tbl, "cbor_name_key", &pc.CBORNameKey)
+	c.getFieldStringSlice(tbl, "cbor_string_fields", &pc.CBORStringFields)
+	c.getFieldString(tbl, "cbor_time_key", &pc.CBORTimeKey)
+	c.getFieldString(tbl, "cbor_time_format", &pc.CBORTimeFormat)
+	c.getFieldString(tbl, "cbor_timezone", &pc.CBORTimezone)
+	c.getFieldBool(tbl, "cbor_strict", &pc.CBORStrict)
+
+	//for cef parser
+	c.getFieldStringSlice(tbl, "cef_tag_keys", &pc.CEFTagKeys)
+	c.getFieldBool(tbl, "cef_resolve_labels", &pc.CEFResolveLabels)
+	c.getFieldString(tbl, "cef_timestamp_key", &pc.CEFTimestampKey)
+	c.getFieldString(tbl, "cef_timestamp_format", &pc.CEFTimestampFormat)
+	c.getFieldString(tbl, "cef_timezone", &pc.CEFTimezone)
+
+	//for leef parser
+	c.getFieldString(tbl, "leef_delimiter", &pc.LEEFDelimiter)
+	c.getFieldStringSlice(tbl, "leef_tag_keys", &pc.LEEFTagKeys)
+	c.getFieldString(tbl, "leef_timestamp_key", &pc.LEEFTimestampKey)
+	c.getFieldString(tbl, "leef_timestamp_format", &pc.LEEFTimestampFormat)
+	c.getFieldString(tbl, "leef_timezone", &pc.LEEFTimezone)
+
+	//for ltsv parser
+	c.getFieldStringSlice(tbl, "ltsv_tag_keys", &pc.LTSVTagKeys)
+	c.getFieldStringMap(tbl, "ltsv_field_types", &pc.LTSVFieldTypes)
+	c.getFieldString(tbl, "ltsv_timestamp_label", &pc.LTSVTimestampLabel)
+	c.getFieldString(tbl, "ltsv_timestamp_format", &pc.LTSVTimestampFormat)
+	c.getFieldString(tbl, "ltsv_timezone", &pc.LTSVTimezone)
+
+	//for w3c parser
+	c.getFieldStringSlice(tbl, "w3c_fields", &pc.W3CFields)
+	c.getFieldStringSlice(tbl, "w3c_tag_keys", &pc.W3CTagKeys)
+	c.getFieldString(tbl, "w3c_timezone", &pc.W3CTimezone)
+
+	//for access_log parser
+	c.getFieldString(tbl, "access_log_format", &pc.AccessLogFormat)
+
+	//for fixed_width parser
+	c.getFieldStringSlice(tbl, "fixed_width_column_names", &pc.FixedWidthColumnNames)
+	c.getFieldIntSlice(tbl, "fixed_width_column_widths", &pc.FixedWidthColumnWidths)
+	c.getFieldIntSlice(tbl, "fixed_width_column_offsets", &pc.FixedWidthColumnOffsets)
+	c.getFieldStringSlice(tbl, "fixed_width_column_types", &pc.FixedWidthColumnTypes)
+	c.getFieldStringSlice(tbl, "fixed_width_tag_columns", &pc.FixedWidthTagColumns)
+	c.getFieldString(tbl, "fixed_width_measurement_column", &pc.FixedWidthMeasurementColumn)
+	c.getFieldString(tbl, "fixed_width_timestamp_column", &pc.FixedWidthTimestampColumn)
+	c.getFieldString(tbl, "fixed_width_timestamp_format", &pc.FixedWidthTimestampFormat)
+	c.getFieldString(tbl, "fixed_width_timezone", &pc.FixedWidthTimezone)
+	c.getFieldInt(tbl, "fixed_width_skip_rows", &pc.FixedWidthSkipRows)
+	c.getFieldStringSlice(tbl, "fixed_width_skip_values", &pc.FixedWidthSkipValues)
+
+	//for ndjson parser
+	c.getFieldStringSlice(tbl, "ndjson_tag_keys", &pc.NDJSONTagKeys)
+	c.getFieldString(tbl, "ndjson_name_key", &pc.NDJSONNameKey)
+	c.getFieldStringSlice(tbl, "ndjson_string_fields", &pc.NDJSONStringFields)
+	c.getFieldString(tbl, "ndjson_time_key", &pc.NDJSONTimeKey)
+	c.getFieldString(tbl, "ndjson_time_format", &pc.NDJSONTimeFormat)
+	c.getFieldString(tbl, "ndjson_timezone", &pc.NDJSONTimezone)
+	c.getFieldInt(tbl, "ndjson_max_line_bytes", &pc.NDJSONMaxLineBytes)
+	c.getFieldBool(tbl, "ndjson_strict", &pc.NDJSONStrict)
+
+	//for yaml parser
+	c.getFieldStringSlice(tbl, "yaml_tag_keys", &pc.YAMLTagKeys)
+	c.getFieldString(tbl, "yaml_name_key", &pc.YAMLNameKey)
+	c.getFieldStringSlice(tbl, "yaml_string_fields", &pc.YAMLStringFields)
+	c.getFieldString(tbl, "yaml_time_key", &pc.YAMLTimeKey)
+	c.getFieldString(tbl, "yaml_time_format", &pc.YAMLTimeFormat)
+	c.getFieldString(tbl, "yaml_timezone", &pc.YAMLTimezone)
+	c.getFieldBool(tbl, "yaml_strict", &pc.YAMLStrict)
+
+	//for syslog parser
+	c.getFieldString(tbl, "syslog_timezone", &pc.SyslogTimezone)
+	c.getFieldBool(tbl, "syslog_best_effort", &pc.SyslogBestEffort)
+	c.getFieldString(tbl, "syslog_sdparam_separator", &pc.SyslogSDParamSeparator)
+
+	//for evtx parser
+	c.getFieldStringSlice(tbl, "evtx_event_tags", &pc.EVTXEventTags)
+	c.getFieldStringSlice(tbl, "evtx_event_fields", &pc.EVTXEventFields)
+	c.getFieldStringSlice(tbl, "evtx_exclude_fields", &pc.EVTXExcludeFields)
+	c.getFieldString(tbl, "evtx_separator", &pc.EVTXSeparator)
+
+	//for hl7 parser
+	c.getFieldStringMap(tbl, "hl7_tags", &pc.HL7Tags)
+	c.getFieldStringMap(tbl, "hl7_fields", &pc.HL7Fields)
+	c.getFieldString(tbl, "hl7_timezone", &pc.HL7Timezone)
+
+	//for fix parser
+	c.getFieldString(tbl, "fix_dictionary", &pc.FIXDictionary)
+	c.getFieldString(tbl, "fix_delimiter", &pc.FIXDelimiter)
+	c.getFieldStringSlice(tbl, "fix_tag_keys", &pc.FIXTagKeys)
+
+	//for pcap parser
+	c.getFieldString(tbl, "pcap_mode", &pc.PcapMode)
+
+	//for netflow parser
+	c.getFieldStringSlice(tbl, "netflow_tag_keys", &pc.NetFlowTagKeys)
+
+	//for binary parser
+	c.getFieldString(tbl, "binary_endianness", &pc.BinaryEndianness)
+	c.getFieldInt(tbl, "binary_header_length", &pc.BinaryHeaderLength)
+	c.getFieldInt(tbl, "binary_record_length", &pc.BinaryRecordLength)
+	if node, ok := tbl.Fields["binary_field"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			pc.BinaryFields = make([]parsers.BinaryField, len(subtbls))
+			for i, subtbl := range subtbls {
+				subcfg := &pc.BinaryFields[i]
+				// Fields without an offset follow the previous field.
+				subcfg.Offset = -1
+				c.getFieldString(subtbl, "name", &subcfg.Name)
+				c.getFieldString(subtbl, "type", &subcfg.Type)
+				c.getFieldInt(subtbl, "offset", &subcfg.Offset)
+				c.getFieldInt(subtbl, "length", &subcfg.Length)
+				c.getFieldInt(subtbl, "count", &subcfg.Count)
+				c.getFieldString(subtbl, "endianness", &subcfg.Endianness)
+			}
+		}
+	}
+	c.getFieldStringSlice(tbl, "binary_tag_fields", &pc.BinaryTagFields)
+	c.getFieldString(tbl, "binary_measurement_field", &pc.BinaryMeasurementField)
+	c.getFieldString(tbl, "binary_timestamp_field", &pc.BinaryTimestampField)
+	c.getFieldString(tbl, "binary_timestamp_format", &pc.BinaryTimestampFormat)
+	c.getFieldString(tbl, "binary_timezone", &pc.BinaryTimezone)
+
+	//for execd parser
+	c.getFieldStringSlice(tbl, "execd_command", &pc.ExecdCommand)
+	c.getFieldDuration(tbl, "execd_restart_delay", &pc.ExecdRestartDelay)
+	c.getFieldDuration(tbl, "execd_timeout", &pc.ExecdTimeout)
+
 	pc.MetricName = name
 
 	if c.hasErrs() {
@@ -1347,8 +1651,44 @@ func (c *Config) buildSerializer(name string, tbl *ast.Table) (serializers.Seria
 
 	c.getFieldString(tbl, "prefix", &sc.Prefix)
 	c.getFieldString(tbl, "template", &sc.Template)
+	c.getFieldString(tbl, "template_batch", &sc.TemplateBatch)
 	c.getFieldStringSlice(tbl, "templates", &sc.Templates)
 	c.getFieldString(tbl, "carbon2_format", &sc.Carbon2Format)
+
+	c.getFieldString(tbl, "csv_separator", &sc.CSVSeparator)
+	c.getFieldBool(tbl, "csv_header", &sc.CSVHeader)
+	c.getFieldStringSlice(tbl, "csv_columns", &sc.CSVColumns)
+	c.getFieldString(tbl, "csv_timestamp_format", &sc.CSVTimestampFormat)
+	c.getFieldString(tbl, "csv_timezone", &sc.CSVTimezone)
+
+	c.getFieldString(tbl, "parquet_timestamp_column", &sc.ParquetTimestampColumn)
+	c.getFieldString(tbl, "parquet_measurement_column", &sc.ParquetMeasurementColumn)
+	c.getFieldStringSlice(tbl, "parquet_columns", &sc.ParquetColumns)
+	c.getFieldStringMap(tbl, "parquet_column_types", &sc.ParquetColumnTypes)
+	c.getFieldSize(tbl, "parquet_row_group_size", &sc.ParquetRowGroupSize)
+	c.getFieldSize(tbl, "parquet_page_size", &sc.ParquetPageSize)
+	c.getFieldString(tbl, "parquet_compression", &sc.ParquetCompression)
+	c.getFieldStringMap(tbl, "parquet_column_compression", &sc.ParquetColumnCompression)
+	c.getFieldStringSlice(tbl, "parquet_dictionary_columns", &sc.ParquetDictionaryColumns)
+
+	c.getFieldString(tbl, "avro_schema", &sc.AvroSchema)
+	c.getFieldString(tbl, "avro_schema_file", &sc.AvroSchemaFile)
+	c.getFieldString(tbl, "avro_schema_registry", &sc.AvroSchemaRegistry)
+	c.getFieldString(tbl, "avro_schema_subject", &sc.AvroSchemaSubject)
+	c.getFieldBool(tbl, "avro_schema_register", &sc.AvroSchemaRegister)
+	c.getFieldString(tbl, "avro_encoding", &sc.AvroEncoding)
+	c.getFieldString(tbl, "avro_measurement_field", &sc.AvroMeasurementField)
+	c.getFieldString(tbl, "avro_timestamp_field", &sc.AvroTimestampField)
+	c.getFieldString(tbl, "avro_timestamp_format", &sc.AvroTimestampFormat)
+	c.getFieldString(tbl, "avro_timezone", &sc.AvroTimezone)
+
+	c.getFieldString(tbl, "xlsx_layout", &sc.XlsxLayout)
+	c.getFieldString(tbl, "xlsx_sheet_name", &sc.XlsxSheetName)
+	c.getFieldStringSlice(tbl, "xlsx_columns", &sc.XlsxColumns)
+	c.getFieldString(tbl, "xlsx_timestamp_column", &sc.XlsxTimestampColumn)
+	c.getFieldString(tbl, "xlsx_measurement_column", &sc.XlsxMeasurementColumn)
+	c.getFieldString(tbl, "xlsx_timezone", &sc.XlsxTimezone)
+
 	c.getFieldInt(tbl, "influx_max_line_bytes", &sc.InfluxMaxLineBytes)
 
 	c.getFieldBool(tbl, "influx_sort_fields", &sc.InfluxSortFields)
@@ -1396,6 +1736,8 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 
 	c.getFieldInt(tbl, "metric_buffer_limit", &oc.MetricBufferLimit)
 	c.getFieldInt(tbl, "metric_batch_size", &oc.MetricBatchSize)
+	c.getFieldString(tbl, "spool_directory", &oc.SpoolDirectory)
+	c.getFieldSize(tbl, "spool_limit", &oc.SpoolLimit)
 	c.getFieldString(tbl, "alias", &oc.Alias)
 	c.getFieldString(tbl, "name_override", &oc.NameOverride)
 	c.getFieldString(tbl, "name_suffix", &oc.NameSuffix)
@@ -1410,25 +1752,62 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 
 func (c *Config) missingTomlField(typ reflect.Type, key string) error {
 	switch key {
-	case "alias", "carbon2_format", "collectd_auth_file", "collectd_parse_multivalue",
+	case "access_log_format", "alias", "avro_encoding", "avro_field_separator", "avro_fields", "avro_measurement_field",
+		"avro_schema", "avro_schema_file", "avro_schema_register", "avro_schema_registry", "avro_schema_subject",
+		"avro_tags", "avro_timestamp_field",
+		"avro_timestamp_format", "avro_timezone", "binary_endianness", "binary_field",
+		"binary_header_length", "binary_measurement_field", "binary_record_length", "binary_tag_fields",
+		"binary_timestamp_field", "binary_timestamp_format", "binary_timezone", "carbon2_format", "cbor_name_key", "cbor_strict",
+		"cbor_string_fields", "cbor_tag_keys", "cbor_time_format", "cbor_time_key", "cbor_timezone",
+		"cef_resolve_labels", "cef_tag_keys", "cef_timestamp_format", "cef_timestamp_key", "cef_timezone", "collectd_auth_file", "collectd_parse_multivalue",
 		"collectd_security_level", "collectd_typesdb", "collection_jitter", "csv_column_names",
-		"csv_column_types", "csv_comment", "csv_delimiter", "csv_header_row_count",
-		"csv_measurement_column", "csv_skip_columns", "csv_skip_rows", "csv_tag_columns",
+		"csv_column_type_map", "csv_columns", "csv_column_types", "csv_comment", "csv_comment_prefix", "csv_delimiter",
+		"csv_escape", "csv_header", "csv_header_row_count", "csv_measurement_column", "csv_null_values", "csv_quote",
+		"csv_separator", "csv_skip_columns", "csv_skip_footer_rows", "csv_skip_rows", "csv_tag_columns",
 		"csv_timestamp_column", "csv_timestamp_format", "csv_timezone", "csv_trim_space", "csv_skip_values",
-		"data_format", "data_type", "delay", "drop", "drop_original", "dropwizard_metric_registry_path",
+		"data_constant_fields", "data_constant_tags", "data_default_fields", "data_default_tags",
+		"data_format", "data_transform", "data_type", "delay", "drop", "drop_original", "dropwizard_metric_registry_path",
 		"dropwizard_tag_paths", "dropwizard_tags_path", "dropwizard_time_format", "dropwizard_time_path",
-		"fielddrop", "fieldpass", "flush_interval", "flush_jitter", "form_urlencoded_tag_keys",
+		"evtx_event_fields", "evtx_event_tags", "evtx_exclude_fields", "evtx_separator", "execd_command",
+		"execd_restart_delay", "execd_timeout",
+		"fielddrop", "fieldpass", "fix_delimiter", "fix_dictionary", "fix_tag_keys",
+		"fixed_width_column_names", "fixed_width_column_offsets",
+		"fixed_width_column_types", "fixed_width_column_widths", "fixed_width_measurement_column",
+		"fixed_width_skip_rows", "fixed_width_skip_values", "fixed_width_tag_columns",
+		"fixed_width_timestamp_column", "fixed_width_timestamp_format", "fixed_width_timezone",
+		"flush_interval", "flush_jitter", "form_urlencoded_tag_keys",
 		"grace", "graphite_separator", "graphite_tag_support", "grok_custom_pattern_files",
-		"grok_custom_patterns", "grok_named_patterns", "grok_patterns", "grok_timezone",
-		"grok_unique_timestamp", "influx_max_line_bytes", "influx_sort_fields", "influx_uint_support",
-		"interval", "json_name_key", "json_query", "json_strict", "json_string_fields",
-		"json_time_format", "json_time_key", "json_timestamp_units", "json_timezone",
-		"metric_batch_size", "metric_buffer_limit", "name_override", "name_prefix",
-		"name_suffix", "namedrop", "namepass", "order", "pass", "period", "precision",
+		"grok_custom_patterns", "grok_named_patterns", "grok_patterns", "grok_tag_keys", "grok_timezone",
+		"grok_timezones", "grok_unique_timestamp", "hl7_fields", "hl7_tags", "hl7_timezone",
+		"influx_max_line_bytes", "influx_sort_fields", "influx_uint_support",
+		"interval", "json_expand_arrays", "json_name_key", "json_path", "json_query", "json_strict",
+		"json_string_fields", "json_time_format", "json_time_key", "json_time_path", "json_timestamp_units", "json_timezone", "leef_delimiter",
+		"leef_tag_keys", "leef_timestamp_format", "leef_timestamp_key", "leef_timezone", "ltsv_field_types",
+		"ltsv_tag_keys", "ltsv_timestamp_format", "ltsv_timestamp_label", "ltsv_timezone",
+		"metric_batch_size", "metric_buffer_limit", "msgpack_name_key", "msgpack_strict",
+		"msgpack_string_fields", "msgpack_tag_keys", "msgpack_time_format", "msgpack_time_key",
+		"msgpack_timezone", "name_override", "name_prefix",
+		"name_suffix", "namedrop", "namepass", "ndjson_max_line_bytes", "ndjson_name_key",
+		"ndjson_strict", "ndjson_string_fields", "ndjson_tag_keys", "ndjson_time_format",
+		"ndjson_time_key", "ndjson_timezone", "netflow_tag_keys", "orc_columns", "orc_measurement_column", "orc_tag_columns",
+		"orc_timestamp_column", "orc_timestamp_format", "orc_timezone", "order", "parquet_column_compression", "parquet_column_types",
+		"parquet_columns", "parquet_compression", "parquet_dictionary_columns", "parquet_measurement_column", "parquet_page_size", "parquet_row_group_size",
+		"parquet_tag_columns", "parquet_timestamp_column", "parquet_timestamp_format", "parquet_timezone",
+		"pass", "pcap_mode", "period", "precision",
 		"prefix", "prometheus_export_timestamp", "prometheus_sort_metrics", "prometheus_string_as_label",
-		"separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
-		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "template", "templates",
-		"wavefront_source_override", "wavefront_use_strict":
+		"protobuf_descriptor_set", "protobuf_field_separator", "protobuf_fields", "protobuf_files",
+		"protobuf_framing", "protobuf_import_paths", "protobuf_measurement_field", "protobuf_message_type",
+		"protobuf_tags", "protobuf_timestamp_field", "protobuf_timestamp_format", "protobuf_timezone",
+		"separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "spool_directory",
+		"spool_limit", "syslog_best_effort", "syslog_sdparam_separator", "syslog_timezone", "tag_keys",
+		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "template", "template_batch", "templates",
+		"value_field_names", "value_separator", "w3c_fields", "w3c_tag_keys", "w3c_timezone",
+		"wavefront_source_override", "wavefront_use_strict", "xlsx_column_names", "xlsx_column_types",
+		"xlsx_columns", "xlsx_header_row", "xlsx_layout", "xlsx_measurement_column", "xlsx_sheet_name",
+		"xlsx_sheet_tag", "xlsx_sheets", "xlsx_skip_rows",
+		"xlsx_tag_columns", "xlsx_timestamp_column", "xlsx_timestamp_format", "xlsx_timezone", "xml",
+		"yaml_name_key", "yaml_strict", "yaml_string_fields", "yaml_tag_keys", "yaml_time_format",
+		"yaml_time_key", "yaml_timezone":
 
 		// ignore fields that are common to all plugins.
 	default:
@@ -1440,9 +1819,12 @@ func (c *Config) missingTomlField(typ reflect.Type, key string) error {
 func (c *Config) getFieldString(tbl *ast.Table, fieldName string, target *string) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
-			if str, ok := kv.Value.(*ast.String); ok {
-				*target = str.Value
+			str, ok := kv.Value.(*ast.String)
+			if !ok {
+				c.addTypeError(kv, fieldName, "string")
+				return
 			}
+			*target = str.Value
 		}
 	}
 }
@@ -1450,15 +1832,34 @@ func (c *Config) getFieldString(tbl *ast.Table, fieldName string, target *string
 func (c *Config) getFieldDuration(tbl *ast.Table, fieldName string, target interface{}) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
-			if str, ok := kv.Value.(*ast.String); ok {
-				d, err := time.ParseDuration(str.Value)
-				if err != nil {
-					c.addError(tbl, fmt.Errorf("error parsing duration: %w", err))
-					return
-				}
-				targetVal := reflect.ValueOf(target).Elem()
-				targetVal.Set(reflect.ValueOf(d))
+			// Parse the same way as internal.Duration plugin settings, so
+			// integers are seconds.
+			switch kv.Value.(type) {
+			case *ast.String, *ast.Integer, *ast.Float:
+			default:
+				c.addTypeError(kv, fieldName, "duration")
+				return
+			}
+			var d internal.Duration
+			if err := d.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				c.addError(kv, fmt.Errorf("%s: error parsing duration: %w", fieldName, err))
+				return
 			}
+			targetVal := reflect.ValueOf(target).Elem()
+			targetVal.Set(reflect.ValueOf(d.Duration))
+		}
+	}
+}
+
+func (c *Config) getFieldSize(tbl *ast.Table, fieldName string, target *int64) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size internal.Size
+			if err := size.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				c.addError(kv, fmt.Errorf("%s: error parsing size: %w", fieldName, err))
+				return
+			}
+			*target = size.Size
 		}
 	}
 }
@@ -1471,17 +1872,17 @@ func (c *Config) getFieldBool(tbl *ast.Table, fieldName string, target *bool) {
 			case *ast.Boolean:
 				*target, err = t.Boolean()
 				if err != nil {
-					c.addError(tbl, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value))
+					c.addTypeError(kv, fieldName, "boolean")
 					return
 				}
 			case *ast.String:
 				*target, err = strconv.ParseBool(t.Value)
 				if err != nil {
-					c.addError(tbl, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value))
+					c.addTypeError(kv, fieldName, "boolean")
 					return
 				}
 			default:
-				c.addError(tbl, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value.Source()))
+				c.addTypeError(kv, fieldName, "boolean")
 				return
 			}
 		}
@@ -1489,60 +1890,100 @@ func (c *Config) getFieldBool(tbl *ast.Table, fieldName string, target *bool) {
 }
 
 func (c *Config) getFieldInt(tbl *ast.Table, fieldName string, target *int) {
+	var i int64
+	if c.getInteger(tbl, fieldName, &i) {
+		*target = int(i)
+	}
+}
+
+func (c *Config) getFieldInt64(tbl *ast.Table, fieldName string, target *int64) {
+	c.getInteger(tbl, fieldName, target)
+}
+
+// getInteger reads an integer field and returns true if it was set.
+func (c *Config) getInteger(tbl *ast.Table, fieldName string, target *int64) bool {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
-			if iAst, ok := kv.Value.(*ast.Integer); ok {
-				i, err := iAst.Int()
-				if err != nil {
-					c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting int", iAst.Value))
-					return
-				}
-				*target = int(i)
+			iAst, ok := kv.Value.(*ast.Integer)
+			if !ok {
+				c.addTypeError(kv, fieldName, "integer")
+				return false
 			}
+			i, err := iAst.Int()
+			if err != nil {
+				c.addTypeError(kv, fieldName, "integer")
+				return false
+			}
+			*target = i
+			return true
 		}
 	}
+	return false
 }
 
-func (c *Config) getFieldInt64(tbl *ast.Table, fieldName string, target *int64) {
+func (c *Config) getFieldStringSlice(tbl *ast.Table, fieldName string, target *[]string) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
-			if iAst, ok := kv.Value.(*ast.Integer); ok {
-				i, err := iAst.Int()
-				if err != nil {
-					c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting int", iAst.Value))
+			ary, ok := kv.Value.(*ast.Array)
+			if !ok {
+				c.addTypeError(kv, fieldName, "array of strings")
+				return
+			}
+			for _, elem := range ary.Value {
+				str, ok := elem.(*ast.String)
+				if !ok {
+					c.addTypeError(kv, fieldName, "array of strings")
 					return
 				}
-				*target = i
+				*target = append(*target, str.Value)
 			}
 		}
 	}
 }
 
-func (c *Config) getFieldStringSlice(tbl *ast.Table, fieldName string, target *[]string) {
+func (c *Config) getFieldIntSlice(tbl *ast.Table, fieldName string, target *[]int) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
-			if ary, ok := kv.Value.(*ast.Array); ok {
-				for _, elem := range ary.Value {
-					if str, ok := elem.(*ast.String); ok {
-						*target = append(*target, str.Value)
-					}
+			ary, ok := kv.Value.(*ast.Array)
+			if !ok {
+				c.addTypeError(kv, fieldName, "array of integers")
+				return
+			}
+			for _, elem := range ary.Value {
+				iAst, ok := elem.(*ast.Integer)
+				if !ok {
+					c.addTypeError(kv, fieldName, "array of integers")
+					return
+				}
+				i, err := iAst.Int()
+				if err != nil {
+					c.addTypeError(kv, fieldName, "array of integers")
+					return
 				}
+				*target = append(*target, int(i))
 			}
 		}
 	}
 }
+
 func (c *Config) getFieldTagFilter(tbl *ast.Table, fieldName string, target *[]models.TagFilter) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
 			for name, val := range subtbl.Fields {
 				if kv, ok := val.(*ast.KeyValue); ok {
 					tagfilter := models.TagFilter{Name: name}
-					if ary, ok := kv.Value.(*ast.Array); ok {
-						for _, elem := range ary.Value {
-							if str, ok := elem.(*ast.String); ok {
-								tagfilter.Filter = append(tagfilter.Filter, str.Value)
-							}
+					ary, ok := kv.Value.(*ast.Array)
+					if !ok {
+						c.addTypeError(kv, fieldName+"."+name, "array of strings")
+						continue
+					}
+					for _, elem := range ary.Value {
+						str, ok := elem.(*ast.String)
+						if !ok {
+							c.addTypeError(kv, fieldName+"."+name, "array of strings")
+							break
 						}
+						tagfilter.Filter = append(tagfilter.Filter, str.Value)
 					}
 					*target = append(*target, tagfilter)
 				}
@@ -1557,9 +1998,44 @@ func (c *Config) getFieldStringMap(tbl *ast.Table, fieldName string, target *map
 		if subtbl, ok := node.(*ast.Table); ok {
 			for name, val := range subtbl.Fields {
 				if kv, ok := val.(*ast.KeyValue); ok {
-					if str, ok := kv.Value.(*ast.String); ok {
-						(*target)[name] = str.Value
+					str, ok := kv.Value.(*ast.String)
+					if !ok {
+						c.addTypeError(kv, fieldName+"."+name, "string")
+						continue
 					}
+					(*target)[name] = str.Value
+				}
+			}
+		}
+	}
+}
+
+// getFieldMap reads a table of string, integer, float and boolean values.
+func (c *Config) getFieldMap(tbl *ast.Table, fieldName string, target *map[string]interface{}) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if subtbl, ok := node.(*ast.Table); ok {
+			*target = map[string]interface{}{}
+			for name, val := range subtbl.Fields {
+				kv, ok := val.(*ast.KeyValue)
+				if !ok {
+					continue
+				}
+
+				var err error
+				switch v := kv.Value.(type) {
+				case *ast.String:
+					(*target)[name] = v.Value
+				case *ast.Integer:
+					(*target)[name], err = v.Int()
+				case *ast.Float:
+					(*target)[name], err = v.Float()
+				case *ast.Boolean:
+					(*target)[name], err = v.Boolean()
+				default:
+					err = fmt.Errorf("unsupported type %q", kv.Value.Source())
+				}
+				if err != nil {
+					c.addError(kv, fmt.Errorf("%s.%s: %w", fieldName, name, err))
 				}
 			}
 		}
@@ -1571,9 +2047,71 @@ func keys(m map[string]bool) []string {
 	for k := range m {
 		result = append(result, k)
 	}
+	sort.Strings(result)
 	return result
 }
 
+// unusedFieldsError returns an error for the fields of the table not used by
+// the plugin, pointing to the line of the first of them.
+func (c *Config) unusedFieldsError(tbl *ast.Table) error {
+	if len(c.UnusedFields) == 0 {
+		return nil
+	}
+
+	line := 0
+	for _, key := range keys(c.UnusedFields) {
+		if kv, ok := tbl.Fields[key].(*ast.KeyValue); ok && (line == 0 || kv.Line < line) {
+			line = kv.Line
+		}
+	}
+	if line == 0 {
+		line = tbl.Line
+	}
+	return fmt.Errorf("line %d: configuration specified the fields %q, but they weren't used", line, keys(c.UnusedFields))
+}
+
+// checkRequired returns an error for the fields of the plugin tagged with
+// `required:"true"` that are missing from the table.
+func checkRequired(tbl *ast.Table, plugin interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(plugin))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var missing []string
+	for _, key := range requiredFields(v.Type()) {
+		if _, ok := tbl.Fields[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("line %d: missing required fields %q", tbl.Line, missing)
+	}
+	return nil
+}
+
+// requiredFields returns the keys of the required fields of the struct type,
+// including those of embedded structs.
+func requiredFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, requiredFields(field.Type)...)
+			continue
+		}
+		if field.Tag.Get("required") != "true" {
+			continue
+		}
+		key := strings.Split(field.Tag.Get("toml"), ",")[0]
+		if key == "" {
+			key = toml.DefaultConfig.FieldToKey(typ, field.Name)
+		}
+		fields = append(fields, key)
+	}
+	return fields
+}
+
 func (c *Config) hasErrs() bool {
 	return len(c.errs) > 0
 }
@@ -1585,8 +2123,14 @@ func (c *Config) firstErr() error {
 	return c.errs[0]
 }
 
-func (c *Config) addError(tbl *ast.Table, err error) {
-	c.errs = append(c.errs, fmt.Errorf("line %d:%d: %w", tbl.Line, tbl.Position, err))
+// addError records an error of the value of the key.
+func (c *Config) addError(kv *ast.KeyValue, err error) {
+	c.errs = append(c.errs, fmt.Errorf("line %d: %w", kv.Line, err))
+}
+
+// addTypeError records a value of the wrong type for the field.
+func (c *Config) addTypeError(kv *ast.KeyValue, fieldName string, expected string) {
+	c.addError(kv, fmt.Errorf("%s: expected %s, got %s", fieldName, expected, kv.Value.Source()))
 }
 
 // unwrappable lets you retrieve the original telegraf.Processor from the
@@ -16,6 +16,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs/azure_monitor"
 	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/toml"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -154,7 +155,7 @@ func TestConfig_LoadDirectory(t *testing.T) {
 		JSONStrict: true,
 	})
 	assert.NoError(t, err)
-	ex.SetParser(p)
+	ex.SetParser(parsers.NewStatsParser(p, map[string]string{"input": "exec", "data_format": "json"}))
 	ex.Command = "/usr/bin/myothercollector --foo=bar"
 	eConfig := &models.InputConfig{
 		Name:              "exec",
@@ -209,7 +210,7 @@ func TestConfig_FieldNotDefined(t *testing.T) {
 	c := NewConfig()
 	err := c.LoadConfig("./testdata/invalid_field.toml")
 	require.Error(t, err, "invalid field name")
-	assert.Equal(t, "Error loading config file ./testdata/invalid_field.toml: plugin inputs.http_listener_v2: line 1: configuration specified the fields [\"not_a_field\"], but they weren't used", err.Error())
+	assert.Equal(t, "Error loading config file ./testdata/invalid_field.toml: plugin inputs.http_listener_v2: line 2: configuration specified the fields [\"not_a_field\"], but they weren't used", err.Error())
 }
 
 func TestConfig_WrongFieldType(t *testing.T) {
@@ -278,3 +279,259 @@ func TestConfig_AzureMonitorNamespacePrefix(t *testing.T) {
 	assert.Equal(t, "", azureMonitor.NamespacePrefix)
 	assert.Equal(t, true, ok)
 }
+
+func TestConfig_ParserXMLSubtables(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "xml"
+
+[[xml]]
+  metric_selection = "/Bus/Sensor"
+  timestamp = "/Bus/Timestamp"
+  timestamp_format = "unix"
+  field_selection = "Variable/@*"
+  [xml.tags]
+    name = "@name"
+
+[[xml]]
+  metric_name = "string('status')"
+  [xml.fields_int]
+    errors = "/Bus/Errors"
+`))
+	require.NoError(t, err)
+
+	c := NewConfig()
+	pc, err := c.getParserConfig("file", tbl)
+	require.NoError(t, err)
+	require.Equal(t, []parsers.XMLConfig{
+		{
+			Selection:       "/Bus/Sensor",
+			Timestamp:       "/Bus/Timestamp",
+			TimestampFormat: "unix",
+			FieldSelection:  "Variable/@*",
+			Tags:            map[string]string{"name": "@name"},
+			Fields:          map[string]string{},
+			FieldsInt:       map[string]string{},
+		},
+		{
+			MetricName: "string('status')",
+			Tags:       map[string]string{},
+			Fields:     map[string]string{},
+			FieldsInt:  map[string]string{"errors": "/Bus/Errors"},
+		},
+	}, pc.XMLConfig)
+}
+
+func TestConfig_ParserBinarySubtables(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "binary"
+binary_endianness = "little"
+
+[[binary_field]]
+  name = "time"
+  type = "uint32"
+
+[[binary_field]]
+  name = "voltage"
+  type = "float32"
+  offset = 8
+  count = 2
+  endianness = "big"
+`))
+	require.NoError(t, err)
+
+	c := NewConfig()
+	pc, err := c.getParserConfig("file", tbl)
+	require.NoError(t, err)
+	require.Equal(t, "little", pc.BinaryEndianness)
+	require.Equal(t, []parsers.BinaryField{
+		{Name: "time", Type: "uint32", Offset: -1},
+		{Name: "voltage", Type: "float32", Offset: 8, Count: 2, Endianness: "big"},
+	}, pc.BinaryFields)
+}
+
+func TestConfig_ParserDataTransforms(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "json"
+
+[[data_transform]]
+  type = "gzip"
+
+[[data_transform]]
+  type = "json_envelope"
+  path = "records"
+`))
+	require.NoError(t, err)
+
+	c := NewConfig()
+	pc, err := c.getParserConfig("file", tbl)
+	require.NoError(t, err)
+	require.Equal(t, []parsers.DataTransform{
+		{Type: "gzip"},
+		{Type: "json_envelope", Path: "records"},
+	}, pc.DataTransforms)
+}
+
+func TestConfig_ParserDataDefaults(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+data_format = "csv"
+
+[data_default_fields]
+  errors = 0
+  ratio = 0.5
+  ok = true
+  status = "unknown"
+
+[data_constant_tags]
+  source = "partner"
+`))
+	require.NoError(t, err)
+
+	c := NewConfig()
+	pc, err := c.getParserConfig("file", tbl)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"errors": int64(0),
+		"ratio":  0.5,
+		"ok":     true,
+		"status": "unknown",
+	}, pc.DataDefaultFields)
+	require.Equal(t, map[string]string{"source": "partner"}, pc.DataConstantTags)
+	require.Empty(t, pc.DataDefaultTags)
+}
+
+func TestConfig_FieldSize(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+parquet_row_group_size = "64MiB"
+parquet_page_size = 4096
+`))
+	require.NoError(t, err)
+
+	c := NewConfig()
+	var rowGroupSize, pageSize int64
+	c.getFieldSize(tbl, "parquet_row_group_size", &rowGroupSize)
+	c.getFieldSize(tbl, "parquet_page_size", &pageSize)
+	require.False(t, c.hasErrs())
+	require.Equal(t, int64(64*1024*1024), rowGroupSize)
+	require.Equal(t, int64(4096), pageSize)
+}
+
+func TestConfig_FieldDuration(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+interval = "1m"
+flush_interval = 10
+`))
+	require.NoError(t, err)
+
+	c := NewConfig()
+	var interval, flushInterval time.Duration
+	c.getFieldDuration(tbl, "interval", &interval)
+	c.getFieldDuration(tbl, "flush_interval", &flushInterval)
+	require.False(t, c.hasErrs())
+	require.Equal(t, time.Minute, interval)
+	require.Equal(t, 10*time.Second, flushInterval)
+}
+
+// Durations given as integers are seconds, for the settings of the plugin
+// and the settings read by telegraf alike.
+func TestConfig_IntegerDurations(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.http_listener_v2]]
+  interval = 10
+  read_timeout = 10
+`))
+	require.NoError(t, err)
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, 10*time.Second, c.Inputs[0].Config.Interval)
+
+	input, ok := c.Inputs[0].Input.(*http_listener_v2.HTTPListenerV2)
+	require.True(t, ok)
+	require.Equal(t, internal.Duration{Duration: 10 * time.Second}, input.ReadTimeout)
+}
+
+func TestConfig_FieldWrongType(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name: "duration",
+			config: `
+[[inputs.http_listener_v2]]
+  interval = true
+`,
+			err: `error parsing http_listener_v2, line 3: interval: expected duration, got true`,
+		},
+		{
+			name: "integer",
+			config: `
+[[outputs.http]]
+  url = "http://localhost"
+  metric_batch_size = "1000"
+`,
+			err: `error parsing http array, line 4: metric_batch_size: expected integer, got "1000"`,
+		},
+		{
+			name: "string slice",
+			config: `
+[[inputs.http_listener_v2]]
+  namepass = "cpu"
+`,
+			err: `error parsing http_listener_v2, line 3: namepass: expected array of strings, got "cpu"`,
+		},
+		{
+			name: "invalid duration",
+			config: `
+[[inputs.http_listener_v2]]
+  read_timeout = "5 seconds"
+`,
+			err: `invalid duration "5 seconds"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConfig()
+			err := c.LoadConfigData([]byte(tt.config))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestConfig_FieldNotDefinedLine(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.http_listener_v2]]
+  service_address = ":8080"
+
+[[inputs.http_listener_v2]]
+  service_address = ":8081"
+  not_a_field = true
+`))
+	require.EqualError(t, err, `plugin inputs.http_listener_v2: line 7: configuration specified the fields ["not_a_field"], but they weren't used`)
+}
+
+func TestConfig_RequiredFields(t *testing.T) {
+	type embedded struct {
+		Address string `toml:"address" required:"true"`
+	}
+	type plugin struct {
+		embedded
+		Directory string `toml:"directory" required:"true"`
+		Timeout   string `toml:"timeout"`
+	}
+
+	tbl, err := toml.Parse([]byte(`
+directory = "/tmp"
+`))
+	require.NoError(t, err)
+	require.EqualError(t, checkRequired(tbl, &plugin{}), `line 1: missing required fields ["address"]`)
+
+	tbl, err = toml.Parse([]byte(`
+address = "localhost:21"
+directory = "/tmp"
+`))
+	require.NoError(t, err)
+	require.NoError(t, checkRequired(tbl, &plugin{}))
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -50,7 +51,11 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 		return nil
 	}
 
-	return nil
+	// An empty string leaves the duration unset.
+	if len(b) == 0 || string(b) == `""` {
+		return nil
+	}
+	return fmt.Errorf("invalid duration %s", b)
 }
 
 func (d *Duration) UnmarshalText(text []byte) error {
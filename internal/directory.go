@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// CheckWritableDir returns an error if the directory does not exist or files
+// can't be created in it.  A temporary file is created and removed to find
+// out.
+func CheckWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	f, err := ioutil.TempFile(dir, ".telegraf-check-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// CheckCreatableDir returns an error if the directory can't be written to
+// after it is created.  Without the directory, its nearest existing parent
+// must be writable.
+func CheckCreatableDir(dir string) error {
+	dir = filepath.Clean(dir)
+	for {
+		_, err := os.Stat(dir)
+		if err == nil {
+			return CheckWritableDir(dir)
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,40 @@
+package internal
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckWritableDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, CheckWritableDir(dir))
+	require.Error(t, CheckWritableDir(filepath.Join(dir, "missing")))
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	require.EqualError(t, CheckWritableDir(file), file+" is not a directory")
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestCheckCreatableDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, CheckCreatableDir(filepath.Join(dir, "a", "b")))
+	require.NoDirExists(t, filepath.Join(dir, "a"))
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	require.Error(t, CheckCreatableDir(filepath.Join(file, "a")))
+}
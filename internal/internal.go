@@ -107,7 +107,11 @@ func (d *Duration) UnmarshalTOML(b []byte) error {
 		return nil
 	}
 
-	return nil
+	// An empty string leaves the duration unset.
+	if len(b) == 0 || string(b) == `""` {
+		return nil
+	}
+	return fmt.Errorf("invalid duration %s", b)
 }
 
 func (s *Size) UnmarshalTOML(b []byte) error {
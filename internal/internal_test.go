@@ -192,6 +192,12 @@ func TestDuration(t *testing.T) {
 	d = Duration{}
 	d.UnmarshalTOML([]byte(`1.5`))
 	assert.Equal(t, time.Second, d.Duration)
+
+	d = Duration{}
+	assert.NoError(t, d.UnmarshalTOML([]byte(`""`)))
+	assert.Equal(t, time.Duration(0), d.Duration)
+
+	assert.Error(t, d.UnmarshalTOML([]byte(`"5 seconds"`)))
 }
 
 func TestSize(t *testing.T) {
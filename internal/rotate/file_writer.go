@@ -2,6 +2,7 @@ package rotate
 
 // Rotating things
 import (
+	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // FilePerm defines the permissions that Writer will use for all
@@ -20,6 +23,30 @@ const (
 	DateFormat = "2006-01-02"
 )
 
+// Options are the optional settings of a FileWriter.
+type Options struct {
+	// MaxArchiveAge is the age after which archives are deleted, 0 keeps
+	// archives regardless of their age.
+	MaxArchiveAge time.Duration
+	// Compression is the compression of archives, "gzip", "zstd" or empty
+	// for none.
+	Compression string
+	// Truncate empties an existing file when the writer is created instead
+	// of appending to it.
+	Truncate bool
+	// Perm is the permissions set on the file, FilePerm subject to the umask
+	// if 0.
+	Perm os.FileMode
+	// Owner is the owner set on the file and archives, unchanged if nil.
+	Owner *Owner
+}
+
+// Owner is the user and group ID of a file, -1 leaves the ID unchanged.
+type Owner struct {
+	UID int
+	GID int
+}
+
 // FileWriter implements the io.Writer interface and writes to the
 // filename specified.
 // Will rotate at the specified interval and/or when the current file size exceeds maxSizeInBytes
@@ -35,13 +62,36 @@ type FileWriter struct {
 	expireTime               time.Time
 	bytesWritten             int64
 	sync.Mutex
+
+	maxArchiveAge time.Duration
+	compression   string
+	options       Options
+
+	// Archives are compressed in the background, one at a time, and the
+	// first error is returned by Close.
+	compressing sync.WaitGroup
+	archiveLock sync.Mutex
+	archiveErr  error
+	lastArchive string
 }
 
 // NewFileWriter creates a new file writer.
 func NewFileWriter(filename string, interval time.Duration, maxSizeInBytes int64, maxArchives int) (io.WriteCloser, error) {
+	return NewFileWriterWithOptions(filename, interval, maxSizeInBytes, maxArchives, Options{})
+}
+
+// NewFileWriterWithOptions creates a new file writer, which optionally
+// compresses archives and deletes them after a maximum age.
+func NewFileWriterWithOptions(filename string, interval time.Duration, maxSizeInBytes int64, maxArchives int, options Options) (io.WriteCloser, error) {
+	switch options.Compression {
+	case "", "gzip", "zstd":
+	default:
+		return nil, fmt.Errorf("unknown compression %q", options.Compression)
+	}
+
 	if interval == 0 && maxSizeInBytes <= 0 {
 		// No rotation needed so a basic io.Writer will do the trick
-		return openFile(filename)
+		return openFile(filename, options)
 	}
 
 	w := &FileWriter{
@@ -50,17 +100,51 @@ func NewFileWriter(filename string, interval time.Duration, maxSizeInBytes int64
 		maxSizeInBytes:           maxSizeInBytes,
 		maxArchives:              maxArchives,
 		filenameRotationTemplate: getFilenameRotationTemplate(filename),
+		maxArchiveAge:            options.MaxArchiveAge,
+		compression:              options.Compression,
+		options:                  options,
 	}
 
 	if err := w.openCurrent(); err != nil {
 		return nil, err
 	}
+	// Only the file present when starting is truncated.
+	w.options.Truncate = false
 
 	return w, nil
 }
 
-func openFile(filename string) (*os.File, error) {
-	return os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, FilePerm)
+// openFile opens the file for appending and applies the permissions and
+// owner of the options.
+func openFile(filename string, options Options) (*os.File, error) {
+	flag := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	if options.Truncate {
+		flag |= os.O_TRUNC
+	}
+	perm := FilePerm
+	if options.Perm != 0 {
+		perm = options.Perm
+	}
+
+	f, err := os.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	// The permissions of OpenFile are subject to the umask and only apply to
+	// new files.
+	if options.Perm != 0 {
+		if err := f.Chmod(options.Perm); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if options.Owner != nil {
+		if err := f.Chown(options.Owner.UID, options.Owner.GID); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
 }
 
 func getFilenameRotationTemplate(filename string) string {
@@ -88,26 +172,35 @@ func (w *FileWriter) Write(p []byte) (n int, err error) {
 	return n, nil
 }
 
-// Close closes the current file.  Writer is unusable after this
-// is called.
+// Sync commits the current file to stable storage.
+func (w *FileWriter) Sync() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.current.Sync()
+}
+
+// Close closes the current file and waits for the archives to be
+// compressed.  Writer is unusable after this is called.
 func (w *FileWriter) Close() (err error) {
 	w.Lock()
 	defer w.Unlock()
 
 	// Rotate before closing
-	if err = w.rotate(); err != nil {
+	err = w.rotate()
+	w.compressing.Wait()
+	if err != nil {
 		return err
 	}
 
 	w.current = nil
-	return nil
+	return w.archiveErr
 }
 
 func (w *FileWriter) openCurrent() (err error) {
 	// In case ModTime() fails, we use time.Now()
 	w.expireTime = time.Now().Add(w.interval)
 	w.bytesWritten = 0
-	w.current, err = openFile(w.filename)
+	w.current, err = openFile(w.filename, w.options)
 
 	if err != nil {
 		return err
@@ -149,32 +242,147 @@ func (w *FileWriter) rotate() (err error) {
 	// Use year-month-date for readability, unix time to make the file name unique with second precision
 	now := time.Now()
 	rotatedFilename := fmt.Sprintf(w.filenameRotationTemplate, now.Format(DateFormat), strconv.FormatInt(now.Unix(), 10))
+	if rotatedFilename == w.lastArchive {
+		// Rotated twice within a second, the previous archive must be
+		// compressed before its name is reused.
+		w.compressing.Wait()
+	}
 	if err = os.Rename(w.filename, rotatedFilename); err != nil {
 		return err
 	}
+	w.lastArchive = rotatedFilename
 
-	if err = w.purgeArchivesIfNeeded(); err != nil {
-		return err
+	if w.compression != "" {
+		// Writes continue to the new file while the archive is compressed.
+		w.compressing.Add(1)
+		go func() {
+			defer w.compressing.Done()
+			if err := w.archive(rotatedFilename); err != nil {
+				fmt.Printf("unable to compress the file '%s', %s", rotatedFilename, err.Error())
+			}
+		}()
+		return nil
 	}
 
-	return nil
+	w.archiveLock.Lock()
+	defer w.archiveLock.Unlock()
+	return w.finishArchive(rotatedFilename)
 }
 
-func (w *FileWriter) purgeArchivesIfNeeded() (err error) {
-	if w.maxArchives == -1 {
-		//Skip archiving
+// archive compresses a rotated file and purges the archives, keeping the
+// first error for Close.
+func (w *FileWriter) archive(filename string) error {
+	w.archiveLock.Lock()
+	defer w.archiveLock.Unlock()
+
+	compressedFilename, err := compressFile(filename, w.compression)
+	if os.IsNotExist(err) {
+		// Purged before it was compressed.
 		return nil
 	}
+	if err == nil {
+		err = w.finishArchive(compressedFilename)
+	}
+	if err != nil && w.archiveErr == nil {
+		w.archiveErr = err
+	}
+	return err
+}
 
+// finishArchive sets the owner of a new archive and purges the archives.
+// The caller must hold the archive lock.
+func (w *FileWriter) finishArchive(filename string) error {
+	if w.options.Owner != nil {
+		if err := os.Chown(filename, w.options.Owner.UID, w.options.Owner.GID); err != nil {
+			return err
+		}
+	}
+	return w.purgeArchivesIfNeeded()
+}
+
+// compressFile replaces the file with a compressed copy, named with the
+// extension of the compression, and returns the name of the copy.
+func compressFile(filename, compression string) (_ string, err error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	compressedFilename := filename + ".gz"
+	if compression == "zstd" {
+		compressedFilename = filename + ".zst"
+	}
+	out, err := os.OpenFile(compressedFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(compressedFilename)
+		}
+	}()
+
+	var encoder io.WriteCloser
+	if compression == "zstd" {
+		if encoder, err = zstd.NewWriter(out); err != nil {
+			return "", err
+		}
+	} else {
+		encoder = gzip.NewWriter(out)
+	}
+	if _, err = io.Copy(encoder, in); err != nil {
+		return "", err
+	}
+	if err = encoder.Close(); err != nil {
+		return "", err
+	}
+	if err = out.Close(); err != nil {
+		return "", err
+	}
+
+	// Keep the time of the archive for purging by age.
+	if err = os.Chtimes(compressedFilename, info.ModTime(), info.ModTime()); err != nil {
+		return "", err
+	}
+	return compressedFilename, os.Remove(filename)
+}
+
+func (w *FileWriter) purgeArchivesIfNeeded() (err error) {
 	var matches []string
-	if matches, err = filepath.Glob(fmt.Sprintf(w.filenameRotationTemplate, "*", "*")); err != nil {
+	if matches, err = w.archives(); err != nil {
 		return err
 	}
 
+	if w.maxArchiveAge > 0 {
+		kept := matches[:0]
+		expired := time.Now().Add(-w.maxArchiveAge)
+		for _, filename := range matches {
+			info, err := os.Stat(filename)
+			if err == nil && info.ModTime().Before(expired) {
+				if err = os.Remove(filename); err != nil {
+					return err
+				}
+				continue
+			}
+			kept = append(kept, filename)
+		}
+		matches = kept
+	}
+
+	if w.maxArchives == -1 {
+		//Skip archiving
+		return nil
+	}
+
 	//if there are more archives than the configured maximum, then purge older files
 	if len(matches) > w.maxArchives {
-		//sort files alphanumerically to delete older files first
-		sort.Strings(matches)
 		for _, filename := range matches[:len(matches)-w.maxArchives] {
 			if err = os.Remove(filename); err != nil {
 				return err
@@ -183,3 +391,28 @@ func (w *FileWriter) purgeArchivesIfNeeded() (err error) {
 	}
 	return nil
 }
+
+// archives returns the rotated files, including compressed ones, sorted from
+// oldest to newest.
+func (w *FileWriter) archives() ([]string, error) {
+	pattern := fmt.Sprintf(w.filenameRotationTemplate, "*", "*")
+	var matches []string
+	found := make(map[string]bool)
+	for _, suffix := range []string{"", ".gz", ".zst"} {
+		m, err := filepath.Glob(pattern + suffix)
+		if err != nil {
+			return nil, err
+		}
+		// Without an extension the pattern matches compressed files too.
+		for _, filename := range m {
+			if !found[filename] {
+				found[filename] = true
+				matches = append(matches, filename)
+			}
+		}
+	}
+
+	//sort files alphanumerically to delete older files first
+	sort.Strings(matches)
+	return matches, nil
+}
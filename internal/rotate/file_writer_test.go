@@ -4,6 +4,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -146,3 +148,111 @@ func TestFileWriter_CloseRotates(t *testing.T) {
 	assert.Equal(t, 1, len(files))
 	assert.Regexp(t, "^test\\.[^\\.]+\\.log$", files[0].Name())
 }
+
+func TestFileWriter_Compression(t *testing.T) {
+	for _, compression := range []string{"gzip", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			tempDir, err := ioutil.TempDir("", "RotationCompression")
+			require.NoError(t, err)
+			defer os.RemoveAll(tempDir)
+
+			writer, err := NewFileWriterWithOptions(filepath.Join(tempDir, "test.log"), 0, 10, 2, Options{Compression: compression})
+			require.NoError(t, err)
+
+			for i := 0; i < 3; i++ {
+				_, err = writer.Write([]byte("Hello World"))
+				require.NoError(t, err)
+				time.Sleep(1 * time.Second)
+			}
+			require.NoError(t, writer.Close())
+
+			// only the two newest archives are kept
+			files, err := ioutil.ReadDir(tempDir)
+			require.NoError(t, err)
+			require.Len(t, files, 2)
+
+			extension := map[string]string{"gzip": ".log.gz", "zstd": ".log.zst"}[compression]
+			for _, f := range files {
+				require.True(t, strings.HasSuffix(f.Name(), extension), f.Name())
+			}
+		})
+	}
+}
+
+func TestFileWriter_MaxArchiveAge(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "RotationAge")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	old := filepath.Join(tempDir, "test.2020-09-13-1600000000.log")
+	require.NoError(t, ioutil.WriteFile(old, []byte("Hello World"), 0644))
+	expired := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(old, expired, expired))
+
+	writer, err := NewFileWriterWithOptions(filepath.Join(tempDir, "test.log"), 0, 10, -1, Options{MaxArchiveAge: time.Hour})
+	require.NoError(t, err)
+
+	_, err = writer.Write([]byte("Hello World"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	files, err := ioutil.ReadDir(tempDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.NotEqual(t, filepath.Base(old), files[0].Name())
+}
+
+func TestFileWriter_Truncate(t *testing.T) {
+	for _, maxSize := range []int64{0, 100} {
+		tempDir, err := ioutil.TempDir("", "RotationTruncate")
+		require.NoError(t, err)
+		defer os.RemoveAll(tempDir)
+
+		filename := filepath.Join(tempDir, "test.log")
+		require.NoError(t, ioutil.WriteFile(filename, []byte("Hello World\n"), 0644))
+
+		writer, err := NewFileWriterWithOptions(filename, 0, maxSize, -1, Options{Truncate: true})
+		require.NoError(t, err)
+		_, err = writer.Write([]byte("Hello World 2\n"))
+		require.NoError(t, err)
+		require.NoError(t, writer.(interface{ Sync() error }).Sync())
+
+		buf, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		require.Equal(t, "Hello World 2\n", string(buf))
+		require.NoError(t, writer.Close())
+	}
+}
+
+func TestFileWriter_Perm(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on Windows, permissions are not supported")
+	}
+
+	tempDir, err := ioutil.TempDir("", "RotationPerm")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writer, err := NewFileWriterWithOptions(filepath.Join(tempDir, "test.log"), 0, 10, -1,
+		Options{Compression: "gzip", Perm: 0600})
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("Hello World"))
+	require.NoError(t, err)
+	_, err = writer.Write([]byte("Hello World"))
+	require.NoError(t, err)
+
+	// the archive keeps the permissions of the file
+	writer.(*FileWriter).compressing.Wait()
+	files, err := ioutil.ReadDir(tempDir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for _, f := range files {
+		require.Equal(t, os.FileMode(0600), f.Mode().Perm(), f.Name())
+	}
+	require.NoError(t, writer.Close())
+}
+
+func TestFileWriter_UnknownCompression(t *testing.T) {
+	_, err := NewFileWriterWithOptions("test.log", 0, 10, 0, Options{Compression: "lzma"})
+	require.EqualError(t, err, `unknown compression "lzma"`)
+}
@@ -11,6 +11,7 @@ Usage:
 The commands & flags are:
 
   config              print out full sample configuration to stdout
+  config check        initialize the plugins to check the configuration, then exit
   version             print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
@@ -49,6 +50,9 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf --input-filter cpu --output-filter influxdb config
 
+  # check a config file and the files of a config directory
+  telegraf --config telegraf.conf --config-directory telegraf.d config check
+
   # run a single telegraf collection, outputting metrics to stdout
   telegraf --config telegraf.conf --test
 
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,6 +17,9 @@ const (
 
 	// Default number of metrics kept. It should be a multiple of batch size.
 	DEFAULT_METRIC_BUFFER_LIMIT = 10000
+
+	// Default size in bytes of the spool.
+	DEFAULT_SPOOL_LIMIT = 1024 * 1024 * 1024
 )
 
 // OutputConfig containing name and filter
@@ -28,6 +33,11 @@ type OutputConfig struct {
 	MetricBufferLimit int
 	MetricBatchSize   int
 
+	// SpoolDirectory enables storing undelivered metrics on disk, up to
+	// SpoolLimit bytes.
+	SpoolDirectory string
+	SpoolLimit     int64
+
 	NameOverride string
 	NamePrefix   string
 	NameSuffix   string
@@ -50,6 +60,7 @@ type RunningOutput struct {
 	BatchReady chan time.Time
 
 	buffer *Buffer
+	spool  *Spool
 	log    telegraf.Logger
 
 	aggMutex sync.Mutex
@@ -107,6 +118,14 @@ func NewRunningOutput(
 		log: logger,
 	}
 
+	if config.SpoolDirectory != "" {
+		spoolLimit := config.SpoolLimit
+		if spoolLimit == 0 {
+			spoolLimit = DEFAULT_SPOOL_LIMIT
+		}
+		ro.spool = NewSpool(config.Name, config.Alias, config.SpoolDirectory, spoolLimit)
+	}
+
 	return ro
 }
 
@@ -120,6 +139,15 @@ func (ro *RunningOutput) metricFiltered(metric telegraf.Metric) {
 }
 
 func (r *RunningOutput) Init() error {
+	if r.spool != nil {
+		if err := r.spool.Open(); err != nil {
+			return fmt.Errorf("opening spool: %w", err)
+		}
+		if n := r.spool.Len(); n > 0 {
+			r.log.Infof("Spool contains %d metrics, replaying them before new metrics", n)
+		}
+	}
+
 	if p, ok := r.Output.(telegraf.Initializer); ok {
 		err := p.Init()
 		if err != nil {
@@ -190,6 +218,10 @@ func (ro *RunningOutput) Write() error {
 
 	atomic.StoreInt64(&ro.newMetricsCount, 0)
 
+	if err := ro.writeSpool(); err != nil {
+		return err
+	}
+
 	// Only process the metrics in the buffer now.  Metrics added while we are
 	// writing will be sent on the next call.
 	nBuffer := ro.buffer.Len()
@@ -203,6 +235,7 @@ func (ro *RunningOutput) Write() error {
 		err := ro.write(batch)
 		if err != nil {
 			ro.buffer.Reject(batch)
+			ro.spoolBuffer()
 			return err
 		}
 		ro.buffer.Accept(batch)
@@ -212,6 +245,10 @@ func (ro *RunningOutput) Write() error {
 
 // WriteBatch writes a single batch of metrics to the output.
 func (ro *RunningOutput) WriteBatch() error {
+	if err := ro.writeSpool(); err != nil {
+		return err
+	}
+
 	batch := ro.buffer.Batch(ro.MetricBatchSize)
 	if len(batch) == 0 {
 		return nil
@@ -220,6 +257,7 @@ func (ro *RunningOutput) WriteBatch() error {
 	err := ro.write(batch)
 	if err != nil {
 		ro.buffer.Reject(batch)
+		ro.spoolBuffer()
 		return err
 	}
 	ro.buffer.Accept(batch)
@@ -227,6 +265,65 @@ func (ro *RunningOutput) WriteBatch() error {
 	return nil
 }
 
+// writeSpool writes the spooled metrics, oldest first, so they are delivered
+// before the metrics in the buffer.  If a write fails the buffer is spooled
+// as well to keep the order.
+func (ro *RunningOutput) writeSpool() error {
+	if ro.spool == nil {
+		return nil
+	}
+
+	for ro.spool.Len() > 0 {
+		batch, err := ro.spool.Peek()
+		if errors.Is(err, errCorruptSegment) {
+			// A corrupt segment would block the spool forever.
+			ro.log.Errorf("Dropping spooled metrics: %v", err)
+		} else if err != nil {
+			ro.spoolBuffer()
+			return fmt.Errorf("reading spooled metrics: %w", err)
+		} else if len(batch) > 0 {
+			if err := ro.write(batch); err != nil {
+				ro.spoolBuffer()
+				return err
+			}
+			AgentMetricsWritten.Incr(int64(len(batch)))
+			ro.buffer.MetricsWritten.Incr(int64(len(batch)))
+		}
+		if err := ro.spool.Pop(); err != nil {
+			return fmt.Errorf("removing spooled metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// spoolBuffer moves the metrics of the buffer to the spool, the metrics stay
+// in the buffer if they can't be spooled.
+func (ro *RunningOutput) spoolBuffer() {
+	if ro.spool == nil {
+		return
+	}
+
+	for {
+		batch := ro.buffer.Batch(ro.MetricBatchSize)
+		if len(batch) == 0 {
+			return
+		}
+
+		dropped, err := ro.spool.Push(batch)
+		if dropped > 0 {
+			AgentMetricsDropped.Incr(int64(dropped))
+			ro.buffer.MetricsDropped.Incr(int64(dropped))
+			ro.log.Warnf("Spool limit reached; %d spooled metrics have been dropped", dropped)
+		}
+		if err != nil {
+			ro.buffer.Reject(batch)
+			ro.log.Errorf("Error spooling metrics: %v", err)
+			return
+		}
+		ro.buffer.Spooled(batch)
+	}
+}
+
 // Close closes the output
 func (r *RunningOutput) Close() {
 	err := r.Output.Close()
@@ -256,12 +353,20 @@ func (r *RunningOutput) write(metrics []telegraf.Metric) error {
 func (r *RunningOutput) LogBufferStatus() {
 	nBuffer := r.buffer.Len()
 	r.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, r.MetricBufferLimit)
+	if r.spool != nil {
+		r.log.Debugf("Spooled: %d metrics", r.spool.Len())
+	}
 }
 
 func (r *RunningOutput) Log() telegraf.Logger {
 	return r.log
 }
 
+// BufferLength returns the number of unsent metrics, including the spooled
+// metrics.
 func (r *RunningOutput) BufferLength() int {
+	if r.spool != nil {
+		return r.buffer.Len() + r.spool.Len()
+	}
 	return r.buffer.Len()
 }
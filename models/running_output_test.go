@@ -2,6 +2,9 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -587,3 +590,123 @@ func (m *perfOutput) Write(metrics []telegraf.Metric) error {
 	}
 	return nil
 }
+
+// Verify that metrics are spooled on write failures and written in order
+// after the output recovers, also after a restart.
+func TestRunningOutputSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:         Filter{},
+		SpoolDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 4)
+	require.NoError(t, ro.Init())
+
+	first := spoolMetrics("metric1", "metric2", "metric3", "metric4", "metric5")
+	for _, metric := range first {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Equal(t, 4, ro.BufferLength())
+
+	// Restart with the spooled metrics.
+	m = &mockOutput{}
+	m.failWrite = true
+	ro = NewRunningOutput("test", m, conf, 2, 4)
+	require.NoError(t, ro.Init())
+	require.Equal(t, 4, ro.BufferLength())
+
+	second := spoolMetrics("metric6", "metric7")
+	for _, metric := range second {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.WriteBatch())
+	require.Equal(t, 6, ro.BufferLength())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+
+	// The buffer overflowed before the first write, dropping metric1.
+	expected := append(first[1:], second...)
+	testutil.RequireMetricsEqual(t, expected, m.Metrics())
+}
+
+func TestRunningOutputSpoolReadError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:         Filter{},
+		SpoolDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 4)
+	require.NoError(t, ro.Init())
+
+	metrics := spoolMetrics("metric1", "metric2")
+	for _, metric := range metrics {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	// A segment that can't be read is kept for the next write.
+	segments, err := filepath.Glob(filepath.Join(dir, "*.spool"))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	data, err := ioutil.ReadFile(segments[0])
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(segments[0]))
+	require.NoError(t, os.Mkdir(segments[0], 0755))
+
+	m.failWrite = false
+	require.Error(t, ro.Write())
+	require.Equal(t, 2, ro.BufferLength())
+	require.Empty(t, m.Metrics())
+
+	require.NoError(t, os.Remove(segments[0]))
+	require.NoError(t, ioutil.WriteFile(segments[0], data, 0644))
+	require.NoError(t, ro.Write())
+	testutil.RequireMetricsEqual(t, metrics, m.Metrics())
+}
+
+func TestRunningOutputSpoolCorruptSegment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:         Filter{},
+		SpoolDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 4)
+	require.NoError(t, ro.Init())
+
+	for _, metric := range spoolMetrics("metric1", "metric2") {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+
+	// A segment that can't be parsed is dropped.
+	segments, err := filepath.Glob(filepath.Join(dir, "*.spool"))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	require.NoError(t, ioutil.WriteFile(segments[0], []byte("not line protocol\n"), 0644))
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.BufferLength())
+	require.Empty(t, m.Metrics())
+}
@@ -0,0 +1,273 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const spoolExtension = ".spool"
+
+// errCorruptSegment is returned for segments that can't be parsed, which
+// won't succeed when retried.
+var errCorruptSegment = errors.New("corrupt spool segment")
+
+// segment is a file of the spool holding one batch of metrics.
+type segment struct {
+	seq   uint64
+	size  int64
+	count int
+}
+
+// Spool is a bounded queue of metric batches stored on disk.  Each batch is
+// written to a file of its own in line protocol and read back in the order it
+// was added.  The files are named by their sequence and metric count, so the
+// spool is loaded without reading them.
+type Spool struct {
+	sync.Mutex
+	dir   string
+	limit int64
+
+	segments []segment // oldest first
+	size     int64     // total size of the segments in bytes
+	count    int       // total number of metrics in the segments
+	seq      uint64    // sequence number of the next segment
+
+	serializer *serializer.Serializer
+
+	MetricsSpooled selfstat.Stat
+	SpoolSize      selfstat.Stat
+}
+
+// NewSpool returns a spool storing up to limit bytes in a subdirectory of the
+// directory named after the output, so outputs can share the directory.  The
+// directory is created and read by Open.
+func NewSpool(name string, alias string, dir string, limit int64) *Spool {
+	tags := map[string]string{"output": name}
+	subdir := name
+	if alias != "" {
+		tags["alias"] = alias
+		subdir += "-" + alias
+	}
+	subdir = strings.NewReplacer("/", "_", "\\", "_").Replace(subdir)
+
+	s := serializer.NewSerializer()
+	s.SetFieldTypeSupport(serializer.UintSupport)
+
+	return &Spool{
+		dir:        filepath.Join(dir, subdir),
+		limit:      limit,
+		serializer: s,
+		MetricsSpooled: selfstat.Register(
+			"write",
+			"metrics_spooled",
+			tags,
+		),
+		SpoolSize: selfstat.Register(
+			"write",
+			"spool_size",
+			tags,
+		),
+	}
+}
+
+// Open creates the directory and loads the segments left by a previous run.
+func (s *Spool) Open() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0750); err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	s.segments = s.segments[:0]
+	s.size, s.count, s.seq = 0, 0, 0
+	for _, file := range files {
+		name := file.Name()
+		// Remove segments that were not completely written.
+		if strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".tmp") {
+			if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		if file.IsDir() || !strings.HasSuffix(name, spoolExtension) {
+			continue
+		}
+		seg, ok := parseSegmentName(name)
+		if !ok {
+			continue
+		}
+		seg.size = file.Size()
+		s.segments = append(s.segments, seg)
+	}
+
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].seq < s.segments[j].seq
+	})
+	for _, seg := range s.segments {
+		s.size += seg.size
+		s.count += seg.count
+		s.seq = seg.seq + 1
+	}
+	s.SpoolSize.Set(s.size)
+	return nil
+}
+
+// Len returns the number of metrics in the spool.
+func (s *Spool) Len() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.count
+}
+
+// Push adds the metrics as a new segment.  When the spool is larger than its
+// limit the oldest segments are removed and the number of metrics they held
+// is returned.
+func (s *Spool) Push(metrics []telegraf.Metric) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	data, err := s.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	// Write to a temporary file first so no partial segment is read after
+	// a crash.
+	tmp, err := ioutil.TempFile(s.dir, ".segment-*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+
+	seg := segment{
+		seq:   s.seq,
+		size:  int64(len(data)),
+		count: bytes.Count(data, []byte("\n")),
+	}
+	if err := os.Rename(tmp.Name(), s.path(seg)); err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	s.seq++
+	s.segments = append(s.segments, seg)
+	s.size += seg.size
+	s.count += seg.count
+	s.MetricsSpooled.Incr(int64(seg.count))
+
+	// Drop the oldest segments, but always keep the new one.
+	dropped := 0
+	for s.limit > 0 && s.size > s.limit && len(s.segments) > 1 {
+		count := s.segments[0].count
+		if err := s.pop(); err != nil {
+			return dropped, err
+		}
+		dropped += count
+	}
+
+	s.SpoolSize.Set(s.size)
+	return dropped, nil
+}
+
+// Peek returns the metrics of the oldest segment, or no metrics if the spool
+// is empty.
+func (s *Spool) Peek() ([]telegraf.Metric, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.segments) == 0 {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(s.path(s.segments[0]))
+	if err != nil {
+		return nil, err
+	}
+	parser := influx.NewParser(influx.NewMetricHandler())
+	metrics, err := parser.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", errCorruptSegment, s.path(s.segments[0]), err)
+	}
+	return metrics, nil
+}
+
+// Pop removes the oldest segment.
+func (s *Spool) Pop() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.segments) == 0 {
+		return nil
+	}
+	err := s.pop()
+	s.SpoolSize.Set(s.size)
+	return err
+}
+
+func (s *Spool) pop() error {
+	seg := s.segments[0]
+	if err := os.Remove(s.path(seg)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.segments = s.segments[1:]
+	s.size -= seg.size
+	s.count -= seg.count
+	return nil
+}
+
+func (s *Spool) path(seg segment) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d-%d%s", seg.seq, seg.count, spoolExtension))
+}
+
+// parseSegmentName returns the sequence and metric count of a segment from
+// its file name.
+func parseSegmentName(name string) (segment, bool) {
+	parts := strings.SplitN(strings.TrimSuffix(name, spoolExtension), "-", 2)
+	if len(parts) != 2 {
+		return segment{}, false
+	}
+	seq, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return segment{}, false
+	}
+	count, err := strconv.Atoi(parts[1])
+	if err != nil || count < 0 {
+		return segment{}, false
+	}
+	return segment{seq: seq, count: count}, true
+}
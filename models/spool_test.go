@@ -0,0 +1,173 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func spoolMetrics(names ...string) []telegraf.Metric {
+	metrics := make([]telegraf.Metric, 0, len(names))
+	for i, name := range names {
+		metrics = append(metrics, testutil.MustMetric(
+			name,
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": int64(i), "count": uint64(i), "state": "ok"},
+			time.Unix(1600000000, int64(i)),
+		))
+	}
+	return metrics
+}
+
+func TestSpool_PushPeekPop(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := NewSpool("test", "", filepath.Join(dir, "spool"), 0)
+	require.NoError(t, s.Open())
+	require.Equal(t, 0, s.Len())
+
+	first := spoolMetrics("a", "b")
+	second := spoolMetrics("c")
+	_, err = s.Push(first)
+	require.NoError(t, err)
+	_, err = s.Push(second)
+	require.NoError(t, err)
+	require.Equal(t, 3, s.Len())
+
+	actual, err := s.Peek()
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, first, actual)
+	require.NoError(t, s.Pop())
+
+	actual, err = s.Peek()
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, second, actual)
+	require.NoError(t, s.Pop())
+
+	require.Equal(t, 0, s.Len())
+	actual, err = s.Peek()
+	require.NoError(t, err)
+	require.Empty(t, actual)
+}
+
+func TestSpool_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := NewSpool("test", "", dir, 0)
+	require.NoError(t, s.Open())
+	_, err = s.Push(spoolMetrics("a"))
+	require.NoError(t, err)
+	_, err = s.Push(spoolMetrics("b", "c"))
+	require.NoError(t, err)
+
+	// A segment left over while being written is removed.
+	tmp := filepath.Join(dir, "test", ".segment-1.tmp")
+	require.NoError(t, ioutil.WriteFile(tmp, []byte("partial"), 0640))
+
+	s = NewSpool("test", "", dir, 0)
+	require.NoError(t, s.Open())
+	require.Equal(t, 3, s.Len())
+	require.NoFileExists(t, tmp)
+
+	actual, err := s.Peek()
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, spoolMetrics("a"), actual)
+	require.NoError(t, s.Pop())
+
+	// New segments are added after the existing ones.
+	_, err = s.Push(spoolMetrics("d"))
+	require.NoError(t, err)
+	actual, err = s.Peek()
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, spoolMetrics("b", "c"), actual)
+}
+
+func TestSpool_Limit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := NewSpool("test", "", dir, 1)
+	require.NoError(t, s.Open())
+
+	dropped, err := s.Push(spoolMetrics("a", "b"))
+	require.NoError(t, err)
+	require.Equal(t, 0, dropped)
+
+	dropped, err = s.Push(spoolMetrics("c"))
+	require.NoError(t, err)
+	require.Equal(t, 2, dropped)
+	require.Equal(t, 1, s.Len())
+
+	actual, err := s.Peek()
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, spoolMetrics("c"), actual)
+}
+
+func TestSpool_Size(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := NewSpool("test", "", dir, 0)
+	require.NoError(t, s.Open())
+	_, err = s.Push(spoolMetrics("a", "b"))
+	require.NoError(t, err)
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "test"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	require.Equal(t, files[0].Size(), s.SpoolSize.Get())
+
+	require.NoError(t, s.Pop())
+	require.Equal(t, int64(0), s.SpoolSize.Get())
+}
+
+func TestSpool_OutputDirectories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	first := NewSpool("test", "", dir, 0)
+	second := NewSpool("test", "other", dir, 0)
+	require.NoError(t, first.Open())
+	require.NoError(t, second.Open())
+	_, err = first.Push(spoolMetrics("a"))
+	require.NoError(t, err)
+
+	require.DirExists(t, filepath.Join(dir, "test"))
+	require.DirExists(t, filepath.Join(dir, "test-other"))
+	require.Equal(t, 1, first.Len())
+	require.Equal(t, 0, second.Len())
+}
+
+func TestSpool_OpenCountsFromNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The segments are counted by their name without being read.
+	spoolDir := filepath.Join(dir, "test")
+	require.NoError(t, os.MkdirAll(spoolDir, 0750))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(spoolDir, "00000000000000000003-5.spool"), nil, 0640))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(spoolDir, "00000000000000000007-2.spool"), nil, 0640))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(spoolDir, "unknown.spool"), nil, 0640))
+
+	s := NewSpool("test", "", dir, 0)
+	require.NoError(t, s.Open())
+	require.Equal(t, 7, s.Len())
+
+	_, err = s.Push(spoolMetrics("a"))
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(spoolDir, "00000000000000000008-1.spool"))
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/encoding/unicode"
 )
 
@@ -16,9 +17,10 @@ import (
 // encoding if you want invalid bytes replaced using the the unicode
 // replacement character.
 //
-// Detection of utf-16 endianness using the BOM is not currently provided due
-// to the tail input plugins requirement to be able to start at the middle or
-// end of the file.
+// Detection of utf-16 endianness using the BOM is not provided by the Decoder
+// due to the tail input plugins requirement to be able to start at the middle
+// or end of the file.  Plugins that read complete files can use Detect to
+// choose an encoding per file.
 func NewDecoder(enc string) (*Decoder, error) {
 	switch enc {
 	case "utf-8":
@@ -27,6 +29,8 @@ func NewDecoder(enc string) (*Decoder, error) {
 		return newDecoder(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()), nil
 	case "utf-16be":
 		return newDecoder(unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()), nil
+	case "latin-1":
+		return newDecoder(charmap.ISO8859_1.NewDecoder()), nil
 	case "none", "":
 		return newDecoder(encoding.Nop.NewDecoder()), nil
 	}
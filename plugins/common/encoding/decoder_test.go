@@ -58,6 +58,12 @@ func TestDecoder(t *testing.T) {
 			input:    []byte("\xfe\xff\x00h\x00o\x00w\x00d\x00y"),
 			expected: []byte("\xef\xbb\xbfhowdy"),
 		},
+		{
+			name:     "latin-1 decoder",
+			encoding: "latin-1",
+			input:    []byte("h\xe9llo"),
+			expected: []byte("h\u00e9llo"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -76,3 +82,75 @@ func TestDecoder(t *testing.T) {
 		})
 	}
 }
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{
+			name:     "empty",
+			input:    []byte(""),
+			expected: "utf-8",
+		},
+		{
+			name:     "ascii",
+			input:    []byte("howdy"),
+			expected: "utf-8",
+		},
+		{
+			name:     "utf-8 multi-byte",
+			input:    []byte("héllo wörld"),
+			expected: "utf-8",
+		},
+		{
+			name:     "utf-8 BOM",
+			input:    []byte("\xef\xbb\xbfhowdy"),
+			expected: "utf-8",
+		},
+		{
+			name:     "utf-16le BOM",
+			input:    []byte("\xff\xfeh\x00o\x00w\x00d\x00y\x00"),
+			expected: "utf-16le",
+		},
+		{
+			name:     "utf-16be BOM",
+			input:    []byte("\xfe\xff\x00h\x00o\x00w\x00d\x00y"),
+			expected: "utf-16be",
+		},
+		{
+			name:     "utf-16le no BOM",
+			input:    []byte("h\x00o\x00w\x00d\x00y\x00"),
+			expected: "utf-16le",
+		},
+		{
+			name:     "utf-16be no BOM",
+			input:    []byte("\x00h\x00o\x00w\x00d\x00y"),
+			expected: "utf-16be",
+		},
+		{
+			name:     "latin-1",
+			input:    []byte("h\xe9llo w\xf6rld"),
+			expected: "latin-1",
+		},
+		{
+			name:     "utf-8 multi-byte cut by sample size",
+			input:    append(bytes.Repeat([]byte("a"), detectSampleSize-1), []byte("é")...),
+			expected: "utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, Detect(tt.input))
+
+			enc, r, err := DetectReader(bytes.NewReader(tt.input))
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, enc)
+			data, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.input, data)
+		})
+	}
+}
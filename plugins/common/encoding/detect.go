@@ -0,0 +1,109 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+)
+
+// detectSampleSize is the maximum number of bytes inspected when guessing the
+// encoding of data without a BOM.
+const detectSampleSize = 4096
+
+// Detect guesses the character encoding of a complete buffer and returns a
+// name suitable for passing to NewDecoder.
+//
+// A BOM always takes precedence.  Without a BOM the data is checked for
+// utf-16 by looking for the characteristic pattern of zero bytes produced by
+// ASCII text, then for valid utf-8.  Anything else is assumed to be latin-1,
+// which can decode any byte sequence.
+//
+// Detection requires the start of the data and so is only suitable for
+// plugins that read files from the beginning.
+func Detect(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, bomUTF8):
+		return "utf-8"
+	case bytes.HasPrefix(b, bomUTF16LE):
+		return "utf-16le"
+	case bytes.HasPrefix(b, bomUTF16BE):
+		return "utf-16be"
+	}
+
+	sample := b
+	if len(sample) > detectSampleSize {
+		sample = sample[:detectSampleSize]
+	}
+
+	if enc := detectUTF16(sample); enc != "" {
+		return enc
+	}
+
+	if utf8.Valid(sample) {
+		return "utf-8"
+	}
+
+	// A truncated sample may end in the middle of a multi-byte sequence.
+	if len(sample) < len(b) {
+		for i := 1; i < utf8.UTFMax; i++ {
+			if utf8.Valid(sample[:len(sample)-i]) {
+				return "utf-8"
+			}
+		}
+	}
+
+	return "latin-1"
+}
+
+// DetectReader guesses the character encoding from the start of the reader
+// like Detect, without reading the data completely.  The returned reader
+// reads the data from the beginning.
+func DetectReader(r io.Reader) (string, io.Reader, error) {
+	// One more character than inspected, so a sample ending in the middle
+	// of a character is recognized as truncated.
+	size := detectSampleSize + utf8.UTFMax
+	br := bufio.NewReaderSize(r, size)
+	b, err := br.Peek(size)
+	if err != nil && err != io.EOF {
+		return "", nil, err
+	}
+	return Detect(b), br, nil
+}
+
+// detectUTF16 reports the endianness of utf-16 data without a BOM by counting
+// zero bytes in even and odd positions, returning "" if the data does not
+// look like utf-16.
+func detectUTF16(b []byte) string {
+	if len(b) < 2 {
+		return ""
+	}
+
+	var even, odd int
+	for i, c := range b {
+		if c != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+
+	// Mostly ASCII text encoded as utf-16 has a zero in every other byte.
+	pairs := len(b) / 2
+	switch {
+	case odd > pairs*3/4 && even <= pairs/10:
+		return "utf-16le"
+	case even > pairs*3/4 && odd <= pairs/10:
+		return "utf-16be"
+	}
+	return ""
+}
@@ -0,0 +1,160 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options are the completion markers written by outputs writing files or
+// objects, so downstream jobs know when the data of a flush is complete.
+type Options struct {
+	SuccessMarker  string `toml:"success_marker"`
+	ManifestPrefix string `toml:"manifest_prefix"`
+}
+
+// File is an entry of the manifest.
+type File struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	Records int    `json:"records"`
+}
+
+// Manifest lists the files written during a flush.
+type Manifest struct {
+	Time  time.Time `json:"time"`
+	Files []File    `json:"files"`
+}
+
+// WriteFunc stores the data under the slash separated name.
+type WriteFunc func(name string, data []byte) error
+
+// Init checks the options.
+func (o *Options) Init() error {
+	if strings.ContainsAny(o.SuccessMarker, `/\`) {
+		return fmt.Errorf("invalid success_marker %q", o.SuccessMarker)
+	}
+	return nil
+}
+
+// Enabled returns true if markers or manifests are written.
+func (o *Options) Enabled() bool {
+	return o.SuccessMarker != "" || o.ManifestPrefix != ""
+}
+
+// Complete writes the manifest of the files written during the flush at the
+// time, and then the success marker in the directory of each file.  Nothing is
+// written without files.
+func (o *Options) Complete(t time.Time, files []File, write WriteFunc) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	if o.ManifestPrefix != "" {
+		data, err := json.MarshalIndent(&Manifest{Time: t.UTC(), Files: files}, "", "  ")
+		if err != nil {
+			return err
+		}
+		name := o.ManifestPrefix + strconv.FormatInt(t.UnixNano(), 10) + ".json"
+		if err := write(name, append(data, '\n')); err != nil {
+			return fmt.Errorf("writing manifest %q: %v", name, err)
+		}
+	}
+
+	if o.SuccessMarker != "" {
+		dirs := make(map[string]bool)
+		for _, f := range files {
+			dirs[path.Dir(f.Name)] = true
+		}
+		sorted := make([]string, 0, len(dirs))
+		for dir := range dirs {
+			sorted = append(sorted, dir)
+		}
+		sort.Strings(sorted)
+
+		for _, dir := range sorted {
+			name := path.Join(dir, o.SuccessMarker)
+			if err := write(name, nil); err != nil {
+				return fmt.Errorf("writing success marker %q: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// NewFile returns the entry of a file with the data.
+func NewFile(name string, data []byte, records int) File {
+	sum := sha256.Sum256(data)
+	return File{
+		Name:    name,
+		Size:    int64(len(data)),
+		SHA256:  hex.EncodeToString(sum[:]),
+		Records: records,
+	}
+}
+
+// Digest computes the entry of a file from the data written to it.
+type Digest struct {
+	hash hash.Hash
+	size int64
+}
+
+// NewDigest returns an empty digest.
+func NewDigest() *Digest {
+	return &Digest{hash: sha256.New()}
+}
+
+func (d *Digest) Write(p []byte) (int, error) {
+	d.size += int64(len(p))
+	return d.hash.Write(p)
+}
+
+// File returns the entry of the file with the data written so far.
+func (d *Digest) File(name string, records int) File {
+	return File{
+		Name:    name,
+		Size:    d.size,
+		SHA256:  hex.EncodeToString(d.hash.Sum(nil)),
+		Records: records,
+	}
+}
+
+// WriteFile writes a local file with the slash separated name, through a
+// temporary file so readers never see a partial file.  The directory is
+// created if missing.
+func WriteFile(name string, data []byte) error {
+	name = filepath.FromSlash(name)
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(name)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
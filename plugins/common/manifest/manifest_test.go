@@ -0,0 +1,83 @@
+package manifest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recorder map[string]string
+
+func (r recorder) write(name string, data []byte) error {
+	r[name] = string(data)
+	return nil
+}
+
+func TestComplete(t *testing.T) {
+	o := &Options{SuccessMarker: "_SUCCESS", ManifestPrefix: "manifests/telegraf-"}
+	require.NoError(t, o.Init())
+
+	files := []File{
+		NewFile("dt=2020-09-13/hour=12/a.out", []byte("a\n"), 1),
+		NewFile("dt=2020-09-13/hour=12/b.out", []byte("b\nc\n"), 2),
+		NewFile("dt=2020-09-13/hour=13/c.out", []byte("d\n"), 1),
+	}
+	r := recorder{}
+	now := time.Unix(1600000000, 123)
+	require.NoError(t, o.Complete(now, files, r.write))
+
+	require.Len(t, r, 3)
+	require.Contains(t, r, "dt=2020-09-13/hour=12/_SUCCESS")
+	require.Contains(t, r, "dt=2020-09-13/hour=13/_SUCCESS")
+
+	var m Manifest
+	require.NoError(t, json.Unmarshal([]byte(r["manifests/telegraf-1600000000000000123.json"]), &m))
+	require.True(t, now.Equal(m.Time))
+	require.Equal(t, files, m.Files)
+	require.Equal(t, File{
+		Name:    "dt=2020-09-13/hour=12/a.out",
+		Size:    2,
+		SHA256:  "87428fc522803d31065e7bce3cf03fe475096631e5e07bbd7a0fde60c4cf25c7",
+		Records: 1,
+	}, m.Files[0])
+}
+
+func TestCompleteWithoutFiles(t *testing.T) {
+	o := &Options{SuccessMarker: "_SUCCESS", ManifestPrefix: "manifest-"}
+	r := recorder{}
+	require.NoError(t, o.Complete(time.Now(), nil, r.write))
+	require.Empty(t, r)
+}
+
+func TestDigest(t *testing.T) {
+	d := NewDigest()
+	_, err := d.Write([]byte("b\n"))
+	require.NoError(t, err)
+	_, err = d.Write([]byte("c\n"))
+	require.NoError(t, err)
+	require.Equal(t, NewFile("b.out", []byte("b\nc\n"), 2), d.File("b.out", 2))
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.ToSlash(filepath.Join(dir, "hour=12", "_SUCCESS"))
+	require.NoError(t, WriteFile(name, nil))
+	require.FileExists(t, filepath.Join(dir, "hour=12", "_SUCCESS"))
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "hour=12"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+}
+
+func TestInvalidOptions(t *testing.T) {
+	o := &Options{SuccessMarker: "done/_SUCCESS"}
+	require.EqualError(t, o.Init(), `invalid success_marker "done/_SUCCESS"`)
+}
@@ -0,0 +1,148 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Values of the tag or metadata marking an object as processed.
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+// Status returns the value marking an object processed with or without
+// success.
+func Status(success bool) string {
+	if success {
+		return StatusSuccess
+	}
+	return StatusError
+}
+
+// Filter selects the files to ingest by name, using the files_to_monitor and
+// files_to_ignore patterns of the monitors.
+type Filter struct {
+	match  []*regexp.Regexp
+	ignore []*regexp.Regexp
+}
+
+// NewFilter compiles the patterns of the files to monitor and to ignore.
+func NewFilter(monitor, ignore []string) (*Filter, error) {
+	match, err := compileRegexes(monitor)
+	if err != nil {
+		return nil, err
+	}
+	skip, err := compileRegexes(ignore)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{match: match, ignore: skip}, nil
+}
+
+// Match reports whether the file should be ingested.  Without patterns to
+// monitor, all files not ignored are.
+func (f *Filter) Match(name string) bool {
+	if len(f.match) > 0 && !matchesAny(f.match, name) {
+		return false
+	}
+	return !matchesAny(f.ignore, name)
+}
+
+// Directories are the directory watched by a monitor of a file server and the
+// directories files are moved to once processed.
+type Directories struct {
+	Directory string
+	Finished  string
+	Error     string
+}
+
+// Validate checks that the directories are set and distinct from the watched
+// one.
+func (d Directories) Validate() error {
+	if d.Directory == "" || d.Finished == "" || d.Error == "" {
+		return errors.New("directory, finished_directory and error_directory must be set")
+	}
+	if d.Finished == d.Directory || d.Error == d.Directory {
+		return errors.New("finished_directory and error_directory must differ from directory")
+	}
+	return nil
+}
+
+// Move moves the file of the watched directory to the finished or error
+// directory with the rename function of the server.
+func (d Directories) Move(name string, success bool, rename func(src, dest string) error) error {
+	dir := d.Finished
+	if !success {
+		dir = d.Error
+	}
+	src := path.Join(d.Directory, name)
+	dest := path.Join(dir, name)
+	if err := rename(src, dest); err != nil {
+		return fmt.Errorf("moving file %q to %q: %v", src, dest, err)
+	}
+	return nil
+}
+
+// Prefixes are the prefix watched by a monitor of a bucket and the prefixes
+// objects are moved to once processed.
+type Prefixes struct {
+	Prefix   string
+	Finished string
+	Error    string
+}
+
+// Validate checks that the prefixes are set and distinct from the watched
+// one.
+func (p Prefixes) Validate() error {
+	if p.Finished == "" || p.Error == "" {
+		return errors.New("finished_prefix and error_prefix must be set when completion_action is \"move\"")
+	}
+	if p.Finished == p.Prefix || p.Error == p.Prefix {
+		return errors.New("finished_prefix and error_prefix must differ from prefix")
+	}
+	return nil
+}
+
+// Processed reports whether the name is below the finished or error prefix.
+func (p Prefixes) Processed(name string) bool {
+	return strings.HasPrefix(name, p.Finished) || strings.HasPrefix(name, p.Error)
+}
+
+// Move moves the object, called kind such as "object" or "blob" in errors,
+// below the finished or error prefix with the move function of the bucket.
+func (p Prefixes) Move(kind, name string, success bool, move func(src, dest string) error) error {
+	prefix := p.Finished
+	if !success {
+		prefix = p.Error
+	}
+	dest := prefix + strings.TrimPrefix(name, p.Prefix)
+	if err := move(name, dest); err != nil {
+		return fmt.Errorf("moving %s %q to %q: %v", kind, name, dest, err)
+	}
+	return nil
+}
+
+func compileRegexes(patterns []string) ([]*regexp.Regexp, error) {
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compiling regex %q: %v", pattern, err)
+		}
+		regexes = append(regexes, re)
+	}
+	return regexes, nil
+}
+
+func matchesAny(regexes []*regexp.Regexp, name string) bool {
+	for _, re := range regexes {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
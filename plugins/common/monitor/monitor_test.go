@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	f, err := NewFilter([]string{`\.csv$`}, []string{`^tmp`})
+	require.NoError(t, err)
+	require.True(t, f.Match("data.csv"))
+	require.False(t, f.Match("data.json"))
+	require.False(t, f.Match("tmp.csv"))
+
+	f, err = NewFilter(nil, []string{`^tmp`})
+	require.NoError(t, err)
+	require.True(t, f.Match("data.json"))
+	require.False(t, f.Match("tmp.json"))
+
+	_, err = NewFilter([]string{"("}, nil)
+	require.EqualError(t, err, "compiling regex \"(\": error parsing regexp: missing closing ): `(`")
+}
+
+func TestDirectories(t *testing.T) {
+	require.Error(t, Directories{Directory: "/in", Finished: "/done"}.Validate())
+	require.Error(t, Directories{Directory: "/in", Finished: "/in", Error: "/error"}.Validate())
+
+	d := Directories{Directory: "/in", Finished: "/done", Error: "/error"}
+	require.NoError(t, d.Validate())
+
+	var moved []string
+	rename := func(src, dest string) error {
+		moved = append(moved, src, dest)
+		return nil
+	}
+	require.NoError(t, d.Move("a.csv", true, rename))
+	require.NoError(t, d.Move("b.csv", false, rename))
+	require.Equal(t, []string{"/in/a.csv", "/done/a.csv", "/in/b.csv", "/error/b.csv"}, moved)
+
+	err := d.Move("a.csv", true, func(src, dest string) error { return errors.New("denied") })
+	require.EqualError(t, err, `moving file "/in/a.csv" to "/done/a.csv": denied`)
+}
+
+func TestPrefixes(t *testing.T) {
+	require.Error(t, Prefixes{Prefix: "in/", Finished: "done/"}.Validate())
+	require.Error(t, Prefixes{Prefix: "in/", Finished: "in/", Error: "error/"}.Validate())
+
+	p := Prefixes{Prefix: "in/", Finished: "done/", Error: "error/"}
+	require.NoError(t, p.Validate())
+	require.True(t, p.Processed("done/a.csv"))
+	require.True(t, p.Processed("error/a.csv"))
+	require.False(t, p.Processed("in/a.csv"))
+
+	var moved []string
+	move := func(src, dest string) error {
+		moved = append(moved, src, dest)
+		return nil
+	}
+	require.NoError(t, p.Move("object", "in/a.csv", true, move))
+	require.NoError(t, p.Move("object", "in/b.csv", false, move))
+	require.Equal(t, []string{"in/a.csv", "done/a.csv", "in/b.csv", "error/b.csv"}, moved)
+
+	err := p.Move("blob", "in/a.csv", true, func(src, dest string) error { return errors.New("denied") })
+	require.EqualError(t, err, `moving blob "in/a.csv" to "done/a.csv": denied`)
+
+	// Objects moved below a finished or error prefix nested in the watched
+	// one are not ingested again.
+	p = Prefixes{Prefix: "in/", Finished: "in/done/", Error: "in/error/"}
+	require.NoError(t, p.Validate())
+	require.True(t, p.Processed("in/done/a.csv"))
+	require.True(t, p.Processed("in/error/a.csv"))
+	require.False(t, p.Processed("in/a.csv"))
+}
@@ -0,0 +1,100 @@
+package upload
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// Partitioner sorts metrics into partitions, such as Hive style
+// "dt=2020-09-13/hour=12" directories, from the time and name of each metric.
+type Partitioner struct {
+	template string
+}
+
+// Partition is the metrics of a partition.
+type Partition struct {
+	Name    string
+	Metrics []telegraf.Metric
+}
+
+var partitionReplacer = strings.NewReplacer("/", "_", "\\", "_")
+
+// NewPartitioner checks the placeholders of the partition template.  The
+// placeholders {{yyyy}}, {{MM}}, {{dd}}, {{HH}}, {{mm}} and {{ss}} are the UTC
+// time of the metric and {{measurement}} its name.  An empty template puts all
+// metrics into one unnamed partition.
+func NewPartitioner(template string) (*Partitioner, error) {
+	template = strings.Trim(template, "/")
+	for _, match := range placeholderRe.FindAllStringSubmatch(template, -1) {
+		if _, ok := partitionPlaceholders("", time.Time{})[match[1]]; !ok {
+			return nil, fmt.Errorf("invalid placeholder %q in partitioning", match[0])
+		}
+	}
+	return &Partitioner{template: template}, nil
+}
+
+// Partition returns the partition of the metric.
+func (p *Partitioner) Partition(m telegraf.Metric) string {
+	if p.template == "" {
+		return ""
+	}
+	values := partitionPlaceholders(m.Name(), m.Time().UTC())
+	return placeholderRe.ReplaceAllStringFunc(p.template, func(placeholder string) string {
+		return values[placeholderRe.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+// Group returns the metrics of each partition, in the order the partitions
+// are first seen.
+func (p *Partitioner) Group(metrics []telegraf.Metric) []Partition {
+	if p.template == "" {
+		return []Partition{{Metrics: metrics}}
+	}
+
+	var partitions []Partition
+	index := make(map[string]int)
+	for _, m := range metrics {
+		name := p.Partition(m)
+		i, ok := index[name]
+		if !ok {
+			i = len(partitions)
+			index[name] = i
+			partitions = append(partitions, Partition{Name: name})
+		}
+		partitions[i].Metrics = append(partitions[i].Metrics, m)
+	}
+	return partitions
+}
+
+// Serialize returns the serialized metrics of each partition.
+func (p *Partitioner) Serialize(serializer serializers.Serializer, metrics []telegraf.Metric) (map[string]Batch, error) {
+	partitions := make(map[string]Batch)
+	for _, partition := range p.Group(metrics) {
+		octets, err := serializer.SerializeBatch(partition.Metrics)
+		if err != nil {
+			return nil, fmt.Errorf("could not serialize metrics: %v", err)
+		}
+		partitions[partition.Name] = Batch{Data: octets, Records: len(partition.Metrics), Metrics: partition.Metrics}
+	}
+	return partitions, nil
+}
+
+func partitionPlaceholders(measurement string, t time.Time) map[string]string {
+	measurement = partitionReplacer.Replace(measurement)
+	if measurement == "." || measurement == ".." {
+		measurement = "_"
+	}
+	return map[string]string{
+		"measurement": measurement,
+		"yyyy":        t.Format("2006"),
+		"MM":          t.Format("01"),
+		"dd":          t.Format("02"),
+		"HH":          t.Format("15"),
+		"mm":          t.Format("04"),
+		"ss":          t.Format("05"),
+	}
+}
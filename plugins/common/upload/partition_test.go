@@ -0,0 +1,47 @@
+package upload
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitioner(t *testing.T) {
+	p, err := NewPartitioner("/{{measurement}}/dt={{yyyy}}-{{MM}}-{{dd}}/hour={{HH}}/")
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 2}, time.Unix(1600003600, 0)),
+		testutil.MustMetric("a/b", nil, map[string]interface{}{"value": 3}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 4}, time.Unix(1600000001, 0)),
+	}
+	partitions := p.Group(metrics)
+	require.Equal(t, []Partition{
+		{Name: "cpu/dt=2020-09-13/hour=12", Metrics: []telegraf.Metric{metrics[0], metrics[3]}},
+		{Name: "cpu/dt=2020-09-13/hour=13", Metrics: []telegraf.Metric{metrics[1]}},
+		{Name: "a_b/dt=2020-09-13/hour=12", Metrics: []telegraf.Metric{metrics[2]}},
+	}, partitions)
+}
+
+func TestPartitionerSerialize(t *testing.T) {
+	p, err := NewPartitioner("")
+	require.NoError(t, err)
+	s, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	partitions, err := p.Serialize(s, testutil.MockMetrics())
+	require.NoError(t, err)
+	require.Equal(t, []byte(line), partitions[""].Data)
+	require.Equal(t, 1, partitions[""].Records)
+	require.Len(t, partitions[""].Metrics, 1)
+}
+
+func TestPartitionerInvalid(t *testing.T) {
+	_, err := NewPartitioner("dt={{date}}")
+	require.Error(t, err)
+}
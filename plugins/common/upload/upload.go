@@ -0,0 +1,321 @@
+package upload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/manifest"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const (
+	defaultObjectName    = "{{host}}/{{yyyy}}/{{MM}}/{{dd}}/{{HH}}-{{instance}}-{{counter}}"
+	defaultFlushSize     = 16 * 1024 * 1024
+	defaultFlushInterval = 5 * time.Minute
+)
+
+// placeholderRe matches the placeholders of an object name template.
+var placeholderRe = regexp.MustCompile(`{{\s*(\w+)\s*}}`)
+
+// Config is the batching and naming of the objects of outputs uploading to
+// object stores.
+type Config struct {
+	ObjectName    string            `toml:"object_name"`
+	Compression   string            `toml:"compression"`
+	FlushSize     internal.Size     `toml:"flush_size"`
+	FlushInterval internal.Duration `toml:"flush_interval"`
+	Partitioning  string            `toml:"partitioning"`
+}
+
+// UploadFunc uploads the data of an object.
+type UploadFunc func(name string, data []byte) error
+
+// Batch is serialized metrics and the number of metrics in it.  The metrics
+// themselves are needed by batchers serializing whole objects.
+type Batch struct {
+	Data    []byte
+	Records int
+	Metrics []telegraf.Metric
+}
+
+// Batcher accumulates serialized metrics into objects, which are uploaded
+// once they reach the flush size or the first data in them is older than the
+// flush interval.  Each partition is accumulated into objects of its own.
+type Batcher struct {
+	name          *NameTemplate
+	compression   string
+	flushSize     int64
+	flushInterval time.Duration
+	upload        UploadFunc
+	serializer    serializers.Serializer
+	now           func() time.Time
+
+	buffers  map[string]*buffer
+	counter  int64
+	record   bool
+	uploaded []manifest.File
+}
+
+type buffer struct {
+	data    bytes.Buffer
+	metrics []telegraf.Metric
+	size    int
+	records int
+	started time.Time
+}
+
+// NewBatcher checks the config and returns a batcher uploading the objects
+// with the function.
+func (c *Config) NewBatcher(upload UploadFunc) (*Batcher, error) {
+	name, err := NewNameTemplate(c.ObjectName)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Batcher{
+		name:          name,
+		compression:   c.Compression,
+		flushSize:     c.FlushSize.Size,
+		flushInterval: c.FlushInterval.Duration,
+		upload:        upload,
+		now:           time.Now,
+		buffers:       make(map[string]*buffer),
+	}
+	switch b.compression {
+	case "", "gzip":
+	default:
+		return nil, fmt.Errorf("invalid compression %q", b.compression)
+	}
+	if b.flushSize == 0 {
+		b.flushSize = defaultFlushSize
+	}
+	if b.flushInterval == 0 {
+		b.flushInterval = defaultFlushInterval
+	}
+	return b, nil
+}
+
+// SerializeObjects makes the batcher keep the metrics instead of their
+// serialized data, and serialize each object as a whole when uploading it.
+// This is needed for formats whose serialized batches can't be concatenated;
+// the size of the batches added still decides when objects are full.
+func (b *Batcher) SerializeObjects(serializer serializers.Serializer) {
+	b.serializer = serializer
+}
+
+// Add adds the data of the records to the current object and uploads it when
+// full or old enough.  When the upload fails the data is removed again, so it
+// is not duplicated when the write is retried.
+func (b *Batcher) Add(octets []byte, records int) error {
+	return b.AddPartitions(map[string]Batch{"": {Data: octets, Records: records}})
+}
+
+// AddPartitions adds the data of each partition to the current object of the
+// partition, and uploads the objects that are full or old enough.  When an
+// upload fails the data not uploaded yet is removed again, only the data of
+// partitions uploaded before the failure is duplicated when the write is
+// retried.
+func (b *Batcher) AddPartitions(partitions map[string]Batch) error {
+	now := b.now()
+
+	// Objects of other partitions are uploaded before any data is added, so
+	// their failure leaves nothing behind.
+	for _, partition := range b.partitions() {
+		if _, ok := partitions[partition]; !ok && b.due(b.buffers[partition], now) {
+			if err := b.flush(partition); err != nil {
+				return err
+			}
+		}
+	}
+
+	type state struct{ size, metrics, records int }
+	previous := make(map[string]state, len(partitions))
+	for partition, batch := range partitions {
+		if len(batch.Data) == 0 {
+			continue
+		}
+		buf, ok := b.buffers[partition]
+		if !ok {
+			buf = &buffer{started: now}
+			b.buffers[partition] = buf
+		}
+		previous[partition] = state{size: buf.size, metrics: len(buf.metrics), records: buf.records}
+		if b.serializer != nil {
+			buf.metrics = append(buf.metrics, batch.Metrics...)
+		} else {
+			buf.data.Write(batch.Data)
+		}
+		buf.size += len(batch.Data)
+		buf.records += batch.Records
+	}
+
+	for _, partition := range b.partitions() {
+		if !b.due(b.buffers[partition], now) {
+			continue
+		}
+		if err := b.flush(partition); err != nil {
+			for p, prev := range previous {
+				if buf, ok := b.buffers[p]; ok {
+					if b.serializer != nil {
+						buf.metrics = buf.metrics[:prev.metrics]
+					} else {
+						buf.data.Truncate(prev.size)
+					}
+					buf.size = prev.size
+					buf.records = prev.records
+					if prev.size == 0 {
+						delete(b.buffers, p)
+					}
+				}
+			}
+			return err
+		}
+		delete(previous, partition)
+	}
+	return nil
+}
+
+// Flush uploads the current objects.
+func (b *Batcher) Flush() error {
+	var err error
+	for _, partition := range b.partitions() {
+		if ferr := b.flush(partition); ferr != nil {
+			err = ferr
+		}
+	}
+	return err
+}
+
+func (b *Batcher) due(buf *buffer, now time.Time) bool {
+	return int64(buf.size) >= b.flushSize || now.Sub(buf.started) >= b.flushInterval
+}
+
+// partitions returns the partitions with data in sorted order.
+func (b *Batcher) partitions() []string {
+	partitions := make([]string, 0, len(b.buffers))
+	for partition := range b.buffers {
+		partitions = append(partitions, partition)
+	}
+	sort.Strings(partitions)
+	return partitions
+}
+
+func (b *Batcher) flush(partition string) error {
+	buf := b.buffers[partition]
+	data := buf.data.Bytes()
+	if b.serializer != nil {
+		var err error
+		if data, err = b.serializer.SerializeBatch(buf.metrics); err != nil {
+			return fmt.Errorf("serializing object: %v", err)
+		}
+	}
+	if b.compression == "gzip" {
+		var compressed bytes.Buffer
+		w := gzip.NewWriter(&compressed)
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		data = compressed.Bytes()
+	}
+
+	name := b.name.Name(buf.started, b.counter)
+	if partition != "" {
+		name = path.Join(partition, name)
+	}
+	if err := b.upload(name, data); err != nil {
+		return fmt.Errorf("uploading %q: %v", name, err)
+	}
+
+	b.counter++
+	if b.record {
+		b.uploaded = append(b.uploaded, manifest.NewFile(name, data, buf.records))
+	}
+	delete(b.buffers, partition)
+	return nil
+}
+
+// RecordUploads makes the batcher keep the entries of the uploaded objects
+// for manifests.
+func (b *Batcher) RecordUploads() {
+	b.record = true
+}
+
+// Uploaded returns the objects uploaded since the last call, uploads are only
+// recorded after calling RecordUploads.
+func (b *Batcher) Uploaded() []manifest.File {
+	uploaded := b.uploaded
+	b.uploaded = nil
+	return uploaded
+}
+
+// NameTemplate builds object names from a template with placeholders.
+type NameTemplate struct {
+	template string
+	hostname string
+	// instance is random and differs for each template, so names with it
+	// don't collide with those of previous runs or other outputs.
+	instance string
+}
+
+// NewNameTemplate checks the placeholders of the template, the default
+// template is used if empty.
+func NewNameTemplate(template string) (*NameTemplate, error) {
+	if template == "" {
+		template = defaultObjectName
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	instance := make([]byte, 4)
+	if _, err := rand.Read(instance); err != nil {
+		return nil, err
+	}
+	t := &NameTemplate{template: template, hostname: hostname, instance: hex.EncodeToString(instance)}
+
+	for _, match := range placeholderRe.FindAllStringSubmatch(template, -1) {
+		if _, ok := t.placeholders(time.Time{}, 0)[match[1]]; !ok {
+			return nil, fmt.Errorf("invalid placeholder %q in object_name", match[0])
+		}
+	}
+	return t, nil
+}
+
+// Name returns the name of the object started at the time, the time
+// placeholders are in UTC.
+func (t *NameTemplate) Name(started time.Time, counter int64) string {
+	values := t.placeholders(started.UTC(), counter)
+	return placeholderRe.ReplaceAllStringFunc(t.template, func(placeholder string) string {
+		return values[placeholderRe.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+func (t *NameTemplate) placeholders(started time.Time, counter int64) map[string]string {
+	return map[string]string{
+		"host":      t.hostname,
+		"yyyy":      started.Format("2006"),
+		"MM":        started.Format("01"),
+		"dd":        started.Format("02"),
+		"HH":        started.Format("15"),
+		"mm":        started.Format("04"),
+		"ss":        started.Format("05"),
+		"timestamp": strconv.FormatInt(started.Unix(), 10),
+		"counter":   strconv.FormatInt(counter, 10),
+		"instance":  t.instance,
+	}
+}
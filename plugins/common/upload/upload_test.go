@@ -0,0 +1,301 @@
+package upload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	parsers_csv "github.com/influxdata/telegraf/plugins/parsers/csv"
+	parsers_parquet "github.com/influxdata/telegraf/plugins/parsers/parquet"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type object struct {
+	name string
+	data string
+}
+
+type recorder struct {
+	objects []object
+	err     error
+}
+
+func (r *recorder) upload(name string, data []byte) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.objects = append(r.objects, object{name: name, data: string(data)})
+	return nil
+}
+
+func newBatcher(t *testing.T, config *Config) (*Batcher, *recorder, *time.Time) {
+	r := &recorder{}
+	b, err := config.NewBatcher(r.upload)
+	require.NoError(t, err)
+
+	now := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	b.name.hostname = "localhost"
+	b.name.instance = "5f0c2a1e"
+	b.now = func() time.Time { return now }
+	return b, r, &now
+}
+
+const line = "test1,tag1=value1 value=1 1257894000000000000\n"
+
+func TestFlushInterval(t *testing.T) {
+	b, r, now := newBatcher(t, &Config{})
+
+	require.NoError(t, b.Add([]byte(line), 1))
+	*now = now.Add(time.Minute)
+	require.NoError(t, b.Add([]byte(line), 1))
+	require.Empty(t, r.objects)
+
+	*now = now.Add(5 * time.Minute)
+	require.NoError(t, b.Add([]byte(line), 1))
+	*now = now.Add(time.Hour)
+	require.NoError(t, b.Add([]byte(line), 1))
+	require.NoError(t, b.Flush())
+
+	require.Equal(t, []object{
+		{name: "localhost/2020/09/13/12-5f0c2a1e-0", data: line + line + line},
+		{name: "localhost/2020/09/13/13-5f0c2a1e-1", data: line},
+	}, r.objects)
+}
+
+func TestFlushSize(t *testing.T) {
+	b, r, _ := newBatcher(t, &Config{
+		ObjectName: "{{yyyy}}{{MM}}{{dd}}T{{HH}}{{mm}}{{ss}}-{{timestamp}}-{{ counter }}.influx",
+		FlushSize:  internal.Size{Size: int64(2 * len(line))},
+	})
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, b.Add([]byte(line), 1))
+	}
+	require.Equal(t, []object{
+		{name: "20200913T122640-1600000000-0.influx", data: line + line},
+		{name: "20200913T122640-1600000000-1.influx", data: line + line},
+	}, r.objects)
+}
+
+func TestSkipEmpty(t *testing.T) {
+	b, r, _ := newBatcher(t, &Config{})
+
+	require.NoError(t, b.Add(nil, 0))
+	require.NoError(t, b.Flush())
+	require.Empty(t, r.objects)
+}
+
+func TestUploadsNotRecorded(t *testing.T) {
+	b, _, _ := newBatcher(t, &Config{})
+
+	require.NoError(t, b.Add([]byte(line), 1))
+	require.NoError(t, b.Flush())
+	require.Empty(t, b.Uploaded())
+}
+
+func TestGzip(t *testing.T) {
+	b, r, _ := newBatcher(t, &Config{Compression: "gzip"})
+
+	require.NoError(t, b.Add([]byte(line), 1))
+	require.NoError(t, b.Flush())
+
+	require.Len(t, r.objects, 1)
+	gr, err := gzip.NewReader(bytes.NewBufferString(r.objects[0].data))
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(gr)
+	require.NoError(t, err)
+	require.Equal(t, line, string(data))
+}
+
+func TestUploadErrorDropsData(t *testing.T) {
+	b, r, _ := newBatcher(t, &Config{
+		FlushSize: internal.Size{Size: int64(2 * len(line))},
+	})
+
+	require.NoError(t, b.Add([]byte(line), 1))
+	r.err = errors.New("unavailable")
+	require.Error(t, b.Add([]byte(line), 1))
+
+	// the retried data is uploaded once
+	r.err = nil
+	require.NoError(t, b.Add([]byte(line), 1))
+	require.Equal(t, []object{
+		{name: "localhost/2020/09/13/12-5f0c2a1e-0", data: line + line},
+	}, r.objects)
+}
+
+func TestPartitions(t *testing.T) {
+	b, r, now := newBatcher(t, &Config{
+		ObjectName: "{{counter}}.influx",
+		FlushSize:  internal.Size{Size: int64(2 * len(line))},
+	})
+	b.RecordUploads()
+
+	require.NoError(t, b.AddPartitions(map[string]Batch{
+		"hour=12": {Data: []byte(line), Records: 1},
+		"hour=13": {Data: []byte(line + line), Records: 2},
+	}))
+	require.Equal(t, []object{
+		{name: "hour=13/0.influx", data: line + line},
+	}, r.objects)
+
+	// partitions not written to are uploaded once old enough
+	*now = now.Add(10 * time.Minute)
+	require.NoError(t, b.AddPartitions(map[string]Batch{"hour=14": {Data: []byte(line), Records: 1}}))
+	require.NoError(t, b.Flush())
+	require.Equal(t, []object{
+		{name: "hour=13/0.influx", data: line + line},
+		{name: "hour=12/1.influx", data: line},
+		{name: "hour=14/2.influx", data: line},
+	}, r.objects)
+
+	uploaded := b.Uploaded()
+	require.Len(t, uploaded, 3)
+	require.Equal(t, "hour=13/0.influx", uploaded[0].Name)
+	require.Equal(t, 2, uploaded[0].Records)
+	require.Equal(t, int64(2*len(line)), uploaded[0].Size)
+	require.Empty(t, b.Uploaded())
+}
+
+func TestPartitionsUploadErrorDropsData(t *testing.T) {
+	b, r, _ := newBatcher(t, &Config{
+		ObjectName: "{{counter}}.influx",
+		FlushSize:  internal.Size{Size: int64(2 * len(line))},
+	})
+	b.RecordUploads()
+
+	require.NoError(t, b.AddPartitions(map[string]Batch{"hour=12": {Data: []byte(line), Records: 1}}))
+	r.err = errors.New("unavailable")
+	require.Error(t, b.AddPartitions(map[string]Batch{
+		"hour=12": {Data: []byte(line), Records: 1},
+		"hour=13": {Data: []byte(line), Records: 1},
+	}))
+
+	r.err = nil
+	require.NoError(t, b.Flush())
+	require.Equal(t, []object{
+		{name: "hour=12/0.influx", data: line},
+	}, r.objects)
+	require.Equal(t, 1, b.Uploaded()[0].Records)
+}
+
+func TestSerializeObjects(t *testing.T) {
+	serializer, err := serializers.NewCSVSerializer(&serializers.Config{
+		CSVHeader:  true,
+		CSVColumns: []string{"timestamp", "measurement", "tag.host", "field.value"},
+	})
+	require.NoError(t, err)
+	require.False(t, serializers.CanJoin(serializer, true))
+
+	b, r, _ := newBatcher(t, &Config{ObjectName: "{{counter}}.csv"})
+	b.SerializeObjects(serializer)
+
+	p, err := NewPartitioner("")
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.5}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.5}, time.Unix(1600000010, 0)),
+	}
+	for _, m := range expected {
+		partitions, err := p.Serialize(serializer, []telegraf.Metric{m})
+		require.NoError(t, err)
+		require.NoError(t, b.AddPartitions(partitions))
+	}
+	require.NoError(t, b.Flush())
+	require.Len(t, r.objects, 1)
+
+	// the object has a single header and parses back into both batches
+	parser, err := parsers_csv.NewParser(&parsers_csv.Config{
+		HeaderRowCount:    1,
+		MeasurementColumn: "measurement",
+		TimestampColumn:   "timestamp",
+		TimestampFormat:   "unix",
+		TagColumns:        []string{"host"},
+	})
+	require.NoError(t, err)
+	actual, err := parser.Parse([]byte(r.objects[0].data))
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestSerializeObjectsParquet(t *testing.T) {
+	serializer, err := serializers.NewParquetSerializer(&serializers.Config{})
+	require.NoError(t, err)
+	require.False(t, serializers.CanJoin(serializer, true))
+
+	b, r, _ := newBatcher(t, &Config{ObjectName: "{{counter}}.parquet"})
+	b.SerializeObjects(serializer)
+
+	p, err := NewPartitioner("")
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.5}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.5}, time.Unix(1600000010, 0)),
+	}
+	for _, m := range expected {
+		partitions, err := p.Serialize(serializer, []telegraf.Metric{m})
+		require.NoError(t, err)
+		require.NoError(t, b.AddPartitions(partitions))
+	}
+	require.NoError(t, b.Flush())
+	require.Len(t, r.objects, 1)
+
+	parser, err := parsers_parquet.New(&parsers_parquet.Config{
+		TagColumns:        []string{"host"},
+		MeasurementColumn: "measurement",
+		TimestampColumn:   "timestamp",
+	})
+	require.NoError(t, err)
+	actual, err := parser.Parse([]byte(r.objects[0].data))
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestSerializeObjectsUploadErrorDropsMetrics(t *testing.T) {
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+	b, r, _ := newBatcher(t, &Config{FlushSize: internal.Size{Size: int64(2 * len(line))}})
+	b.SerializeObjects(serializer)
+
+	m := testutil.MockMetrics()
+	require.NoError(t, b.AddPartitions(map[string]Batch{"": {Data: []byte(line), Records: 1, Metrics: m}}))
+	r.err = errors.New("unavailable")
+	require.Error(t, b.AddPartitions(map[string]Batch{"": {Data: []byte(line), Records: 1, Metrics: m}}))
+
+	r.err = nil
+	require.NoError(t, b.AddPartitions(map[string]Batch{"": {Data: []byte(line), Records: 1, Metrics: m}}))
+	require.Equal(t, []object{
+		{name: "localhost/2020/09/13/12-5f0c2a1e-0", data: line + line},
+	}, r.objects)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := (&Config{ObjectName: "{{host}}/{{month}}"}).NewBatcher(nil)
+	require.Error(t, err)
+
+	_, err = (&Config{Compression: "bzip2"}).NewBatcher(nil)
+	require.Error(t, err)
+}
+
+func TestDefaultNamesUnique(t *testing.T) {
+	var names []string
+	for i := 0; i < 2; i++ {
+		r := &recorder{}
+		b, err := (&Config{}).NewBatcher(r.upload)
+		require.NoError(t, err)
+		b.now = func() time.Time { return time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC) }
+
+		require.NoError(t, b.Add([]byte(line), 1))
+		require.NoError(t, b.Flush())
+		require.Len(t, r.objects, 1)
+		names = append(names, r.objects[0].name)
+	}
+	require.NotEqual(t, names[0], names[1])
+}
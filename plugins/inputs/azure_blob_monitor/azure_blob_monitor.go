@@ -0,0 +1,465 @@
+package azure_blob_monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const (
+	completionMove     = "move"
+	completionMetadata = "metadata"
+
+	defaultEndpointSuffix      = "core.windows.net"
+	defaultFinishedPrefix      = "finished/"
+	defaultErrorPrefix         = "error/"
+	defaultMaxBlobsPerGather   = 1000
+	defaultMaxBlobSize         = 100 * 1024 * 1024
+	defaultProcessedKey        = "telegraf_processed"
+	copyPollInterval           = 500 * time.Millisecond
+	storageResource            = "https://storage.azure.com/"
+	minTokenRefreshInterval    = 10 * time.Second
+	tokenRefreshBeforeDeadline = 5 * time.Minute
+)
+
+var sampleConfig = `
+  ## Storage account, container and blob name prefix to monitor for new blobs.
+  account_name = "mystorageaccount"
+  container = "mycontainer"
+  prefix = "incoming/"
+
+  ## DNS suffix of the storage service, change it for sovereign clouds.
+  # endpoint_suffix = "core.windows.net"
+
+  ## Credentials, the first one set is used:
+  ##   sas_token:   Shared access signature with list, read, write and delete
+  ##                permissions on the container.
+  ##   account_key: Storage account access key.
+  ## If neither is set, a token for the managed identity of the host is
+  ## requested.  Set 'managed_identity_client_id' to use a user assigned
+  ## identity.
+  # sas_token = ""
+  # account_key = ""
+  # managed_identity_client_id = ""
+
+  ## What to do with a blob once it has been processed.
+  ##   move:     Copy the blob below 'finished_prefix' on success or
+  ##             'error_prefix' on failure, then delete the original.
+  ##   metadata: Leave the blob in place and set the 'processed_metadata_key'
+  ##             metadata to "success" or "error".
+  # completion_action = "move"
+
+  ## Name prefixes that processed blobs are moved to when using the move
+  ## completion action.  Blobs under these prefixes are never ingested.
+  # finished_prefix = "finished/"
+  # error_prefix = "error/"
+
+  ## Metadata key set on processed blobs when using the metadata completion
+  ## action.
+  # processed_metadata_key = "telegraf_processed"
+
+  ## Regular expressions matching the blob names, relative to 'prefix', that
+  ## should be ingested.  An empty list matches all names.
+  # files_to_monitor = ["^.*\\.csv"]
+
+  ## Regular expressions matching blob names, relative to 'prefix', that
+  ## should be skipped.
+  # files_to_ignore = []
+
+  ## Maximum number of blobs to ingest each interval.
+  # max_blobs_per_gather = 1000
+
+  ## Blobs larger than this are treated as failures instead of being read
+  ## into memory.
+  # max_blob_size = "100MB"
+
+  ## Name a tag containing the name of the blob the data was parsed from.
+  ## Leave empty to disable.
+  # blob_tag = ""
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+// blob is the subset of the blob properties used by the plugin.
+type blob struct {
+	Name     string
+	Size     int64
+	Metadata map[string]string
+}
+
+// container abstracts the Blob Storage operations used by the plugin.
+type container interface {
+	List(ctx context.Context, prefix string, fn func(blob) bool) error
+	Read(ctx context.Context, name string) ([]byte, error)
+	Move(ctx context.Context, src, dest string) error
+	SetMetadata(ctx context.Context, name, key, value string) error
+}
+
+type AzureBlobMonitor struct {
+	AccountName             string        `toml:"account_name" required:"true"`
+	Container               string        `toml:"container" required:"true"`
+	Prefix                  string        `toml:"prefix"`
+	EndpointSuffix          string        `toml:"endpoint_suffix"`
+	SASToken                string        `toml:"sas_token"`
+	AccountKey              string        `toml:"account_key"`
+	ManagedIdentityClientID string        `toml:"managed_identity_client_id"`
+	CompletionAction        string        `toml:"completion_action"`
+	FinishedPrefix          string        `toml:"finished_prefix"`
+	ErrorPrefix             string        `toml:"error_prefix"`
+	ProcessedMetadataKey    string        `toml:"processed_metadata_key"`
+	FilesToMonitor          []string      `toml:"files_to_monitor"`
+	FilesToIgnore           []string      `toml:"files_to_ignore"`
+	MaxBlobsPerGather       int           `toml:"max_blobs_per_gather"`
+	MaxBlobSize             internal.Size `toml:"max_blob_size"`
+	BlobTag                 string        `toml:"blob_tag"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	container container
+	parser    parsers.Parser
+	prefixes  monitor.Prefixes
+	filter    *monitor.Filter
+}
+
+func (a *AzureBlobMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AzureBlobMonitor) Description() string {
+	return "Ingest blobs dropped into an Azure Blob Storage container prefix"
+}
+
+func (a *AzureBlobMonitor) SetParser(parser parsers.Parser) {
+	a.parser = parser
+}
+
+func (a *AzureBlobMonitor) Init() error {
+	if a.AccountName == "" {
+		return errors.New("account_name must be set")
+	}
+	if a.Container == "" {
+		return errors.New("container must be set")
+	}
+
+	switch a.CompletionAction {
+	case "":
+		a.CompletionAction = completionMove
+	case completionMove, completionMetadata:
+	default:
+		return fmt.Errorf("unknown completion_action %q", a.CompletionAction)
+	}
+
+	a.prefixes = monitor.Prefixes{
+		Prefix:   a.Prefix,
+		Finished: a.FinishedPrefix,
+		Error:    a.ErrorPrefix,
+	}
+	if a.CompletionAction == completionMove {
+		if err := a.prefixes.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// The service returns metadata keys in lower case.
+	a.ProcessedMetadataKey = strings.ToLower(a.ProcessedMetadataKey)
+
+	var err error
+	a.filter, err = monitor.NewFilter(a.FilesToMonitor, a.FilesToIgnore)
+	if err != nil {
+		return err
+	}
+
+	if a.container == nil {
+		a.container, err = a.newContainer()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *AzureBlobMonitor) newContainer() (container, error) {
+	if a.EndpointSuffix == "" {
+		a.EndpointSuffix = defaultEndpointSuffix
+	}
+	u, err := url.Parse(fmt.Sprintf("https://%s.blob.%s/%s", a.AccountName, a.EndpointSuffix, a.Container))
+	if err != nil {
+		return nil, err
+	}
+
+	var credential azblob.Credential
+	switch {
+	case a.SASToken != "":
+		u.RawQuery = strings.TrimPrefix(a.SASToken, "?")
+		credential = azblob.NewAnonymousCredential()
+	case a.AccountKey != "":
+		credential, err = azblob.NewSharedKeyCredential(a.AccountName, a.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account_key: %v", err)
+		}
+	default:
+		credential, err = a.managedIdentityCredential()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get managed identity token: %v", err)
+		}
+	}
+
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{
+		Telemetry: azblob.TelemetryOptions{Value: internal.ProductToken()},
+	})
+	return &azureContainer{url: azblob.NewContainerURL(*u, pipeline)}, nil
+}
+
+// managedIdentityCredential returns a token credential for the managed
+// identity of the host, refreshed in the background before it expires.
+func (a *AzureBlobMonitor) managedIdentityCredential() (azblob.Credential, error) {
+	endpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	var spt *adal.ServicePrincipalToken
+	if a.ManagedIdentityClientID != "" {
+		spt, err = adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(endpoint, storageResource, a.ManagedIdentityClientID)
+	} else {
+		spt, err = adal.NewServicePrincipalTokenFromMSI(endpoint, storageResource)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := spt.Refresh(); err != nil {
+		return nil, err
+	}
+
+	// The refresher is called right away and then whenever the returned
+	// duration has passed.
+	refresher := func(credential azblob.TokenCredential) time.Duration {
+		if err := spt.EnsureFresh(); err != nil {
+			a.Log.Errorf("Refreshing managed identity token: %v", err)
+			return minTokenRefreshInterval
+		}
+		token := spt.Token()
+		credential.SetToken(token.AccessToken)
+
+		next := time.Until(token.Expires()) - tokenRefreshBeforeDeadline
+		if next < minTokenRefreshInterval {
+			next = minTokenRefreshInterval
+		}
+		return next
+	}
+	return azblob.NewTokenCredential(spt.Token().AccessToken, refresher), nil
+}
+
+func (a *AzureBlobMonitor) Gather(acc telegraf.Accumulator) error {
+	ctx := context.Background()
+
+	blobs, err := a.listBlobs(ctx)
+	if err != nil {
+		return fmt.Errorf("listing blobs in container %q: %v", a.Container, err)
+	}
+
+	for _, b := range blobs {
+		if err := a.processBlob(ctx, acc, b); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// listBlobs returns up to MaxBlobsPerGather blobs below the prefix that should
+// be ingested.
+func (a *AzureBlobMonitor) listBlobs(ctx context.Context) ([]blob, error) {
+	var blobs []blob
+	err := a.container.List(ctx, a.Prefix, func(b blob) bool {
+		if !a.isMonitored(b) {
+			return true
+		}
+		blobs = append(blobs, b)
+		return len(blobs) < a.MaxBlobsPerGather
+	})
+	return blobs, err
+}
+
+func (a *AzureBlobMonitor) isMonitored(b blob) bool {
+	if strings.HasSuffix(b.Name, "/") {
+		return false
+	}
+
+	switch a.CompletionAction {
+	case completionMove:
+		if a.prefixes.Processed(b.Name) {
+			return false
+		}
+	case completionMetadata:
+		if _, ok := b.Metadata[a.ProcessedMetadataKey]; ok {
+			return false
+		}
+	}
+
+	name := strings.TrimPrefix(b.Name, a.Prefix)
+	return a.filter.Match(name)
+}
+
+func (a *AzureBlobMonitor) processBlob(ctx context.Context, acc telegraf.Accumulator, b blob) error {
+	metrics, parseErr := a.readMetrics(ctx, b)
+	if parseErr != nil {
+		acc.AddError(fmt.Errorf("processing blob %q: %v", b.Name, parseErr))
+		return a.complete(ctx, b.Name, false)
+	}
+
+	for _, m := range metrics {
+		if a.BlobTag != "" {
+			m.AddTag(a.BlobTag, b.Name)
+		}
+		acc.AddMetric(m)
+	}
+	return a.complete(ctx, b.Name, true)
+}
+
+func (a *AzureBlobMonitor) readMetrics(ctx context.Context, b blob) ([]telegraf.Metric, error) {
+	if b.Size > a.MaxBlobSize.Size {
+		return nil, fmt.Errorf("blob size %d exceeds max_blob_size", b.Size)
+	}
+
+	body, err := a.container.Read(ctx, b.Name)
+	if err != nil {
+		return nil, err
+	}
+	return a.parser.Parse(body)
+}
+
+// complete moves the blob or sets its metadata according to the completion
+// action.
+func (a *AzureBlobMonitor) complete(ctx context.Context, name string, success bool) error {
+	if a.CompletionAction == completionMetadata {
+		if err := a.container.SetMetadata(ctx, name, a.ProcessedMetadataKey, monitor.Status(success)); err != nil {
+			return fmt.Errorf("updating metadata of blob %q: %v", name, err)
+		}
+		return nil
+	}
+
+	return a.prefixes.Move("blob", name, success, func(src, dest string) error {
+		return a.container.Move(ctx, src, dest)
+	})
+}
+
+// azureContainer implements container using the Blob Storage client.
+type azureContainer struct {
+	url azblob.ContainerURL
+}
+
+func (c *azureContainer) List(ctx context.Context, prefix string, fn func(blob) bool) error {
+	options := azblob.ListBlobsSegmentOptions{
+		Prefix:  prefix,
+		Details: azblob.BlobListingDetails{Metadata: true},
+	}
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := c.url.ListBlobsFlatSegment(ctx, marker, options)
+		if err != nil {
+			return err
+		}
+		marker = resp.NextMarker
+
+		for _, item := range resp.Segment.BlobItems {
+			b := blob{
+				Name:     item.Name,
+				Metadata: item.Metadata,
+			}
+			if item.Properties.ContentLength != nil {
+				b.Size = *item.Properties.ContentLength
+			}
+			if !fn(b) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (c *azureContainer) Read(ctx context.Context, name string) ([]byte, error) {
+	resp, err := c.url.NewBlobURL(name).Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		return nil, err
+	}
+	body := resp.Body(azblob.RetryReaderOptions{MaxRetryRequests: 3})
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+func (c *azureContainer) Move(ctx context.Context, src, dest string) error {
+	srcURL := c.url.NewBlobURL(src)
+	destURL := c.url.NewBlobURL(dest)
+
+	resp, err := destURL.StartCopyFromURL(ctx, srcURL.URL(), nil, azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{})
+	if err != nil {
+		return err
+	}
+
+	// Copies within an account usually complete synchronously, but the
+	// service is free to run them in the background.
+	status := resp.CopyStatus()
+	for status == azblob.CopyStatusPending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(copyPollInterval):
+		}
+		props, err := destURL.GetProperties(ctx, azblob.BlobAccessConditions{})
+		if err != nil {
+			return err
+		}
+		status = props.CopyStatus()
+	}
+	if status != azblob.CopyStatusSuccess {
+		return fmt.Errorf("copy finished with status %q", status)
+	}
+
+	_, err = srcURL.Delete(ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+	return err
+}
+
+func (c *azureContainer) SetMetadata(ctx context.Context, name, key, value string) error {
+	blobURL := c.url.NewBlobURL(name)
+
+	// Setting metadata replaces all existing entries, so keep the others.
+	props, err := blobURL.GetProperties(ctx, azblob.BlobAccessConditions{})
+	if err != nil {
+		return err
+	}
+	metadata := props.NewMetadata()
+	metadata[key] = value
+
+	_, err = blobURL.SetMetadata(ctx, metadata, azblob.BlobAccessConditions{})
+	return err
+}
+
+func init() {
+	inputs.Add("azure_blob_monitor", func() telegraf.Input {
+		return &AzureBlobMonitor{
+			EndpointSuffix:       defaultEndpointSuffix,
+			CompletionAction:     completionMove,
+			FinishedPrefix:       defaultFinishedPrefix,
+			ErrorPrefix:          defaultErrorPrefix,
+			ProcessedMetadataKey: defaultProcessedKey,
+			MaxBlobsPerGather:    defaultMaxBlobsPerGather,
+			MaxBlobSize:          internal.Size{Size: defaultMaxBlobSize},
+		}
+	})
+}
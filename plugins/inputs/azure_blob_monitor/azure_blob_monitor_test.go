@@ -0,0 +1,187 @@
+package azure_blob_monitor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockContainer struct {
+	blobs    map[string][]byte
+	metadata map[string]map[string]string
+}
+
+func newMockContainer(blobs map[string]string) *mockContainer {
+	c := &mockContainer{
+		blobs:    make(map[string][]byte),
+		metadata: make(map[string]map[string]string),
+	}
+	for k, v := range blobs {
+		c.blobs[k] = []byte(v)
+	}
+	return c
+}
+
+func (c *mockContainer) names() []string {
+	names := make([]string, 0, len(c.blobs))
+	for k := range c.blobs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *mockContainer) List(_ context.Context, prefix string, fn func(blob) bool) error {
+	for _, name := range c.names() {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		item := blob{
+			Name:     name,
+			Size:     int64(len(c.blobs[name])),
+			Metadata: c.metadata[name],
+		}
+		if !fn(item) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *mockContainer) Read(_ context.Context, name string) ([]byte, error) {
+	data, ok := c.blobs[name]
+	if !ok {
+		return nil, errors.New("blob doesn't exist")
+	}
+	return data, nil
+}
+
+func (c *mockContainer) Move(_ context.Context, src, dest string) error {
+	data, ok := c.blobs[src]
+	if !ok {
+		return errors.New("blob doesn't exist")
+	}
+	c.blobs[dest] = data
+	delete(c.blobs, src)
+	return nil
+}
+
+func (c *mockContainer) SetMetadata(_ context.Context, name, key, value string) error {
+	if c.metadata[name] == nil {
+		c.metadata[name] = make(map[string]string)
+	}
+	c.metadata[name][key] = value
+	return nil
+}
+
+func newTestMonitor(c container) *AzureBlobMonitor {
+	return &AzureBlobMonitor{
+		AccountName:          "account",
+		Container:            "container",
+		Prefix:               "incoming/",
+		CompletionAction:     completionMove,
+		FinishedPrefix:       defaultFinishedPrefix,
+		ErrorPrefix:          defaultErrorPrefix,
+		ProcessedMetadataKey: defaultProcessedKey,
+		MaxBlobsPerGather:    defaultMaxBlobsPerGather,
+		MaxBlobSize:          internal.Size{Size: defaultMaxBlobSize},
+		Log:                  testutil.Logger{},
+		container:            c,
+		parser:               influx.NewParser(influx.NewMetricHandler()),
+	}
+}
+
+func TestInitRequiresContainer(t *testing.T) {
+	plugin := newTestMonitor(newMockContainer(nil))
+	plugin.Container = ""
+	require.Error(t, plugin.Init())
+}
+
+func TestInitInvalidCompletionAction(t *testing.T) {
+	plugin := newTestMonitor(newMockContainer(nil))
+	plugin.CompletionAction = "tag"
+	require.Error(t, plugin.Init())
+}
+
+func TestMoveCompletion(t *testing.T) {
+	c := newMockContainer(map[string]string{
+		"incoming/a.influx":   "cpu value=42 0\n",
+		"incoming/bad.influx": "not line protocol\n",
+	})
+
+	plugin := newTestMonitor(c)
+	plugin.BlobTag = "blob"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"blob": "incoming/a.influx"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{
+		"error/bad.influx",
+		"finished/a.influx",
+	}, c.names())
+}
+
+func TestMetadataCompletion(t *testing.T) {
+	c := newMockContainer(map[string]string{
+		"incoming/a.influx":   "cpu value=42 0\n",
+		"incoming/bad.influx": "not line protocol\n",
+	})
+
+	plugin := newTestMonitor(c)
+	plugin.CompletionAction = completionMetadata
+	plugin.ProcessedMetadataKey = "Telegraf_Processed"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, map[string]map[string]string{
+		"incoming/a.influx":   {defaultProcessedKey: monitor.StatusSuccess},
+		"incoming/bad.influx": {defaultProcessedKey: monitor.StatusError},
+	}, c.metadata)
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestMaxBlobsPerGather(t *testing.T) {
+	c := newMockContainer(map[string]string{
+		"incoming/a.influx": "cpu value=1 0\n",
+		"incoming/b.influx": "cpu value=2 0\n",
+		"incoming/c.influx": "cpu value=3 0\n",
+	})
+
+	plugin := newTestMonitor(c)
+	plugin.MaxBlobsPerGather = 2
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+}
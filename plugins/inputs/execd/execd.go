@@ -94,9 +94,9 @@ func (e *Execd) Stop() {
 }
 
 func (e *Execd) cmdReadOut(out io.Reader) {
-	if _, isInfluxParser := e.parser.(*influx.Parser); isInfluxParser {
+	if record, ok := parsers.InfluxStreamable(e.parser); ok {
 		// work around the lack of built-in streaming parser. :(
-		e.cmdReadOutStream(out)
+		e.cmdReadOutStream(out, record)
 		return
 	}
 
@@ -118,7 +118,7 @@ func (e *Execd) cmdReadOut(out io.Reader) {
 	}
 }
 
-func (e *Execd) cmdReadOutStream(out io.Reader) {
+func (e *Execd) cmdReadOutStream(out io.Reader, record func(metrics int, err error)) {
 	parser := influx.NewStreamParser(out)
 
 	for {
@@ -129,6 +129,7 @@ func (e *Execd) cmdReadOutStream(out io.Reader) {
 			}
 			if parseErr, isParseError := err.(*influx.ParseError); isParseError {
 				// parse error.
+				record(0, parseErr)
 				e.acc.AddError(parseErr)
 				continue
 			}
@@ -137,6 +138,7 @@ func (e *Execd) cmdReadOutStream(out io.Reader) {
 			return
 		}
 
+		record(1, nil)
 		e.acc.AddMetric(metric)
 	}
 }
@@ -2,6 +2,7 @@ package execd
 
 import (
 	"bufio"
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"os"
@@ -117,6 +118,36 @@ func TestParsesLinesContainingNewline(t *testing.T) {
 	}
 }
 
+func TestParserWrappersApplied(t *testing.T) {
+	parser, err := parsers.NewParser(&parsers.Config{
+		DataFormat:        "influx",
+		DataTransforms:    []parsers.DataTransform{{Type: "base64"}},
+		DataDefaultFields: map[string]interface{}{"status": "ok"},
+		DataConstantTags:  map[string]string{"source": "execd"},
+	})
+	require.NoError(t, err)
+
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	acc := agent.NewAccumulator(&TestMetricMaker{}, metrics)
+
+	e := &Execd{
+		RestartDelay: config.Duration(5 * time.Second),
+		parser:       parsers.NewStatsParser(parser, map[string]string{"input": "execd_test"}),
+		Signal:       "STDIN",
+		acc:          acc,
+		Log:          testutil.Logger{},
+	}
+
+	line := base64.StdEncoding.EncodeToString([]byte("event value=1 1587128639239000000"))
+	e.cmdReadOut(strings.NewReader(line + "\n"))
+
+	m := readChanWithTimeout(t, metrics, 1*time.Second)
+	require.Equal(t, "event", m.Name())
+	require.Equal(t, map[string]interface{}{"value": 1.0, "status": "ok"}, m.Fields())
+	require.Equal(t, map[string]string{"source": "execd"}, m.Tags())
+}
+
 func readChanWithTimeout(t *testing.T, metrics chan telegraf.Metric, timeout time.Duration) telegraf.Metric {
 	to := time.NewTimer(timeout)
 	defer to.Stop()
@@ -2,6 +2,7 @@ package file
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -35,10 +36,14 @@ const sampleConfig = `
 
   ## Character encoding to use when interpreting the file contents.  Invalid
   ## characters are replaced using the unicode replacement character.  When set
-  ## to the empty string the data is not decoded to text.
+  ## to the empty string the data is not decoded to text.  When set to "auto"
+  ## the encoding is detected separately for each file using the BOM, falling
+  ## back to utf-16, utf-8 or latin-1 based on the contents.
   ##   ex: character_encoding = "utf-8"
   ##       character_encoding = "utf-16le"
   ##       character_encoding = "utf-16be"
+  ##       character_encoding = "latin-1"
+  ##       character_encoding = "auto"
   ##       character_encoding = ""
   # character_encoding = ""
 
@@ -59,6 +64,11 @@ func (f *File) Description() string {
 }
 
 func (f *File) Init() error {
+	if f.CharacterEncoding == "auto" {
+		// The decoder is selected separately for each file.
+		return nil
+	}
+
 	var err error
 	f.decoder, err = encoding.NewDecoder(f.CharacterEncoding)
 	return err
@@ -70,16 +80,17 @@ func (f *File) Gather(acc telegraf.Accumulator) error {
 		return err
 	}
 	for _, k := range f.filenames {
-		metrics, err := f.readMetric(k)
-		if err != nil {
-			return err
-		}
-
-		for _, m := range metrics {
+		// Streamed metrics are added as they are parsed, so the metrics
+		// preceding an error in the file are added as well.
+		err := f.readMetric(k, func(m telegraf.Metric) error {
 			if f.FileTag != "" {
 				m.AddTag(f.FileTag, filepath.Base(k))
 			}
 			acc.AddMetric(m)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 	}
 	return nil
@@ -107,19 +118,57 @@ func (f *File) refreshFilePaths() error {
 	return nil
 }
 
-func (f *File) readMetric(filename string) ([]telegraf.Metric, error) {
+func (f *File) readMetric(filename string, fn func(telegraf.Metric) error) error {
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer file.Close()
 
+	if f.CharacterEncoding == "auto" {
+		return f.readMetricDetectEncoding(filename, file, fn)
+	}
+
 	r, _ := utfbom.Skip(f.decoder.Reader(file))
+	return f.parse(filename, r, fn)
+}
+
+func (f *File) readMetricDetectEncoding(filename string, file io.Reader, fn func(telegraf.Metric) error) error {
+	enc, data, err := encoding.DetectReader(file)
+	if err != nil {
+		return fmt.Errorf("E! Error file: %v could not be read, %s", filename, err)
+	}
+
+	decoder, err := encoding.NewDecoder(enc)
+	if err != nil {
+		return err
+	}
+
+	r, _ := utfbom.Skip(decoder.Reader(data))
+	return f.parse(filename, r, fn)
+}
+
+// parse passes the metrics of the file to fn, parsers supporting streams
+// read the file incrementally instead of reading it completely first.
+func (f *File) parse(filename string, r io.Reader, fn func(telegraf.Metric) error) error {
+	if parser, ok := f.parser.(parsers.StreamParser); ok {
+		return parser.ParseStream(r, fn)
+	}
+
 	fileContents, err := ioutil.ReadAll(r)
 	if err != nil {
-		return nil, fmt.Errorf("E! Error file: %v could not be read, %s", filename, err)
+		return fmt.Errorf("E! Error file: %v could not be read, %s", filename, err)
 	}
-	return f.parser.Parse(fileContents)
+	metrics, err := f.parser.Parse(fileContents)
+	if err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func init() {
@@ -6,6 +6,9 @@
 package file
 
 import (
+	"bufio"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
@@ -234,6 +237,45 @@ func TestCharacterEncoding(t *testing.T) {
 				TagColumns:  []string{"dest", "hop", "ip"},
 			},
 		},
+		{
+			name: "auto character_encoding with utf-8",
+			plugin: &File{
+				Files:             []string{"testdata/mtr-utf-8.csv"},
+				CharacterEncoding: "auto",
+			},
+			csv: &csv.Config{
+				MetricName:  "file",
+				SkipRows:    1,
+				ColumnNames: []string{"", "", "status", "dest", "hop", "ip", "loss", "snt", "", "", "avg", "best", "worst", "stdev"},
+				TagColumns:  []string{"dest", "hop", "ip"},
+			},
+		},
+		{
+			name: "auto character_encoding with utf-16le",
+			plugin: &File{
+				Files:             []string{"testdata/mtr-utf-16le.csv"},
+				CharacterEncoding: "auto",
+			},
+			csv: &csv.Config{
+				MetricName:  "file",
+				SkipRows:    1,
+				ColumnNames: []string{"", "", "status", "dest", "hop", "ip", "loss", "snt", "", "", "avg", "best", "worst", "stdev"},
+				TagColumns:  []string{"dest", "hop", "ip"},
+			},
+		},
+		{
+			name: "auto character_encoding with utf-16be",
+			plugin: &File{
+				Files:             []string{"testdata/mtr-utf-16be.csv"},
+				CharacterEncoding: "auto",
+			},
+			csv: &csv.Config{
+				MetricName:  "file",
+				SkipRows:    1,
+				ColumnNames: []string{"", "", "status", "dest", "hop", "ip", "loss", "snt", "", "", "avg", "best", "worst", "stdev"},
+				TagColumns:  []string{"dest", "hop", "ip"},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -252,3 +294,58 @@ func TestCharacterEncoding(t *testing.T) {
 		})
 	}
 }
+
+// streamParser only supports parsing streams.
+type streamParser struct {
+	parsers.Parser
+}
+
+func (p *streamParser) ParseStream(r io.Reader, fn func(telegraf.Metric) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := testutil.MustMetric("stream", map[string]string{}, map[string]interface{}{"line": scanner.Text()}, time.Unix(0, 0))
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func TestStreamParser(t *testing.T) {
+	var acc testutil.Accumulator
+	r := File{
+		Files:   []string{"dev/testfiles/grok_a.log"},
+		FileTag: "filename",
+	}
+	require.NoError(t, r.Init())
+	r.SetParser(&streamParser{})
+
+	require.NoError(t, r.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+	for _, m := range acc.GetTelegrafMetrics() {
+		require.Equal(t, "stream", m.Name())
+		require.Equal(t, map[string]string{"filename": "grok_a.log"}, m.Tags())
+	}
+}
+
+func TestStreamParserError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "metrics.influx")
+	require.NoError(t, ioutil.WriteFile(name, []byte("cpu value=1\ncpu value=\n"), 0644))
+
+	var acc testutil.Accumulator
+	r := File{
+		Files: []string{name},
+	}
+	require.NoError(t, r.Init())
+	parser, err := parsers.NewParser(&parsers.Config{DataFormat: "influx"})
+	require.NoError(t, err)
+	r.SetParser(parser)
+
+	// The metrics preceding the error are added.
+	require.Error(t, r.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+}
@@ -0,0 +1,390 @@
+package ftp_monitor
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/jlaffaye/ftp"
+)
+
+const (
+	tlsModeNone     = "none"
+	tlsModeExplicit = "explicit"
+	tlsModeImplicit = "implicit"
+
+	defaultPort              = "21"
+	defaultImplicitTLSPort   = "990"
+	defaultUsername          = "anonymous"
+	defaultTimeout           = 30 * time.Second
+	defaultMaxFilesPerGather = 1000
+	defaultMaxFileSize       = 100 * 1024 * 1024
+)
+
+var sampleConfig = `
+  ## Address of the FTP server; the port defaults to 21, or 990 when using
+  ## implicit TLS.
+  address = "ftp.example.com:21"
+
+  ## Login credentials.
+  # username = "anonymous"
+  # password = ""
+
+  ## FTPS mode:
+  ##   none:     Plain FTP.
+  ##   explicit: Upgrade the connection using AUTH TLS.
+  ##   implicit: Connect using TLS, usually on port 990.
+  # tls_mode = "none"
+
+  ## Optional TLS Config, used for the explicit and implicit modes.
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Data connections always use passive mode.  Set to true to use PASV
+  ## instead of EPSV, for servers behind NAT that answer EPSV incorrectly.
+  # disable_epsv = false
+
+  ## Timeout for establishing connections.
+  # timeout = "30s"
+
+  ## Remote directory to monitor for new files.
+  directory = "/upload"
+
+  ## Remote directories that files are moved to after processing, on success
+  ## and failure respectively.
+  finished_directory = "/upload/finished"
+  error_directory = "/upload/error"
+
+  ## Regular expressions matching the file names that should be ingested.  An
+  ## empty list matches all names.
+  # files_to_monitor = ["^.*\\.csv"]
+
+  ## Regular expressions matching file names that should be skipped.
+  # files_to_ignore = []
+
+  ## Files modified more recently than this are left alone, so that uploads
+  ## still in progress aren't picked up.
+  # min_file_age = "0s"
+
+  ## Maximum number of files to ingest each interval.
+  # max_files_per_gather = 1000
+
+  ## Files larger than this are moved to 'error_directory' instead of being
+  ## read into memory.
+  # max_file_size = "100MB"
+
+  ## Name a tag containing the name of the file the data was parsed from.
+  ## Leave empty to disable.
+  # file_tag = ""
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+// file is the subset of a directory entry used by the plugin.
+type file struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// client abstracts the FTP operations used by the plugin.
+type client interface {
+	List(dir string) ([]file, error)
+	ReadFile(name string) ([]byte, error)
+	Rename(oldname, newname string) error
+	Close() error
+}
+
+type FTPMonitor struct {
+	Address           string            `toml:"address" required:"true"`
+	Username          string            `toml:"username"`
+	Password          string            `toml:"password"`
+	TLSMode           string            `toml:"tls_mode"`
+	DisableEPSV       bool              `toml:"disable_epsv"`
+	Timeout           internal.Duration `toml:"timeout"`
+	Directory         string            `toml:"directory" required:"true"`
+	FinishedDirectory string            `toml:"finished_directory" required:"true"`
+	ErrorDirectory    string            `toml:"error_directory" required:"true"`
+	FilesToMonitor    []string          `toml:"files_to_monitor"`
+	FilesToIgnore     []string          `toml:"files_to_ignore"`
+	MinFileAge        internal.Duration `toml:"min_file_age"`
+	MaxFilesPerGather int               `toml:"max_files_per_gather"`
+	MaxFileSize       internal.Size     `toml:"max_file_size"`
+	FileTag           string            `toml:"file_tag"`
+	tlsint.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	connect func() (client, error)
+	parser  parsers.Parser
+	dirs    monitor.Directories
+	filter  *monitor.Filter
+}
+
+func (f *FTPMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *FTPMonitor) Description() string {
+	return "Ingest files dropped into a directory of a remote FTP server"
+}
+
+func (f *FTPMonitor) SetParser(parser parsers.Parser) {
+	f.parser = parser
+}
+
+func (f *FTPMonitor) Init() error {
+	if f.Address == "" {
+		return errors.New("address must be set")
+	}
+	f.dirs = monitor.Directories{
+		Directory: f.Directory,
+		Finished:  f.FinishedDirectory,
+		Error:     f.ErrorDirectory,
+	}
+	if err := f.dirs.Validate(); err != nil {
+		return err
+	}
+
+	var err error
+	f.filter, err = monitor.NewFilter(f.FilesToMonitor, f.FilesToIgnore)
+	if err != nil {
+		return err
+	}
+
+	if f.connect == nil {
+		address, options, err := f.dialOptions()
+		if err != nil {
+			return err
+		}
+		f.connect = func() (client, error) {
+			return dial(address, f.Username, f.Password, options)
+		}
+	}
+
+	return nil
+}
+
+// dialOptions returns the address including the port and the options used to
+// connect to the server.
+func (f *FTPMonitor) dialOptions() (string, []ftp.DialOption, error) {
+	options := []ftp.DialOption{
+		ftp.DialWithTimeout(f.Timeout.Duration),
+		ftp.DialWithDisabledEPSV(f.DisableEPSV),
+	}
+
+	port := defaultPort
+	switch f.TLSMode {
+	case "", tlsModeNone:
+	case tlsModeExplicit, tlsModeImplicit:
+		tlsConfig, err := f.tlsConfig()
+		if err != nil {
+			return "", nil, err
+		}
+		if f.TLSMode == tlsModeExplicit {
+			options = append(options, ftp.DialWithExplicitTLS(tlsConfig))
+		} else {
+			options = append(options, ftp.DialWithTLS(tlsConfig))
+			port = defaultImplicitTLSPort
+		}
+	default:
+		return "", nil, fmt.Errorf("unknown tls_mode %q", f.TLSMode)
+	}
+
+	address := f.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, port)
+	}
+	return address, options, nil
+}
+
+func (f *FTPMonitor) tlsConfig() (*tls.Config, error) {
+	tlsConfig, err := f.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+
+	// The server name is needed to verify the certificate; the FTP client
+	// doesn't set it from the address.
+	if tlsConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(f.Address)
+		if err != nil {
+			host = f.Address
+		}
+		tlsConfig.ServerName = host
+	}
+
+	// Data connections must resume the control connection's TLS session on
+	// many servers.
+	tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	return tlsConfig, nil
+}
+
+func (f *FTPMonitor) Gather(acc telegraf.Accumulator) error {
+	c, err := f.connect()
+	if err != nil {
+		return fmt.Errorf("connecting to %q: %v", f.Address, err)
+	}
+	defer c.Close()
+
+	files, err := f.listFiles(c)
+	if err != nil {
+		return fmt.Errorf("listing directory %q: %v", f.Directory, err)
+	}
+
+	for _, file := range files {
+		if err := f.processFile(c, acc, file); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// listFiles returns up to MaxFilesPerGather files of the directory that
+// should be ingested, oldest first.
+func (f *FTPMonitor) listFiles(c client) ([]file, error) {
+	entries, err := c.List(f.Directory)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime.Before(entries[j].ModTime)
+	})
+
+	var files []file
+	for _, entry := range entries {
+		if len(files) >= f.MaxFilesPerGather {
+			break
+		}
+		if f.isMonitored(entry) {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
+
+func (f *FTPMonitor) isMonitored(entry file) bool {
+	if time.Since(entry.ModTime) < f.MinFileAge.Duration {
+		return false
+	}
+	return f.filter.Match(entry.Name)
+}
+
+func (f *FTPMonitor) processFile(c client, acc telegraf.Accumulator, entry file) error {
+	name := path.Join(f.Directory, entry.Name)
+
+	metrics, parseErr := f.readMetrics(c, name, entry.Size)
+	if parseErr != nil {
+		acc.AddError(fmt.Errorf("processing file %q: %v", name, parseErr))
+		return f.dirs.Move(entry.Name, false, c.Rename)
+	}
+
+	for _, m := range metrics {
+		if f.FileTag != "" {
+			m.AddTag(f.FileTag, entry.Name)
+		}
+		acc.AddMetric(m)
+	}
+	return f.dirs.Move(entry.Name, true, c.Rename)
+}
+
+func (f *FTPMonitor) readMetrics(c client, name string, size int64) ([]telegraf.Metric, error) {
+	if size > f.MaxFileSize.Size {
+		return nil, fmt.Errorf("file size %d exceeds max_file_size", size)
+	}
+
+	body, err := c.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.parser.Parse(body)
+}
+
+// ftpClient implements client using an FTP control connection.
+type ftpClient struct {
+	conn *ftp.ServerConn
+}
+
+func dial(address, username, password string, options []ftp.DialOption) (client, error) {
+	conn, err := ftp.Dial(address, options...)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Login(username, password); err != nil {
+		conn.Quit()
+		return nil, err
+	}
+	return &ftpClient{conn: conn}, nil
+}
+
+func (c *ftpClient) List(dir string) ([]file, error) {
+	entries, err := c.conn.List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]file, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type != ftp.EntryTypeFile {
+			continue
+		}
+		files = append(files, file{
+			Name:    path.Base(entry.Name),
+			Size:    int64(entry.Size),
+			ModTime: entry.Time,
+		})
+	}
+	return files, nil
+}
+
+func (c *ftpClient) ReadFile(name string) ([]byte, error) {
+	resp, err := c.conn.Retr(name)
+	if err != nil {
+		return nil, err
+	}
+	// The transfer must be completed by closing the response before another
+	// command can be sent.
+	defer resp.Close()
+	return ioutil.ReadAll(resp)
+}
+
+func (c *ftpClient) Rename(oldname, newname string) error {
+	return c.conn.Rename(oldname, newname)
+}
+
+func (c *ftpClient) Close() error {
+	return c.conn.Quit()
+}
+
+func init() {
+	inputs.Add("ftp_monitor", func() telegraf.Input {
+		return &FTPMonitor{
+			Username:          defaultUsername,
+			TLSMode:           tlsModeNone,
+			Timeout:           internal.Duration{Duration: defaultTimeout},
+			MaxFilesPerGather: defaultMaxFilesPerGather,
+			MaxFileSize:       internal.Size{Size: defaultMaxFileSize},
+		}
+	})
+}
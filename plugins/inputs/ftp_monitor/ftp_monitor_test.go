@@ -0,0 +1,231 @@
+package ftp_monitor
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+type mockClient struct {
+	files  map[string]mockFile
+	closed int
+}
+
+func newMockClient(files map[string]string) *mockClient {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	c := &mockClient{files: make(map[string]mockFile)}
+	modTime := time.Now().Add(-time.Hour)
+	for _, name := range names {
+		c.files[name] = mockFile{data: []byte(files[name]), modTime: modTime}
+		modTime = modTime.Add(time.Minute)
+	}
+	return c
+}
+
+func (c *mockClient) names() []string {
+	names := make([]string, 0, len(c.files))
+	for k := range c.files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *mockClient) List(dir string) ([]file, error) {
+	var files []file
+	for name, f := range c.files {
+		if path.Dir(name) == dir {
+			files = append(files, file{
+				Name:    path.Base(name),
+				Size:    int64(len(f.data)),
+				ModTime: f.modTime,
+			})
+		}
+	}
+	return files, nil
+}
+
+func (c *mockClient) ReadFile(name string) ([]byte, error) {
+	f, ok := c.files[name]
+	if !ok {
+		return nil, errors.New("550 file not found")
+	}
+	return f.data, nil
+}
+
+func (c *mockClient) Rename(oldname, newname string) error {
+	f, ok := c.files[oldname]
+	if !ok {
+		return errors.New("550 file not found")
+	}
+	c.files[newname] = f
+	delete(c.files, oldname)
+	return nil
+}
+
+func (c *mockClient) Close() error {
+	c.closed++
+	return nil
+}
+
+func newTestMonitor(c *mockClient) *FTPMonitor {
+	return &FTPMonitor{
+		Address:           "localhost",
+		Username:          defaultUsername,
+		Directory:         "/upload",
+		FinishedDirectory: "/upload/finished",
+		ErrorDirectory:    "/upload/error",
+		MaxFilesPerGather: defaultMaxFilesPerGather,
+		MaxFileSize:       internal.Size{Size: defaultMaxFileSize},
+		Log:               testutil.Logger{},
+		connect:           func() (client, error) { return c, nil },
+		parser:            influx.NewParser(influx.NewMetricHandler()),
+	}
+}
+
+func TestDialOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		tlsMode  string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "default port",
+			address:  "ftp.example.com",
+			expected: "ftp.example.com:21",
+		},
+		{
+			name:     "explicit tls keeps default port",
+			address:  "ftp.example.com",
+			tlsMode:  tlsModeExplicit,
+			expected: "ftp.example.com:21",
+		},
+		{
+			name:     "implicit tls default port",
+			address:  "ftp.example.com",
+			tlsMode:  tlsModeImplicit,
+			expected: "ftp.example.com:990",
+		},
+		{
+			name:     "port given",
+			address:  "ftp.example.com:2121",
+			tlsMode:  tlsModeImplicit,
+			expected: "ftp.example.com:2121",
+		},
+		{
+			name:    "unknown tls mode",
+			address: "ftp.example.com",
+			tlsMode: "starttls",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &FTPMonitor{Address: tt.address, TLSMode: tt.tlsMode}
+			address, _, err := plugin.dialOptions()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, address)
+		})
+	}
+}
+
+func TestTLSConfigServerName(t *testing.T) {
+	plugin := &FTPMonitor{Address: "ftp.example.com:21", TLSMode: tlsModeExplicit}
+	tlsConfig, err := plugin.tlsConfig()
+	require.NoError(t, err)
+	require.Equal(t, "ftp.example.com", tlsConfig.ServerName)
+	require.NotNil(t, tlsConfig.ClientSessionCache)
+}
+
+func TestGather(t *testing.T) {
+	c := newMockClient(map[string]string{
+		"/upload/a.influx":   "cpu value=42 0\n",
+		"/upload/bad.influx": "not line protocol\n",
+	})
+
+	plugin := newTestMonitor(c)
+	plugin.FileTag = "file"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"file": "a.influx"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{
+		"/upload/error/bad.influx",
+		"/upload/finished/a.influx",
+	}, c.names())
+	require.Equal(t, 1, c.closed)
+}
+
+func TestMinFileAge(t *testing.T) {
+	c := newMockClient(map[string]string{
+		"/upload/a.influx": "cpu value=42 0\n",
+	})
+	c.files["/upload/new.influx"] = mockFile{data: []byte("cpu value=1 0\n"), modTime: time.Now()}
+
+	plugin := newTestMonitor(c)
+	plugin.MinFileAge = internal.Duration{Duration: 2 * time.Minute}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, []string{
+		"/upload/finished/a.influx",
+		"/upload/new.influx",
+	}, c.names())
+}
+
+func TestMaxFilesPerGatherOldestFirst(t *testing.T) {
+	c := newMockClient(map[string]string{
+		"/upload/a.influx": "cpu value=1 0\n",
+		"/upload/b.influx": "cpu value=2 0\n",
+		"/upload/c.influx": "cpu value=3 0\n",
+	})
+
+	plugin := newTestMonitor(c)
+	plugin.MaxFilesPerGather = 2
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, []string{
+		"/upload/c.influx",
+		"/upload/finished/a.influx",
+		"/upload/finished/b.influx",
+	}, c.names())
+}
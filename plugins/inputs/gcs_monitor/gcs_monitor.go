@@ -0,0 +1,344 @@
+package gcs_monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const (
+	completionMove     = "move"
+	completionMetadata = "metadata"
+
+	defaultFinishedPrefix      = "finished/"
+	defaultErrorPrefix         = "error/"
+	defaultMaxObjectsPerGather = 1000
+	defaultMaxObjectSize       = 100 * 1024 * 1024
+	defaultProcessedKey        = "telegraf_processed"
+)
+
+var sampleConfig = `
+  ## Bucket and object name prefix to monitor for new objects.
+  bucket = "my-bucket"
+  prefix = "incoming/"
+
+  ## Filepath for GCP credentials JSON file to authorize calls to the Cloud
+  ## Storage API.  If not set explicitly, Telegraf will attempt to use
+  ## Application Default Credentials, which is preferred.
+  # credentials_file = "path/to/my/creds.json"
+
+  ## What to do with an object once it has been processed.
+  ##   move:     Copy the object below 'finished_prefix' on success or
+  ##             'error_prefix' on failure, then delete the original.
+  ##   metadata: Leave the object in place and set the 'processed_metadata_key'
+  ##             custom metadata to "success" or "error".
+  # completion_action = "move"
+
+  ## Name prefixes that processed objects are moved to when using the move
+  ## completion action.  Objects under these prefixes are never ingested.
+  # finished_prefix = "finished/"
+  # error_prefix = "error/"
+
+  ## Custom metadata key set on processed objects when using the metadata
+  ## completion action.
+  # processed_metadata_key = "telegraf_processed"
+
+  ## Regular expressions matching the object names, relative to 'prefix', that
+  ## should be ingested.  An empty list matches all names.
+  # files_to_monitor = ["^.*\\.csv"]
+
+  ## Regular expressions matching object names, relative to 'prefix', that
+  ## should be skipped.
+  # files_to_ignore = []
+
+  ## Maximum number of objects to ingest each interval.
+  # max_objects_per_gather = 1000
+
+  ## Objects larger than this are treated as failures instead of being read
+  ## into memory.
+  # max_object_size = "100MB"
+
+  ## Name a tag containing the name of the object the data was parsed from.
+  ## Leave empty to disable.
+  # object_tag = ""
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+// object is the subset of the object attributes used by the plugin.
+type object struct {
+	Name     string
+	Size     int64
+	Metadata map[string]string
+}
+
+// bucket abstracts the Cloud Storage operations used by the plugin.
+type bucket interface {
+	List(ctx context.Context, prefix string, fn func(object) bool) error
+	Read(ctx context.Context, name string) ([]byte, error)
+	Move(ctx context.Context, src, dest string) error
+	SetMetadata(ctx context.Context, name, key, value string) error
+}
+
+type GCSMonitor struct {
+	Bucket               string        `toml:"bucket" required:"true"`
+	Prefix               string        `toml:"prefix"`
+	CredentialsFile      string        `toml:"credentials_file"`
+	CompletionAction     string        `toml:"completion_action"`
+	FinishedPrefix       string        `toml:"finished_prefix"`
+	ErrorPrefix          string        `toml:"error_prefix"`
+	ProcessedMetadataKey string        `toml:"processed_metadata_key"`
+	FilesToMonitor       []string      `toml:"files_to_monitor"`
+	FilesToIgnore        []string      `toml:"files_to_ignore"`
+	MaxObjectsPerGather  int           `toml:"max_objects_per_gather"`
+	MaxObjectSize        internal.Size `toml:"max_object_size"`
+	ObjectTag            string        `toml:"object_tag"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	bucket   bucket
+	parser   parsers.Parser
+	prefixes monitor.Prefixes
+	filter   *monitor.Filter
+}
+
+func (g *GCSMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GCSMonitor) Description() string {
+	return "Ingest objects dropped into a Google Cloud Storage bucket prefix"
+}
+
+func (g *GCSMonitor) SetParser(parser parsers.Parser) {
+	g.parser = parser
+}
+
+func (g *GCSMonitor) Init() error {
+	if g.Bucket == "" {
+		return errors.New("bucket must be set")
+	}
+
+	switch g.CompletionAction {
+	case "":
+		g.CompletionAction = completionMove
+	case completionMove, completionMetadata:
+	default:
+		return fmt.Errorf("unknown completion_action %q", g.CompletionAction)
+	}
+
+	g.prefixes = monitor.Prefixes{
+		Prefix:   g.Prefix,
+		Finished: g.FinishedPrefix,
+		Error:    g.ErrorPrefix,
+	}
+	if g.CompletionAction == completionMove {
+		if err := g.prefixes.Validate(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	g.filter, err = monitor.NewFilter(g.FilesToMonitor, g.FilesToIgnore)
+	if err != nil {
+		return err
+	}
+
+	if g.bucket == nil {
+		g.bucket, err = g.newBucket()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *GCSMonitor) newBucket() (bucket, error) {
+	options := []option.ClientOption{
+		option.WithScopes(storage.ScopeReadWrite),
+		option.WithUserAgent(internal.ProductToken()),
+	}
+	if g.CredentialsFile != "" {
+		options = append(options, option.WithCredentialsFile(g.CredentialsFile))
+	}
+
+	client, err := storage.NewClient(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Cloud Storage client: %v", err)
+	}
+	return &gcsBucket{handle: client.Bucket(g.Bucket)}, nil
+}
+
+func (g *GCSMonitor) Gather(acc telegraf.Accumulator) error {
+	ctx := context.Background()
+
+	objects, err := g.listObjects(ctx)
+	if err != nil {
+		return fmt.Errorf("listing objects in bucket %q: %v", g.Bucket, err)
+	}
+
+	for _, obj := range objects {
+		if err := g.processObject(ctx, acc, obj); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// listObjects returns up to MaxObjectsPerGather objects below the prefix that
+// should be ingested.
+func (g *GCSMonitor) listObjects(ctx context.Context) ([]object, error) {
+	var objects []object
+	err := g.bucket.List(ctx, g.Prefix, func(obj object) bool {
+		if !g.isMonitored(obj) {
+			return true
+		}
+		objects = append(objects, obj)
+		return len(objects) < g.MaxObjectsPerGather
+	})
+	return objects, err
+}
+
+func (g *GCSMonitor) isMonitored(obj object) bool {
+	if strings.HasSuffix(obj.Name, "/") {
+		return false
+	}
+
+	switch g.CompletionAction {
+	case completionMove:
+		if g.prefixes.Processed(obj.Name) {
+			return false
+		}
+	case completionMetadata:
+		if _, ok := obj.Metadata[g.ProcessedMetadataKey]; ok {
+			return false
+		}
+	}
+
+	name := strings.TrimPrefix(obj.Name, g.Prefix)
+	return g.filter.Match(name)
+}
+
+func (g *GCSMonitor) processObject(ctx context.Context, acc telegraf.Accumulator, obj object) error {
+	metrics, parseErr := g.readMetrics(ctx, obj)
+	if parseErr != nil {
+		acc.AddError(fmt.Errorf("processing object %q: %v", obj.Name, parseErr))
+		return g.complete(ctx, obj.Name, false)
+	}
+
+	for _, m := range metrics {
+		if g.ObjectTag != "" {
+			m.AddTag(g.ObjectTag, obj.Name)
+		}
+		acc.AddMetric(m)
+	}
+	return g.complete(ctx, obj.Name, true)
+}
+
+func (g *GCSMonitor) readMetrics(ctx context.Context, obj object) ([]telegraf.Metric, error) {
+	if obj.Size > g.MaxObjectSize.Size {
+		return nil, fmt.Errorf("object size %d exceeds max_object_size", obj.Size)
+	}
+
+	body, err := g.bucket.Read(ctx, obj.Name)
+	if err != nil {
+		return nil, err
+	}
+	return g.parser.Parse(body)
+}
+
+// complete moves the object or sets its metadata according to the completion
+// action.
+func (g *GCSMonitor) complete(ctx context.Context, name string, success bool) error {
+	if g.CompletionAction == completionMetadata {
+		if err := g.bucket.SetMetadata(ctx, name, g.ProcessedMetadataKey, monitor.Status(success)); err != nil {
+			return fmt.Errorf("updating metadata of object %q: %v", name, err)
+		}
+		return nil
+	}
+
+	return g.prefixes.Move("object", name, success, func(src, dest string) error {
+		return g.bucket.Move(ctx, src, dest)
+	})
+}
+
+// gcsBucket implements bucket using the Cloud Storage client.
+type gcsBucket struct {
+	handle *storage.BucketHandle
+}
+
+func (b *gcsBucket) List(ctx context.Context, prefix string, fn func(object) bool) error {
+	it := b.handle.Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		obj := object{
+			Name:     attrs.Name,
+			Size:     attrs.Size,
+			Metadata: attrs.Metadata,
+		}
+		if !fn(obj) {
+			return nil
+		}
+	}
+}
+
+func (b *gcsBucket) Read(ctx context.Context, name string) ([]byte, error) {
+	r, err := b.handle.Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (b *gcsBucket) Move(ctx context.Context, src, dest string) error {
+	srcHandle := b.handle.Object(src)
+	if _, err := b.handle.Object(dest).CopierFrom(srcHandle).Run(ctx); err != nil {
+		return err
+	}
+	return srcHandle.Delete(ctx)
+}
+
+func (b *gcsBucket) SetMetadata(ctx context.Context, name, key, value string) error {
+	// Metadata entries not mentioned in the update are left untouched.
+	_, err := b.handle.Object(name).Update(ctx, storage.ObjectAttrsToUpdate{
+		Metadata: map[string]string{key: value},
+	})
+	return err
+}
+
+func init() {
+	inputs.Add("gcs_monitor", func() telegraf.Input {
+		return &GCSMonitor{
+			CompletionAction:     completionMove,
+			FinishedPrefix:       defaultFinishedPrefix,
+			ErrorPrefix:          defaultErrorPrefix,
+			ProcessedMetadataKey: defaultProcessedKey,
+			MaxObjectsPerGather:  defaultMaxObjectsPerGather,
+			MaxObjectSize:        internal.Size{Size: defaultMaxObjectSize},
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package gcs_monitor
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockBucket struct {
+	objects  map[string][]byte
+	metadata map[string]map[string]string
+}
+
+func newMockBucket(objects map[string]string) *mockBucket {
+	b := &mockBucket{
+		objects:  make(map[string][]byte),
+		metadata: make(map[string]map[string]string),
+	}
+	for k, v := range objects {
+		b.objects[k] = []byte(v)
+	}
+	return b
+}
+
+func (b *mockBucket) names() []string {
+	names := make([]string, 0, len(b.objects))
+	for k := range b.objects {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (b *mockBucket) List(_ context.Context, prefix string, fn func(object) bool) error {
+	for _, name := range b.names() {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		obj := object{
+			Name:     name,
+			Size:     int64(len(b.objects[name])),
+			Metadata: b.metadata[name],
+		}
+		if !fn(obj) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (b *mockBucket) Read(_ context.Context, name string) ([]byte, error) {
+	data, ok := b.objects[name]
+	if !ok {
+		return nil, errors.New("object doesn't exist")
+	}
+	return data, nil
+}
+
+func (b *mockBucket) Move(_ context.Context, src, dest string) error {
+	data, ok := b.objects[src]
+	if !ok {
+		return errors.New("object doesn't exist")
+	}
+	b.objects[dest] = data
+	delete(b.objects, src)
+	return nil
+}
+
+func (b *mockBucket) SetMetadata(_ context.Context, name, key, value string) error {
+	if b.metadata[name] == nil {
+		b.metadata[name] = make(map[string]string)
+	}
+	b.metadata[name][key] = value
+	return nil
+}
+
+func newTestMonitor(b bucket) *GCSMonitor {
+	return &GCSMonitor{
+		Bucket:               "bucket",
+		Prefix:               "incoming/",
+		CompletionAction:     completionMove,
+		FinishedPrefix:       defaultFinishedPrefix,
+		ErrorPrefix:          defaultErrorPrefix,
+		ProcessedMetadataKey: defaultProcessedKey,
+		MaxObjectsPerGather:  defaultMaxObjectsPerGather,
+		MaxObjectSize:        internal.Size{Size: defaultMaxObjectSize},
+		Log:                  testutil.Logger{},
+		bucket:               b,
+		parser:               influx.NewParser(influx.NewMetricHandler()),
+	}
+}
+
+func TestInitInvalidCompletionAction(t *testing.T) {
+	plugin := newTestMonitor(newMockBucket(nil))
+	plugin.CompletionAction = "tag"
+	require.Error(t, plugin.Init())
+}
+
+func TestMoveCompletion(t *testing.T) {
+	b := newMockBucket(map[string]string{
+		"incoming/a.influx":   "cpu value=42 0\n",
+		"incoming/bad.influx": "not line protocol\n",
+	})
+
+	plugin := newTestMonitor(b)
+	plugin.ObjectTag = "object"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"object": "incoming/a.influx"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{
+		"error/bad.influx",
+		"finished/a.influx",
+	}, b.names())
+}
+
+func TestMetadataCompletion(t *testing.T) {
+	b := newMockBucket(map[string]string{
+		"incoming/a.influx":   "cpu value=42 0\n",
+		"incoming/bad.influx": "not line protocol\n",
+	})
+
+	plugin := newTestMonitor(b)
+	plugin.CompletionAction = completionMetadata
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, map[string]map[string]string{
+		"incoming/a.influx":   {defaultProcessedKey: monitor.StatusSuccess},
+		"incoming/bad.influx": {defaultProcessedKey: monitor.StatusError},
+	}, b.metadata)
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestMaxObjectsPerGather(t *testing.T) {
+	b := newMockBucket(map[string]string{
+		"incoming/a.influx": "cpu value=1 0\n",
+		"incoming/b.influx": "cpu value=2 0\n",
+		"incoming/c.influx": "cpu value=3 0\n",
+	})
+
+	plugin := newTestMonitor(b)
+	plugin.MaxObjectsPerGather = 2
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+}
@@ -0,0 +1,353 @@
+package hdfs_monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const (
+	webhdfsPrefix = "/webhdfs/v1"
+
+	defaultTimeout           = time.Minute
+	defaultMaxFilesPerGather = 1000
+	defaultMaxFileSize       = 100 * 1024 * 1024
+)
+
+var sampleConfig = `
+  ## URL of the NameNode WebHDFS endpoint, or of an HttpFS gateway.
+  url = "http://namenode:9870"
+
+  ## User to act as when the cluster uses simple authentication.
+  # username = "telegraf"
+
+  ## Delegation token used instead of 'username' on secured clusters.
+  # delegation_token = ""
+
+  ## HTTP request timeout, including reading the file contents.
+  # timeout = "1m"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## HDFS directory to monitor for new files.
+  directory = "/data/incoming"
+
+  ## HDFS directories that files are moved to after processing, on success and
+  ## failure respectively.
+  finished_directory = "/data/finished"
+  error_directory = "/data/error"
+
+  ## Regular expressions matching the file names that should be ingested.  An
+  ## empty list matches all names.
+  # files_to_monitor = ["^.*\\.csv"]
+
+  ## Regular expressions matching file names that should be skipped.
+  # files_to_ignore = ["^_", "^\\."]
+
+  ## Files modified more recently than this are left alone, so that files
+  ## still being written aren't picked up.
+  # min_file_age = "0s"
+
+  ## Maximum number of files to ingest each interval.
+  # max_files_per_gather = 1000
+
+  ## Files larger than this are moved to 'error_directory' instead of being
+  ## read into memory.
+  # max_file_size = "100MB"
+
+  ## Name a tag containing the name of the file the data was parsed from.
+  ## Leave empty to disable.
+  # file_tag = ""
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+// fileStatus is an entry of a LISTSTATUS response.
+type fileStatus struct {
+	PathSuffix       string `json:"pathSuffix"`
+	Type             string `json:"type"`
+	Length           int64  `json:"length"`
+	ModificationTime int64  `json:"modificationTime"`
+}
+
+func (f fileStatus) modTime() time.Time {
+	return time.Unix(0, f.ModificationTime*int64(time.Millisecond))
+}
+
+// remoteException is the error body returned by WebHDFS.
+type remoteException struct {
+	RemoteException struct {
+		Exception string `json:"exception"`
+		Message   string `json:"message"`
+	} `json:"RemoteException"`
+}
+
+type HDFSMonitor struct {
+	URL               string            `toml:"url" required:"true"`
+	Username          string            `toml:"username"`
+	DelegationToken   string            `toml:"delegation_token"`
+	Timeout           internal.Duration `toml:"timeout"`
+	Directory         string            `toml:"directory" required:"true"`
+	FinishedDirectory string            `toml:"finished_directory" required:"true"`
+	ErrorDirectory    string            `toml:"error_directory" required:"true"`
+	FilesToMonitor    []string          `toml:"files_to_monitor"`
+	FilesToIgnore     []string          `toml:"files_to_ignore"`
+	MinFileAge        internal.Duration `toml:"min_file_age"`
+	MaxFilesPerGather int               `toml:"max_files_per_gather"`
+	MaxFileSize       internal.Size     `toml:"max_file_size"`
+	FileTag           string            `toml:"file_tag"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client  *http.Client
+	baseURL *url.URL
+	parser  parsers.Parser
+	dirs    monitor.Directories
+	filter  *monitor.Filter
+}
+
+func (h *HDFSMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HDFSMonitor) Description() string {
+	return "Ingest files dropped into an HDFS directory using WebHDFS"
+}
+
+func (h *HDFSMonitor) SetParser(parser parsers.Parser) {
+	h.parser = parser
+}
+
+func (h *HDFSMonitor) Init() error {
+	if h.URL == "" {
+		return errors.New("url must be set")
+	}
+	h.dirs = monitor.Directories{
+		Directory: h.Directory,
+		Finished:  h.FinishedDirectory,
+		Error:     h.ErrorDirectory,
+	}
+	if err := h.dirs.Validate(); err != nil {
+		return err
+	}
+
+	var err error
+	h.baseURL, err = url.Parse(h.URL)
+	if err != nil {
+		return fmt.Errorf("parsing url: %v", err)
+	}
+
+	h.filter, err = monitor.NewFilter(h.FilesToMonitor, h.FilesToIgnore)
+	if err != nil {
+		return err
+	}
+
+	tlsCfg, err := h.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	h.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: h.Timeout.Duration,
+	}
+
+	return nil
+}
+
+func (h *HDFSMonitor) Gather(acc telegraf.Accumulator) error {
+	files, err := h.listFiles()
+	if err != nil {
+		return fmt.Errorf("listing directory %q: %v", h.Directory, err)
+	}
+
+	for _, file := range files {
+		if err := h.processFile(acc, file); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// listFiles returns up to MaxFilesPerGather files of the directory that
+// should be ingested, oldest first.
+func (h *HDFSMonitor) listFiles() ([]fileStatus, error) {
+	var resp struct {
+		FileStatuses struct {
+			FileStatus []fileStatus `json:"FileStatus"`
+		} `json:"FileStatuses"`
+	}
+	if err := h.call(http.MethodGet, h.Directory, "LISTSTATUS", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	entries := resp.FileStatuses.FileStatus
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModificationTime < entries[j].ModificationTime
+	})
+
+	var files []fileStatus
+	for _, entry := range entries {
+		if len(files) >= h.MaxFilesPerGather {
+			break
+		}
+		if h.isMonitored(entry) {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
+
+func (h *HDFSMonitor) isMonitored(file fileStatus) bool {
+	if file.Type != "FILE" {
+		return false
+	}
+	if time.Since(file.modTime()) < h.MinFileAge.Duration {
+		return false
+	}
+	return h.filter.Match(file.PathSuffix)
+}
+
+func (h *HDFSMonitor) processFile(acc telegraf.Accumulator, file fileStatus) error {
+	name := path.Join(h.Directory, file.PathSuffix)
+
+	metrics, parseErr := h.readMetrics(name, file.Length)
+	if parseErr != nil {
+		acc.AddError(fmt.Errorf("processing file %q: %v", name, parseErr))
+		return h.dirs.Move(file.PathSuffix, false, h.rename)
+	}
+
+	for _, m := range metrics {
+		if h.FileTag != "" {
+			m.AddTag(h.FileTag, file.PathSuffix)
+		}
+		acc.AddMetric(m)
+	}
+	return h.dirs.Move(file.PathSuffix, true, h.rename)
+}
+
+func (h *HDFSMonitor) readMetrics(name string, size int64) ([]telegraf.Metric, error) {
+	if size > h.MaxFileSize.Size {
+		return nil, fmt.Errorf("file size %d exceeds max_file_size", size)
+	}
+
+	// The NameNode redirects the request to a DataNode holding the data,
+	// which the client follows.
+	resp, err := h.do(http.MethodGet, name, "OPEN", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return h.parser.Parse(body)
+}
+
+func (h *HDFSMonitor) rename(src, dest string) error {
+	var resp struct {
+		Boolean bool `json:"boolean"`
+	}
+	params := url.Values{"destination": {dest}}
+	if err := h.call(http.MethodPut, src, "RENAME", params, &resp); err != nil {
+		return err
+	}
+	// HDFS refuses to rename onto an existing file or into a missing
+	// directory without giving a reason.
+	if !resp.Boolean {
+		return errors.New("rename refused, check that the directory exists and the file isn't already there")
+	}
+	return nil
+}
+
+// call performs a WebHDFS operation and decodes the JSON response into v.
+func (h *HDFSMonitor) call(method, name, op string, params url.Values, v interface{}) error {
+	resp, err := h.do(method, name, op, params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// do performs a WebHDFS operation and returns the response if it succeeded.
+func (h *HDFSMonitor) do(method, name, op string, params url.Values) (*http.Response, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("op", op)
+	if h.DelegationToken != "" {
+		params.Set("delegation", h.DelegationToken)
+	} else if h.Username != "" {
+		params.Set("user.name", h.Username)
+	}
+
+	u := *h.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + webhdfsPrefix + name
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var e remoteException
+	if err := json.Unmarshal(body, &e); err == nil && e.RemoteException.Exception != "" {
+		return fmt.Errorf("%s: %s", e.RemoteException.Exception, e.RemoteException.Message)
+	}
+	return fmt.Errorf("received status code %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+}
+
+func init() {
+	inputs.Add("hdfs_monitor", func() telegraf.Input {
+		return &HDFSMonitor{
+			Timeout:           internal.Duration{Duration: defaultTimeout},
+			MaxFilesPerGather: defaultMaxFilesPerGather,
+			MaxFileSize:       internal.Size{Size: defaultMaxFileSize},
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package hdfs_monitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type hdfsFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// fakeHDFS implements the subset of WebHDFS used by the plugin.
+type fakeHDFS struct {
+	sync.Mutex
+	files map[string]hdfsFile
+	users []string
+}
+
+func newFakeHDFS(files map[string]string) *fakeHDFS {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f := &fakeHDFS{files: make(map[string]hdfsFile)}
+	modTime := time.Now().Add(-time.Hour)
+	for _, name := range names {
+		f.files[name] = hdfsFile{data: []byte(files[name]), modTime: modTime}
+		modTime = modTime.Add(time.Second)
+	}
+	return f
+}
+
+func (f *fakeHDFS) names() []string {
+	f.Lock()
+	defer f.Unlock()
+	names := make([]string, 0, len(f.files))
+	for k := range f.files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *fakeHDFS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	if r.URL.Path == "/datanode" {
+		w.Write(f.files[r.URL.Query().Get("path")].data)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, webhdfsPrefix)
+	query := r.URL.Query()
+	f.users = append(f.users, query.Get("user.name"))
+
+	switch query.Get("op") {
+	case "LISTSTATUS":
+		statuses := []fileStatus{}
+		for k, v := range f.files {
+			if path.Dir(k) == name {
+				statuses = append(statuses, fileStatus{
+					PathSuffix:       path.Base(k),
+					Type:             "FILE",
+					Length:           int64(len(v.data)),
+					ModificationTime: v.modTime.UnixNano() / int64(time.Millisecond),
+				})
+			}
+		}
+		statuses = append(statuses, fileStatus{PathSuffix: "subdir", Type: "DIRECTORY"})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"FileStatuses": map[string]interface{}{"FileStatus": statuses},
+		})
+	case "OPEN":
+		if _, ok := f.files[name]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"RemoteException":{"exception":"FileNotFoundException","message":"File does not exist: ` + name + `"}}`))
+			return
+		}
+		http.Redirect(w, r, "/datanode?path="+name, http.StatusTemporaryRedirect)
+	case "RENAME":
+		dest := query.Get("destination")
+		_, exists := f.files[dest]
+		file, ok := f.files[name]
+		if ok && !exists {
+			f.files[dest] = file
+			delete(f.files, name)
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"boolean": ok && !exists})
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func newTestMonitor(url string) *HDFSMonitor {
+	return &HDFSMonitor{
+		URL:               url,
+		Username:          "telegraf",
+		Timeout:           internal.Duration{Duration: defaultTimeout},
+		Directory:         "/data/incoming",
+		FinishedDirectory: "/data/finished",
+		ErrorDirectory:    "/data/error",
+		MaxFilesPerGather: defaultMaxFilesPerGather,
+		MaxFileSize:       internal.Size{Size: defaultMaxFileSize},
+		Log:               testutil.Logger{},
+		parser:            influx.NewParser(influx.NewMetricHandler()),
+	}
+}
+
+func TestGather(t *testing.T) {
+	fs := newFakeHDFS(map[string]string{
+		"/data/incoming/a.influx":   "cpu value=42 0\n",
+		"/data/incoming/bad.influx": "not line protocol\n",
+	})
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	plugin := newTestMonitor(ts.URL)
+	plugin.FileTag = "file"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"file": "a.influx"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{
+		"/data/error/bad.influx",
+		"/data/finished/a.influx",
+	}, fs.names())
+	require.Contains(t, fs.users, "telegraf")
+}
+
+func TestRenameRefused(t *testing.T) {
+	fs := newFakeHDFS(map[string]string{
+		"/data/incoming/a.influx": "cpu value=42 0\n",
+		"/data/finished/a.influx": "cpu value=1 0\n",
+	})
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	plugin := newTestMonitor(ts.URL)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "rename refused")
+}
+
+func TestRemoteException(t *testing.T) {
+	fs := newFakeHDFS(nil)
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	plugin := newTestMonitor(ts.URL)
+	require.NoError(t, plugin.Init())
+
+	_, err := plugin.readMetrics("/data/incoming/missing", 0)
+	require.EqualError(t, err, "FileNotFoundException: File does not exist: /data/incoming/missing")
+}
+
+func TestMaxFilesPerGatherOldestFirst(t *testing.T) {
+	fs := newFakeHDFS(map[string]string{
+		"/data/incoming/a.influx": "cpu value=1 0\n",
+		"/data/incoming/b.influx": "cpu value=2 0\n",
+		"/data/incoming/c.influx": "cpu value=3 0\n",
+	})
+	ts := httptest.NewServer(fs)
+	defer ts.Close()
+
+	plugin := newTestMonitor(ts.URL)
+	plugin.MaxFilesPerGather = 2
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, []string{
+		"/data/finished/a.influx",
+		"/data/finished/b.influx",
+		"/data/incoming/c.influx",
+	}, fs.names())
+}
@@ -77,7 +77,7 @@ func (r *IntelRDT) SampleConfig() string {
 	## Optionally set sampling interval to Nx100ms. 
 	## This value is propagated to pqos tool. Interval format is defined by pqos itself.
 	## If not provided or provided 0, will be set to 10 = 10x100ms = 1s.
-	# sampling_interval = "10"
+	# sampling_interval = 10
 	
 	## Optionally specify the path to pqos executable. 
 	## If not provided, auto discovery will be performed.
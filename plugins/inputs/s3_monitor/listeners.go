@@ -0,0 +1,254 @@
+package s3_monitor
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	defaultWebhookPath = "/"
+	maxWebhookBodySize = 1024 * 1024
+)
+
+var errPendingFull = errors.New("too many objects pending")
+
+// listening reports whether notifications are pushed to the plugin by a
+// webhook or NATS subscription.
+func (s *S3Monitor) listening() bool {
+	return s.WebhookServiceAddress != "" || len(s.NATSServers) > 0
+}
+
+func (s *S3Monitor) Start(_ telegraf.Accumulator) error {
+	// Objects announced while stopped are found by listing the prefix.
+	s.Lock()
+	s.relist = s.listening()
+	s.Unlock()
+
+	if s.WebhookServiceAddress != "" {
+		if err := s.startWebhook(); err != nil {
+			return err
+		}
+	}
+	if len(s.NATSServers) > 0 {
+		if err := s.subscribeNATS(); err != nil {
+			s.Stop()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Monitor) Stop() {
+	if s.natsConn != nil {
+		s.natsConn.Close()
+		s.natsConn = nil
+	}
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+	}
+	s.wg.Wait()
+}
+
+func (s *S3Monitor) startWebhook() error {
+	listener, err := net.Listen("tcp", s.WebhookServiceAddress)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	server := &http.Server{
+		Handler:      s,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		server.Serve(listener)
+	}()
+
+	s.Log.Infof("Listening for notifications on %s", listener.Addr().String())
+	return nil
+}
+
+// ServeHTTP receives event notifications from a MinIO webhook target.
+func (s *S3Monitor) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != s.WebhookPath {
+		http.NotFound(res, req)
+		return
+	}
+
+	if s.WebhookAuthToken != "" {
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.WebhookAuthToken)) != 1 {
+			http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	// MinIO checks that the target is reachable before sending events.
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(res, req.Body, maxWebhookBodySize))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err := s.enqueueNotification(body); err == errPendingFull {
+		// MinIO retries the event later.
+		http.Error(res, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		s.Log.Errorf("Decoding webhook notification: %v", err)
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+func (s *S3Monitor) subscribeNATS() error {
+	options := []nats.Option{
+		nats.MaxReconnects(-1),
+		nats.Name(internal.ProductToken()),
+	}
+	if s.NATSUsername != "" {
+		options = append(options, nats.UserInfo(s.NATSUsername, s.NATSPassword))
+	}
+
+	conn, err := nats.Connect(strings.Join(s.NATSServers, ","), options...)
+	if err != nil {
+		return fmt.Errorf("connecting to NATS: %v", err)
+	}
+	s.natsConn = conn
+
+	_, err = conn.Subscribe(s.NATSSubject, func(msg *nats.Msg) {
+		if err := s.enqueueNotification(msg.Data); err == errPendingFull {
+			s.Log.Warnf("Dropping NATS notification: %v", err)
+		} else if err != nil {
+			s.Log.Errorf("Decoding NATS notification: %v", err)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("subscribing to %q: %v", s.NATSSubject, err)
+	}
+	return nil
+}
+
+// enqueueNotification adds the keys announced by an event notification to the
+// objects to ingest on the next interval, errPendingFull is returned if any
+// of them is dropped.
+func (s *S3Monitor) enqueueNotification(body []byte) error {
+	keys, err := s.createdKeys(body)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, key := range keys {
+		if !s.enqueue(key) {
+			err = errPendingFull
+		}
+	}
+	return err
+}
+
+// enqueue adds a key to the pending keys unless it's already queued, and
+// returns false if the key is dropped as too many keys are pending.  The
+// prefix is listed again once there is room to find the dropped objects.
+// The caller must hold the lock.
+func (s *S3Monitor) enqueue(key string) bool {
+	if s.queued[key] {
+		return true
+	}
+	if len(s.pending) >= s.maxPending {
+		s.NotificationsDropped.Incr(1)
+		s.relist = true
+		return false
+	}
+	s.queued[key] = true
+	s.pending = append(s.pending, key)
+	return true
+}
+
+// gatherPending ingests the objects announced by pushed notifications.
+// Objects that fail to be moved or tagged are retried on the next interval.
+func (s *S3Monitor) gatherPending(acc telegraf.Accumulator) error {
+	if err := s.relistPending(); err != nil {
+		acc.AddError(fmt.Errorf("listing objects in bucket %q: %v", s.Bucket, err))
+	}
+
+	s.Lock()
+	n := len(s.pending)
+	if n > s.MaxObjectsPerGather {
+		n = s.MaxObjectsPerGather
+	}
+	keys := s.pending[:n:n]
+	s.pending = s.pending[n:]
+	for _, key := range keys {
+		delete(s.queued, key)
+	}
+	s.Unlock()
+
+	for _, key := range keys {
+		if err := s.processPending(acc, key); err != nil {
+			acc.AddError(err)
+			s.Lock()
+			s.enqueue(key)
+			s.Unlock()
+		}
+	}
+	return nil
+}
+
+// relistPending lists the prefix when objects may have been missed, and adds
+// the objects found to the pending keys.  The prefix is listed again on the
+// next interval until a listing finds fewer than max_objects_per_gather
+// objects.
+func (s *S3Monitor) relistPending() error {
+	s.Lock()
+	relist := s.relist && len(s.pending) < s.maxPending
+	if relist {
+		s.relist = false
+	}
+	s.Unlock()
+	if !relist {
+		return nil
+	}
+
+	keys, err := s.listObjects()
+
+	s.Lock()
+	defer s.Unlock()
+	if err != nil || len(keys) >= s.MaxObjectsPerGather {
+		s.relist = true
+	}
+	for _, key := range keys {
+		s.enqueue(key)
+	}
+	return err
+}
+
+func (s *S3Monitor) processPending(acc telegraf.Accumulator, key string) error {
+	if s.CompletionAction == completionTag {
+		done, err := s.isTagged(key)
+		if err != nil || done {
+			return err
+		}
+	}
+	return s.processObject(acc, key)
+}
@@ -0,0 +1,144 @@
+package s3_monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/influxdata/telegraf"
+)
+
+const sqsMaxMessages = 10
+
+// s3Event is an S3 event notification, as sent by Amazon S3 and compatible
+// stores such as MinIO.
+type s3Event struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// snsEnvelope wraps messages delivered to SQS through an SNS topic without
+// raw message delivery.
+type snsEnvelope struct {
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// gatherNotifications ingests the objects announced by event notifications
+// on the SQS queue.  Messages are deleted once all of their objects have been
+// processed, so failed ones are delivered again after the queue's visibility
+// timeout.
+func (s *S3Monitor) gatherNotifications(acc telegraf.Accumulator) error {
+	var count int
+	for count < s.MaxObjectsPerGather {
+		out, err := s.queue.ReceiveMessage(&sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.SQSQueueURL),
+			MaxNumberOfMessages: aws.Int64(sqsMaxMessages),
+			WaitTimeSeconds:     aws.Int64(int64(s.SQSWaitTime.Duration.Seconds())),
+		})
+		if err != nil {
+			return fmt.Errorf("receiving messages from %q: %v", s.SQSQueueURL, err)
+		}
+		if len(out.Messages) == 0 {
+			return nil
+		}
+
+		for _, msg := range out.Messages {
+			keys, err := s.notifiedKeys([]byte(aws.StringValue(msg.Body)))
+			if err != nil {
+				acc.AddError(fmt.Errorf("decoding message %q: %v", aws.StringValue(msg.MessageId), err))
+				continue
+			}
+
+			done := true
+			for _, key := range keys {
+				count++
+				if err := s.processObject(acc, key); err != nil {
+					acc.AddError(err)
+					done = false
+				}
+			}
+			if !done {
+				continue
+			}
+
+			_, err = s.queue.DeleteMessage(&sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(s.SQSQueueURL),
+				ReceiptHandle: msg.ReceiptHandle,
+			})
+			if err != nil {
+				acc.AddError(fmt.Errorf("deleting message %q: %v", aws.StringValue(msg.MessageId), err))
+			}
+		}
+	}
+	return nil
+}
+
+// notifiedKeys returns the keys of created objects in an event notification
+// that should be ingested.  Test events and events for other buckets or keys
+// result in an empty list.
+func (s *S3Monitor) notifiedKeys(body []byte) ([]string, error) {
+	keys, err := s.createdKeys(body)
+	if err != nil || s.CompletionAction != completionTag {
+		return keys, err
+	}
+
+	untagged := keys[:0]
+	for _, key := range keys {
+		done, err := s.isTagged(key)
+		if err != nil {
+			return nil, err
+		}
+		if !done {
+			untagged = append(untagged, key)
+		}
+	}
+	return untagged, nil
+}
+
+// createdKeys returns the keys of created objects in an event notification
+// that match the monitored prefix and patterns.
+func (s *S3Monitor) createdKeys(body []byte) ([]string, error) {
+	var envelope snsEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Type == "Notification" {
+		body = []byte(envelope.Message)
+	}
+
+	var event s3Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, record := range event.Records {
+		if !strings.Contains(record.EventName, "ObjectCreated:") || record.S3.Bucket.Name != s.Bucket {
+			continue
+		}
+
+		// Keys are URL encoded in notifications, with spaces as '+'.
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %v", record.S3.Object.Key, err)
+		}
+		if !strings.HasPrefix(key, s.Prefix) || !s.isMonitoredKey(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
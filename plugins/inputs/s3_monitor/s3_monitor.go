@@ -0,0 +1,534 @@
+package s3_monitor
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/influxdata/telegraf"
+	internalaws "github.com/influxdata/telegraf/config/aws"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	completionMove = "move"
+	completionTag  = "tag"
+
+	defaultFinishedPrefix      = "finished/"
+	defaultErrorPrefix         = "error/"
+	defaultMaxObjectsPerGather = 1000
+	defaultMaxObjectSize       = 100 * 1024 * 1024
+	defaultProcessedTagKey     = "telegraf_processed"
+	defaultSQSWaitTime         = time.Second
+	maxSQSWaitTime             = 20 * time.Second
+	maxProcessedKeys           = 100000
+	maxPendingKeys             = 100000
+)
+
+var sampleConfig = `
+  ## Amazon Region
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+  # role_arn = ""
+  # profile = ""
+  # shared_credential_file = ""
+
+  ## Endpoint to make request against, the correct endpoint is automatically
+  ## determined and this option should only be set if you wish to override the
+  ## default.
+  ##   ex: endpoint_url = "http://localhost:9000"
+  # endpoint_url = ""
+
+  ## Use path-style addressing for the bucket, required by most S3 compatible
+  ## object stores such as MinIO.
+  # force_path_style = false
+
+  ## Bucket and key prefix to monitor for new objects.
+  bucket = "my-bucket"
+  prefix = "incoming/"
+
+  ## URL of an SQS queue receiving the bucket's s3:ObjectCreated event
+  ## notifications, directly or through an SNS topic.  When set, objects are
+  ## ingested as notifications arrive instead of by listing the bucket.
+  # sqs_queue_url = ""
+
+  ## Endpoint to make SQS requests against, overriding 'endpoint_url'.
+  # sqs_endpoint_url = ""
+
+  ## How long to wait for notifications to arrive on each receive request,
+  ## up to 20s.
+  # sqs_wait_time = "1s"
+
+  ## Address to listen on for event notifications from a MinIO webhook
+  ## target, such as ":8090".  When set, objects are ingested as
+  ## notifications arrive instead of by listing the bucket.
+  # webhook_service_address = ""
+
+  ## Path notifications are posted to, and token expected in the
+  ## Authorization header when the target is configured with an auth_token.
+  # webhook_path = "/"
+  # webhook_auth_token = ""
+
+  ## NATS servers and subject receiving the bucket's event notifications from
+  ## a MinIO NATS target.  When set, objects are ingested as notifications
+  ## arrive instead of by listing the bucket.
+  # nats_servers = ["nats://localhost:4222"]
+  # nats_subject = "minio"
+  # nats_username = ""
+  # nats_password = ""
+
+  ## What to do with an object once it has been processed.
+  ##   move: Copy the object below 'finished_prefix' on success or
+  ##         'error_prefix' on failure, then delete the original.
+  ##   tag:  Leave the object in place and set the 'processed_tag_key'
+  ##         object tag to "success" or "error".
+  # completion_action = "move"
+
+  ## Key prefixes that processed objects are moved to when using the move
+  ## completion action.  Objects under these prefixes are never ingested.
+  # finished_prefix = "finished/"
+  # error_prefix = "error/"
+
+  ## Object tag set on processed objects when using the tag completion action.
+  # processed_tag_key = "telegraf_processed"
+
+  ## Regular expressions matching the object keys, relative to 'prefix', that
+  ## should be ingested.  An empty list matches all keys.
+  # files_to_monitor = ["^.*\\.csv"]
+
+  ## Regular expressions matching object keys, relative to 'prefix', that
+  ## should be skipped.
+  # files_to_ignore = []
+
+  ## Maximum number of objects to ingest each interval.
+  # max_objects_per_gather = 1000
+
+  ## Objects larger than this are treated as failures instead of being read
+  ## into memory.
+  # max_object_size = "100MB"
+
+  ## Name a tag containing the key of the object the data was parsed from.
+  ## Leave empty to disable.
+  # object_tag = ""
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+type S3Monitor struct {
+	Region         string `toml:"region"`
+	AccessKey      string `toml:"access_key"`
+	SecretKey      string `toml:"secret_key"`
+	RoleARN        string `toml:"role_arn"`
+	Profile        string `toml:"profile"`
+	Filename       string `toml:"shared_credential_file"`
+	Token          string `toml:"token"`
+	EndpointURL    string `toml:"endpoint_url"`
+	ForcePathStyle bool   `toml:"force_path_style"`
+
+	Bucket              string        `toml:"bucket" required:"true"`
+	Prefix              string        `toml:"prefix"`
+	CompletionAction    string        `toml:"completion_action"`
+	FinishedPrefix      string        `toml:"finished_prefix"`
+	ErrorPrefix         string        `toml:"error_prefix"`
+	ProcessedTagKey     string        `toml:"processed_tag_key"`
+	FilesToMonitor      []string      `toml:"files_to_monitor"`
+	FilesToIgnore       []string      `toml:"files_to_ignore"`
+	MaxObjectsPerGather int           `toml:"max_objects_per_gather"`
+	MaxObjectSize       internal.Size `toml:"max_object_size"`
+	ObjectTag           string        `toml:"object_tag"`
+
+	SQSQueueURL    string            `toml:"sqs_queue_url"`
+	SQSEndpointURL string            `toml:"sqs_endpoint_url"`
+	SQSWaitTime    internal.Duration `toml:"sqs_wait_time"`
+
+	WebhookServiceAddress string   `toml:"webhook_service_address"`
+	WebhookPath           string   `toml:"webhook_path"`
+	WebhookAuthToken      string   `toml:"webhook_auth_token"`
+	NATSServers           []string `toml:"nats_servers"`
+	NATSSubject           string   `toml:"nats_subject"`
+	NATSUsername          string   `toml:"nats_username"`
+	NATSPassword          string   `toml:"nats_password"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+
+	svc      s3iface.S3API
+	queue    sqsiface.SQSAPI
+	parser   parsers.Parser
+	prefixes monitor.Prefixes
+	filter   *monitor.Filter
+
+	// processed holds the keys already tagged when using the tag completion
+	// action, to avoid looking up the tags of every object on each interval.
+	// Keys no longer listed are pruned and it is cleared when it holds
+	// maxProcessedKeys keys, so the tags are looked up again.
+	processed map[string]bool
+
+	listener net.Listener
+	natsConn *nats.Conn
+	wg       sync.WaitGroup
+
+	// pending holds the keys announced by pushed notifications that haven't
+	// been ingested yet, and queued the same keys for deduplication.  Keys
+	// beyond maxPending are dropped, and the prefix is listed to find the
+	// objects missed while stopped or dropped when relist is set.
+	pending    []string
+	queued     map[string]bool
+	maxPending int
+	relist     bool
+
+	NotificationsDropped selfstat.Stat
+}
+
+func (s *S3Monitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *S3Monitor) Description() string {
+	return "Ingest objects dropped into an S3 bucket prefix"
+}
+
+func (s *S3Monitor) SetParser(parser parsers.Parser) {
+	s.parser = parser
+}
+
+func (s *S3Monitor) Init() error {
+	if s.Bucket == "" {
+		return errors.New("bucket must be set")
+	}
+
+	switch s.CompletionAction {
+	case "":
+		s.CompletionAction = completionMove
+	case completionMove, completionTag:
+	default:
+		return fmt.Errorf("unknown completion_action %q", s.CompletionAction)
+	}
+
+	s.prefixes = monitor.Prefixes{
+		Prefix:   s.Prefix,
+		Finished: s.FinishedPrefix,
+		Error:    s.ErrorPrefix,
+	}
+	if s.CompletionAction == completionMove {
+		if err := s.prefixes.Validate(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	s.filter, err = monitor.NewFilter(s.FilesToMonitor, s.FilesToIgnore)
+	if err != nil {
+		return err
+	}
+
+	if s.SQSWaitTime.Duration < 0 || s.SQSWaitTime.Duration > maxSQSWaitTime {
+		return fmt.Errorf("sqs_wait_time must be between 0s and %s", maxSQSWaitTime)
+	}
+
+	if s.SQSQueueURL != "" && s.listening() {
+		return errors.New("sqs_queue_url cannot be combined with webhook_service_address or nats_servers")
+	}
+	if len(s.NATSServers) > 0 && s.NATSSubject == "" {
+		return errors.New("nats_subject must be set when using nats_servers")
+	}
+	if s.WebhookPath == "" {
+		s.WebhookPath = defaultWebhookPath
+	}
+
+	s.processed = make(map[string]bool)
+	s.queued = make(map[string]bool)
+	s.maxPending = maxPendingKeys
+	s.NotificationsDropped = selfstat.Register("s3_monitor", "notifications_dropped", map[string]string{"bucket": s.Bucket})
+
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      s.Region,
+		AccessKey:   s.AccessKey,
+		SecretKey:   s.SecretKey,
+		RoleARN:     s.RoleARN,
+		Profile:     s.Profile,
+		Filename:    s.Filename,
+		Token:       s.Token,
+		EndpointURL: s.EndpointURL,
+	}
+	if s.svc == nil {
+		s.svc = s3.New(credentialConfig.Credentials(), &aws.Config{
+			S3ForcePathStyle: aws.Bool(s.ForcePathStyle),
+		})
+	}
+	if s.SQSQueueURL != "" && s.queue == nil {
+		// An empty endpoint resets 'endpoint_url', which usually points at
+		// an S3 compatible store, to the default SQS endpoint.
+		s.queue = sqs.New(credentialConfig.Credentials(), &aws.Config{
+			Endpoint: aws.String(s.SQSEndpointURL),
+		})
+	}
+
+	return nil
+}
+
+func (s *S3Monitor) Gather(acc telegraf.Accumulator) error {
+	if s.SQSQueueURL != "" {
+		return s.gatherNotifications(acc)
+	}
+	if s.listening() {
+		return s.gatherPending(acc)
+	}
+
+	keys, err := s.listObjects()
+	if err != nil {
+		return fmt.Errorf("listing objects in bucket %q: %v", s.Bucket, err)
+	}
+
+	for _, key := range keys {
+		if err := s.processObject(acc, key); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// listObjects returns up to MaxObjectsPerGather keys below the prefix that
+// should be ingested.
+func (s *S3Monitor) listObjects() ([]string, error) {
+	var keys []string
+	var tagErr error
+	seen := make(map[string]bool)
+	complete := true
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(s.Prefix),
+	}
+	err := s.svc.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			key := aws.StringValue(object.Key)
+			if !s.isMonitoredKey(key) {
+				continue
+			}
+
+			if s.CompletionAction == completionTag {
+				seen[key] = true
+				done, err := s.isTagged(key)
+				if err != nil {
+					tagErr = err
+					complete = false
+					return false
+				}
+				if done {
+					continue
+				}
+			}
+
+			keys = append(keys, key)
+			if len(keys) >= s.MaxObjectsPerGather {
+				complete = false
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Forget the tagged objects that were deleted or moved away.
+	if complete {
+		for key := range s.processed {
+			if !seen[key] {
+				delete(s.processed, key)
+			}
+		}
+	}
+	return keys, tagErr
+}
+
+func (s *S3Monitor) isMonitoredKey(key string) bool {
+	if strings.HasSuffix(key, "/") {
+		return false
+	}
+
+	if s.CompletionAction == completionMove && s.prefixes.Processed(key) {
+		return false
+	}
+
+	name := strings.TrimPrefix(key, s.Prefix)
+	return s.filter.Match(name)
+}
+
+func (s *S3Monitor) isTagged(key string) (bool, error) {
+	if s.processed[key] {
+		return true, nil
+	}
+
+	tags, err := s.svc.GetObjectTagging(&s3.GetObjectTaggingInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return false, fmt.Errorf("getting tags of object %q: %v", key, err)
+	}
+
+	for _, tag := range tags.TagSet {
+		if aws.StringValue(tag.Key) == s.ProcessedTagKey {
+			s.markProcessed(key)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *S3Monitor) markProcessed(key string) {
+	if len(s.processed) >= maxProcessedKeys {
+		s.processed = make(map[string]bool)
+	}
+	s.processed[key] = true
+}
+
+func (s *S3Monitor) processObject(acc telegraf.Accumulator, key string) error {
+	metrics, parseErr := s.readMetrics(key)
+	if aerr, ok := parseErr.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		// Already processed by another instance or deleted.
+		s.Log.Debugf("Object %q no longer exists", key)
+		return nil
+	}
+	if parseErr != nil {
+		acc.AddError(fmt.Errorf("processing object %q: %v", key, parseErr))
+		return s.complete(key, false)
+	}
+
+	for _, m := range metrics {
+		if s.ObjectTag != "" {
+			m.AddTag(s.ObjectTag, key)
+		}
+		acc.AddMetric(m)
+	}
+	return s.complete(key, true)
+}
+
+func (s *S3Monitor) readMetrics(key string) ([]telegraf.Metric, error) {
+	out, err := s.svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	if aws.Int64Value(out.ContentLength) > s.MaxObjectSize.Size {
+		return nil, fmt.Errorf("object size %d exceeds max_object_size", aws.Int64Value(out.ContentLength))
+	}
+
+	body, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	return s.parser.Parse(body)
+}
+
+// complete moves or tags the object according to the completion action.
+func (s *S3Monitor) complete(key string, success bool) error {
+	if s.CompletionAction == completionTag {
+		if err := s.tagObject(key, monitor.Status(success)); err != nil {
+			return fmt.Errorf("tagging object %q: %v", key, err)
+		}
+		s.markProcessed(key)
+		return nil
+	}
+
+	return s.prefixes.Move("object", key, success, s.moveObject)
+}
+
+func (s *S3Monitor) moveObject(src, dest string) error {
+	_, err := s.svc.CopyObject(&s3.CopyObjectInput{
+		Bucket:     aws.String(s.Bucket),
+		CopySource: aws.String(url.PathEscape(s.Bucket + "/" + src)),
+		Key:        aws.String(dest),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(src),
+	})
+	return err
+}
+
+// tagObject adds the processed tag while keeping any existing object tags,
+// as PutObjectTagging replaces the complete tag set.
+func (s *S3Monitor) tagObject(key, value string) error {
+	current, err := s.svc.GetObjectTagging(&s3.GetObjectTaggingInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+
+	tagSet := make([]*s3.Tag, 0, len(current.TagSet)+1)
+	for _, tag := range current.TagSet {
+		if aws.StringValue(tag.Key) != s.ProcessedTagKey {
+			tagSet = append(tagSet, tag)
+		}
+	}
+	tagSet = append(tagSet, &s3.Tag{
+		Key:   aws.String(s.ProcessedTagKey),
+		Value: aws.String(value),
+	})
+
+	_, err = s.svc.PutObjectTagging(&s3.PutObjectTaggingInput{
+		Bucket:  aws.String(s.Bucket),
+		Key:     aws.String(key),
+		Tagging: &s3.Tagging{TagSet: tagSet},
+	})
+	return err
+}
+
+func init() {
+	inputs.Add("s3_monitor", func() telegraf.Input {
+		return &S3Monitor{
+			CompletionAction:    completionMove,
+			FinishedPrefix:      defaultFinishedPrefix,
+			ErrorPrefix:         defaultErrorPrefix,
+			ProcessedTagKey:     defaultProcessedTagKey,
+			MaxObjectsPerGather: defaultMaxObjectsPerGather,
+			MaxObjectSize:       internal.Size{Size: defaultMaxObjectSize},
+			SQSWaitTime:         internal.Duration{Duration: defaultSQSWaitTime},
+			WebhookPath:         defaultWebhookPath,
+		}
+	})
+}
@@ -0,0 +1,457 @@
+package s3_monitor
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockS3 struct {
+	s3iface.S3API
+	bucket  string
+	objects map[string][]byte
+	tags    map[string][]*s3.Tag
+}
+
+func newMockS3(bucket string, objects map[string]string) *mockS3 {
+	m := &mockS3{
+		bucket:  bucket,
+		objects: make(map[string][]byte),
+		tags:    make(map[string][]*s3.Tag),
+	}
+	for k, v := range objects {
+		m.objects[k] = []byte(v)
+	}
+	return m
+}
+
+func (m *mockS3) keys() []string {
+	keys := make([]string, 0, len(m.objects))
+	for k := range m.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *mockS3) ListObjectsV2Pages(input *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	page := &s3.ListObjectsV2Output{}
+	for _, k := range m.keys() {
+		if strings.HasPrefix(k, aws.StringValue(input.Prefix)) {
+			page.Contents = append(page.Contents, &s3.Object{Key: aws.String(k)})
+		}
+	}
+	fn(page, true)
+	return nil
+}
+
+func (m *mockS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	data, ok := m.objects[aws.StringValue(input.Key)]
+	if !ok {
+		return nil, awserr.New(s3.ErrCodeNoSuchKey, "The specified key does not exist.", nil)
+	}
+	return &s3.GetObjectOutput{
+		Body:          ioutil.NopCloser(bytes.NewReader(data)),
+		ContentLength: aws.Int64(int64(len(data))),
+	}, nil
+}
+
+func (m *mockS3) CopyObject(input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	source, err := url.PathUnescape(aws.StringValue(input.CopySource))
+	if err != nil {
+		return nil, err
+	}
+	data, ok := m.objects[strings.TrimPrefix(source, m.bucket+"/")]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	m.objects[aws.StringValue(input.Key)] = data
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (m *mockS3) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	delete(m.objects, aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (m *mockS3) GetObjectTagging(input *s3.GetObjectTaggingInput) (*s3.GetObjectTaggingOutput, error) {
+	return &s3.GetObjectTaggingOutput{TagSet: m.tags[aws.StringValue(input.Key)]}, nil
+}
+
+func (m *mockS3) PutObjectTagging(input *s3.PutObjectTaggingInput) (*s3.PutObjectTaggingOutput, error) {
+	m.tags[aws.StringValue(input.Key)] = input.Tagging.TagSet
+	return &s3.PutObjectTaggingOutput{}, nil
+}
+
+type mockSQS struct {
+	sqsiface.SQSAPI
+	messages []*sqs.Message
+	deleted  []string
+}
+
+func (m *mockSQS) ReceiveMessage(input *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
+	n := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if n > len(m.messages) {
+		n = len(m.messages)
+	}
+	out := &sqs.ReceiveMessageOutput{Messages: m.messages[:n]}
+	m.messages = m.messages[n:]
+	return out, nil
+}
+
+func (m *mockSQS) DeleteMessage(input *sqs.DeleteMessageInput) (*sqs.DeleteMessageOutput, error) {
+	m.deleted = append(m.deleted, aws.StringValue(input.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func newMessage(id, body string) *sqs.Message {
+	return &sqs.Message{
+		MessageId:     aws.String(id),
+		ReceiptHandle: aws.String(id),
+		Body:          aws.String(body),
+	}
+}
+
+func newTestMonitor(svc s3iface.S3API) *S3Monitor {
+	return &S3Monitor{
+		Bucket:              "bucket",
+		Prefix:              "incoming/",
+		CompletionAction:    completionMove,
+		FinishedPrefix:      defaultFinishedPrefix,
+		ErrorPrefix:         defaultErrorPrefix,
+		ProcessedTagKey:     defaultProcessedTagKey,
+		MaxObjectsPerGather: defaultMaxObjectsPerGather,
+		MaxObjectSize:       internal.Size{Size: defaultMaxObjectSize},
+		Log:                 testutil.Logger{},
+		svc:                 svc,
+		parser:              influx.NewParser(influx.NewMetricHandler()),
+	}
+}
+
+func TestInitRequiresBucket(t *testing.T) {
+	plugin := newTestMonitor(newMockS3("bucket", nil))
+	plugin.Bucket = ""
+	require.Error(t, plugin.Init())
+}
+
+func TestInitInvalidCompletionAction(t *testing.T) {
+	plugin := newTestMonitor(newMockS3("bucket", nil))
+	plugin.CompletionAction = "rename"
+	require.Error(t, plugin.Init())
+}
+
+func TestMoveCompletion(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a.influx":   "cpu value=42 0\n",
+		"incoming/bad.influx": "not line protocol\n",
+		"other/b.influx":      "cpu value=43 0\n",
+	})
+
+	plugin := newTestMonitor(svc)
+	plugin.ObjectTag = "key"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"key": "incoming/a.influx"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+
+	require.Equal(t, []string{
+		"error/bad.influx",
+		"finished/a.influx",
+		"other/b.influx",
+	}, svc.keys())
+
+	// Nothing is left to ingest on the next interval.
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestTagCompletion(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a.influx":   "cpu value=42 0\n",
+		"incoming/bad.influx": "not line protocol\n",
+	})
+	svc.tags["incoming/a.influx"] = []*s3.Tag{{Key: aws.String("owner"), Value: aws.String("partner")}}
+
+	plugin := newTestMonitor(svc)
+	plugin.CompletionAction = completionTag
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Len(t, acc.Errors, 1)
+
+	require.Equal(t, []string{"incoming/a.influx", "incoming/bad.influx"}, svc.keys())
+	require.Equal(t, []*s3.Tag{
+		{Key: aws.String("owner"), Value: aws.String("partner")},
+		{Key: aws.String(defaultProcessedTagKey), Value: aws.String(monitor.StatusSuccess)},
+	}, svc.tags["incoming/a.influx"])
+	require.Equal(t, []*s3.Tag{
+		{Key: aws.String(defaultProcessedTagKey), Value: aws.String(monitor.StatusError)},
+	}, svc.tags["incoming/bad.influx"])
+
+	// Tagged objects are not ingested again, even by a new instance.
+	plugin = newTestMonitor(svc)
+	plugin.CompletionAction = completionTag
+	require.NoError(t, plugin.Init())
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestTagCompletionForgetsRemovedObjects(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a.influx": "cpu value=1 0\n",
+		"incoming/b.influx": "cpu value=2 0\n",
+	})
+
+	plugin := newTestMonitor(svc)
+	plugin.CompletionAction = completionTag
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+	require.Len(t, plugin.processed, 2)
+
+	delete(svc.objects, "incoming/a.influx")
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+	require.Equal(t, map[string]bool{"incoming/b.influx": true}, plugin.processed)
+}
+
+func TestMaxObjectsPerGather(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a.influx": "cpu value=1 0\n",
+		"incoming/b.influx": "cpu value=2 0\n",
+		"incoming/c.influx": "cpu value=3 0\n",
+	})
+
+	plugin := newTestMonitor(svc)
+	plugin.MaxObjectsPerGather = 2
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+}
+
+func TestMaxObjectSize(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/big.influx": "cpu value=1 0\n",
+	})
+
+	plugin := newTestMonitor(svc)
+	plugin.MaxObjectSize = internal.Size{Size: 4}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{"error/big.influx"}, svc.keys())
+}
+
+func TestSQSNotifications(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a b.influx":   "cpu value=1 0\n",
+		"incoming/c.influx":     "cpu value=2 0\n",
+		"incoming/never.influx": "cpu value=4 0\n",
+	})
+	queue := &mockSQS{
+		messages: []*sqs.Message{
+			newMessage("direct", `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"incoming/a+b.influx"}}}]}`),
+			newMessage("sns", `{"Type":"Notification","Message":"{\"Records\":[{\"eventName\":\"ObjectCreated:CompleteMultipartUpload\",\"s3\":{\"bucket\":{\"name\":\"bucket\"},\"object\":{\"key\":\"incoming/c.influx\"}}}]}"}`),
+			newMessage("other", `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"other"},"object":{"key":"incoming/never.influx"}}}]}`),
+			newMessage("removed", `{"Records":[{"eventName":"ObjectRemoved:Delete","s3":{"bucket":{"name":"bucket"},"object":{"key":"incoming/never.influx"}}}]}`),
+			newMessage("gone", `{"Records":[{"eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"incoming/gone.influx"}}}]}`),
+			newMessage("test", `{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"bucket"}`),
+			newMessage("invalid", `not json`),
+		},
+	}
+
+	plugin := newTestMonitor(svc)
+	plugin.SQSQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/events"
+	plugin.queue = queue
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{"direct", "sns", "other", "removed", "gone", "test"}, queue.deleted)
+	require.Equal(t, []string{
+		"finished/a b.influx",
+		"finished/c.influx",
+		"incoming/never.influx",
+	}, svc.keys())
+}
+
+func TestWebhookNotifications(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a.influx": "cpu value=1 0\n",
+		"incoming/b.influx": "cpu value=2 0\n",
+		"incoming/c.influx": "cpu value=3 0\n",
+	})
+
+	plugin := newTestMonitor(svc)
+	plugin.WebhookServiceAddress = "localhost:0"
+	plugin.WebhookPath = "/minio/events"
+	plugin.WebhookAuthToken = "secret"
+	plugin.MaxObjectsPerGather = 1
+	require.NoError(t, plugin.Init())
+
+	post := func(path, token, body string) int {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Authorization", token)
+		res := httptest.NewRecorder()
+		plugin.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	event := func(key string) string {
+		return `{"EventName":"s3:ObjectCreated:Put","Key":"bucket/` + key + `","Records":[{"eventName":"s3:ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"` + key + `"}}}]}`
+	}
+
+	require.Equal(t, http.StatusUnauthorized, post("/minio/events", "Bearer wrong", event("incoming/a.influx")))
+	require.Equal(t, http.StatusNotFound, post("/other", "Bearer secret", event("incoming/a.influx")))
+	require.Equal(t, http.StatusBadRequest, post("/minio/events", "Bearer secret", "not json"))
+	require.Equal(t, http.StatusNoContent, post("/minio/events", "Bearer secret", event("incoming/a.influx")))
+	require.Equal(t, http.StatusNoContent, post("/minio/events", "secret", event("incoming/a.influx")))
+	require.Equal(t, http.StatusNoContent, post("/minio/events", "Bearer secret", event("incoming/b.influx")))
+
+	// Only notified objects are ingested, once each.
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.NoError(t, plugin.Gather(&acc))
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+	require.Empty(t, acc.Errors)
+	require.Equal(t, []string{
+		"finished/a.influx",
+		"finished/b.influx",
+		"incoming/c.influx",
+	}, svc.keys())
+}
+
+func TestWebhookListener(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a.influx": "cpu value=1 0\n",
+	})
+
+	plugin := newTestMonitor(svc)
+	plugin.WebhookServiceAddress = "localhost:0"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	url := "http://" + plugin.listener.Addr().String() + "/"
+	res, err := http.Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	body := `{"Records":[{"eventName":"s3:ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"incoming/a.influx"}}}]}`
+	res, err = http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, []string{"finished/a.influx"}, svc.keys())
+}
+
+func TestPendingLimit(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a.influx": "cpu value=1 0\n",
+		"incoming/b.influx": "cpu value=2 0\n",
+		"incoming/c.influx": "cpu value=3 0\n",
+	})
+
+	plugin := newTestMonitor(svc)
+	plugin.WebhookServiceAddress = "localhost:0"
+	require.NoError(t, plugin.Init())
+	plugin.maxPending = 1
+
+	post := func(key string) int {
+		body := `{"Records":[{"eventName":"s3:ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"` + key + `"}}}]}`
+		res := httptest.NewRecorder()
+		plugin.ServeHTTP(res, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		return res.Code
+	}
+	require.Equal(t, http.StatusNoContent, post("incoming/a.influx"))
+	require.Equal(t, http.StatusServiceUnavailable, post("incoming/b.influx"))
+	require.Equal(t, int64(1), plugin.NotificationsDropped.Get())
+
+	// The dropped objects are found by listing the prefix once there is room.
+	var acc testutil.Accumulator
+	for i := 0; i < 3; i++ {
+		require.NoError(t, plugin.Gather(&acc))
+	}
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+	require.Empty(t, acc.Errors)
+	require.Equal(t, []string{
+		"finished/a.influx",
+		"finished/b.influx",
+		"finished/c.influx",
+	}, svc.keys())
+}
+
+func TestStartListsMissedObjects(t *testing.T) {
+	svc := newMockS3("bucket", map[string]string{
+		"incoming/a.influx": "cpu value=1 0\n",
+	})
+
+	plugin := newTestMonitor(svc)
+	plugin.WebhookServiceAddress = "localhost:0"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Objects created while stopped are ingested without a notification.
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, []string{"finished/a.influx"}, svc.keys())
+}
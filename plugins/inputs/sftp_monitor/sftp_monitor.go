@@ -0,0 +1,355 @@
+package sftp_monitor
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	defaultPort              = "22"
+	defaultTimeout           = 30 * time.Second
+	defaultMaxFilesPerGather = 1000
+	defaultMaxFileSize       = 100 * 1024 * 1024
+)
+
+var sampleConfig = `
+  ## Address of the SFTP server; the port defaults to 22.
+  address = "sftp.example.com:22"
+
+  ## Login credentials.  Set 'password', 'private_key' or both.
+  username = "telegraf"
+  # password = ""
+  # private_key = "/etc/telegraf/id_ed25519"
+  # private_key_passphrase = ""
+
+  ## File in OpenSSH known_hosts format used to verify the server host key.
+  ## Setting 'insecure_ignore_host_key' skips verification instead.
+  known_hosts = "/etc/telegraf/known_hosts"
+  # insecure_ignore_host_key = false
+
+  ## Timeout for establishing the connection.
+  # timeout = "30s"
+
+  ## Remote directory to monitor for new files.
+  directory = "/upload"
+
+  ## Remote directories that files are moved to after processing, on success
+  ## and failure respectively.  Both must be on the same filesystem as
+  ## 'directory', as files are renamed into place.
+  finished_directory = "/upload/finished"
+  error_directory = "/upload/error"
+
+  ## Regular expressions matching the file names that should be ingested.  An
+  ## empty list matches all names.
+  # files_to_monitor = ["^.*\\.csv"]
+
+  ## Regular expressions matching file names that should be skipped.
+  # files_to_ignore = []
+
+  ## Files modified more recently than this are left alone, so that uploads
+  ## still in progress aren't picked up.
+  # min_file_age = "0s"
+
+  ## Maximum number of files to ingest each interval.
+  # max_files_per_gather = 1000
+
+  ## Files larger than this are moved to 'error_directory' instead of being
+  ## read into memory.
+  # max_file_size = "100MB"
+
+  ## Name a tag containing the name of the file the data was parsed from.
+  ## Leave empty to disable.
+  # file_tag = ""
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+// client abstracts the SFTP operations used by the plugin.
+type client interface {
+	ReadDir(dir string) ([]os.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	Rename(oldname, newname string) error
+	Close() error
+}
+
+type SFTPMonitor struct {
+	Address               string            `toml:"address"`
+	Username              string            `toml:"username"`
+	Password              string            `toml:"password"`
+	PrivateKey            string            `toml:"private_key"`
+	PrivateKeyPassphrase  string            `toml:"private_key_passphrase"`
+	KnownHosts            string            `toml:"known_hosts"`
+	InsecureIgnoreHostKey bool              `toml:"insecure_ignore_host_key"`
+	Timeout               internal.Duration `toml:"timeout"`
+	Directory             string            `toml:"directory"`
+	FinishedDirectory     string            `toml:"finished_directory"`
+	ErrorDirectory        string            `toml:"error_directory"`
+	FilesToMonitor        []string          `toml:"files_to_monitor"`
+	FilesToIgnore         []string          `toml:"files_to_ignore"`
+	MinFileAge            internal.Duration `toml:"min_file_age"`
+	MaxFilesPerGather     int               `toml:"max_files_per_gather"`
+	MaxFileSize           internal.Size     `toml:"max_file_size"`
+	FileTag               string            `toml:"file_tag"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	connect func() (client, error)
+	parser  parsers.Parser
+	dirs    monitor.Directories
+	filter  *monitor.Filter
+}
+
+func (s *SFTPMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SFTPMonitor) Description() string {
+	return "Ingest files dropped into a directory of a remote SFTP server"
+}
+
+func (s *SFTPMonitor) SetParser(parser parsers.Parser) {
+	s.parser = parser
+}
+
+func (s *SFTPMonitor) Init() error {
+	if s.Address == "" {
+		return errors.New("address must be set")
+	}
+	s.dirs = monitor.Directories{
+		Directory: s.Directory,
+		Finished:  s.FinishedDirectory,
+		Error:     s.ErrorDirectory,
+	}
+	if err := s.dirs.Validate(); err != nil {
+		return err
+	}
+
+	var err error
+	s.filter, err = monitor.NewFilter(s.FilesToMonitor, s.FilesToIgnore)
+	if err != nil {
+		return err
+	}
+
+	if s.connect == nil {
+		config, err := s.clientConfig()
+		if err != nil {
+			return err
+		}
+		s.connect = func() (client, error) {
+			return dial(s.Address, config)
+		}
+	}
+
+	return nil
+}
+
+func (s *SFTPMonitor) clientConfig() (*ssh.ClientConfig, error) {
+	if s.Username == "" {
+		return nil, errors.New("username must be set")
+	}
+
+	var auth []ssh.AuthMethod
+	if s.PrivateKey != "" {
+		key, err := ioutil.ReadFile(s.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("reading private_key: %v", err)
+		}
+		var signer ssh.Signer
+		if s.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(s.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing private_key: %v", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if s.Password != "" {
+		auth = append(auth, ssh.Password(s.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("password or private_key must be set")
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case s.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	case s.KnownHosts != "":
+		var err error
+		hostKeyCallback, err = knownhosts.New(s.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("loading known_hosts: %v", err)
+		}
+	default:
+		return nil, errors.New("known_hosts must be set unless insecure_ignore_host_key is enabled")
+	}
+
+	return &ssh.ClientConfig{
+		User:            s.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         s.Timeout.Duration,
+	}, nil
+}
+
+func (s *SFTPMonitor) Gather(acc telegraf.Accumulator) error {
+	c, err := s.connect()
+	if err != nil {
+		return fmt.Errorf("connecting to %q: %v", s.Address, err)
+	}
+	defer c.Close()
+
+	files, err := s.listFiles(c)
+	if err != nil {
+		return fmt.Errorf("listing directory %q: %v", s.Directory, err)
+	}
+
+	for _, file := range files {
+		if err := s.processFile(c, acc, file); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// listFiles returns up to MaxFilesPerGather files of the directory that
+// should be ingested, oldest first.
+func (s *SFTPMonitor) listFiles(c client) ([]os.FileInfo, error) {
+	entries, err := c.ReadDir(s.Directory)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if len(files) >= s.MaxFilesPerGather {
+			break
+		}
+		if s.isMonitored(entry) {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
+
+func (s *SFTPMonitor) isMonitored(file os.FileInfo) bool {
+	if !file.Mode().IsRegular() {
+		return false
+	}
+	if time.Since(file.ModTime()) < s.MinFileAge.Duration {
+		return false
+	}
+
+	name := file.Name()
+	return s.filter.Match(name)
+}
+
+func (s *SFTPMonitor) processFile(c client, acc telegraf.Accumulator, file os.FileInfo) error {
+	name := path.Join(s.Directory, file.Name())
+
+	metrics, parseErr := s.readMetrics(c, name, file.Size())
+	if parseErr != nil {
+		acc.AddError(fmt.Errorf("processing file %q: %v", name, parseErr))
+		return s.dirs.Move(file.Name(), false, c.Rename)
+	}
+
+	for _, m := range metrics {
+		if s.FileTag != "" {
+			m.AddTag(s.FileTag, file.Name())
+		}
+		acc.AddMetric(m)
+	}
+	return s.dirs.Move(file.Name(), true, c.Rename)
+}
+
+func (s *SFTPMonitor) readMetrics(c client, name string, size int64) ([]telegraf.Metric, error) {
+	if size > s.MaxFileSize.Size {
+		return nil, fmt.Errorf("file size %d exceeds max_file_size", size)
+	}
+
+	body, err := c.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.parser.Parse(body)
+}
+
+// sftpClient implements client over an SSH connection.
+type sftpClient struct {
+	*sftp.Client
+	conn *ssh.Client
+}
+
+func dial(address string, config *ssh.ClientConfig) (client, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultPort)
+	}
+
+	conn, err := ssh.Dial("tcp", address, config)
+	if err != nil {
+		return nil, err
+	}
+	c, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &sftpClient{Client: c, conn: conn}, nil
+}
+
+func (c *sftpClient) ReadFile(name string) ([]byte, error) {
+	f, err := c.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// Rename replaces an existing file of the same name if the server supports
+// the posix-rename extension, as plain SFTP renames refuse to overwrite.
+func (c *sftpClient) Rename(oldname, newname string) error {
+	if err := c.Client.PosixRename(oldname, newname); err == nil {
+		return nil
+	}
+	return c.Client.Rename(oldname, newname)
+}
+
+func (c *sftpClient) Close() error {
+	c.Client.Close()
+	return c.conn.Close()
+}
+
+func init() {
+	inputs.Add("sftp_monitor", func() telegraf.Input {
+		return &SFTPMonitor{
+			Timeout:           internal.Duration{Duration: defaultTimeout},
+			MaxFilesPerGather: defaultMaxFilesPerGather,
+			MaxFileSize:       internal.Size{Size: defaultMaxFileSize},
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package sftp_monitor
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (f fileInfo) Name() string       { return f.name }
+func (f fileInfo) Size() int64        { return f.size }
+func (f fileInfo) Mode() os.FileMode  { return 0644 }
+func (f fileInfo) ModTime() time.Time { return f.modTime }
+func (f fileInfo) IsDir() bool        { return false }
+func (f fileInfo) Sys() interface{}   { return nil }
+
+type mockFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+type mockClient struct {
+	files  map[string]mockFile
+	closed int
+}
+
+func newMockClient(files map[string]string) *mockClient {
+	c := &mockClient{files: make(map[string]mockFile)}
+	modTime := time.Now().Add(-time.Hour)
+	for _, name := range sortedKeys(files) {
+		c.files[name] = mockFile{data: []byte(files[name]), modTime: modTime}
+		modTime = modTime.Add(time.Second)
+	}
+	return c
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *mockClient) names() []string {
+	names := make([]string, 0, len(c.files))
+	for k := range c.files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *mockClient) ReadDir(dir string) ([]os.FileInfo, error) {
+	var entries []os.FileInfo
+	for name, f := range c.files {
+		if path.Dir(name) == dir {
+			entries = append(entries, fileInfo{
+				name:    path.Base(name),
+				size:    int64(len(f.data)),
+				modTime: f.modTime,
+			})
+		}
+	}
+	return entries, nil
+}
+
+func (c *mockClient) ReadFile(name string) ([]byte, error) {
+	f, ok := c.files[name]
+	if !ok {
+		return nil, errors.New("file does not exist")
+	}
+	return f.data, nil
+}
+
+func (c *mockClient) Rename(oldname, newname string) error {
+	f, ok := c.files[oldname]
+	if !ok {
+		return errors.New("file does not exist")
+	}
+	c.files[newname] = f
+	delete(c.files, oldname)
+	return nil
+}
+
+func (c *mockClient) Close() error {
+	c.closed++
+	return nil
+}
+
+func newTestMonitor(c *mockClient) *SFTPMonitor {
+	return &SFTPMonitor{
+		Address:           "localhost",
+		Directory:         "/upload",
+		FinishedDirectory: "/upload/finished",
+		ErrorDirectory:    "/upload/error",
+		MaxFilesPerGather: defaultMaxFilesPerGather,
+		MaxFileSize:       internal.Size{Size: defaultMaxFileSize},
+		Log:               testutil.Logger{},
+		connect:           func() (client, error) { return c, nil },
+		parser:            influx.NewParser(influx.NewMetricHandler()),
+	}
+}
+
+func TestInitRequiresHostKeyVerification(t *testing.T) {
+	plugin := newTestMonitor(nil)
+	plugin.connect = nil
+	plugin.Username = "telegraf"
+	plugin.Password = "secret"
+	require.Error(t, plugin.Init())
+
+	plugin.InsecureIgnoreHostKey = true
+	require.NoError(t, plugin.Init())
+}
+
+func TestInitRequiresCredentials(t *testing.T) {
+	plugin := newTestMonitor(nil)
+	plugin.connect = nil
+	plugin.Username = "telegraf"
+	plugin.InsecureIgnoreHostKey = true
+	require.Error(t, plugin.Init())
+}
+
+func TestGather(t *testing.T) {
+	c := newMockClient(map[string]string{
+		"/upload/a.influx":   "cpu value=42 0\n",
+		"/upload/bad.influx": "not line protocol\n",
+	})
+
+	plugin := newTestMonitor(c)
+	plugin.FileTag = "file"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"file": "a.influx"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{
+		"/upload/error/bad.influx",
+		"/upload/finished/a.influx",
+	}, c.names())
+	require.Equal(t, 1, c.closed)
+}
+
+func TestMinFileAge(t *testing.T) {
+	c := newMockClient(map[string]string{
+		"/upload/a.influx": "cpu value=42 0\n",
+	})
+	c.files["/upload/new.influx"] = mockFile{data: []byte("cpu value=1 0\n"), modTime: time.Now()}
+
+	plugin := newTestMonitor(c)
+	plugin.MinFileAge = internal.Duration{Duration: time.Minute}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, []string{
+		"/upload/finished/a.influx",
+		"/upload/new.influx",
+	}, c.names())
+}
+
+func TestMaxFilesPerGatherOldestFirst(t *testing.T) {
+	c := newMockClient(map[string]string{
+		"/upload/a.influx": "cpu value=1 0\n",
+		"/upload/b.influx": "cpu value=2 0\n",
+		"/upload/c.influx": "cpu value=3 0\n",
+	})
+
+	plugin := newTestMonitor(c)
+	plugin.MaxFilesPerGather = 2
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Equal(t, []string{
+		"/upload/c.influx",
+		"/upload/finished/a.influx",
+		"/upload/finished/b.influx",
+	}, c.names())
+}
+
+func TestMaxFileSize(t *testing.T) {
+	c := newMockClient(map[string]string{
+		"/upload/big.influx": "cpu value=1 0\n",
+	})
+
+	plugin := newTestMonitor(c)
+	plugin.MaxFileSize = internal.Size{Size: 4}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, []string{"/upload/error/big.influx"}, c.names())
+}
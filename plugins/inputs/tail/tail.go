@@ -283,7 +283,7 @@ func (t *Tail) tailNewFiles(fromBeginning bool) error {
 
 // ParseLine parses a line of text.
 func parseLine(parser parsers.Parser, line string, firstLine bool) ([]telegraf.Metric, error) {
-	switch parser.(type) {
+	switch parsers.Unwrap(parser).(type) {
 	case *csv.Parser:
 		// The csv parser parses headers in Parse and skips them in ParseLine.
 		// As a temporary solution call Parse only when getting the first
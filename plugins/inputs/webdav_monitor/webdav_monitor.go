@@ -0,0 +1,379 @@
+package webdav_monitor
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/monitor"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const (
+	defaultTimeout           = time.Minute
+	defaultMaxFilesPerGather = 1000
+	defaultMaxFileSize       = 100 * 1024 * 1024
+)
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getlastmodified/>
+  </d:prop>
+</d:propfind>`
+
+var sampleConfig = `
+  ## URL of the WebDAV server root, including any path prefix such as
+  ## "/remote.php/dav/files/<user>" for Nextcloud.
+  url = "https://cloud.example.com/remote.php/dav/files/telegraf"
+
+  ## HTTP basic authentication credentials.
+  # username = "telegraf"
+  # password = ""
+
+  ## HTTP request timeout, including reading the file contents.
+  # timeout = "1m"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Collection to monitor for new files, relative to 'url'.
+  directory = "/incoming"
+
+  ## Collections that files are moved to after processing, on success and
+  ## failure respectively.
+  finished_directory = "/finished"
+  error_directory = "/error"
+
+  ## Regular expressions matching the file names that should be ingested.  An
+  ## empty list matches all names.
+  # files_to_monitor = ["^.*\\.csv"]
+
+  ## Regular expressions matching file names that should be skipped.
+  # files_to_ignore = []
+
+  ## Files modified more recently than this are left alone, so that uploads
+  ## still in progress aren't picked up.
+  # min_file_age = "0s"
+
+  ## Maximum number of files to ingest each interval.
+  # max_files_per_gather = 1000
+
+  ## Files larger than this are moved to 'error_directory' instead of being
+  ## read into memory.
+  # max_file_size = "100MB"
+
+  ## Name a tag containing the name of the file the data was parsed from.
+  ## Leave empty to disable.
+  # file_tag = ""
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+// multistatus is the body of a PROPFIND response.
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"DAV: collection"`
+				} `xml:"DAV: resourcetype"`
+				ContentLength int64  `xml:"DAV: getcontentlength"`
+				LastModified  string `xml:"DAV: getlastmodified"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// file is a member of the monitored collection.
+type file struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+type WebDAVMonitor struct {
+	URL               string            `toml:"url"`
+	Username          string            `toml:"username"`
+	Password          string            `toml:"password"`
+	Timeout           internal.Duration `toml:"timeout"`
+	Directory         string            `toml:"directory"`
+	FinishedDirectory string            `toml:"finished_directory"`
+	ErrorDirectory    string            `toml:"error_directory"`
+	FilesToMonitor    []string          `toml:"files_to_monitor"`
+	FilesToIgnore     []string          `toml:"files_to_ignore"`
+	MinFileAge        internal.Duration `toml:"min_file_age"`
+	MaxFilesPerGather int               `toml:"max_files_per_gather"`
+	MaxFileSize       internal.Size     `toml:"max_file_size"`
+	FileTag           string            `toml:"file_tag"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client  *http.Client
+	baseURL *url.URL
+	parser  parsers.Parser
+	dirs    monitor.Directories
+	filter  *monitor.Filter
+}
+
+func (w *WebDAVMonitor) SampleConfig() string {
+	return sampleConfig
+}
+
+func (w *WebDAVMonitor) Description() string {
+	return "Ingest files dropped into a WebDAV collection"
+}
+
+func (w *WebDAVMonitor) SetParser(parser parsers.Parser) {
+	w.parser = parser
+}
+
+func (w *WebDAVMonitor) Init() error {
+	if w.URL == "" {
+		return errors.New("url must be set")
+	}
+	w.dirs = monitor.Directories{
+		Directory: w.Directory,
+		Finished:  w.FinishedDirectory,
+		Error:     w.ErrorDirectory,
+	}
+	if err := w.dirs.Validate(); err != nil {
+		return err
+	}
+
+	var err error
+	w.baseURL, err = url.Parse(w.URL)
+	if err != nil {
+		return fmt.Errorf("parsing url: %v", err)
+	}
+
+	w.filter, err = monitor.NewFilter(w.FilesToMonitor, w.FilesToIgnore)
+	if err != nil {
+		return err
+	}
+
+	tlsCfg, err := w.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	w.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: w.Timeout.Duration,
+	}
+
+	return nil
+}
+
+func (w *WebDAVMonitor) Gather(acc telegraf.Accumulator) error {
+	files, err := w.listFiles()
+	if err != nil {
+		return fmt.Errorf("listing directory %q: %v", w.Directory, err)
+	}
+
+	for _, f := range files {
+		if err := w.processFile(acc, f); err != nil {
+			acc.AddError(err)
+		}
+	}
+	return nil
+}
+
+// listFiles returns up to MaxFilesPerGather files of the collection that
+// should be ingested, oldest first.
+func (w *WebDAVMonitor) listFiles() ([]file, error) {
+	entries, err := w.propfind(w.Directory)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ModTime.Before(entries[j].ModTime)
+	})
+
+	var files []file
+	for _, entry := range entries {
+		if len(files) >= w.MaxFilesPerGather {
+			break
+		}
+		if w.isMonitored(entry) {
+			files = append(files, entry)
+		}
+	}
+	return files, nil
+}
+
+func (w *WebDAVMonitor) isMonitored(f file) bool {
+	if time.Since(f.ModTime) < w.MinFileAge.Duration {
+		return false
+	}
+	return w.filter.Match(f.Name)
+}
+
+// propfind lists the non-collection members of a collection.
+func (w *WebDAVMonitor) propfind(dir string) ([]file, error) {
+	req, err := w.newRequest("PROPFIND", strings.TrimSuffix(dir, "/")+"/", strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+	resp, err := w.do(req, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("decoding PROPFIND response: %v", err)
+	}
+
+	var files []file
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") || ps.Prop.ResourceType.Collection != nil {
+				continue
+			}
+			modTime, _ := http.ParseTime(ps.Prop.LastModified)
+			files = append(files, file{
+				Name:    path.Base(href.Path),
+				Size:    ps.Prop.ContentLength,
+				ModTime: modTime,
+			})
+		}
+	}
+	return files, nil
+}
+
+func (w *WebDAVMonitor) processFile(acc telegraf.Accumulator, f file) error {
+	name := path.Join(w.Directory, f.Name)
+
+	metrics, parseErr := w.readMetrics(name, f.Size)
+	if parseErr != nil {
+		acc.AddError(fmt.Errorf("processing file %q: %v", name, parseErr))
+		return w.dirs.Move(f.Name, false, w.rename)
+	}
+
+	for _, m := range metrics {
+		if w.FileTag != "" {
+			m.AddTag(w.FileTag, f.Name)
+		}
+		acc.AddMetric(m)
+	}
+	return w.dirs.Move(f.Name, true, w.rename)
+}
+
+func (w *WebDAVMonitor) readMetrics(name string, size int64) ([]telegraf.Metric, error) {
+	if size > w.MaxFileSize.Size {
+		return nil, fmt.Errorf("file size %d exceeds max_file_size", size)
+	}
+
+	req, err := w.newRequest(http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := w.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return w.parser.Parse(body)
+}
+
+func (w *WebDAVMonitor) rename(src, dest string) error {
+	req, err := w.newRequest("MOVE", src, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Destination", w.resolve(dest).String())
+	req.Header.Set("Overwrite", "T")
+
+	resp, err := w.do(req, http.StatusCreated, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// resolve returns the URL of a path relative to the server root.
+func (w *WebDAVMonitor) resolve(name string) *url.URL {
+	u := *w.baseURL
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + strings.TrimPrefix(name, "/")
+	return &u
+}
+
+func (w *WebDAVMonitor) newRequest(method, name string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, w.resolve(name).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+	if w.Username != "" || w.Password != "" {
+		req.SetBasicAuth(w.Username, w.Password)
+	}
+	return req, nil
+}
+
+// do sends the request and returns the response if its status is one of the
+// expected codes.
+func (w *WebDAVMonitor) do(req *http.Request, expected ...int) (*http.Response, error) {
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s received status code %d (%s)", req.Method, resp.StatusCode, http.StatusText(resp.StatusCode))
+}
+
+func init() {
+	inputs.Add("webdav_monitor", func() telegraf.Input {
+		return &WebDAVMonitor{
+			Timeout:           internal.Duration{Duration: defaultTimeout},
+			MaxFilesPerGather: defaultMaxFilesPerGather,
+			MaxFileSize:       internal.Size{Size: defaultMaxFileSize},
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package webdav_monitor
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/webdav"
+)
+
+func newTestServer(t *testing.T, files map[string]string) (*httptest.Server, webdav.FileSystem) {
+	ctx := context.Background()
+	fs := webdav.NewMemFS()
+	for _, dir := range []string{"/dav", "/dav/incoming", "/dav/finished", "/dav/error"} {
+		require.NoError(t, fs.Mkdir(ctx, dir, 0755))
+	}
+	for name, content := range files {
+		f, err := fs.OpenFile(ctx, name, os.O_CREATE|os.O_WRONLY, 0644)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+	}
+
+	handler := &webdav.Handler{
+		FileSystem: fs,
+		LockSystem: webdav.NewMemLS(),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "telegraf" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	return ts, fs
+}
+
+func listFiles(t *testing.T, fs webdav.FileSystem, dir string) []string {
+	f, err := fs.OpenFile(context.Background(), dir, os.O_RDONLY, 0)
+	require.NoError(t, err)
+	defer f.Close()
+
+	infos, err := f.Readdir(-1)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newTestMonitor(url string) *WebDAVMonitor {
+	return &WebDAVMonitor{
+		URL:               url + "/dav",
+		Username:          "telegraf",
+		Password:          "secret",
+		Timeout:           internal.Duration{Duration: defaultTimeout},
+		Directory:         "/incoming",
+		FinishedDirectory: "/finished",
+		ErrorDirectory:    "/error",
+		MaxFilesPerGather: defaultMaxFilesPerGather,
+		MaxFileSize:       internal.Size{Size: defaultMaxFileSize},
+		Log:               testutil.Logger{},
+		parser:            influx.NewParser(influx.NewMetricHandler()),
+	}
+}
+
+func TestGather(t *testing.T) {
+	ts, fs := newTestServer(t, map[string]string{
+		"/dav/incoming/a.influx":   "cpu value=42 0\n",
+		"/dav/incoming/bad.influx": "not line protocol\n",
+	})
+	defer ts.Close()
+
+	plugin := newTestMonitor(ts.URL)
+	plugin.FileTag = "file"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"file": "a.influx"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.Len(t, acc.Errors, 1)
+
+	require.Empty(t, listFiles(t, fs, "/dav/incoming"))
+	require.Equal(t, []string{"a.influx"}, listFiles(t, fs, "/dav/finished"))
+	require.Equal(t, []string{"bad.influx"}, listFiles(t, fs, "/dav/error"))
+
+	f, err := fs.OpenFile(context.Background(), "/dav/finished/a.influx", os.O_RDONLY, 0)
+	require.NoError(t, err)
+	defer f.Close()
+	content, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=42 0\n", string(content))
+}
+
+func TestGatherSpecialCharacters(t *testing.T) {
+	ts, fs := newTestServer(t, map[string]string{
+		"/dav/incoming/data 2021#1.influx": "cpu value=42 0\n",
+	})
+	defer ts.Close()
+
+	plugin := newTestMonitor(ts.URL)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Empty(t, acc.Errors)
+	require.Equal(t, []string{"data 2021#1.influx"}, listFiles(t, fs, "/dav/finished"))
+}
+
+func TestMaxFilesPerGather(t *testing.T) {
+	ts, fs := newTestServer(t, map[string]string{
+		"/dav/incoming/a.influx": "cpu value=1 0\n",
+		"/dav/incoming/b.influx": "cpu value=2 0\n",
+		"/dav/incoming/c.influx": "cpu value=3 0\n",
+	})
+	defer ts.Close()
+
+	plugin := newTestMonitor(ts.URL)
+	plugin.MaxFilesPerGather = 2
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.Len(t, acc.GetTelegrafMetrics(), 2)
+	require.Len(t, listFiles(t, fs, "/dav/incoming"), 1)
+}
+
+func TestUnauthorized(t *testing.T) {
+	ts, _ := newTestServer(t, nil)
+	defer ts.Close()
+
+	plugin := newTestMonitor(ts.URL)
+	plugin.Password = "wrong"
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, plugin.Gather(&acc))
+}
@@ -0,0 +1,382 @@
+package azure_blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/upload"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const (
+	blobTypeBlock  = "block"
+	blobTypeAppend = "append"
+
+	defaultEndpointSuffix      = "core.windows.net"
+	defaultAppendFlushSize     = 1024 * 1024 * 1024
+	defaultAppendFlushInterval = time.Hour
+	storageResource            = "https://storage.azure.com/"
+	minTokenRefreshInterval    = 10 * time.Second
+	tokenRefreshBeforeDeadline = 5 * time.Minute
+)
+
+var sampleConfig = `
+  ## Storage account and container to write the blobs to, the container must
+  ## exist prior to starting telegraf.
+  account_name = "mystorageaccount"
+  container = "metrics"
+
+  ## DNS suffix of the storage service, change it for sovereign clouds.
+  # endpoint_suffix = "core.windows.net"
+
+  ## Credentials, the first one set is used:
+  ##   sas_token:   Shared access signature with create and write permissions
+  ##                on the container.
+  ##   account_key: Storage account access key.
+  ## If neither is set, a token for the managed identity of the host is
+  ## requested.  Set 'managed_identity_client_id' to use a user assigned
+  ## identity.
+  # sas_token = ""
+  # account_key = ""
+  # managed_identity_client_id = ""
+
+  ## Type of the blobs written:
+  ##   block:  Metrics are accumulated and uploaded as a new block blob once
+  ##           the serialized data reaches flush_size or the first data is
+  ##           older than flush_interval.
+  ##   append: Metrics are appended to an append blob on each write, a new
+  ##           blob is started once it reaches flush_size or is older than
+  ##           flush_interval.
+  # blob_type = "block"
+
+  ## Template of the blob names.  Available placeholders are {{host}},
+  ## {{yyyy}}, {{MM}}, {{dd}}, {{HH}}, {{mm}} and {{ss}} for the UTC time the
+  ## blob was started, {{timestamp}} for the same time in Unix seconds and
+  ## {{counter}} for the number of blobs written since telegraf started and
+  ## {{instance}} for a random id chosen when telegraf starts.
+  # object_name = "{{host}}/{{yyyy}}/{{MM}}/{{dd}}/{{HH}}-{{instance}}-{{counter}}"
+
+  ## Compression of block blobs, either "gzip" or "" for none.  The
+  ## extension is not added to the blob name and no Content-Encoding is set,
+  ## the blobs are stored compressed.
+  # compression = ""
+
+  ## Content type of the blobs.
+  # content_type = ""
+
+  ## Partitions of block blobs by the time and name of the metrics, such as
+  ## "dt={{yyyy}}-{{MM}}-{{dd}}/hour={{HH}}" for Hive style partitioning.
+  ## The partition is prepended to the blob name.  Available placeholders are
+  ## {{yyyy}}, {{MM}}, {{dd}}, {{HH}}, {{mm}} and {{ss}} for the UTC time of
+  ## the metric and {{measurement}} for its name.
+  # partitioning = ""
+
+  ## When to upload a block blob or start a new append blob.  Defaults to
+  ## "16MiB" and "5m" for block blobs, "1GiB" and "1h" for append blobs.
+  # flush_size = "16MiB"
+  # flush_interval = "5m"
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+// container abstracts the Blob Storage operations used by the plugin.
+type container interface {
+	Upload(ctx context.Context, name string, data []byte) error
+	CreateAppendBlob(ctx context.Context, name string) error
+	AppendBlock(ctx context.Context, name string, data []byte) error
+}
+
+type AzureBlob struct {
+	AccountName             string          `toml:"account_name"`
+	Container               string          `toml:"container"`
+	EndpointSuffix          string          `toml:"endpoint_suffix"`
+	SASToken                string          `toml:"sas_token"`
+	AccountKey              string          `toml:"account_key"`
+	ManagedIdentityClientID string          `toml:"managed_identity_client_id"`
+	BlobType                string          `toml:"blob_type"`
+	ContentType             string          `toml:"content_type"`
+	Log                     telegraf.Logger `toml:"-"`
+	upload.Config
+
+	container   container
+	serializer  serializers.Serializer
+	batcher     *upload.Batcher
+	partitioner *upload.Partitioner
+	now         func() time.Time
+
+	// current append blob
+	blobNames   *upload.NameTemplate
+	blobName    string
+	blobStarted time.Time
+	blobSize    int64
+	blobCounter int64
+}
+
+func (a *AzureBlob) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *AzureBlob) Description() string {
+	return "Write metrics to blobs in an Azure Blob Storage container"
+}
+
+func (a *AzureBlob) SetSerializer(serializer serializers.Serializer) {
+	a.serializer = serializer
+}
+
+func (a *AzureBlob) Init() error {
+	if a.AccountName == "" {
+		return errors.New("account_name must be set")
+	}
+	if a.Container == "" {
+		return errors.New("container must be set")
+	}
+
+	switch a.BlobType {
+	case "":
+		a.BlobType = blobTypeBlock
+	case blobTypeBlock, blobTypeAppend:
+	default:
+		return fmt.Errorf("unknown blob_type %q", a.BlobType)
+	}
+
+	a.now = time.Now
+
+	var err error
+	if a.BlobType == blobTypeBlock {
+		a.batcher, err = a.Config.NewBatcher(a.upload)
+		if err != nil {
+			return err
+		}
+		if !serializers.CanJoin(a.serializer, true) {
+			a.batcher.SerializeObjects(a.serializer)
+		}
+		a.partitioner, err = upload.NewPartitioner(a.Partitioning)
+		return err
+	}
+
+	if a.Compression != "" {
+		return errors.New("compression is not supported with append blobs")
+	}
+	if a.Partitioning != "" {
+		return errors.New("partitioning is not supported with append blobs")
+	}
+	if !serializers.CanJoin(a.serializer, true) {
+		return errors.New("the data format can't be appended to, use block blobs")
+	}
+	if a.FlushSize.Size == 0 {
+		a.FlushSize.Size = defaultAppendFlushSize
+	}
+	if a.FlushInterval.Duration == 0 {
+		a.FlushInterval.Duration = defaultAppendFlushInterval
+	}
+	a.blobNames, err = upload.NewNameTemplate(a.ObjectName)
+	return err
+}
+
+func (a *AzureBlob) Connect() error {
+	if a.container != nil {
+		return nil
+	}
+
+	var err error
+	a.container, err = a.newContainer()
+	return err
+}
+
+func (a *AzureBlob) newContainer() (container, error) {
+	if a.EndpointSuffix == "" {
+		a.EndpointSuffix = defaultEndpointSuffix
+	}
+	u, err := url.Parse(fmt.Sprintf("https://%s.blob.%s/%s", a.AccountName, a.EndpointSuffix, a.Container))
+	if err != nil {
+		return nil, err
+	}
+
+	var credential azblob.Credential
+	switch {
+	case a.SASToken != "":
+		u.RawQuery = strings.TrimPrefix(a.SASToken, "?")
+		credential = azblob.NewAnonymousCredential()
+	case a.AccountKey != "":
+		credential, err = azblob.NewSharedKeyCredential(a.AccountName, a.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account_key: %v", err)
+		}
+	default:
+		credential, err = a.managedIdentityCredential()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get managed identity token: %v", err)
+		}
+	}
+
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{
+		Telemetry: azblob.TelemetryOptions{Value: internal.ProductToken()},
+	})
+	return &azureContainer{
+		url:     azblob.NewContainerURL(*u, pipeline),
+		headers: azblob.BlobHTTPHeaders{ContentType: a.ContentType},
+	}, nil
+}
+
+// managedIdentityCredential returns a token credential for the managed
+// identity of the host, refreshed in the background before it expires.
+func (a *AzureBlob) managedIdentityCredential() (azblob.Credential, error) {
+	endpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	var spt *adal.ServicePrincipalToken
+	if a.ManagedIdentityClientID != "" {
+		spt, err = adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(endpoint, storageResource, a.ManagedIdentityClientID)
+	} else {
+		spt, err = adal.NewServicePrincipalTokenFromMSI(endpoint, storageResource)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := spt.Refresh(); err != nil {
+		return nil, err
+	}
+
+	// The refresher is called right away and then whenever the returned
+	// duration has passed.
+	refresher := func(credential azblob.TokenCredential) time.Duration {
+		if err := spt.EnsureFresh(); err != nil {
+			a.Log.Errorf("Refreshing managed identity token: %v", err)
+			return minTokenRefreshInterval
+		}
+		token := spt.Token()
+		credential.SetToken(token.AccessToken)
+
+		next := time.Until(token.Expires()) - tokenRefreshBeforeDeadline
+		if next < minTokenRefreshInterval {
+			next = minTokenRefreshInterval
+		}
+		return next
+	}
+	return azblob.NewTokenCredential(spt.Token().AccessToken, refresher), nil
+}
+
+// Close uploads the metrics accumulated for the current block blob.
+func (a *AzureBlob) Close() error {
+	if a.BlobType == blobTypeAppend {
+		return nil
+	}
+	return a.batcher.Flush()
+}
+
+func (a *AzureBlob) Write(metrics []telegraf.Metric) error {
+	if a.BlobType == blobTypeBlock {
+		partitions, err := a.partitioner.Serialize(a.serializer, metrics)
+		if err != nil {
+			return err
+		}
+		return a.batcher.AddPartitions(partitions)
+	}
+
+	octets, err := a.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("could not serialize metrics: %v", err)
+	}
+	return a.append(octets)
+}
+
+func (a *AzureBlob) upload(name string, data []byte) error {
+	if err := a.container.Upload(context.Background(), name, data); err != nil {
+		return err
+	}
+	a.Log.Debugf("Uploaded %d bytes to %q", len(data), name)
+	return nil
+}
+
+// append appends the data to the current append blob, starting a new one if
+// it is full or too old.  Data larger than the maximum block size is split
+// over several blocks, if one of them fails the data written before it is
+// duplicated when the write is retried.
+func (a *AzureBlob) append(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	now := a.now()
+	if a.blobName != "" && (a.blobSize >= a.FlushSize.Size || now.Sub(a.blobStarted) >= a.FlushInterval.Duration) {
+		a.blobName = ""
+		a.blobCounter++
+	}
+	if a.blobName == "" {
+		name := a.blobNames.Name(now, a.blobCounter)
+		if err := a.container.CreateAppendBlob(ctx, name); err != nil {
+			return fmt.Errorf("creating %q: %v", name, err)
+		}
+		a.Log.Debugf("Appending to %q", name)
+		a.blobName = name
+		a.blobStarted = now
+		a.blobSize = 0
+	}
+
+	for len(data) > 0 {
+		n := len(data)
+		if n > azblob.AppendBlobMaxAppendBlockBytes {
+			n = azblob.AppendBlobMaxAppendBlockBytes
+		}
+		if err := a.container.AppendBlock(ctx, a.blobName, data[:n]); err != nil {
+			return fmt.Errorf("appending to %q: %v", a.blobName, err)
+		}
+		a.blobSize += int64(n)
+		data = data[n:]
+	}
+	return nil
+}
+
+type azureContainer struct {
+	url     azblob.ContainerURL
+	headers azblob.BlobHTTPHeaders
+}
+
+func (c *azureContainer) Upload(ctx context.Context, name string, data []byte) error {
+	_, err := azblob.UploadBufferToBlockBlob(ctx, data, c.url.NewBlockBlobURL(name), azblob.UploadToBlockBlobOptions{
+		BlobHTTPHeaders: c.headers,
+	})
+	return err
+}
+
+// CreateAppendBlob creates the blob unless it exists, such as when telegraf
+// is restarted within the same blob name.
+func (c *azureContainer) CreateAppendBlob(ctx context.Context, name string) error {
+	conditions := azblob.BlobAccessConditions{
+		ModifiedAccessConditions: azblob.ModifiedAccessConditions{IfNoneMatch: azblob.ETagAny},
+	}
+	_, err := c.url.NewAppendBlobURL(name).Create(ctx, c.headers, azblob.Metadata{}, conditions)
+	if serr, ok := err.(azblob.StorageError); ok && serr.ServiceCode() == azblob.ServiceCodeBlobAlreadyExists {
+		return nil
+	}
+	return err
+}
+
+func (c *azureContainer) AppendBlock(ctx context.Context, name string, data []byte) error {
+	_, err := c.url.NewAppendBlobURL(name).AppendBlock(ctx, bytes.NewReader(data), azblob.AppendBlobAccessConditions{}, nil)
+	return err
+}
+
+func init() {
+	outputs.Add("azure_blob", func() telegraf.Output {
+		return &AzureBlob{}
+	})
+}
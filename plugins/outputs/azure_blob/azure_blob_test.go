@@ -0,0 +1,146 @@
+package azure_blob
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/upload"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockContainer struct {
+	blobs   map[string]string
+	appends int
+	err     error
+}
+
+func (c *mockContainer) Upload(_ context.Context, name string, data []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.blobs[name] = string(data)
+	return nil
+}
+
+func (c *mockContainer) CreateAppendBlob(_ context.Context, name string) error {
+	if c.err != nil {
+		return c.err
+	}
+	if _, ok := c.blobs[name]; !ok {
+		c.blobs[name] = ""
+	}
+	return nil
+}
+
+func (c *mockContainer) AppendBlock(_ context.Context, name string, data []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	if len(data) > azblob.AppendBlobMaxAppendBlockBytes {
+		return errors.New("block too large")
+	}
+	c.blobs[name] += string(data)
+	c.appends++
+	return nil
+}
+
+const line = "test1,tag1=value1 value=1 1257894000000000000\n"
+
+func newAzureBlob(t *testing.T, a *AzureBlob) (*AzureBlob, *mockContainer, *time.Time) {
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	c := &mockContainer{blobs: make(map[string]string)}
+	a.AccountName = "account"
+	a.Container = "metrics"
+	a.Log = testutil.Logger{}
+	a.container = c
+	a.SetSerializer(serializer)
+	require.NoError(t, a.Init())
+	require.NoError(t, a.Connect())
+
+	now := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	a.now = func() time.Time { return now }
+	return a, c, &now
+}
+
+func TestBlockBlob(t *testing.T) {
+	a, c, _ := newAzureBlob(t, &AzureBlob{
+		Config: upload.Config{
+			ObjectName: "metrics-{{counter}}.influx",
+			FlushSize:  internal.Size{Size: int64(2 * len(line))},
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, a.Write(testutil.MockMetrics()))
+	}
+	require.NoError(t, a.Close())
+
+	require.Equal(t, map[string]string{
+		"metrics-0.influx": line + line,
+		"metrics-1.influx": line,
+	}, c.blobs)
+}
+
+func TestAppendBlob(t *testing.T) {
+	a, c, now := newAzureBlob(t, &AzureBlob{
+		BlobType: "append",
+		Config: upload.Config{
+			ObjectName: "{{HH}}{{mm}}-{{counter}}.influx",
+			FlushSize:  internal.Size{Size: int64(2 * len(line))},
+		},
+	})
+
+	// a new blob is started when full or older than the interval
+	for i := 0; i < 3; i++ {
+		require.NoError(t, a.Write(testutil.MockMetrics()))
+	}
+	*now = now.Add(90 * time.Minute)
+	require.NoError(t, a.Write(testutil.MockMetrics()))
+	require.NoError(t, a.Close())
+
+	require.Equal(t, map[string]string{
+		"1226-0.influx": line + line,
+		"1226-1.influx": line,
+		"1356-2.influx": line,
+	}, c.blobs)
+}
+
+func TestAppendBlobSplitsLargeWrites(t *testing.T) {
+	a, c, _ := newAzureBlob(t, &AzureBlob{
+		BlobType: "append",
+		Config:   upload.Config{ObjectName: "metrics.influx"},
+	})
+
+	data := strings.Repeat(line, 2*azblob.AppendBlobMaxAppendBlockBytes/len(line)+1)
+	require.NoError(t, a.append([]byte(data)))
+	require.Equal(t, 3, c.appends)
+	require.Equal(t, data, c.blobs["metrics.influx"])
+}
+
+func TestAppendBlobError(t *testing.T) {
+	a, c, _ := newAzureBlob(t, &AzureBlob{BlobType: "append"})
+	c.err = errors.New("unavailable")
+
+	require.Error(t, a.Write(testutil.MockMetrics()))
+	require.Empty(t, c.blobs)
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&AzureBlob{Container: "metrics"}).Init())
+	require.Error(t, (&AzureBlob{AccountName: "account", Container: "metrics", BlobType: "page"}).Init())
+	require.Error(t, (&AzureBlob{
+		AccountName: "account",
+		Container:   "metrics",
+		BlobType:    "append",
+		Config:      upload.Config{Compression: "gzip"},
+	}).Init())
+}
@@ -0,0 +1,205 @@
+package directory
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/manifest"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const (
+	defaultFilenamePrefix = "metrics"
+	defaultFileExtension  = ".out"
+	doneMarkerExtension   = ".done"
+)
+
+// Directory writes each batch to a new file in a spool directory.
+type Directory struct {
+	Directory      string          `toml:"directory" required:"true"`
+	FilenamePrefix string          `toml:"filename_prefix"`
+	FileExtension  string          `toml:"file_extension"`
+	DoneMarker     bool            `toml:"done_marker"`
+	Log            telegraf.Logger `toml:"-"`
+	manifest.Options
+
+	serializer serializers.Serializer
+}
+
+var sampleConfig = `
+  ## Directory to write the files to, it is created if missing.
+  directory = "/var/spool/telegraf/outgoing"
+
+  ## Prefix and extension of the file names.  Files are named with the
+  ## prefix, the time they are written and a random suffix, such as
+  ## "metrics-1600000000123456789-d3adb33f.out".
+  # filename_prefix = "metrics"
+  # file_extension = ".out"
+
+  ## Write an empty marker file with the ".done" extension added to the file
+  ## name after each file is complete.
+  # done_marker = false
+
+  ## After each file, write an empty marker file with this name to the
+  ## directory, such as "_SUCCESS".  The marker is replaced on every write.
+  # success_marker = ""
+
+  ## After each file, write a JSON manifest with the name, size, SHA-256
+  ## checksum and number of metrics of the file.  The manifest is named by
+  ## the prefix and the time in Unix nanoseconds, relative to the directory,
+  ## such as "manifests/metrics-1600000000000000000.json".
+  # manifest_prefix = ""
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+func (d *Directory) SetSerializer(serializer serializers.Serializer) {
+	d.serializer = serializer
+}
+
+func (d *Directory) Init() error {
+	if d.Directory == "" {
+		return errors.New("directory must be set")
+	}
+	if d.FilenamePrefix == "" {
+		d.FilenamePrefix = defaultFilenamePrefix
+	}
+	if d.FileExtension == "" {
+		d.FileExtension = defaultFileExtension
+	}
+	return d.Options.Init()
+}
+
+// Check checks that the directory is writable, or can be created.
+func (d *Directory) Check() error {
+	return internal.CheckCreatableDir(d.Directory)
+}
+
+// Connect creates the directory and removes temporary files left behind
+// when telegraf was stopped while writing.
+func (d *Directory) Connect() error {
+	if err := os.MkdirAll(d.Directory, 0755); err != nil {
+		return err
+	}
+
+	stale, err := filepath.Glob(filepath.Join(d.Directory, d.tempPattern()))
+	if err != nil {
+		return err
+	}
+	for _, filename := range stale {
+		d.Log.Debugf("Removing incomplete file %q", filename)
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Directory) Close() error {
+	return nil
+}
+
+func (d *Directory) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Directory) Description() string {
+	return "Write each batch of metrics to a new file in a directory"
+}
+
+// Write writes the batch to a hidden temporary file, which is renamed to its
+// final name once it is complete.  Readers of the directory never see
+// partially written files.
+func (d *Directory) Write(metrics []telegraf.Metric) error {
+	octets, err := d.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("could not serialize metrics: %v", err)
+	}
+	if len(octets) == 0 {
+		return nil
+	}
+
+	tmp, err := ioutil.TempFile(d.Directory, d.tempPattern())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(octets); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	filename := filepath.Join(d.Directory, d.filename())
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+
+	if d.DoneMarker {
+		marker, err := os.OpenFile(filename+doneMarkerExtension, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return fmt.Errorf("writing done marker: %v", err)
+		}
+		if err := marker.Close(); err != nil {
+			return err
+		}
+	}
+
+	d.complete(manifest.NewFile(filepath.Base(filename), octets, len(metrics)))
+	return nil
+}
+
+// complete writes the manifest and success marker of the file.  Failures are
+// only logged, as returning them would write the metrics again.
+func (d *Directory) complete(file manifest.File) {
+	if !d.Options.Enabled() {
+		return
+	}
+	dir := filepath.ToSlash(d.Directory)
+	err := d.Options.Complete(time.Now(), []manifest.File{file}, func(name string, data []byte) error {
+		return manifest.WriteFile(path.Join(dir, name), data)
+	})
+	if err != nil {
+		d.Log.Errorf("Error completing file: %v", err)
+	}
+}
+
+// filename returns a name unique across restarts and instances writing to
+// the same directory.
+func (d *Directory) filename() string {
+	return d.FilenamePrefix + "-" + strconv.FormatInt(time.Now().UnixNano(), 10) + "-" +
+		internal.RandomString(8) + d.FileExtension
+}
+
+func (d *Directory) tempPattern() string {
+	return "." + d.FilenamePrefix + "-*.tmp"
+}
+
+func init() {
+	outputs.Add("directory", func() telegraf.Output {
+		return &Directory{}
+	})
+}
@@ -0,0 +1,136 @@
+package directory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/common/manifest"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newDirectory(t *testing.T, dir string) *Directory {
+	s, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	d := &Directory{
+		Directory:  dir,
+		DoneMarker: true,
+		Log:        testutil.Logger{},
+	}
+	d.SetSerializer(s)
+	require.NoError(t, d.Init())
+	require.NoError(t, d.Connect())
+	return d
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "directory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	d := newDirectory(t, filepath.Join(dir, "outgoing"))
+	require.NoError(t, d.Write(testutil.MockMetrics()))
+	require.NoError(t, d.Write(testutil.MockMetrics()))
+	require.NoError(t, d.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "outgoing", "*"))
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+
+	var written int
+	for _, filename := range files {
+		name := filepath.Base(filename)
+		require.True(t, strings.HasPrefix(name, "metrics-"), name)
+		if strings.HasSuffix(name, ".done") {
+			require.FileExists(t, strings.TrimSuffix(filename, ".done"))
+			continue
+		}
+		require.True(t, strings.HasSuffix(name, ".out"), name)
+		buf, err := ioutil.ReadFile(filename)
+		require.NoError(t, err)
+		require.Equal(t, "test1,tag1=value1 value=1 1257894000000000000\n", string(buf))
+		written++
+	}
+	require.Equal(t, 2, written)
+}
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "directory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	d := newDirectory(t, dir)
+	d.DoneMarker = false
+	d.Options = manifest.Options{SuccessMarker: "_SUCCESS", ManifestPrefix: "manifests/metrics-"}
+	require.NoError(t, d.Write(testutil.MockMetrics()))
+
+	require.FileExists(t, filepath.Join(dir, "_SUCCESS"))
+	files, err := filepath.Glob(filepath.Join(dir, "metrics-*.out"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	manifests, err := filepath.Glob(filepath.Join(dir, "manifests", "metrics-*.json"))
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+
+	buf, err := ioutil.ReadFile(manifests[0])
+	require.NoError(t, err)
+	var m manifest.Manifest
+	require.NoError(t, json.Unmarshal(buf, &m))
+	require.Equal(t, []manifest.File{
+		manifest.NewFile(filepath.Base(files[0]), []byte("test1,tag1=value1 value=1 1257894000000000000\n"), 1),
+	}, m.Files)
+}
+
+func TestRemoveIncompleteFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "directory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	stale := filepath.Join(dir, ".metrics-123.tmp")
+	require.NoError(t, ioutil.WriteFile(stale, []byte("partial"), 0600))
+	other := filepath.Join(dir, "other.out")
+	require.NoError(t, ioutil.WriteFile(other, []byte("keep"), 0600))
+
+	newDirectory(t, dir)
+	require.NoFileExists(t, stale)
+	require.FileExists(t, other)
+}
+
+func TestSkipEmptyBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "directory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	d := newDirectory(t, dir)
+	require.NoError(t, d.Write(nil))
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestInit(t *testing.T) {
+	d := &Directory{}
+	require.Error(t, d.Init())
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "directory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	d := &Directory{Directory: filepath.Join(dir, "metrics")}
+	require.NoError(t, d.Init())
+	require.NoError(t, d.Check())
+
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0644))
+	d.Directory = filepath.Join(file, "metrics")
+	require.Error(t, d.Check())
+}
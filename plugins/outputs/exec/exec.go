@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"os"
 	"os/exec"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -16,11 +19,22 @@ import (
 
 const maxStderrBytes = 512
 
+// filePlaceholder is replaced by the path of the temporary file in the
+// arguments of the command.
+const filePlaceholder = "{{file}}"
+
 // Exec defines the exec output plugin.
 type Exec struct {
-	Command []string          `toml:"command"`
+	Command []string          `toml:"command" required:"true"`
 	Timeout internal.Duration `toml:"timeout"`
 
+	InputMode       string `toml:"input_mode"`
+	TempDirectory   string `toml:"temp_directory"`
+	TempFilePattern string `toml:"temp_file_pattern"`
+	KeepFailedFiles bool   `toml:"keep_failed_files"`
+
+	Log telegraf.Logger `toml:"-"`
+
 	runner     Runner
 	serializer serializers.Serializer
 }
@@ -32,6 +46,22 @@ var sampleConfig = `
   ## Timeout for command to complete.
   # timeout = "5s"
 
+  ## How each batch is passed to the command, either "stdin" or "file".  In
+  ## file mode the batch is written to a temporary file and the command is
+  ## run with the path of the file in place of "{{file}}" in its arguments,
+  ## or as its last argument if there is no placeholder.  The file is
+  ## removed after the command completes.
+  # input_mode = "stdin"
+
+  ## Directory of the temporary files, the system temporary directory by
+  ## default.  The last "*" of the pattern is replaced by a random string.
+  # temp_directory = ""
+  # temp_file_pattern = "telegraf-*"
+
+  ## Keep the files of failed commands for inspection.  The batch is retried
+  ## with a new file, so failures leave a file per attempt.
+  # keep_failed_files = false
+
   ## Data format to output.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -39,6 +69,45 @@ var sampleConfig = `
   # data_format = "influx"
 `
 
+// Init validates the configuration.
+func (e *Exec) Init() error {
+	if len(e.Command) == 0 {
+		return fmt.Errorf("command must be set")
+	}
+	switch e.InputMode {
+	case "", "stdin":
+		e.InputMode = "stdin"
+	case "file":
+	default:
+		return fmt.Errorf("invalid input_mode %q", e.InputMode)
+	}
+	if e.TempFilePattern == "" {
+		e.TempFilePattern = "telegraf-*"
+	}
+	if strings.ContainsRune(e.TempFilePattern, os.PathSeparator) {
+		return fmt.Errorf("invalid temp_file_pattern %q", e.TempFilePattern)
+	}
+	return nil
+}
+
+// Check checks that the command can be found and, in file input mode, that
+// the temporary directory is writable.
+func (e *Exec) Check() error {
+	if _, err := exec.LookPath(e.Command[0]); err != nil {
+		return err
+	}
+	if e.InputMode == "file" {
+		dir := e.TempDirectory
+		if dir == "" {
+			dir = os.TempDir()
+		}
+		if err := internal.CheckWritableDir(dir); err != nil {
+			return fmt.Errorf("temp_directory: %w", err)
+		}
+	}
+	return nil
+}
+
 // SetSerializer sets the serializer for the output.
 func (e *Exec) SetSerializer(serializer serializers.Serializer) {
 	e.serializer = serializer
@@ -77,9 +146,59 @@ func (e *Exec) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
+	if e.InputMode == "file" {
+		return e.runWithFile(buffer.Bytes())
+	}
 	return e.runner.Run(e.Timeout.Duration, e.Command, &buffer)
 }
 
+// runWithFile writes the data to a temporary file and runs the command with
+// its path.
+func (e *Exec) runWithFile(data []byte) error {
+	file, err := ioutil.TempFile(e.TempDirectory, e.TempFilePattern)
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	path := file.Name()
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return fmt.Errorf("writing temporary file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("writing temporary file: %w", err)
+	}
+
+	err = e.runner.Run(e.Timeout.Duration, fileCommand(e.Command, path), nil)
+	if err != nil && e.KeepFailedFiles {
+		e.Log.Warnf("Keeping %q of the failed command", path)
+		return err
+	}
+	if rerr := os.Remove(path); rerr != nil && !os.IsNotExist(rerr) {
+		e.Log.Errorf("Removing temporary file: %v", rerr)
+	}
+	return err
+}
+
+// fileCommand returns the command with the placeholders replaced by the path,
+// the path is appended if there are none.
+func fileCommand(command []string, path string) []string {
+	result := make([]string, 0, len(command)+1)
+	found := false
+	for _, arg := range command {
+		if strings.Contains(arg, filePlaceholder) {
+			arg = strings.Replace(arg, filePlaceholder, path, -1)
+			found = true
+		}
+		result = append(result, arg)
+	}
+	if !found {
+		result = append(result, path)
+	}
+	return result
+}
+
 // Runner provides an interface for running exec.Cmd.
 type Runner interface {
 	Run(time.Duration, []string, io.Reader) error
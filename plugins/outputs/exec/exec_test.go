@@ -2,6 +2,11 @@ package exec
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -66,6 +71,112 @@ func TestExec(t *testing.T) {
 	}
 }
 
+// fileRunner records the commands and the content of their last argument.
+type fileRunner struct {
+	commands [][]string
+	data     []string
+	err      error
+}
+
+func (r *fileRunner) Run(_ time.Duration, command []string, stdin io.Reader) error {
+	if stdin != nil {
+		return errors.New("unexpected stdin")
+	}
+	r.commands = append(r.commands, command)
+	data, err := ioutil.ReadFile(command[len(command)-1])
+	if err != nil {
+		return err
+	}
+	r.data = append(r.data, string(data))
+	return r.err
+}
+
+func TestExecFileMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	runner := &fileRunner{}
+	e := &Exec{
+		Command:         []string{"load", "--table", "metrics"},
+		InputMode:       "file",
+		TempDirectory:   dir,
+		TempFilePattern: "batch-*.lp",
+		Log:             testutil.Logger{},
+		runner:          runner,
+	}
+	require.NoError(t, e.Init())
+	s, _ := serializers.NewInfluxSerializer()
+	e.SetSerializer(s)
+
+	require.NoError(t, e.Write(testutil.MockMetrics()))
+
+	require.Len(t, runner.commands, 1)
+	command := runner.commands[0]
+	require.Equal(t, []string{"load", "--table", "metrics"}, command[:3])
+	require.Equal(t, dir, filepath.Dir(command[3]))
+	require.True(t, strings.HasPrefix(filepath.Base(command[3]), "batch-"))
+	require.True(t, strings.HasSuffix(command[3], ".lp"))
+	require.Equal(t, "test1,tag1=value1 value=1 1257894000000000000\n", runner.data[0])
+
+	// The file is removed after the command succeeded.
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestExecFileModeFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	runner := &fileRunner{err: errors.New("failed")}
+	e := &Exec{
+		Command:       []string{"load", "--input={{file}}", "{{file}}"},
+		InputMode:     "file",
+		TempDirectory: dir,
+		Log:           testutil.Logger{},
+		runner:        runner,
+	}
+	require.NoError(t, e.Init())
+	s, _ := serializers.NewInfluxSerializer()
+	e.SetSerializer(s)
+
+	require.Error(t, e.Write(testutil.MockMetrics()))
+	path := runner.commands[0][2]
+	require.Equal(t, []string{"load", "--input=" + path, path}, runner.commands[0])
+	require.NoFileExists(t, path)
+
+	e.KeepFailedFiles = true
+	require.Error(t, e.Write(testutil.MockMetrics()))
+	require.FileExists(t, runner.commands[1][2])
+}
+
+func TestExecInvalidConfig(t *testing.T) {
+	e := &Exec{Command: []string{"tee"}, InputMode: "argv"}
+	require.EqualError(t, e.Init(), `invalid input_mode "argv"`)
+
+	e = &Exec{InputMode: "file"}
+	require.EqualError(t, e.Init(), "command must be set")
+}
+
+func TestExecCheck(t *testing.T) {
+	e := &Exec{Command: []string{"telegraf-missing-command"}}
+	require.NoError(t, e.Init())
+	require.Error(t, e.Check())
+
+	dir, err := ioutil.TempDir("", "exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e = &Exec{Command: []string{"tee"}, InputMode: "file", TempDirectory: dir}
+	require.NoError(t, e.Init())
+	require.NoError(t, e.Check())
+
+	e.TempDirectory = filepath.Join(dir, "missing")
+	require.Error(t, e.Check())
+}
+
 func TestTruncate(t *testing.T) {
 	tests := []struct {
 		name string
@@ -1,13 +1,23 @@
 package file
 
 import (
+	"container/list"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/rotate"
+	"github.com/influxdata/telegraf/plugins/common/manifest"
+	"github.com/influxdata/telegraf/plugins/common/upload"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
@@ -17,21 +27,75 @@ type File struct {
 	RotationInterval    internal.Duration `toml:"rotation_interval"`
 	RotationMaxSize     internal.Size     `toml:"rotation_max_size"`
 	RotationMaxArchives int               `toml:"rotation_max_archives"`
+	RotationMaxAge      internal.Duration `toml:"rotation_max_archive_age"`
+	RotationCompression string            `toml:"rotation_compression"`
 	UseBatchFormat      bool              `toml:"use_batch_format"`
 	Log                 telegraf.Logger   `toml:"-"`
 
+	Fsync           bool   `toml:"fsync"`
+	WriteMode       string `toml:"write_mode"`
+	FilePermissions string `toml:"file_permissions"`
+	FileOwner       string `toml:"file_owner"`
+	FileGroup       string `toml:"file_group"`
+	Partitioning    string `toml:"partitioning"`
+	MaxOpenFiles    int    `toml:"max_open_files"`
+	manifest.Options
+
+	options    rotate.Options
 	writer     io.Writer
 	closers    []io.Closer
 	serializer serializers.Serializer
+
+	// names of the files written by writer, without stdout
+	names []string
+
+	// files with a name template, and their open writers by rendered name
+	// with the most recently used first
+	templates   []*template.Template
+	partitioner *upload.Partitioner
+	templated   map[string]*list.Element
+	recent      *list.List
+
+	// time of Connect, templated files modified since were written by this
+	// output and are appended to when opened again
+	started time.Time
+
+	// metrics of a failed write that were written to some of the files, so
+	// they are skipped for these files when the write is retried
+	written map[writtenMetric]bool
+}
+
+// templatedFile is an open file with a rendered name.
+type templatedFile struct {
+	name   string
+	writer io.WriteCloser
+}
+
+// writtenMetric is a metric written to the file with the name, the files
+// without a template have an empty name.
+type writtenMetric struct {
+	name   string
+	metric telegraf.Metric
 }
 
+const defaultMaxOpenFiles = 64
+
 var sampleConfig = `
-  ## Files to write to, "stdout" is a specially handled file.
+  ## Files to write to, "stdout" is a specially handled file.  Names may be
+  ## templates to write the metrics to separate files, using {{.Name}} for
+  ## the measurement name and {{.Tag "key"}} for tag values, such as
+  ## "/tmp/metrics/{{.Name}}.out".
   files = ["stdout", "/tmp/metrics.out"]
 
+  ## Maximum number of files with a templated name kept open.  When another
+  ## file is opened the least recently written one is closed.
+  # max_open_files = 64
+
   ## Use batch serialization format instead of line based delimiting.  The
   ## batch format allows for the production of non line based output formats and
-  ## may more efficiently encode metric groups.
+  ## may more efficiently encode metric groups.  Formats whose output can't be
+  ## appended to a file, such as parquet, xlsx or csv with a header in batch
+  ## format, are rejected.
   # use_batch_format = false
 
   ## The file will be rotated after the time interval specified.  When set
@@ -46,6 +110,49 @@ var sampleConfig = `
   ## If set to -1, no archives are removed.
   # rotation_max_archives = 5
 
+  ## Rotated archives older than the specified age are deleted.  When set to
+  ## 0 archives are kept regardless of their age.
+  # rotation_max_archive_age = "0d"
+
+  ## Compression of rotated archives, either "gzip" or "zstd".  Compressed
+  ## archives get the ".gz" or ".zst" extension.  By default archives are not
+  ## compressed.
+  # rotation_compression = ""
+
+  ## Commit the files to stable storage after each write, so written metrics
+  ## survive a power loss.
+  # fsync = false
+
+  ## Whether to "append" to existing files or "truncate" them when telegraf
+  ## starts.  Templated files are truncated if last modified before telegraf
+  ## started, so files closed and opened again are appended to.
+  # write_mode = "append"
+
+  ## Permissions, owner and group of the files, as octal permissions and
+  ## user and group names or IDs.  Ownership is not supported on Windows.
+  # file_permissions = "0644"
+  # file_owner = ""
+  # file_group = ""
+
+  ## Write the metrics to partition directories by their time and name, such
+  ## as "dt={{yyyy}}-{{MM}}-{{dd}}/hour={{HH}}" for Hive style partitioning.
+  ## The partition is inserted before the file name.  Available placeholders
+  ## are {{yyyy}}, {{MM}}, {{dd}}, {{HH}}, {{mm}} and {{ss}} for the UTC time
+  ## of the metric and {{measurement}} for its name.
+  # partitioning = ""
+
+  ## After each write, write an empty marker file with this name to the
+  ## directory of each file written to, such as "_SUCCESS".  The marker is
+  ## replaced on every write.
+  # success_marker = ""
+
+  ## After each write, write a JSON manifest listing the files written to
+  ## with the size, SHA-256 checksum and number of metrics of the data
+  ## appended by the write.  The manifest is named by the prefix and the time
+  ## in Unix nanoseconds, such as
+  ## "/tmp/metrics/manifests/telegraf-1600000000000000000.json".
+  # manifest_prefix = ""
+
   ## Data format to output.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -60,28 +167,140 @@ func (f *File) SetSerializer(serializer serializers.Serializer) {
 func (f *File) Connect() error {
 	writers := []io.Writer{}
 
+	if err := f.initOptions(); err != nil {
+		return err
+	}
+	if err := f.Options.Init(); err != nil {
+		return err
+	}
+	partitioner, err := upload.NewPartitioner(f.Partitioning)
+	if err != nil {
+		return err
+	}
+	f.partitioner = partitioner
+	if !serializers.CanJoin(f.serializer, f.UseBatchFormat) {
+		return errors.New("the data format can't be appended to files, write each flush to an object instead, for example with the s3 output")
+	}
+
 	if len(f.Files) == 0 {
 		f.Files = []string{"stdout"}
 	}
 
+	if f.MaxOpenFiles <= 0 {
+		f.MaxOpenFiles = defaultMaxOpenFiles
+	}
+	f.templated = make(map[string]*list.Element)
+	f.recent = list.New()
+	f.started = time.Now()
 	for _, file := range f.Files {
-		if file == "stdout" {
+		switch {
+		case file == "stdout":
 			writers = append(writers, os.Stdout)
-		} else {
-			of, err := rotate.NewFileWriter(
-				file, f.RotationInterval.Duration, f.RotationMaxSize.Size, f.RotationMaxArchives)
+		case strings.Contains(file, "{{") || f.Partitioning != "":
+			tmpl, err := template.New(file).Option("missingkey=zero").Parse(file)
+			if err != nil {
+				return fmt.Errorf("invalid file name template %q: %v", file, err)
+			}
+			f.templates = append(f.templates, tmpl)
+		default:
+			of, err := f.openFile(file)
 			if err != nil {
 				return err
 			}
-
 			writers = append(writers, of)
-			f.closers = append(f.closers, of)
+			f.names = append(f.names, file)
+		}
+	}
+	if len(writers) > 0 {
+		f.writer = io.MultiWriter(writers...)
+	}
+	return nil
+}
+
+// initOptions checks the durability settings and returns them as options of
+// the file writers.
+func (f *File) initOptions() error {
+	f.options = rotate.Options{
+		MaxArchiveAge: f.RotationMaxAge.Duration,
+		Compression:   f.RotationCompression,
+	}
+
+	switch f.WriteMode {
+	case "", "append":
+	case "truncate":
+		f.options.Truncate = true
+	default:
+		return fmt.Errorf("invalid write_mode %q", f.WriteMode)
+	}
+
+	if f.FilePermissions != "" {
+		perm, err := strconv.ParseUint(f.FilePermissions, 8, 32)
+		if err != nil || perm > 0777 || perm == 0 {
+			return fmt.Errorf("invalid file_permissions %q", f.FilePermissions)
 		}
+		f.options.Perm = os.FileMode(perm)
+	}
+
+	if f.FileOwner != "" || f.FileGroup != "" {
+		owner := &rotate.Owner{UID: -1, GID: -1}
+		if f.FileOwner != "" {
+			uid, err := lookupID(f.FileOwner, func(name string) (string, error) {
+				u, err := user.Lookup(name)
+				if err != nil {
+					return "", err
+				}
+				return u.Uid, nil
+			})
+			if err != nil {
+				return fmt.Errorf("invalid file_owner %q: %v", f.FileOwner, err)
+			}
+			owner.UID = uid
+		}
+		if f.FileGroup != "" {
+			gid, err := lookupID(f.FileGroup, func(name string) (string, error) {
+				g, err := user.LookupGroup(name)
+				if err != nil {
+					return "", err
+				}
+				return g.Gid, nil
+			})
+			if err != nil {
+				return fmt.Errorf("invalid file_group %q: %v", f.FileGroup, err)
+			}
+			owner.GID = gid
+		}
+		f.options.Owner = owner
 	}
-	f.writer = io.MultiWriter(writers...)
 	return nil
 }
 
+// lookupID returns the numeric ID of a user or group given by name or ID.
+func lookupID(nameOrID string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		return id, nil
+	}
+	id, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
+
+func (f *File) openFile(file string) (io.Writer, error) {
+	of, err := f.newFileWriter(file, f.options)
+	if err != nil {
+		return nil, err
+	}
+	f.closers = append(f.closers, of)
+	return of, nil
+}
+
+func (f *File) newFileWriter(file string, options rotate.Options) (io.WriteCloser, error) {
+	return rotate.NewFileWriterWithOptions(
+		file, f.RotationInterval.Duration, f.RotationMaxSize.Size, f.RotationMaxArchives,
+		options)
+}
+
 func (f *File) Close() error {
 	var err error
 	for _, c := range f.closers {
@@ -90,6 +309,11 @@ func (f *File) Close() error {
 			err = errClose
 		}
 	}
+	for f.recent != nil && f.recent.Len() > 0 {
+		if errClose := f.closeTemplated(f.recent.Back()); errClose != nil {
+			err = errClose
+		}
+	}
 	return err
 }
 
@@ -101,8 +325,127 @@ func (f *File) Description() string {
 	return "Send telegraf metrics to file(s)"
 }
 
+// Write writes the metrics to every file.  When writing some of the files
+// fails, the metrics written to the others are remembered and skipped for
+// them when the agent retries the write, so they are not duplicated.
 func (f *File) Write(metrics []telegraf.Metric) error {
+	var writeErr error
+	var files []manifest.File
+	written := make(map[writtenMetric]bool)
+	if f.writer != nil {
+		digest := manifest.NewDigest()
+		group := f.unwritten("", metrics)
+		var records int
+		var err error
+		if len(group) > 0 {
+			records, err = f.write(io.MultiWriter(f.writer, digest), group)
+		}
+		if err != nil {
+			writeErr = err
+		} else {
+			markWritten(written, "", group)
+		}
+		for _, name := range f.names {
+			files = append(files, digest.File(filepath.ToSlash(name), records))
+		}
+	}
+
+	if len(f.templates) > 0 {
+		names, groups, err := f.group(metrics)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			group := f.unwritten(name, groups[name])
+			if len(group) == 0 {
+				continue
+			}
+			w, err := f.templatedWriter(name)
+			if err != nil {
+				writeErr = err
+				continue
+			}
+			digest := manifest.NewDigest()
+			records, err := f.write(io.MultiWriter(w, digest), group)
+			if err != nil {
+				writeErr = err
+			} else {
+				markWritten(written, name, group)
+			}
+			files = append(files, digest.File(filepath.ToSlash(name), records))
+		}
+		if f.Partitioning != "" {
+			f.closeUnused(groups)
+		}
+	}
+
+	if f.Fsync {
+		if err := f.sync(); err != nil {
+			writeErr = err
+		}
+	}
+
+	if writeErr != nil {
+		for m := range f.written {
+			written[m] = true
+		}
+		f.written = written
+		return writeErr
+	}
+	f.written = nil
+
+	if f.Options.Enabled() {
+		if err := f.Options.Complete(time.Now(), files, manifest.WriteFile); err != nil {
+			f.Log.Errorf("Error completing write: %v", err)
+		}
+	}
+	return nil
+}
+
+// unwritten returns the metrics not written to the file by a failed write
+// before.
+func (f *File) unwritten(name string, metrics []telegraf.Metric) []telegraf.Metric {
+	if len(f.written) == 0 {
+		return metrics
+	}
+	var group []telegraf.Metric
+	for _, m := range metrics {
+		if !f.written[writtenMetric{name: name, metric: m}] {
+			group = append(group, m)
+		}
+	}
+	return group
+}
+
+func markWritten(written map[writtenMetric]bool, name string, metrics []telegraf.Metric) {
+	for _, m := range metrics {
+		written[writtenMetric{name: name, metric: m}] = true
+	}
+}
+
+// sync commits all files to stable storage.
+func (f *File) sync() error {
+	var syncErr error
+	closers := f.closers
+	if f.recent != nil {
+		for e := f.recent.Front(); e != nil; e = e.Next() {
+			closers = append(closers, e.Value.(*templatedFile).writer)
+		}
+	}
+	for _, c := range closers {
+		if s, ok := c.(interface{ Sync() error }); ok {
+			if err := s.Sync(); err != nil {
+				syncErr = fmt.Errorf("syncing file: %v", err)
+			}
+		}
+	}
+	return syncErr
+}
+
+// write writes the metrics and returns the number of metrics written.
+func (f *File) write(w io.Writer, metrics []telegraf.Metric) (int, error) {
 	var writeErr error = nil
+	var records int
 
 	if f.UseBatchFormat {
 		octets, err := f.serializer.SerializeBatch(metrics)
@@ -110,9 +453,11 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 			f.Log.Errorf("Could not serialize metric: %v", err)
 		}
 
-		_, err = f.writer.Write(octets)
+		_, err = w.Write(octets)
 		if err != nil {
 			f.Log.Errorf("Error writing to file: %v", err)
+		} else if len(octets) > 0 {
+			records = len(metrics)
 		}
 	} else {
 		for _, metric := range metrics {
@@ -121,14 +466,119 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 				f.Log.Debugf("Could not serialize metric: %v", err)
 			}
 
-			_, err = f.writer.Write(b)
+			_, err = w.Write(b)
 			if err != nil {
 				writeErr = fmt.Errorf("E! [outputs.file] failed to write message: %v", err)
+			} else if len(b) > 0 {
+				records++
+			}
+		}
+	}
+
+	return records, writeErr
+}
+
+// group renders the templated file names of the metrics and returns the
+// metrics of each name, with the names in the order first seen.
+func (f *File) group(metrics []telegraf.Metric) ([]string, map[string][]telegraf.Metric, error) {
+	var names []string
+	groups := make(map[string][]telegraf.Metric)
+	var buf strings.Builder
+	for _, metric := range metrics {
+		for _, tmpl := range f.templates {
+			buf.Reset()
+			if err := tmpl.Execute(&buf, &fileMetric{metric: metric}); err != nil {
+				return nil, nil, fmt.Errorf("rendering file name %q: %v", tmpl.Name(), err)
+			}
+			name := buf.String()
+			if partition := f.partitioner.Partition(metric); partition != "" {
+				name = filepath.Join(filepath.Dir(name), filepath.FromSlash(partition), filepath.Base(name))
+			}
+			if _, ok := groups[name]; !ok {
+				names = append(names, name)
+			}
+			groups[name] = append(groups[name], metric)
+		}
+	}
+	return names, groups, nil
+}
+
+// templatedWriter returns the writer of the file, which is opened and its
+// directory created on first use.  Opening a file closes the least recently
+// used one when max_open_files are open.  A file modified since Connect was
+// written before and is appended to, even with write_mode "truncate".
+func (f *File) templatedWriter(name string) (io.Writer, error) {
+	if e, ok := f.templated[name]; ok {
+		f.recent.MoveToFront(e)
+		return e.Value.(*templatedFile).writer, nil
+	}
+	for f.recent.Len() >= f.MaxOpenFiles {
+		if err := f.closeTemplated(f.recent.Back()); err != nil {
+			f.Log.Errorf("Error closing file: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return nil, err
+	}
+	options := f.options
+	if options.Truncate {
+		if info, err := os.Stat(name); err == nil && !info.ModTime().Before(f.started) {
+			options.Truncate = false
+		}
+	}
+	w, err := f.newFileWriter(name, options)
+	if err != nil {
+		return nil, err
+	}
+	f.templated[name] = f.recent.PushFront(&templatedFile{name: name, writer: w})
+	return w, nil
+}
+
+// closeUnused closes the templated files not written to by the last write,
+// such as the files of partitions that have rolled over.
+func (f *File) closeUnused(groups map[string][]telegraf.Metric) {
+	for e := f.recent.Front(); e != nil; {
+		next := e.Next()
+		if _, ok := groups[e.Value.(*templatedFile).name]; !ok {
+			if err := f.closeTemplated(e); err != nil {
+				f.Log.Errorf("Error closing file: %v", err)
 			}
 		}
+		e = next
 	}
+}
+
+// closeTemplated closes the file of a templated writer and forgets it.
+func (f *File) closeTemplated(e *list.Element) error {
+	file := f.recent.Remove(e).(*templatedFile)
+	delete(f.templated, file.name)
+	return file.writer.Close()
+}
 
-	return writeErr
+// fileMetric is the metric passed to the file name templates.  Path
+// separators in the values are replaced, so a metric can't write outside of
+// the directory of the template.
+type fileMetric struct {
+	metric telegraf.Metric
+}
+
+var pathReplacer = strings.NewReplacer("/", "_", "\\", "_")
+
+func (m *fileMetric) Name() string {
+	return sanitize(m.metric.Name())
+}
+
+func (m *fileMetric) Tag(key string) string {
+	value, _ := m.metric.GetTag(key)
+	return sanitize(value)
+}
+
+func sanitize(value string) string {
+	value = pathReplacer.Replace(value)
+	if value == "." || value == ".." {
+		return "_"
+	}
+	return value
 }
 
 func init() {
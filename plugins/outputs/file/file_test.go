@@ -2,14 +2,23 @@ package file
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/manifest"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -180,6 +189,318 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileRotationCompression(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:               []string{filepath.Join(dir, "metrics.out")},
+		RotationMaxSize:     internal.Size{Size: 10},
+		RotationMaxArchives: -1,
+		RotationCompression: "gzip",
+		serializer:          s,
+	}
+	require.NoError(t, f.Connect())
+	require.NoError(t, f.Write(testutil.MockMetrics()))
+
+	// the archive is compressed in the background
+	require.Eventually(t, func() bool {
+		archives, err := filepath.Glob(filepath.Join(dir, "metrics.*.out.gz"))
+		if err != nil || len(archives) != 1 {
+			return false
+		}
+		archive, err := os.Open(archives[0])
+		if err != nil {
+			return false
+		}
+		defer archive.Close()
+		r, err := gzip.NewReader(archive)
+		if err != nil {
+			return false
+		}
+		buf, err := ioutil.ReadAll(r)
+		return err == nil && string(buf) == expNewFile
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, f.Close())
+}
+
+func TestFileNameTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:      []string{filepath.Join(dir, `{{.Name}}/{{.Tag "host"}}.out`)},
+		serializer: s,
+	}
+	require.NoError(t, f.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{"host": "a"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 4}, time.Unix(1, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "../b"}, map[string]interface{}{"value": 5}, time.Unix(0, 0)),
+	}
+	require.NoError(t, f.Write(metrics))
+	require.NoError(t, f.Close())
+
+	validateFile(filepath.Join(dir, "cpu", "a.out"),
+		"cpu,host=a value=1i 0\ncpu,host=a value=4i 1000000000\n", t)
+	validateFile(filepath.Join(dir, "mem", "a.out"), "mem,host=a value=2i 0\n", t)
+	validateFile(filepath.Join(dir, "cpu", "b.out"), "cpu,host=b value=3i 0\n", t)
+	validateFile(filepath.Join(dir, "cpu", ".._b.out"), "cpu,host=../b value=5i 0\n", t)
+}
+
+func TestFileMaxOpenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:        []string{filepath.Join(dir, "{{.Name}}.out")},
+		WriteMode:    "truncate",
+		MaxOpenFiles: 2,
+		Log:          testutil.Logger{},
+		serializer:   s,
+	}
+
+	// files from before telegraf started are truncated once
+	old := filepath.Join(dir, "cpu.out")
+	require.NoError(t, ioutil.WriteFile(old, []byte("old\n"), 0644))
+	require.NoError(t, os.Chtimes(old, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)))
+	require.NoError(t, f.Connect())
+
+	for i, name := range []string{"cpu", "mem", "disk", "cpu"} {
+		m := testutil.MustMetric(name, map[string]string{}, map[string]interface{}{"value": i}, time.Unix(0, 0))
+		require.NoError(t, f.Write([]telegraf.Metric{m}))
+		require.LessOrEqual(t, len(f.templated), 2)
+	}
+	require.Contains(t, f.templated, filepath.Join(dir, "cpu.out"))
+	require.NotContains(t, f.templated, filepath.Join(dir, "mem.out"))
+	require.NoError(t, f.Close())
+	require.Empty(t, f.templated)
+
+	validateFile(filepath.Join(dir, "cpu.out"), "cpu value=0i 0\ncpu value=3i 0\n", t)
+	validateFile(filepath.Join(dir, "mem.out"), "mem value=1i 0\n", t)
+	validateFile(filepath.Join(dir, "disk.out"), "disk value=2i 0\n", t)
+}
+
+func TestFileRetryWritesFailedFilesOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:      []string{filepath.Join(dir, "{{.Name}}", "metrics.out")},
+		Log:        testutil.Logger{},
+		serializer: s,
+	}
+	require.NoError(t, f.Connect())
+
+	// the directory of the mem file can't be created
+	blocked := filepath.Join(dir, "mem")
+	require.NoError(t, ioutil.WriteFile(blocked, nil, 0644))
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+	}
+	require.Error(t, f.Write(metrics))
+
+	// the retry only writes the file that failed
+	require.NoError(t, os.Remove(blocked))
+	require.NoError(t, f.Write(metrics))
+	require.Empty(t, f.written)
+	require.NoError(t, f.Close())
+
+	validateFile(filepath.Join(dir, "cpu", "metrics.out"), "cpu value=1i 0\n", t)
+	validateFile(filepath.Join(dir, "mem", "metrics.out"), "mem value=2i 0\n", t)
+}
+
+func TestFileNameTemplateInvalid(t *testing.T) {
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:      []string{"/tmp/{{.Name}"},
+		serializer: s,
+	}
+	require.Error(t, f.Connect())
+}
+
+func TestFileNotJoinable(t *testing.T) {
+	s, err := serializers.NewCSVSerializer(&serializers.Config{CSVHeader: true})
+	require.NoError(t, err)
+	f := File{
+		Files:          []string{"stdout"},
+		UseBatchFormat: true,
+		serializer:     s,
+	}
+	require.Error(t, f.Connect())
+
+	f.UseBatchFormat = false
+	require.NoError(t, f.Connect())
+}
+
+func TestFileTruncateSync(t *testing.T) {
+	fh := createFile()
+	defer os.Remove(fh.Name())
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:      []string{fh.Name()},
+		WriteMode:  "truncate",
+		Fsync:      true,
+		serializer: s,
+	}
+
+	require.NoError(t, f.Connect())
+	require.NoError(t, f.Write(testutil.MockMetrics()))
+	validateFile(fh.Name(), expNewFile, t)
+	require.NoError(t, f.Close())
+}
+
+func TestFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on Windows, permissions are not supported")
+	}
+
+	fh := tmpFile()
+	defer os.Remove(fh)
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:           []string{fh},
+		FilePermissions: "0600",
+		FileOwner:       strconv.Itoa(os.Getuid()),
+		FileGroup:       strconv.Itoa(os.Getgid()),
+		serializer:      s,
+	}
+
+	require.NoError(t, f.Connect())
+	require.NoError(t, f.Write(testutil.MockMetrics()))
+	require.NoError(t, f.Close())
+
+	info, err := os.Stat(fh)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestFileInvalidDurability(t *testing.T) {
+	for _, f := range []File{
+		{WriteMode: "overwrite"},
+		{FilePermissions: "rw-r--r--"},
+		{FilePermissions: "01777"},
+		{FileOwner: "no-such-user-telegraf"},
+		{FileGroup: "no-such-group-telegraf"},
+	} {
+		f.Files = []string{"stdout"}
+		require.Error(t, f.Connect())
+	}
+}
+
+func TestFilePartitioning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:        []string{filepath.Join(dir, "metrics.out")},
+		Partitioning: "dt={{yyyy}}-{{MM}}-{{dd}}/hour={{HH}}",
+		serializer:   s,
+	}
+	require.NoError(t, f.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(1600003600, 0)),
+	}
+	require.NoError(t, f.Write(metrics))
+	require.NoError(t, f.Close())
+
+	validateFile(filepath.Join(dir, "dt=2020-09-13", "hour=12", "metrics.out"), "cpu value=1i 1600000000000000000\n", t)
+	validateFile(filepath.Join(dir, "dt=2020-09-13", "hour=13", "metrics.out"), "cpu value=2i 1600003600000000000\n", t)
+}
+
+func TestFilePartitioningClosesRolledOver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:        []string{filepath.Join(dir, "metrics.out")},
+		Partitioning: "hour={{HH}}",
+		Log:          testutil.Logger{},
+		serializer:   s,
+	}
+	require.NoError(t, f.Connect())
+
+	first := filepath.Join(dir, "hour=12", "metrics.out")
+	second := filepath.Join(dir, "hour=13", "metrics.out")
+	require.NoError(t, f.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(1600000000, 0)),
+	}))
+	require.Contains(t, f.templated, first)
+
+	require.NoError(t, f.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(1600003600, 0)),
+	}))
+	require.NotContains(t, f.templated, first)
+	require.Contains(t, f.templated, second)
+
+	require.NoError(t, f.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 3}, time.Unix(1600000001, 0)),
+	}))
+	require.NoError(t, f.Close())
+
+	validateFile(first, "cpu value=1i 1600000000000000000\ncpu value=3i 1600000001000000000\n", t)
+	validateFile(second, "cpu value=2i 1600003600000000000\n", t)
+}
+
+func TestFileManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:        []string{filepath.Join(dir, "metrics.out")},
+		Partitioning: "dt={{yyyy}}-{{MM}}-{{dd}}/hour={{HH}}",
+		serializer:   s,
+		Log:          testutil.Logger{},
+	}
+	f.SuccessMarker = "_SUCCESS"
+	f.ManifestPrefix = filepath.Join(dir, "manifests", "telegraf-")
+	require.NoError(t, f.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(1600003600, 0)),
+	}
+	require.NoError(t, f.Write(metrics))
+	require.NoError(t, f.Close())
+
+	require.FileExists(t, filepath.Join(dir, "dt=2020-09-13", "hour=12", "_SUCCESS"))
+	require.FileExists(t, filepath.Join(dir, "dt=2020-09-13", "hour=13", "_SUCCESS"))
+
+	manifests, err := filepath.Glob(filepath.Join(dir, "manifests", "telegraf-*.json"))
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	buf, err := ioutil.ReadFile(manifests[0])
+	require.NoError(t, err)
+	var m manifest.Manifest
+	require.NoError(t, json.Unmarshal(buf, &m))
+
+	name := filepath.ToSlash(filepath.Join(dir, "dt=2020-09-13", "hour=12", "metrics.out"))
+	require.Len(t, m.Files, 2)
+	require.Equal(t, manifest.NewFile(name, []byte("cpu value=1i 1600000000000000000\n"), 1), m.Files[0])
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
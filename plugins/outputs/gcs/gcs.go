@@ -0,0 +1,199 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/upload"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"google.golang.org/api/option"
+)
+
+const defaultChunkSize = 16 * 1024 * 1024
+
+var sampleConfig = `
+  ## Bucket to upload the objects to, it must exist prior to starting telegraf.
+  bucket = "metrics"
+
+  ## Filepath for GCP credentials JSON file to authorize calls to the Cloud
+  ## Storage API.  If not set explicitly, Telegraf will attempt to use
+  ## Application Default Credentials, which is preferred.
+  # credentials_file = "path/to/my/creds.json"
+
+  ## Template of the object names.  Available placeholders are {{host}},
+  ## {{yyyy}}, {{MM}}, {{dd}}, {{HH}}, {{mm}} and {{ss}} for the UTC time the
+  ## object was started, {{timestamp}} for the same time in Unix seconds and
+  ## {{counter}} for the number of objects uploaded since telegraf started and
+  ## {{instance}} for a random id chosen when telegraf starts.
+  # object_name = "{{host}}/{{yyyy}}/{{MM}}/{{dd}}/{{HH}}-{{instance}}-{{counter}}"
+
+  ## Compression of the objects, either "gzip" or "" for none.  The
+  ## extension is not added to the object name and no Content-Encoding is
+  ## set, the objects are stored compressed.
+  # compression = ""
+
+  ## Content type of the objects.
+  # content_type = ""
+
+  ## Partitions of the objects by the time and name of the metrics, such as
+  ## "dt={{yyyy}}-{{MM}}-{{dd}}/hour={{HH}}" for Hive style partitioning.
+  ## The partition is prepended to the object name.  Available placeholders
+  ## are {{yyyy}}, {{MM}}, {{dd}}, {{HH}}, {{mm}} and {{ss}} for the UTC time
+  ## of the metric and {{measurement}} for its name.
+  # partitioning = ""
+
+  ## Metrics are accumulated and uploaded once the serialized data reaches
+  ## flush_size or the first data is older than flush_interval.  The
+  ## interval is checked on each write, so it is only as precise as the
+  ## agent flush_interval.
+  # flush_size = "16MiB"
+  # flush_interval = "5m"
+
+  ## Objects are sent with resumable uploads in chunks of this size, smaller
+  ## objects are sent in a single request.
+  # chunk_size = "16MiB"
+
+  ## Cloud KMS key encrypting the objects, instead of the default key of the
+  ## bucket, in the form
+  ## "projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>".
+  # kms_key_name = ""
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+// bucket abstracts the Cloud Storage operations used by the plugin.
+type bucket interface {
+	Upload(ctx context.Context, name string, data []byte) error
+}
+
+type GCS struct {
+	Bucket          string          `toml:"bucket"`
+	CredentialsFile string          `toml:"credentials_file"`
+	ContentType     string          `toml:"content_type"`
+	ChunkSize       internal.Size   `toml:"chunk_size"`
+	KMSKeyName      string          `toml:"kms_key_name"`
+	Log             telegraf.Logger `toml:"-"`
+	upload.Config
+
+	bucket      bucket
+	serializer  serializers.Serializer
+	batcher     *upload.Batcher
+	partitioner *upload.Partitioner
+}
+
+func (g *GCS) SetSerializer(serializer serializers.Serializer) {
+	g.serializer = serializer
+}
+
+func (g *GCS) Init() error {
+	if g.Bucket == "" {
+		return errors.New("bucket must be set")
+	}
+	if g.ChunkSize.Size == 0 {
+		g.ChunkSize.Size = defaultChunkSize
+	}
+
+	var err error
+	g.batcher, err = g.Config.NewBatcher(g.upload)
+	if err != nil {
+		return err
+	}
+	if !serializers.CanJoin(g.serializer, true) {
+		g.batcher.SerializeObjects(g.serializer)
+	}
+	g.partitioner, err = upload.NewPartitioner(g.Partitioning)
+	return err
+}
+
+func (g *GCS) Connect() error {
+	if g.bucket != nil {
+		return nil
+	}
+
+	options := []option.ClientOption{
+		option.WithScopes(storage.ScopeReadWrite),
+		option.WithUserAgent(internal.ProductToken()),
+	}
+	if g.CredentialsFile != "" {
+		options = append(options, option.WithCredentialsFile(g.CredentialsFile))
+	}
+
+	client, err := storage.NewClient(context.Background(), options...)
+	if err != nil {
+		return fmt.Errorf("unable to create Cloud Storage client: %v", err)
+	}
+	g.bucket = &gcsBucket{
+		handle:      client.Bucket(g.Bucket),
+		contentType: g.ContentType,
+		chunkSize:   int(g.ChunkSize.Size),
+		kmsKeyName:  g.KMSKeyName,
+	}
+	return nil
+}
+
+// Close uploads the metrics accumulated so far.
+func (g *GCS) Close() error {
+	return g.batcher.Flush()
+}
+
+func (g *GCS) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GCS) Description() string {
+	return "Upload metrics as objects to a Google Cloud Storage bucket"
+}
+
+// Write adds the metrics to the current object, which is uploaded when full
+// or old enough.
+func (g *GCS) Write(metrics []telegraf.Metric) error {
+	partitions, err := g.partitioner.Serialize(g.serializer, metrics)
+	if err != nil {
+		return err
+	}
+	return g.batcher.AddPartitions(partitions)
+}
+
+func (g *GCS) upload(name string, data []byte) error {
+	if err := g.bucket.Upload(context.Background(), name, data); err != nil {
+		return err
+	}
+	g.Log.Debugf("Uploaded %d bytes to %q", len(data), name)
+	return nil
+}
+
+type gcsBucket struct {
+	handle      *storage.BucketHandle
+	contentType string
+	chunkSize   int
+	kmsKeyName  string
+}
+
+func (b *gcsBucket) Upload(ctx context.Context, name string, data []byte) error {
+	w := b.handle.Object(name).NewWriter(ctx)
+	w.ChunkSize = b.chunkSize
+	w.ContentType = b.contentType
+	w.KMSKeyName = b.kmsKeyName
+
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	// The object is only created once the writer is closed.
+	return w.Close()
+}
+
+func init() {
+	outputs.Add("gcs", func() telegraf.Output {
+		return &GCS{}
+	})
+}
@@ -0,0 +1,76 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/upload"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockBucket struct {
+	objects map[string]string
+	err     error
+}
+
+func (b *mockBucket) Upload(_ context.Context, name string, data []byte) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.objects[name] = string(data)
+	return nil
+}
+
+const line = "test1,tag1=value1 value=1 1257894000000000000\n"
+
+func newGCS(t *testing.T) (*GCS, *mockBucket) {
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	b := &mockBucket{objects: make(map[string]string)}
+	g := &GCS{
+		Bucket: "metrics",
+		Log:    testutil.Logger{},
+		Config: upload.Config{
+			ObjectName: "metrics-{{counter}}.influx",
+			FlushSize:  internal.Size{Size: int64(2 * len(line))},
+		},
+		bucket: b,
+	}
+	g.SetSerializer(serializer)
+	require.NoError(t, g.Init())
+	require.NoError(t, g.Connect())
+	return g, b
+}
+
+func TestWrite(t *testing.T) {
+	g, b := newGCS(t)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, g.Write(testutil.MockMetrics()))
+	}
+	require.NoError(t, g.Close())
+
+	require.Equal(t, map[string]string{
+		"metrics-0.influx": line + line,
+		"metrics-1.influx": line,
+	}, b.objects)
+}
+
+func TestUploadError(t *testing.T) {
+	g, b := newGCS(t)
+	b.err = errors.New("unavailable")
+
+	require.NoError(t, g.Write(testutil.MockMetrics()))
+	require.Error(t, g.Write(testutil.MockMetrics()))
+	require.Empty(t, b.objects)
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&GCS{}).Init())
+	require.Error(t, (&GCS{Bucket: "metrics", Config: upload.Config{Compression: "lz4"}}).Init())
+}
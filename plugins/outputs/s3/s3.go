@@ -0,0 +1,254 @@
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"github.com/influxdata/telegraf"
+	internalaws "github.com/influxdata/telegraf/config/aws"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/manifest"
+	"github.com/influxdata/telegraf/plugins/common/upload"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+type S3 struct {
+	Region      string `toml:"region"`
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	Token       string `toml:"token"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	Bucket            string          `toml:"bucket"`
+	ForcePathStyle    bool            `toml:"force_path_style"`
+	ContentType       string          `toml:"content_type"`
+	PartSize          internal.Size   `toml:"part_size"`
+	UploadConcurrency int             `toml:"upload_concurrency"`
+	Log               telegraf.Logger `toml:"-"`
+	upload.Config
+	manifest.Options
+
+	serializer  serializers.Serializer
+	uploader    s3manageriface.UploaderAPI
+	batcher     *upload.Batcher
+	partitioner *upload.Partitioner
+}
+
+var sampleConfig = `
+  ## Amazon REGION of the bucket.
+  region = "us-east-1"
+
+  ## Amazon Credentials
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  #access_key = ""
+  #secret_key = ""
+  #token = ""
+  #role_arn = ""
+  #profile = ""
+  #shared_credential_file = ""
+
+  ## Endpoint to make request against, the correct endpoint is automatically
+  ## determined and this option should only be set if you wish to override the
+  ## default.
+  ##   ex: endpoint_url = "http://localhost:9000"
+  # endpoint_url = ""
+
+  ## Use path style addressing of the bucket, required by some S3 compatible
+  ## services.
+  # force_path_style = false
+
+  ## Bucket to upload the objects to, it must exist prior to starting telegraf.
+  bucket = "metrics"
+
+  ## Template of the object names.  Available placeholders are {{host}},
+  ## {{yyyy}}, {{MM}}, {{dd}}, {{HH}}, {{mm}} and {{ss}} for the UTC time the
+  ## object was started, {{timestamp}} for the same time in Unix seconds and
+  ## {{counter}} for the number of objects uploaded since telegraf started and
+  ## {{instance}} for a random id chosen when telegraf starts.
+  # object_name = "{{host}}/{{yyyy}}/{{MM}}/{{dd}}/{{HH}}-{{instance}}-{{counter}}"
+
+  ## Compression of the objects, either "gzip" or "" for none.  The
+  ## extension is not added to the object name and no Content-Encoding is
+  ## set, the objects are stored compressed.
+  # compression = ""
+
+  ## Content type of the objects.
+  # content_type = ""
+
+  ## Partitions of the objects by the time and name of the metrics, such as
+  ## "dt={{yyyy}}-{{MM}}-{{dd}}/hour={{HH}}" for Hive style partitioning.
+  ## The partition is prepended to the object name.  Available placeholders
+  ## are {{yyyy}}, {{MM}}, {{dd}}, {{HH}}, {{mm}} and {{ss}} for the UTC time
+  ## of the metric and {{measurement}} for its name.
+  # partitioning = ""
+
+  ## Metrics are accumulated and uploaded once the serialized data reaches
+  ## flush_size or the first data is older than flush_interval.  The
+  ## interval is checked on each write, so it is only as precise as the
+  ## agent flush_interval.
+  # flush_size = "16MiB"
+  # flush_interval = "5m"
+
+  ## After each write that uploaded objects, upload an empty marker object
+  ## with this name next to each uploaded object, such as "_SUCCESS".
+  # success_marker = ""
+
+  ## After each write that uploaded objects, upload a JSON manifest listing
+  ## their names, sizes, SHA-256 checksums and number of metrics.  The
+  ## manifest is named by the prefix and the time in Unix nanoseconds, such
+  ## as "manifests/telegraf-1600000000000000000.json".
+  # manifest_prefix = ""
+
+  ## Size of the parts and number of parts uploaded in parallel for
+  ## multipart uploads, used for objects larger than the part size.
+  # part_size = "5MiB"
+  # upload_concurrency = 5
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+func (s *S3) SetSerializer(serializer serializers.Serializer) {
+	s.serializer = serializer
+}
+
+func (s *S3) Init() error {
+	if s.Bucket == "" {
+		return fmt.Errorf("bucket must be set")
+	}
+	if s.PartSize.Size == 0 {
+		s.PartSize.Size = s3manager.DefaultUploadPartSize
+	}
+	if s.PartSize.Size < s3manager.MinUploadPartSize {
+		return fmt.Errorf("part_size must be at least %d bytes", s3manager.MinUploadPartSize)
+	}
+	if s.UploadConcurrency == 0 {
+		s.UploadConcurrency = s3manager.DefaultUploadConcurrency
+	}
+
+	if err := s.Options.Init(); err != nil {
+		return err
+	}
+
+	var err error
+	s.batcher, err = s.Config.NewBatcher(s.upload)
+	if err != nil {
+		return err
+	}
+	if s.Options.Enabled() {
+		s.batcher.RecordUploads()
+	}
+	if !serializers.CanJoin(s.serializer, true) {
+		s.batcher.SerializeObjects(s.serializer)
+	}
+	s.partitioner, err = upload.NewPartitioner(s.Partitioning)
+	return err
+}
+
+func (s *S3) Connect() error {
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      s.Region,
+		AccessKey:   s.AccessKey,
+		SecretKey:   s.SecretKey,
+		RoleARN:     s.RoleARN,
+		Profile:     s.Profile,
+		Filename:    s.Filename,
+		Token:       s.Token,
+		EndpointURL: s.EndpointURL,
+	}
+	svc := s3.New(credentialConfig.Credentials(), &aws.Config{
+		S3ForcePathStyle: aws.Bool(s.ForcePathStyle),
+	})
+	s.uploader = s3manager.NewUploaderWithClient(svc, func(u *s3manager.Uploader) {
+		u.PartSize = s.PartSize.Size
+		u.Concurrency = s.UploadConcurrency
+	})
+	return nil
+}
+
+// Close uploads the metrics accumulated so far.
+func (s *S3) Close() error {
+	err := s.batcher.Flush()
+	s.complete()
+	return err
+}
+
+func (s *S3) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *S3) Description() string {
+	return "Upload metrics as objects to an Amazon S3 bucket"
+}
+
+// Write adds the metrics to the current object, which is uploaded when full
+// or old enough.
+func (s *S3) Write(metrics []telegraf.Metric) error {
+	partitions, err := s.partitioner.Serialize(s.serializer, metrics)
+	if err != nil {
+		return err
+	}
+	err = s.batcher.AddPartitions(partitions)
+	s.complete()
+	return err
+}
+
+// complete uploads the manifest and success markers of the objects uploaded
+// so far.  Failures are only logged, as returning them would upload the
+// metrics of the objects again.
+func (s *S3) complete() {
+	if !s.Options.Enabled() {
+		return
+	}
+	err := s.Options.Complete(time.Now(), s.batcher.Uploaded(), func(name string, data []byte) error {
+		return s.put(name, data, "")
+	})
+	if err != nil {
+		s.Log.Errorf("Error completing upload: %v", err)
+	}
+}
+
+func (s *S3) upload(name string, data []byte) error {
+	return s.put(name, data, s.ContentType)
+}
+
+func (s *S3) put(name string, data []byte, contentType string) error {
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(name),
+		Body:   bytes.NewReader(data),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	if _, err := s.uploader.Upload(input); err != nil {
+		return err
+	}
+	s.Log.Debugf("Uploaded %d bytes to %q", len(data), name)
+	return nil
+}
+
+func init() {
+	outputs.Add("s3", func() telegraf.Output {
+		return &S3{}
+	})
+}
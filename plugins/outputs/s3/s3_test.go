@@ -0,0 +1,137 @@
+package s3
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/manifest"
+	"github.com/influxdata/telegraf/plugins/common/upload"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type object struct {
+	key         string
+	contentType string
+	body        string
+}
+
+type mockUploader struct {
+	objects []object
+	err     error
+}
+
+func (u *mockUploader) Upload(input *s3manager.UploadInput, _ ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	return u.UploadWithContext(aws.BackgroundContext(), input)
+}
+
+func (u *mockUploader) UploadWithContext(_ aws.Context, input *s3manager.UploadInput, _ ...func(*s3manager.Uploader)) (*s3manager.UploadOutput, error) {
+	if u.err != nil {
+		return nil, u.err
+	}
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	o := object{key: *input.Key, body: string(body)}
+	if input.ContentType != nil {
+		o.contentType = *input.ContentType
+	}
+	u.objects = append(u.objects, o)
+	return &s3manager.UploadOutput{}, nil
+}
+
+const line = "test1,tag1=value1 value=1 1257894000000000000\n"
+
+func newS3(t *testing.T) (*S3, *mockUploader) {
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	s := &S3{
+		Bucket:      "metrics",
+		ContentType: "text/plain",
+		Log:         testutil.Logger{},
+		Config: upload.Config{
+			ObjectName: "metrics-{{counter}}.influx",
+			FlushSize:  internal.Size{Size: int64(2 * len(line))},
+		},
+	}
+	s.SetSerializer(serializer)
+	require.NoError(t, s.Init())
+
+	uploader := &mockUploader{}
+	s.uploader = uploader
+	return s, uploader
+}
+
+func TestWrite(t *testing.T) {
+	s, uploader := newS3(t)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Write(testutil.MockMetrics()))
+	}
+	require.NoError(t, s.Close())
+
+	require.Equal(t, []object{
+		{key: "metrics-0.influx", contentType: "text/plain", body: line + line},
+		{key: "metrics-1.influx", contentType: "text/plain", body: line},
+	}, uploader.objects)
+}
+
+func TestPartitioning(t *testing.T) {
+	s, uploader := newS3(t)
+	s.partitioner, _ = upload.NewPartitioner("dt={{yyyy}}-{{MM}}-{{dd}}")
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, s.Write(testutil.MockMetrics()))
+	}
+	require.Equal(t, []object{
+		{key: "dt=2009-11-10/metrics-0.influx", contentType: "text/plain", body: line + line},
+	}, uploader.objects)
+}
+
+func TestManifest(t *testing.T) {
+	s, uploader := newS3(t)
+	s.Options = manifest.Options{SuccessMarker: "_SUCCESS", ManifestPrefix: "manifests/telegraf-"}
+	s.Partitioning = "dt={{yyyy}}-{{MM}}-{{dd}}"
+	require.NoError(t, s.Init())
+
+	// nothing is marked before an object is uploaded
+	require.NoError(t, s.Write(testutil.MockMetrics()))
+	require.Empty(t, uploader.objects)
+
+	require.NoError(t, s.Write(testutil.MockMetrics()))
+	require.Len(t, uploader.objects, 3)
+	require.Equal(t, "dt=2009-11-10/metrics-0.influx", uploader.objects[0].key)
+	require.True(t, strings.HasPrefix(uploader.objects[1].key, "manifests/telegraf-"))
+	require.Equal(t, object{key: "dt=2009-11-10/_SUCCESS"}, uploader.objects[2])
+
+	var m manifest.Manifest
+	require.NoError(t, json.Unmarshal([]byte(uploader.objects[1].body), &m))
+	require.Equal(t, []manifest.File{
+		manifest.NewFile("dt=2009-11-10/metrics-0.influx", []byte(line+line), 2),
+	}, m.Files)
+}
+
+func TestUploadError(t *testing.T) {
+	s, uploader := newS3(t)
+	uploader.err = errors.New("unavailable")
+
+	require.NoError(t, s.Write(testutil.MockMetrics()))
+	require.Error(t, s.Write(testutil.MockMetrics()))
+	require.Empty(t, uploader.objects)
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&S3{}).Init())
+	require.Error(t, (&S3{Bucket: "metrics", PartSize: internal.Size{Size: 1024}}).Init())
+	require.Error(t, (&S3{Bucket: "metrics", Config: upload.Config{ObjectName: "{{month}}"}}).Init())
+	require.Error(t, (&S3{Bucket: "metrics", Options: manifest.Options{SuccessMarker: "a/b"}}).Init())
+}
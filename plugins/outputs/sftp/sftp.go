@@ -0,0 +1,305 @@
+package sftp
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	defaultPort           = "22"
+	defaultTimeout        = 30 * time.Second
+	defaultFilenamePrefix = "metrics"
+	defaultFileExtension  = ".out"
+)
+
+var sampleConfig = `
+  ## Address of the SFTP server; the port defaults to 22.
+  address = "sftp.example.com:22"
+
+  ## Login credentials.  Set 'password', 'private_key' or both.
+  username = "telegraf"
+  # password = ""
+  # private_key = "/etc/telegraf/id_ed25519"
+  # private_key_passphrase = ""
+
+  ## File in OpenSSH known_hosts format used to verify the server host key.
+  ## Setting 'insecure_ignore_host_key' skips verification instead.
+  known_hosts = "/etc/telegraf/known_hosts"
+  # insecure_ignore_host_key = false
+
+  ## Timeout for establishing the connection.
+  # timeout = "30s"
+
+  ## Remote directory to write the files to, it must exist.
+  directory = "/upload"
+
+  ## Prefix and extension of the file names.  Files are named with the
+  ## prefix, the time they are written and a random suffix, such as
+  ## "metrics-1600000000123456789-d3adb33f.out".
+  # filename_prefix = "metrics"
+  # file_extension = ".out"
+
+  ## Data format to output.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+// client abstracts the SFTP operations used by the plugin.
+type client interface {
+	WriteFile(name string, data []byte) error
+	Rename(oldname, newname string) error
+	Remove(name string) error
+	Close() error
+}
+
+type SFTP struct {
+	Address               string            `toml:"address"`
+	Username              string            `toml:"username"`
+	Password              string            `toml:"password"`
+	PrivateKey            string            `toml:"private_key"`
+	PrivateKeyPassphrase  string            `toml:"private_key_passphrase"`
+	KnownHosts            string            `toml:"known_hosts"`
+	InsecureIgnoreHostKey bool              `toml:"insecure_ignore_host_key"`
+	Timeout               internal.Duration `toml:"timeout"`
+	Directory             string            `toml:"directory"`
+	FilenamePrefix        string            `toml:"filename_prefix"`
+	FileExtension         string            `toml:"file_extension"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	connect    func() (client, error)
+	client     client
+	serializer serializers.Serializer
+}
+
+func (s *SFTP) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SFTP) Description() string {
+	return "Write each batch of metrics to a new file on a remote SFTP server"
+}
+
+func (s *SFTP) SetSerializer(serializer serializers.Serializer) {
+	s.serializer = serializer
+}
+
+func (s *SFTP) Init() error {
+	if s.Address == "" {
+		return errors.New("address must be set")
+	}
+	if s.Directory == "" {
+		return errors.New("directory must be set")
+	}
+	if s.FilenamePrefix == "" {
+		s.FilenamePrefix = defaultFilenamePrefix
+	}
+	if s.FileExtension == "" {
+		s.FileExtension = defaultFileExtension
+	}
+
+	if s.connect == nil {
+		config, err := s.clientConfig()
+		if err != nil {
+			return err
+		}
+		s.connect = func() (client, error) {
+			return dial(s.Address, config)
+		}
+	}
+
+	return nil
+}
+
+func (s *SFTP) clientConfig() (*ssh.ClientConfig, error) {
+	if s.Username == "" {
+		return nil, errors.New("username must be set")
+	}
+
+	var auth []ssh.AuthMethod
+	if s.PrivateKey != "" {
+		key, err := ioutil.ReadFile(s.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("reading private_key: %v", err)
+		}
+		var signer ssh.Signer
+		if s.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(s.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing private_key: %v", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if s.Password != "" {
+		auth = append(auth, ssh.Password(s.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("password or private_key must be set")
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case s.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	case s.KnownHosts != "":
+		var err error
+		hostKeyCallback, err = knownhosts.New(s.KnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("loading known_hosts: %v", err)
+		}
+	default:
+		return nil, errors.New("known_hosts must be set unless insecure_ignore_host_key is enabled")
+	}
+
+	return &ssh.ClientConfig{
+		User:            s.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         s.Timeout.Duration,
+	}, nil
+}
+
+func (s *SFTP) Connect() error {
+	c, err := s.connect()
+	if err != nil {
+		return fmt.Errorf("connecting to %q: %v", s.Address, err)
+	}
+	s.client = c
+	return nil
+}
+
+func (s *SFTP) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client = nil
+	return err
+}
+
+// Write writes the batch to a hidden temporary file, which is renamed to its
+// final name once it is complete.  After an error the connection is closed
+// and established again on the next write.
+func (s *SFTP) Write(metrics []telegraf.Metric) error {
+	octets, err := s.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("could not serialize metrics: %v", err)
+	}
+	if len(octets) == 0 {
+		return nil
+	}
+
+	if s.client == nil {
+		if err := s.Connect(); err != nil {
+			return err
+		}
+	}
+
+	name := s.filename()
+	tmp := path.Join(s.Directory, "."+name+".tmp")
+	if err := s.client.WriteFile(tmp, octets); err != nil {
+		s.reset(tmp)
+		return fmt.Errorf("writing file %q: %v", tmp, err)
+	}
+
+	dest := path.Join(s.Directory, name)
+	if err := s.client.Rename(tmp, dest); err != nil {
+		s.reset(tmp)
+		return fmt.Errorf("renaming file %q to %q: %v", tmp, dest, err)
+	}
+	return nil
+}
+
+// reset removes the temporary file, if the connection still allows it, and
+// closes the connection.
+func (s *SFTP) reset(tmp string) {
+	if err := s.client.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		s.Log.Debugf("Removing temporary file %q: %v", tmp, err)
+	}
+	if err := s.Close(); err != nil {
+		s.Log.Debugf("Closing connection: %v", err)
+	}
+}
+
+// filename returns a name unique across restarts and instances writing to
+// the same directory.
+func (s *SFTP) filename() string {
+	return s.FilenamePrefix + "-" + strconv.FormatInt(time.Now().UnixNano(), 10) + "-" +
+		internal.RandomString(8) + s.FileExtension
+}
+
+// sftpClient implements client over an SSH connection.
+type sftpClient struct {
+	*sftp.Client
+	conn *ssh.Client
+}
+
+func dial(address string, config *ssh.ClientConfig) (client, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultPort)
+	}
+
+	conn, err := ssh.Dial("tcp", address, config)
+	if err != nil {
+		return nil, err
+	}
+	c, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &sftpClient{Client: c, conn: conn}, nil
+}
+
+func (c *sftpClient) WriteFile(name string, data []byte) error {
+	f, err := c.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Rename replaces an existing file of the same name if the server supports
+// the posix-rename extension, as plain SFTP renames refuse to overwrite.
+func (c *sftpClient) Rename(oldname, newname string) error {
+	if err := c.Client.PosixRename(oldname, newname); err == nil {
+		return nil
+	}
+	return c.Client.Rename(oldname, newname)
+}
+
+func (c *sftpClient) Close() error {
+	c.Client.Close()
+	return c.conn.Close()
+}
+
+func init() {
+	outputs.Add("sftp", func() telegraf.Output {
+		return &SFTP{
+			Timeout: internal.Duration{Duration: defaultTimeout},
+		}
+	})
+}
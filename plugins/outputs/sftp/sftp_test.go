@@ -0,0 +1,114 @@
+package sftp
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	files     map[string]string
+	renameErr error
+	closed    int
+}
+
+func (c *mockClient) WriteFile(name string, data []byte) error {
+	c.files[name] = string(data)
+	return nil
+}
+
+func (c *mockClient) Rename(oldname, newname string) error {
+	if c.renameErr != nil {
+		return c.renameErr
+	}
+	c.files[newname] = c.files[oldname]
+	delete(c.files, oldname)
+	return nil
+}
+
+func (c *mockClient) Remove(name string) error {
+	if _, ok := c.files[name]; !ok {
+		return os.ErrNotExist
+	}
+	delete(c.files, name)
+	return nil
+}
+
+func (c *mockClient) Close() error {
+	c.closed++
+	return nil
+}
+
+const line = "test1,tag1=value1 value=1 1257894000000000000\n"
+
+func newSFTP(t *testing.T) (*SFTP, *mockClient, *int) {
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	c := &mockClient{files: make(map[string]string)}
+	var dials int
+	s := &SFTP{
+		Address:   "localhost",
+		Directory: "/upload",
+		Log:       testutil.Logger{},
+		connect: func() (client, error) {
+			dials++
+			return c, nil
+		},
+	}
+	s.SetSerializer(serializer)
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	return s, c, &dials
+}
+
+func TestWrite(t *testing.T) {
+	s, c, dials := newSFTP(t)
+
+	require.NoError(t, s.Write(testutil.MockMetrics()))
+	require.NoError(t, s.Write(testutil.MockMetrics()))
+	require.NoError(t, s.Close())
+
+	require.Len(t, c.files, 2)
+	for name, data := range c.files {
+		require.Equal(t, "/upload", path.Dir(name))
+		require.True(t, strings.HasPrefix(path.Base(name), "metrics-"), name)
+		require.True(t, strings.HasSuffix(name, ".out"), name)
+		require.Equal(t, line, data)
+	}
+	require.Equal(t, 1, *dials)
+	require.Equal(t, 1, c.closed)
+}
+
+func TestRenameErrorReconnects(t *testing.T) {
+	s, c, dials := newSFTP(t)
+
+	c.renameErr = errors.New("permission denied")
+	require.Error(t, s.Write(testutil.MockMetrics()))
+	require.Empty(t, c.files)
+	require.Equal(t, 1, c.closed)
+
+	c.renameErr = nil
+	require.NoError(t, s.Write(testutil.MockMetrics()))
+	require.Len(t, c.files, 1)
+	require.Equal(t, 2, *dials)
+}
+
+func TestInit(t *testing.T) {
+	require.Error(t, (&SFTP{Directory: "/upload"}).Init())
+	require.Error(t, (&SFTP{Address: "localhost", Directory: "/upload", Username: "telegraf"}).Init())
+	require.Error(t, (&SFTP{Address: "localhost", Directory: "/upload", Username: "telegraf", Password: "secret"}).Init())
+	require.NoError(t, (&SFTP{
+		Address:               "localhost",
+		Directory:             "/upload",
+		Username:              "telegraf",
+		Password:              "secret",
+		InsecureIgnoreHostKey: true,
+	}).Init())
+}
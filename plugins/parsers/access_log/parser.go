@@ -0,0 +1,64 @@
+package access_log
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf/plugins/parsers/grok"
+)
+
+const (
+	FormatCommon        = "common"
+	FormatCombined      = "combined"
+	FormatCombinedVhost = "combined_vhost"
+	FormatAuto          = "auto"
+)
+
+// Patterns of the access log formats, using the built-in grok patterns.
+const patterns = `
+ACCESS_LOG_PATH [^\s?]+
+ACCESS_LOG_REQUEST (?:%{WORD:method:tag} %{ACCESS_LOG_PATH:path}(?:\?%{NOTSPACE:query})?(?: HTTP/%{NUMBER:http_version})?|%{DATA:request})
+ACCESS_LOG_ENTRY %{IPORHOST:client_ip} (?:-|%{NOTSPACE:ident}) (?:-|%{NOTSPACE:user}) \[%{HTTPDATE:ts:ts-httpd}\] "%{ACCESS_LOG_REQUEST}" %{NUMBER:status:tag} (?:%{NUMBER:bytes:int}|-)
+ACCESS_LOG_AGENT "(?:-|%{DATA:referrer})" "(?:-|%{DATA:agent})"
+
+ACCESS_LOG_COMMON ^%{ACCESS_LOG_ENTRY}$
+ACCESS_LOG_COMBINED ^%{ACCESS_LOG_ENTRY} %{ACCESS_LOG_AGENT}$
+ACCESS_LOG_COMBINED_VHOST ^%{IPORHOST:vhost:tag}(?::%{POSINT:port:tag})? %{ACCESS_LOG_ENTRY} %{ACCESS_LOG_AGENT}$
+`
+
+// Patterns to match for each format, tried in order.
+var formats = map[string][]string{
+	FormatCommon:        {"%{ACCESS_LOG_COMMON}"},
+	FormatCombined:      {"%{ACCESS_LOG_COMBINED}"},
+	FormatCombinedVhost: {"%{ACCESS_LOG_COMBINED_VHOST}"},
+	FormatAuto:          {"%{ACCESS_LOG_COMBINED}", "%{ACCESS_LOG_COMBINED_VHOST}", "%{ACCESS_LOG_COMMON}"},
+}
+
+type Config struct {
+	MetricName  string
+	Format      string
+	DefaultTags map[string]string
+}
+
+// New returns a grok parser matching the lines of the access log format used
+// by Apache and Nginx.
+func New(config *Config) (*grok.Parser, error) {
+	format := config.Format
+	if format == "" {
+		format = FormatAuto
+	}
+	named, ok := formats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown access log format %q", format)
+	}
+
+	parser := &grok.Parser{
+		Measurement:    config.MetricName,
+		Patterns:       named,
+		CustomPatterns: patterns,
+		DefaultTags:    config.DefaultTags,
+	}
+	if err := parser.Compile(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
@@ -0,0 +1,120 @@
+package access_log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		line     string
+		expected telegraf.Metric
+	}{
+		{
+			name:   "common",
+			format: FormatCommon,
+			line:   `127.0.0.1 - frank [13/Sep/2020:14:26:40 +0200] "GET /apache_pb.gif?size=large HTTP/1.0" 200 2326`,
+			expected: testutil.MustMetric(
+				"access_log",
+				map[string]string{"method": "GET", "status": "200"},
+				map[string]interface{}{
+					"client_ip":    "127.0.0.1",
+					"user":         "frank",
+					"path":         "/apache_pb.gif",
+					"query":        "size=large",
+					"http_version": "1.0",
+					"bytes":        int64(2326),
+				},
+				time.Unix(1600000000, 0),
+			),
+		},
+		{
+			name:   "combined",
+			format: FormatCombined,
+			line:   `10.0.0.2 - - [13/Sep/2020:12:26:40 +0000] "POST /login HTTP/1.1" 302 - "https://example.org/" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			expected: testutil.MustMetric(
+				"access_log",
+				map[string]string{"method": "POST", "status": "302"},
+				map[string]interface{}{
+					"client_ip":    "10.0.0.2",
+					"path":         "/login",
+					"http_version": "1.1",
+					"referrer":     "https://example.org/",
+					"agent":        "Mozilla/5.0 (X11; Linux x86_64)",
+				},
+				time.Unix(1600000000, 0),
+			),
+		},
+		{
+			name:   "combined with vhost",
+			format: FormatCombinedVhost,
+			line:   `www.example.org:443 10.0.0.3 - - [13/Sep/2020:12:26:40 +0000] "GET / HTTP/2.0" 200 512 "-" "curl/7.68.0"`,
+			expected: testutil.MustMetric(
+				"access_log",
+				map[string]string{"vhost": "www.example.org", "port": "443", "method": "GET", "status": "200"},
+				map[string]interface{}{
+					"client_ip":    "10.0.0.3",
+					"path":         "/",
+					"http_version": "2.0",
+					"bytes":        int64(512),
+					"agent":        "curl/7.68.0",
+				},
+				time.Unix(1600000000, 0),
+			),
+		},
+		{
+			name:   "malformed request",
+			format: FormatAuto,
+			line:   `10.0.0.4 - - [13/Sep/2020:12:26:40 +0000] "\x16\x03\x01" 400 0 "-" "-"`,
+			expected: testutil.MustMetric(
+				"access_log",
+				map[string]string{"status": "400"},
+				map[string]interface{}{
+					"client_ip": "10.0.0.4",
+					"request":   `\x16\x03\x01`,
+					"bytes":     int64(0),
+				},
+				time.Unix(1600000000, 0),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := New(&Config{MetricName: "access_log", Format: tt.format})
+			require.NoError(t, err)
+
+			m, err := parser.ParseLine(tt.line)
+			require.NoError(t, err)
+			testutil.RequireMetricEqual(t, tt.expected, m)
+		})
+	}
+}
+
+func TestParseAuto(t *testing.T) {
+	parser, err := New(&Config{MetricName: "access_log"})
+	require.NoError(t, err)
+
+	data := `127.0.0.1 - - [13/Sep/2020:12:26:40 +0000] "GET /a HTTP/1.1" 200 1
+127.0.0.1 - - [13/Sep/2020:12:26:41 +0000] "GET /b HTTP/1.1" 200 2 "-" "curl/7.68.0"
+example.org 127.0.0.1 - - [13/Sep/2020:12:26:42 +0000] "GET /c HTTP/1.1" 200 3 "-" "curl/7.68.0"
+`
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	require.Equal(t, "/a", metrics[0].Fields()["path"])
+	require.Equal(t, "curl/7.68.0", metrics[1].Fields()["agent"])
+	require.Equal(t, map[string]string{"vhost": "example.org", "method": "GET", "status": "200"}, metrics[2].Tags())
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := New(&Config{MetricName: "access_log", Format: "extended"})
+	require.EqualError(t, err, `unknown access log format "extended"`)
+}
@@ -0,0 +1,446 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	defaultFieldSeparator = "_"
+	registryTimeout       = 10 * time.Second
+)
+
+var (
+	ocfMagic          = []byte("Obj\x01")
+	singleObjectMagic = []byte{0xc3, 0x01}
+
+	// Avro type names used as keys of union values.
+	primitiveTypes = map[string]bool{
+		"null": true, "boolean": true, "int": true, "long": true, "float": true,
+		"double": true, "bytes": true, "string": true, "array": true, "map": true,
+	}
+)
+
+type Config struct {
+	MetricName       string
+	Schema           string
+	SchemaFile       string
+	SchemaRegistry   string
+	MeasurementField string
+	Tags             []string
+	Fields           []string
+	TimestampField   string
+	TimestampFormat  string
+	Timezone         string
+	FieldSeparator   string
+	DefaultTags      map[string]string
+}
+
+// Parser decodes Avro object container files, single-object encoded datums,
+// Confluent Schema Registry framed messages and plain binary datums.
+type Parser struct {
+	metricName       string
+	measurementField string
+	tags             map[string]bool
+	fields           filter.Filter
+	timestampField   string
+	timestampFormat  string
+	timezone         string
+	separator        string
+	defaultTags      map[string]string
+
+	schema   *schemaCodec
+	registry string
+	client   *http.Client
+
+	sync.Mutex
+	registryCodecs map[uint32]*schemaCodec
+
+	TimeFunc func() time.Time
+}
+
+// schemaCodec is a codec along with the names of the named types defined by
+// its schema, which are needed to recognize union values.
+type schemaCodec struct {
+	codec *goavro.Codec
+	names map[string]bool
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.Schema != "" && config.SchemaFile != "" {
+		return nil, errors.New("only one of avro_schema and avro_schema_file can be set")
+	}
+
+	schema := config.Schema
+	if config.SchemaFile != "" {
+		b, err := ioutil.ReadFile(config.SchemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading avro_schema_file: %v", err)
+		}
+		schema = string(b)
+	}
+
+	fields, err := filter.Compile(config.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Parser{
+		metricName:       config.MetricName,
+		measurementField: config.MeasurementField,
+		tags:             make(map[string]bool),
+		fields:           fields,
+		timestampField:   config.TimestampField,
+		timestampFormat:  config.TimestampFormat,
+		timezone:         config.Timezone,
+		separator:        config.FieldSeparator,
+		defaultTags:      config.DefaultTags,
+		registry:         strings.TrimSuffix(config.SchemaRegistry, "/"),
+		client:           &http.Client{Timeout: registryTimeout},
+		registryCodecs:   make(map[uint32]*schemaCodec),
+		TimeFunc:         time.Now,
+	}
+	if p.separator == "" {
+		p.separator = defaultFieldSeparator
+	}
+	if p.timestampField != "" && p.timestampFormat == "" {
+		p.timestampFormat = "unix"
+	}
+	for _, tag := range config.Tags {
+		p.tags[tag] = true
+	}
+
+	if schema != "" {
+		p.schema, err = newSchemaCodec(schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema: %v", err)
+		}
+	}
+	return p, nil
+}
+
+func newSchemaCodec(schema string) (*schemaCodec, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	collectNames(parsed, "", names)
+	return &schemaCodec{codec: codec, names: names}, nil
+}
+
+// collectNames adds the short and full names of all records, enums and fixed
+// types defined in a schema.
+func collectNames(schema interface{}, namespace string, names map[string]bool) {
+	switch s := schema.(type) {
+	case []interface{}:
+		for _, item := range s {
+			collectNames(item, namespace, names)
+		}
+	case map[string]interface{}:
+		if ns, ok := s["namespace"].(string); ok {
+			namespace = ns
+		}
+		if name, ok := s["name"].(string); ok {
+			switch s["type"] {
+			case "record", "error", "enum", "fixed":
+				names[name] = true
+				if !strings.Contains(name, ".") && namespace != "" {
+					name = namespace + "." + name
+				}
+				names[name] = true
+				if i := strings.LastIndex(name, "."); i >= 0 {
+					namespace = name[:i]
+				}
+			}
+		}
+		for _, key := range []string{"type", "items", "values", "fields"} {
+			if v, ok := s[key]; ok {
+				collectNames(v, namespace, names)
+			}
+		}
+	}
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	var records []interface{}
+	var schema *schemaCodec
+	var err error
+
+	switch {
+	case bytes.HasPrefix(buf, ocfMagic):
+		records, schema, err = p.decodeContainer(buf)
+	case bytes.HasPrefix(buf, singleObjectMagic):
+		records, schema, err = p.decodeSingleObjects(buf)
+	case p.registry != "" && len(buf) > 0 && buf[0] == 0:
+		records, schema, err = p.decodeRegistryMessage(buf)
+	default:
+		records, schema, err = p.decodeBinary(buf)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(records))
+	for _, record := range records {
+		m, err := p.createMetric(record, schema)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+// decodeContainer decodes an object container file, using the schema stored
+// in the file header.
+func (p *Parser) decodeContainer(buf []byte) ([]interface{}, *schemaCodec, error) {
+	ocf, err := goavro.NewOCFReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, nil, err
+	}
+	schema, err := newSchemaCodec(ocf.Codec().Schema())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records []interface{}
+	for ocf.Scan() {
+		record, err := ocf.Read()
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+	}
+	return records, schema, ocf.Err()
+}
+
+// decodeSingleObjects decodes one or more consecutive single-object encoded
+// datums, which must have been written with the configured schema.
+func (p *Parser) decodeSingleObjects(buf []byte) ([]interface{}, *schemaCodec, error) {
+	if p.schema == nil {
+		return nil, nil, errors.New("avro_schema or avro_schema_file required for single-object encoding")
+	}
+
+	var records []interface{}
+	for len(buf) > 0 {
+		record, rest, err := p.schema.codec.NativeFromSingle(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+		buf = rest
+	}
+	return records, p.schema, nil
+}
+
+// decodeRegistryMessage decodes a datum framed with a magic byte and schema
+// ID, as written by the Confluent serializers.
+func (p *Parser) decodeRegistryMessage(buf []byte) ([]interface{}, *schemaCodec, error) {
+	if len(buf) < 5 {
+		return nil, nil, errors.New("message too short for schema registry framing")
+	}
+
+	id := binary.BigEndian.Uint32(buf[1:5])
+	schema, err := p.registrySchema(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record, _, err := schema.codec.NativeFromBinary(buf[5:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return []interface{}{record}, schema, nil
+}
+
+// decodeBinary decodes one or more consecutive binary datums without any
+// framing, which must have been written with the configured schema.
+func (p *Parser) decodeBinary(buf []byte) ([]interface{}, *schemaCodec, error) {
+	if p.schema == nil {
+		return nil, nil, errors.New("avro_schema or avro_schema_file required for data without an embedded schema")
+	}
+
+	var records []interface{}
+	for len(buf) > 0 {
+		record, rest, err := p.schema.codec.NativeFromBinary(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+		buf = rest
+	}
+	return records, p.schema, nil
+}
+
+// registrySchema returns the schema with the given ID from the schema
+// registry.  Schemas are immutable, so they are cached forever.
+func (p *Parser) registrySchema(id uint32) (*schemaCodec, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if schema, ok := p.registryCodecs[id]; ok {
+		return schema, nil
+	}
+
+	resp, err := p.client.Get(p.registry + "/schemas/ids/" + strconv.FormatUint(uint64(id), 10))
+	if err != nil {
+		return nil, fmt.Errorf("fetching schema %d: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching schema %d: %s", id, resp.Status)
+	}
+
+	var body struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decoding schema %d: %v", id, err)
+	}
+
+	schema, err := newSchemaCodec(body.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema %d: %v", id, err)
+	}
+	p.registryCodecs[id] = schema
+	return schema, nil
+}
+
+func (p *Parser) createMetric(record interface{}, schema *schemaCodec) (telegraf.Metric, error) {
+	values := make(map[string]interface{})
+	p.flatten("", record, schema, values)
+
+	name := p.metricName
+	if p.measurementField != "" {
+		if v, ok := values[p.measurementField]; ok {
+			name = fmt.Sprint(v)
+			delete(values, p.measurementField)
+		}
+	}
+
+	timestamp := p.TimeFunc()
+	if p.timestampField != "" {
+		v, ok := values[p.timestampField]
+		if !ok {
+			return nil, fmt.Errorf("timestamp field %q not found", p.timestampField)
+		}
+		var err error
+		timestamp, err = p.parseTimestamp(v)
+		if err != nil {
+			return nil, err
+		}
+		delete(values, p.timestampField)
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	for k, v := range values {
+		if p.tags[k] {
+			tags[k] = fmt.Sprint(v)
+			continue
+		}
+		if p.fields != nil && !p.fields.Match(k) {
+			continue
+		}
+		if t, ok := v.(time.Time); ok {
+			v = t.UnixNano()
+		}
+		fields[k] = v
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+func (p *Parser) parseTimestamp(v interface{}) (time.Time, error) {
+	// Values of the timestamp logical types are decoded as times already.
+	if ts, ok := v.(time.Time); ok {
+		return ts, nil
+	}
+	return internal.ParseTimestamp(p.timestampFormat, v, p.timezone)
+}
+
+// flatten adds the values of a decoded datum to values, joining the names of
+// nested records, maps and arrays with the field separator.
+func (p *Parser) flatten(prefix string, value interface{}, schema *schemaCodec, values map[string]interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for k, inner := range v {
+				if primitiveTypes[k] || schema.names[k] {
+					// Union values are wrapped in a map keyed by the type name.
+					p.flatten(prefix, inner, schema, values)
+					return
+				}
+			}
+		}
+		for k, inner := range v {
+			p.flatten(p.join(prefix, k), inner, schema, values)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			p.flatten(p.join(prefix, strconv.Itoa(i)), inner, schema, values)
+		}
+	case int32:
+		values[prefix] = int64(v)
+	case float32:
+		values[prefix] = float64(v)
+	case []byte:
+		values[prefix] = string(v)
+	case *big.Rat:
+		f, _ := v.Float64()
+		values[prefix] = f
+	case time.Duration:
+		values[prefix] = int64(v)
+	default:
+		values[prefix] = v
+	}
+}
+
+func (p *Parser) join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + p.separator + name
+}
@@ -0,0 +1,220 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `
+{
+  "type": "record",
+  "name": "Reading",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "sensor", "type": "string"},
+    {"name": "time", "type": "long"},
+    {"name": "value", "type": "double"},
+    {"name": "count", "type": ["null", "int"]},
+    {"name": "location", "type": {
+      "type": "record",
+      "name": "Location",
+      "fields": [
+        {"name": "site", "type": "string"},
+        {"name": "rack", "type": "int"}
+      ]
+    }}
+  ]
+}`
+
+func testRecord(sensor string, value float64) map[string]interface{} {
+	return map[string]interface{}{
+		"sensor":   sensor,
+		"time":     int64(1600000000),
+		"value":    value,
+		"count":    goavro.Union("int", int32(3)),
+		"location": map[string]interface{}{"site": "berlin", "rack": int32(7)},
+	}
+}
+
+func expectedMetric(sensor string, value float64) telegraf.Metric {
+	return testutil.MustMetric(
+		"avro",
+		map[string]string{"sensor": sensor},
+		map[string]interface{}{
+			"value":         value,
+			"count":         int64(3),
+			"location_site": "berlin",
+			"location_rack": int64(7),
+		},
+		time.Unix(1600000000, 0),
+	)
+}
+
+func newTestParser(t *testing.T, config *Config) *Parser {
+	config.MetricName = "avro"
+	config.Tags = []string{"sensor"}
+	config.TimestampField = "time"
+	parser, err := New(config)
+	require.NoError(t, err)
+	return parser
+}
+
+func TestParseBinary(t *testing.T) {
+	codec, err := goavro.NewCodec(testSchema)
+	require.NoError(t, err)
+
+	buf, err := codec.BinaryFromNative(nil, testRecord("a", 1.5))
+	require.NoError(t, err)
+	buf, err = codec.BinaryFromNative(buf, testRecord("b", 2.5))
+	require.NoError(t, err)
+
+	parser := newTestParser(t, &Config{Schema: testSchema})
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{expectedMetric("a", 1.5), expectedMetric("b", 2.5)}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSingleObject(t *testing.T) {
+	codec, err := goavro.NewCodec(testSchema)
+	require.NoError(t, err)
+
+	buf, err := codec.SingleFromNative(nil, testRecord("a", 1.5))
+	require.NoError(t, err)
+
+	parser := newTestParser(t, &Config{Schema: testSchema})
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expectedMetric("a", 1.5)}, metrics)
+
+	other, err := New(&Config{Schema: `{"type": "record", "name": "Other", "fields": [{"name": "x", "type": "int"}]}`})
+	require.NoError(t, err)
+	_, err = other.Parse(buf)
+	require.Error(t, err)
+}
+
+func TestParseContainerFile(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buf,
+		Schema:          testSchema,
+		CompressionName: goavro.CompressionDeflateLabel,
+	})
+	require.NoError(t, err)
+	require.NoError(t, writer.Append([]interface{}{testRecord("a", 1.5), testRecord("b", 2.5)}))
+
+	// The schema is read from the file.
+	parser := newTestParser(t, &Config{})
+	metrics, err := parser.Parse(buf.Bytes())
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{expectedMetric("a", 1.5), expectedMetric("b", 2.5)}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSchemaRegistry(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/schemas/ids/42" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"schema": testSchema})
+	}))
+	defer ts.Close()
+
+	codec, err := goavro.NewCodec(testSchema)
+	require.NoError(t, err)
+
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], 42)
+	buf, err := codec.BinaryFromNative(header, testRecord("a", 1.5))
+	require.NoError(t, err)
+
+	parser := newTestParser(t, &Config{SchemaRegistry: ts.URL + "/"})
+	for i := 0; i < 2; i++ {
+		metrics, err := parser.Parse(buf)
+		require.NoError(t, err)
+		testutil.RequireMetricsEqual(t, []telegraf.Metric{expectedMetric("a", 1.5)}, metrics)
+	}
+	require.Equal(t, 1, requests)
+
+	binary.BigEndian.PutUint32(buf[1:], 43)
+	_, err = parser.Parse(buf)
+	require.Error(t, err)
+}
+
+func TestMeasurementAndFieldSelection(t *testing.T) {
+	schema := `
+{
+  "type": "record",
+  "name": "Event",
+  "fields": [
+    {"name": "kind", "type": "string"},
+    {"name": "at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["OK", "FAILED"]}},
+    {"name": "latency", "type": "float"},
+    {"name": "debug", "type": "string"}
+  ]
+}`
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+
+	at := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"kind":    "request",
+		"at":      at,
+		"status":  "OK",
+		"latency": float32(0.5),
+		"debug":   "verbose",
+	})
+	require.NoError(t, err)
+
+	parser, err := New(&Config{
+		MetricName:       "avro",
+		Schema:           schema,
+		MeasurementField: "kind",
+		Tags:             []string{"status"},
+		Fields:           []string{"lat*"},
+		TimestampField:   "at",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"request",
+			map[string]string{"status": "OK"},
+			map[string]interface{}{"latency": 0.5},
+			at,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestMissingSchema(t *testing.T) {
+	parser, err := New(&Config{MetricName: "avro"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte{0x02, 0x61})
+	require.Error(t, err)
+}
+
+func TestInvalidSchema(t *testing.T) {
+	_, err := New(&Config{Schema: `{"type": "nope"}`})
+	require.Error(t, err)
+}
@@ -0,0 +1,307 @@
+package binary
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrLineNotSupported = errors.New("binary records can't be parsed by line")
+
+// sizes are the lengths of the fixed size types.
+var sizes = map[string]int{
+	"int8":    1,
+	"int16":   2,
+	"int32":   4,
+	"int64":   8,
+	"uint8":   1,
+	"uint16":  2,
+	"uint32":  4,
+	"uint64":  8,
+	"float32": 4,
+	"float64": 8,
+	"bool":    1,
+}
+
+type Config struct {
+	MetricName       string
+	Endianness       string
+	HeaderLength     int
+	RecordLength     int
+	Fields           []Field
+	TagFields        []string
+	MeasurementField string
+	TimestampField   string
+	TimestampFormat  string
+	Timezone         string
+	DefaultTags      map[string]string
+}
+
+// Field describes a value of the record.  Fields without an offset, given as
+// a negative offset, directly follow the previous field.
+type Field struct {
+	Name       string
+	Type       string
+	Offset     int
+	Length     int
+	Count      int
+	Endianness string
+}
+
+type field struct {
+	name   string
+	typ    string
+	offset int
+	size   int
+	count  int
+	order  binary.ByteOrder
+}
+
+// Parser decodes files of fixed length binary records, such as the telemetry
+// of embedded devices, with the layout of the records given by the
+// configuration.
+type Parser struct {
+	metricName       string
+	headerLength     int
+	recordLength     int
+	fields           []field
+	tagFields        map[string]bool
+	measurementField string
+	timestampField   string
+	timestampFormat  string
+	timezone         string
+	defaultTags      map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if len(config.Fields) == 0 {
+		return nil, errors.New("fields must be specified")
+	}
+	if config.HeaderLength < 0 {
+		return nil, errors.New("header length must not be negative")
+	}
+
+	order, err := byteOrder(config.Endianness, binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]field, 0, len(config.Fields))
+	offset, end := 0, 0
+	for _, f := range config.Fields {
+		if f.Name == "" && f.Type != "padding" {
+			return nil, errors.New("fields must have a name")
+		}
+		if f.Offset >= 0 {
+			offset = f.Offset
+		}
+
+		size, ok := sizes[f.Type]
+		switch {
+		case ok:
+		case f.Type == "string" || f.Type == "bytes" || f.Type == "padding":
+			if f.Length <= 0 {
+				return nil, fmt.Errorf("length must be specified for field %q", f.Name)
+			}
+			size = f.Length
+		default:
+			return nil, fmt.Errorf("invalid type %q for field %q", f.Type, f.Name)
+		}
+
+		count := f.Count
+		if count < 0 {
+			return nil, fmt.Errorf("invalid count %d for field %q", count, f.Name)
+		}
+		if count == 0 {
+			count = 1
+		}
+
+		fieldOrder, err := byteOrder(f.Endianness, order)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.Type != "padding" {
+			fields = append(fields, field{
+				name:   f.Name,
+				typ:    f.Type,
+				offset: offset,
+				size:   size,
+				count:  count,
+				order:  fieldOrder,
+			})
+		}
+		offset += size * count
+		if offset > end {
+			end = offset
+		}
+	}
+
+	// By default records end with the last field.
+	recordLength := config.RecordLength
+	if recordLength == 0 {
+		recordLength = end
+	}
+	if recordLength < end {
+		return nil, fmt.Errorf("record length %d is shorter than the fields", recordLength)
+	}
+
+	tagFields := make(map[string]bool)
+	for _, name := range config.TagFields {
+		tagFields[name] = true
+	}
+
+	timestampFormat := config.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = "unix"
+	}
+
+	return &Parser{
+		metricName:       config.MetricName,
+		headerLength:     config.HeaderLength,
+		recordLength:     recordLength,
+		fields:           fields,
+		tagFields:        tagFields,
+		measurementField: config.MeasurementField,
+		timestampField:   config.TimestampField,
+		timestampFormat:  timestampFormat,
+		timezone:         config.Timezone,
+		defaultTags:      config.DefaultTags,
+		TimeFunc:         time.Now,
+	}, nil
+}
+
+// Parse parses the records following the header, the data must end with a
+// complete record.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < p.headerLength {
+		return nil, errors.New("truncated header")
+	}
+	buf = buf[p.headerLength:]
+
+	metrics := make([]telegraf.Metric, 0, len(buf)/p.recordLength)
+	for i := 0; len(buf) > 0; i++ {
+		if len(buf) < p.recordLength {
+			return nil, fmt.Errorf("record %d: truncated record", i+1)
+		}
+		m, err := p.parseRecord(buf[:p.recordLength])
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %v", i+1, err)
+		}
+		metrics = append(metrics, m)
+		buf = buf[p.recordLength:]
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	return nil, ErrLineNotSupported
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseRecord(record []byte) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	name := p.metricName
+	timestamp := p.TimeFunc()
+
+	for _, f := range p.fields {
+		for i := 0; i < f.count; i++ {
+			start := f.offset + i*f.size
+			value := decode(f, record[start:start+f.size])
+
+			// Repeated fields are numbered by their index.
+			key := f.name
+			if f.count > 1 {
+				key += "_" + strconv.Itoa(i)
+			}
+
+			switch {
+			case key == p.measurementField:
+				name = fmt.Sprint(value)
+			case key == p.timestampField:
+				if v, ok := value.(uint64); ok {
+					value = int64(v)
+				}
+				var err error
+				timestamp, err = internal.ParseTimestamp(p.timestampFormat, value, p.timezone)
+				if err != nil {
+					return nil, fmt.Errorf("field %q: %v", key, err)
+				}
+			case p.tagFields[key]:
+				tags[key] = fmt.Sprint(value)
+			default:
+				fields[key] = value
+			}
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// decode converts the bytes of a field, integers other than uint64 are
+// returned as int64 and float32 as float64.
+func decode(f field, data []byte) interface{} {
+	switch f.typ {
+	case "int8":
+		return int64(int8(data[0]))
+	case "int16":
+		return int64(int16(f.order.Uint16(data)))
+	case "int32":
+		return int64(int32(f.order.Uint32(data)))
+	case "int64":
+		return int64(f.order.Uint64(data))
+	case "uint8":
+		return int64(data[0])
+	case "uint16":
+		return int64(f.order.Uint16(data))
+	case "uint32":
+		return int64(f.order.Uint32(data))
+	case "uint64":
+		return f.order.Uint64(data)
+	case "float32":
+		return float64(math.Float32frombits(f.order.Uint32(data)))
+	case "float64":
+		return math.Float64frombits(f.order.Uint64(data))
+	case "bool":
+		return data[0] != 0
+	case "string":
+		// C strings are padded with NUL bytes.
+		if i := strings.IndexByte(string(data), 0); i >= 0 {
+			data = data[:i]
+		}
+		return strings.TrimSpace(string(data))
+	default:
+		return hex.EncodeToString(data)
+	}
+}
+
+func byteOrder(endianness string, fallback binary.ByteOrder) (binary.ByteOrder, error) {
+	switch strings.ToLower(endianness) {
+	case "":
+		return fallback, nil
+	case "big", "be":
+		return binary.BigEndian, nil
+	case "little", "le":
+		return binary.LittleEndian, nil
+	default:
+		return nil, fmt.Errorf("invalid endianness %q", endianness)
+	}
+}
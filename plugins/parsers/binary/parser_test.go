@@ -0,0 +1,156 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func build(order binary.ByteOrder, values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if err := binary.Write(&buf, order, v); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:   "binary",
+		Endianness:   "little",
+		HeaderLength: 4,
+		Fields: []Field{
+			{Name: "time", Type: "uint32", Offset: -1},
+			{Name: "device", Type: "string", Offset: -1, Length: 8},
+			{Name: "temperature", Type: "int16", Offset: -1},
+			{Type: "padding", Offset: -1, Length: 2},
+			{Name: "voltage", Type: "float32", Offset: -1, Count: 2},
+			{Name: "status", Type: "uint16", Offset: -1, Endianness: "big"},
+			{Name: "ok", Type: "bool", Offset: 26},
+			{Name: "serial", Type: "bytes", Offset: -1, Length: 3},
+		},
+		RecordLength:   32,
+		TagFields:      []string{"device"},
+		TimestampField: "time",
+	})
+	require.NoError(t, err)
+
+	le := binary.LittleEndian
+	data := build(le, []byte("HDR1"))
+	data = append(data, build(le, uint32(1600000000), []byte("pump-1\x00\x00"), int16(-125), uint16(0),
+		float32(3.25), float32(12.5))...)
+	data = append(data, build(binary.BigEndian, uint16(0x0102))...)
+	data = append(data, build(le, uint8(1), []byte{0xca, 0xfe, 0x01}, []byte{0, 0})...)
+	data = append(data, build(le, uint32(1600000060), []byte("pump-2  "), int16(210), uint16(0),
+		float32(3.5), float32(12), uint16(0), uint8(0), []byte{0, 0, 2}, []byte{0, 0})...)
+
+	metrics, err := parser.Parse(data)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"binary",
+			map[string]string{"device": "pump-1"},
+			map[string]interface{}{
+				"temperature": int64(-125),
+				"voltage_0":   3.25,
+				"voltage_1":   12.5,
+				"status":      int64(258),
+				"ok":          true,
+				"serial":      "cafe01",
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"binary",
+			map[string]string{"device": "pump-2"},
+			map[string]interface{}{
+				"temperature": int64(210),
+				"voltage_0":   3.5,
+				"voltage_1":   12.0,
+				"status":      int64(0),
+				"ok":          false,
+				"serial":      "000002",
+			},
+			time.Unix(1600000060, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	_, err = parser.Parse(data[:len(data)-1])
+	require.EqualError(t, err, "record 2: truncated record")
+}
+
+func TestParseMeasurement(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "binary",
+		Fields: []Field{
+			{Name: "name", Type: "string", Offset: 0, Length: 4},
+			{Name: "value", Type: "uint64", Offset: -1},
+		},
+		MeasurementField: "name",
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	metrics, err := parser.Parse(build(binary.BigEndian, []byte("cpu\x00"), uint64(1<<63)))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"value": uint64(1 << 63)},
+			time.Unix(42, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		err    string
+	}{
+		{
+			name:   "no fields",
+			config: Config{},
+			err:    "fields must be specified",
+		},
+		{
+			name:   "invalid type",
+			config: Config{Fields: []Field{{Name: "a", Type: "int12"}}},
+			err:    `invalid type "int12" for field "a"`,
+		},
+		{
+			name:   "string without length",
+			config: Config{Fields: []Field{{Name: "a", Type: "string"}}},
+			err:    `length must be specified for field "a"`,
+		},
+		{
+			name:   "invalid endianness",
+			config: Config{Endianness: "middle", Fields: []Field{{Name: "a", Type: "int8"}}},
+			err:    `invalid endianness "middle"`,
+		},
+		{
+			name:   "short record",
+			config: Config{RecordLength: 2, Fields: []Field{{Name: "a", Type: "int32"}}},
+			err:    "record length 2 is shorter than the fields",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(&tt.config)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
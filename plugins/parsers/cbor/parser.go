@@ -0,0 +1,246 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrWrongType = errors.New("must be a map or an array of maps")
+
+type Config struct {
+	MetricName   string
+	TagKeys      []string
+	NameKey      string
+	StringFields []string
+	TimeKey      string
+	TimeFormat   string
+	Timezone     string
+	DefaultTags  map[string]string
+	Strict       bool
+}
+
+// Parser decodes CBOR maps, arrays of maps, or sequences of either, with the
+// same key mapping as the JSON parser.
+type Parser struct {
+	metricName   string
+	tagKeys      filter.Filter
+	stringFields filter.Filter
+	nameKey      string
+	timeKey      string
+	timeFormat   string
+	timezone     string
+	defaultTags  map[string]string
+	strict       bool
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	stringFilter, err := filter.Compile(config.StringFields)
+	if err != nil {
+		return nil, err
+	}
+
+	tagKeyFilter, err := filter.Compile(config.TagKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Parser{
+		metricName:   config.MetricName,
+		tagKeys:      tagKeyFilter,
+		stringFields: stringFilter,
+		nameKey:      config.NameKey,
+		timeKey:      config.TimeKey,
+		timeFormat:   config.TimeFormat,
+		timezone:     config.Timezone,
+		defaultTags:  config.DefaultTags,
+		strict:       config.Strict,
+		TimeFunc:     time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	dec := cbor.NewDecoder(bytes.NewReader(buf))
+
+	timestamp := p.TimeFunc().UTC()
+	metrics := make([]telegraf.Metric, 0)
+	for {
+		var data interface{}
+		err := dec.Decode(&data)
+		if err == io.EOF {
+			// The decoder also stops at the end of a truncated item.
+			if dec.NumBytesRead() != len(buf) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := data.(type) {
+		case []interface{}:
+			for _, item := range v {
+				m, err := p.parseItem(item, timestamp)
+				if err != nil {
+					if p.strict {
+						return nil, err
+					}
+					continue
+				}
+				metrics = append(metrics, m)
+			}
+		default:
+			m, err := p.parseItem(v, timestamp)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseItem(item interface{}, timestamp time.Time) (telegraf.Metric, error) {
+	if _, ok := item.(map[interface{}]interface{}); !ok {
+		return nil, ErrWrongType
+	}
+
+	fields := make(map[string]interface{})
+	flatten("", item, fields)
+
+	name := p.metricName
+	if p.nameKey != "" {
+		if v, ok := fields[p.nameKey].(string); ok {
+			name = v
+		}
+	}
+
+	if p.timeKey != "" {
+		v, ok := fields[p.timeKey]
+		if !ok {
+			return nil, errors.New("cbor time key could not be found")
+		}
+		if t, ok := v.(time.Time); ok {
+			timestamp = t
+		} else {
+			if p.timeFormat == "" {
+				return nil, errors.New("use of 'cbor_time_key' requires 'cbor_time_format'")
+			}
+			var err error
+			timestamp, err = internal.ParseTimestamp(p.timeFormat, v, p.timezone)
+			if err != nil {
+				return nil, err
+			}
+		}
+		delete(fields, p.timeKey)
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for k, v := range fields {
+		if p.tagKeys != nil && p.tagKeys.Match(k) {
+			tags[k] = toString(v)
+			delete(fields, k)
+			continue
+		}
+
+		// Like with JSON, strings and booleans are only kept if selected.
+		switch v := v.(type) {
+		case string, bool:
+			if p.stringFields == nil || !p.stringFields.Match(k) {
+				delete(fields, k)
+			}
+		case time.Time:
+			fields[k] = v.UnixNano()
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// flatten adds the values of nested maps and arrays to fields, joining the
+// keys and indexes with "_".
+func flatten(prefix string, value interface{}, fields map[string]interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "_" + key
+	}
+
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		for k, item := range v {
+			flatten(join(toString(k)), item, fields)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(join(strconv.Itoa(i)), item, fields)
+		}
+	case cbor.Tag:
+		// Tags not known to the decoder are ignored, keeping their content.
+		flatten(prefix, v.Content, fields)
+	case big.Int:
+		// Integers not fitting into 64 bits are kept as floats.
+		f, _ := new(big.Float).SetInt(&v).Float64()
+		fields[prefix] = f
+	case uint64:
+		// Integers fitting into an int64 are kept as such, so that a field
+		// has the same type for positive and negative values.
+		if v <= math.MaxInt64 {
+			fields[prefix] = int64(v)
+		} else {
+			fields[prefix] = v
+		}
+	case []byte:
+		fields[prefix] = string(v)
+	case int64, float64, string, bool, time.Time:
+		fields[prefix] = v
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,302 @@
+package cef
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrNoMetric = errors.New("no metric in line")
+
+// Names of the header fields following the version.
+var headerNames = []string{
+	"device_vendor",
+	"device_product",
+	"device_version",
+	"signature_id",
+	"name",
+	"severity",
+}
+
+type Config struct {
+	MetricName      string
+	TagKeys         []string
+	ResolveLabels   bool
+	TimestampKey    string
+	TimestampFormat string
+	Timezone        string
+	DefaultTags     map[string]string
+}
+
+// Parser decodes messages in the ArcSight Common Event Format, one message
+// per line.
+type Parser struct {
+	metricName      string
+	tagKeys         filter.Filter
+	resolveLabels   bool
+	timestampKey    string
+	timestampFormat string
+	timezone        string
+	defaultTags     map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.TimestampKey != "" && config.TimestampFormat == "" {
+		return nil, errors.New("timestamp format must be specified")
+	}
+
+	tagKeys, err := filter.Compile(config.TagKeys)
+	if err != nil {
+		return nil, fmt.Errorf("compiling tag key filter: %v", err)
+	}
+
+	return &Parser{
+		metricName:      config.MetricName,
+		tagKeys:         tagKeys,
+		resolveLabels:   config.ResolveLabels,
+		timestampKey:    config.TimestampKey,
+		timestampFormat: config.TimestampFormat,
+		timezone:        config.Timezone,
+		defaultTags:     config.DefaultTags,
+		TimeFunc:        time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for i, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m, err := p.parseMessage(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseMessage(line string) (telegraf.Metric, error) {
+	// Messages are often prefixed with a syslog header, which is skipped.
+	start := strings.Index(line, "CEF:")
+	if start < 0 {
+		return nil, errors.New("not a CEF message")
+	}
+
+	header, extension, err := splitHeader(line[start+len("CEF:"):])
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := strconv.ParseInt(strings.TrimSpace(header[0]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q", header[0])
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := map[string]interface{}{
+		"cef_version": version,
+	}
+	for i, name := range headerNames {
+		value := header[i+1]
+		switch {
+		case name == "name":
+			fields[name] = value
+		case value != "":
+			tags[name] = value
+		}
+	}
+
+	values, err := parseExtension(extension)
+	if err != nil {
+		return nil, err
+	}
+	if p.resolveLabels {
+		resolveLabels(values)
+	}
+
+	timestamp := p.TimeFunc()
+	if p.timestampKey != "" {
+		// Not all events of a device carry the same keys, so events without
+		// a timestamp use the current time.
+		if v, ok := values[p.timestampKey]; ok {
+			timestamp, err = internal.ParseTimestamp(p.timestampFormat, v, p.timezone)
+			if err != nil {
+				return nil, err
+			}
+			delete(values, p.timestampKey)
+		}
+	}
+
+	for k, v := range values {
+		if p.tagKeys != nil && p.tagKeys.Match(k) {
+			tags[k] = v
+			continue
+		}
+		fields[k] = convert(v)
+	}
+
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+// splitHeader splits the version and header fields at unescaped pipes, and
+// returns the remainder as the extension.
+func splitHeader(s string) ([]string, string, error) {
+	header := make([]string, 0, len(headerNames)+1)
+	var field strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			field.WriteByte(s[i+1])
+			i++
+		case c == '|':
+			header = append(header, field.String())
+			field.Reset()
+			if len(header) == len(headerNames)+1 {
+				return header, s[i+1:], nil
+			}
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return nil, "", fmt.Errorf("expected %d header fields, got %d", len(headerNames)+1, len(header)+1)
+}
+
+// parseExtension parses the space separated key-value pairs of the
+// extension.  Values may contain spaces, so a value ends where the next key
+// starts.  Equal signs within values must be escaped.
+func parseExtension(s string) (map[string]string, error) {
+	type pair struct {
+		key        string
+		start      int
+		valueStart int
+	}
+
+	var pairs []pair
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=':
+			j := i
+			for j > 0 && isKeyChar(s[j-1]) {
+				j--
+			}
+			if j == i || (j > 0 && s[j-1] != ' ') {
+				continue
+			}
+			pairs = append(pairs, pair{key: s[j:i], start: j, valueStart: i + 1})
+		}
+	}
+
+	if len(pairs) == 0 {
+		if strings.TrimSpace(s) != "" {
+			return nil, fmt.Errorf("invalid extension %q", s)
+		}
+		return map[string]string{}, nil
+	}
+	if strings.TrimSpace(s[:pairs[0].start]) != "" {
+		return nil, fmt.Errorf("invalid extension %q", s)
+	}
+
+	values := make(map[string]string, len(pairs))
+	for n, p := range pairs {
+		end := len(s)
+		if n+1 < len(pairs) {
+			end = pairs[n+1].start
+		}
+		values[p.key] = unescapeValue(strings.TrimRight(s[p.valueStart:end], " "))
+	}
+	return values, nil
+}
+
+func isKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '.' || c == '-' || c == '[' || c == ']'
+}
+
+func unescapeValue(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case '=', '\\', '|':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// resolveLabels renames custom extension keys, such as cs1 or cn2, to the
+// value of their label key, such as cs1Label.
+func resolveLabels(values map[string]string) {
+	labels := make(map[string]string)
+	for k, v := range values {
+		if strings.HasSuffix(k, "Label") {
+			labels[strings.TrimSuffix(k, "Label")] = v
+			delete(values, k)
+		}
+	}
+	for key, label := range labels {
+		if v, ok := values[key]; ok && label != "" {
+			delete(values, key)
+			values[label] = v
+		}
+	}
+}
+
+// convert returns integers and floats as such, and other values as strings.
+func convert(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v
+	}
+	return value
+}
@@ -0,0 +1,125 @@
+package cef
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:      "cef",
+		TagKeys:         []string{"src", "dst"},
+		TimestampKey:    "rt",
+		TimestampFormat: "unix_ms",
+	})
+	require.NoError(t, err)
+
+	data := `<134>Sep 13 12:26:40 fw01 CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 rt=1600000000000 msg=Detected a threat. No action needed. cnt=1.5
+CEF:1|Vendor \| Inc.|Product\\X|2.0|200|Login|Low|suser=admin msg=a\=b c\\d\nnext request=https://example.org/?q\=1`
+
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cef",
+			map[string]string{
+				"device_vendor":  "Security",
+				"device_product": "threatmanager",
+				"device_version": "1.0",
+				"signature_id":   "100",
+				"severity":       "10",
+				"src":            "10.0.0.1",
+				"dst":            "2.1.2.2",
+			},
+			map[string]interface{}{
+				"cef_version": int64(0),
+				"name":        "worm successfully stopped",
+				"spt":         int64(1232),
+				"msg":         "Detected a threat. No action needed.",
+				"cnt":         1.5,
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"cef",
+			map[string]string{
+				"device_vendor":  "Vendor | Inc.",
+				"device_product": `Product\X`,
+				"device_version": "2.0",
+				"signature_id":   "200",
+				"severity":       "Low",
+			},
+			map[string]interface{}{
+				"cef_version": int64(1),
+				"name":        "Login",
+				"suser":       "admin",
+				"msg":         "a=b c\\d\nnext",
+				"request":     "https://example.org/?q=1",
+			},
+			time.Unix(42, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseResolveLabels(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:    "cef",
+		ResolveLabels: true,
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	m, err := parser.ParseLine("CEF:0|V|P|1|1|Event|3|cs1Label=policy cs1=block-all cn1Label=score cn1=7 cs2=unlabeled")
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"cef",
+		map[string]string{
+			"device_vendor":  "V",
+			"device_product": "P",
+			"device_version": "1",
+			"signature_id":   "1",
+			"severity":       "3",
+		},
+		map[string]interface{}{
+			"cef_version": int64(0),
+			"name":        "Event",
+			"policy":      "block-all",
+			"score":       int64(7),
+			"cs2":         "unlabeled",
+		},
+		time.Unix(42, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := New(&Config{MetricName: "cef", TimestampKey: "rt"})
+	require.EqualError(t, err, "timestamp format must be specified")
+
+	parser, err := New(&Config{MetricName: "cef"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("foo=bar"))
+	require.EqualError(t, err, "line 1: not a CEF message")
+
+	_, err = parser.Parse([]byte("CEF:0|V|P|1|1|Event|3|src=a\nCEF:0|V|P|1|1|Event"))
+	require.EqualError(t, err, "line 2: expected 7 header fields, got 6")
+
+	_, err = parser.Parse([]byte("CEF:x|V|P|1|1|Event|3|"))
+	require.EqualError(t, err, `line 1: invalid version "x"`)
+
+	_, err = parser.Parse([]byte("CEF:0|V|P|1|1|Event|3|no pairs"))
+	require.EqualError(t, err, `line 1: invalid extension "no pairs"`)
+
+	metrics, err := parser.Parse([]byte("\n\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+}
@@ -1,12 +1,14 @@
 package csv
 
 import (
+	"bufio"
 	"bytes"
-	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -20,11 +22,15 @@ type Config struct {
 	ColumnNames       []string `toml:"csv_column_names"`
 	ColumnTypes       []string `toml:"csv_column_types"`
 	Comment           string   `toml:"csv_comment"`
+	CommentPrefix     string   `toml:"csv_comment_prefix"`
 	Delimiter         string   `toml:"csv_delimiter"`
+	Escape            string   `toml:"csv_escape"`
 	HeaderRowCount    int      `toml:"csv_header_row_count"`
 	MeasurementColumn string   `toml:"csv_measurement_column"`
 	MetricName        string   `toml:"metric_name"`
+	Quote             string   `toml:"csv_quote"`
 	SkipColumns       int      `toml:"csv_skip_columns"`
+	SkipFooterRows    int      `toml:"csv_skip_footer_rows"`
 	SkipRows          int      `toml:"csv_skip_rows"`
 	TagColumns        []string `toml:"csv_tag_columns"`
 	TimestampColumn   string   `toml:"csv_timestamp_column"`
@@ -33,6 +39,9 @@ type Config struct {
 	TrimSpace         bool     `toml:"csv_trim_space"`
 	SkipValues        []string `toml:"csv_skip_values"`
 
+	ColumnTypeMap map[string]string `toml:"csv_column_type_map"`
+	NullValues    []string          `toml:"csv_null_values"`
+
 	gotColumnNames bool
 
 	TimeFunc    func() time.Time
@@ -42,8 +51,21 @@ type Config struct {
 // Parser is a CSV parser, you should use NewParser to create a new instance.
 type Parser struct {
 	*Config
+
+	// pending holds the lines of a record with a quoted field continuing
+	// on the next line given to ParseLine, guarded by the mutex.
+	sync.Mutex
+	pending      string
+	pendingLines int
 }
 
+// Limits of a record continued over several lines given to ParseLine, so a
+// stray quote doesn't hold back all following lines.
+const (
+	maxPendingLines = 1000
+	maxPendingSize  = 1024 * 1024
+)
+
 func NewParser(c *Config) (*Parser, error) {
 	if c.HeaderRowCount == 0 && len(c.ColumnNames) == 0 {
 		return nil, fmt.Errorf("`csv_header_row_count` must be defined if `csv_column_names` is not specified")
@@ -59,14 +81,44 @@ func NewParser(c *Config) (*Parser, error) {
 	if c.Comment != "" {
 		runeStr := []rune(c.Comment)
 		if len(runeStr) > 1 {
-			return nil, fmt.Errorf("csv_delimiter must be a single character, got: %s", c.Comment)
+			return nil, fmt.Errorf("csv_comment must be a single character, got: %s", c.Comment)
+		}
+	}
+
+	if c.SkipFooterRows < 0 {
+		return nil, fmt.Errorf("csv_skip_footer_rows must not be negative")
+	}
+
+	if c.Quote != "" {
+		runeStr := []rune(c.Quote)
+		if len(runeStr) > 1 {
+			return nil, fmt.Errorf("csv_quote must be a single character, got: %s", c.Quote)
+		}
+	}
+
+	if c.Escape != "" {
+		runeStr := []rune(c.Escape)
+		if len(runeStr) > 1 {
+			return nil, fmt.Errorf("csv_escape must be a single character, got: %s", c.Escape)
 		}
 	}
 
+	if c.Delimiter != "" && (c.Delimiter == c.Quote || c.Delimiter == c.Escape) {
+		return nil, fmt.Errorf("csv_delimiter must differ from csv_quote and csv_escape")
+	}
+
 	if len(c.ColumnNames) > 0 && len(c.ColumnTypes) > 0 && len(c.ColumnNames) != len(c.ColumnTypes) {
 		return nil, fmt.Errorf("csv_column_names field count doesn't match with csv_column_types")
 	}
 
+	for column, typ := range c.ColumnTypeMap {
+		switch typ {
+		case "int", "float", "bool", "string":
+		default:
+			return nil, fmt.Errorf("csv_column_type_map: invalid type %q for column %q", typ, column)
+		}
+	}
+
 	c.gotColumnNames = len(c.ColumnNames) > 0
 
 	if c.TimeFunc == nil {
@@ -80,31 +132,50 @@ func (p *Parser) SetTimeFunc(fn TimeFunc) {
 	p.TimeFunc = fn
 }
 
-func (p *Parser) compile(r io.Reader) (*csv.Reader, error) {
-	csvReader := csv.NewReader(r)
-	// ensures that the reader reads records of different lengths without an error
-	csvReader.FieldsPerRecord = -1
+func (p *Parser) compile(r io.Reader) (*reader, error) {
+	csvReader := &reader{
+		r:                bufio.NewReader(r),
+		comma:            ',',
+		quote:            '"',
+		trimLeadingSpace: p.TrimSpace,
+	}
 	if p.Delimiter != "" {
-		csvReader.Comma = []rune(p.Delimiter)[0]
+		csvReader.comma = []rune(p.Delimiter)[0]
+	}
+	if p.Quote != "" {
+		csvReader.quote = []rune(p.Quote)[0]
+	}
+	if p.Escape != "" {
+		csvReader.escape = []rune(p.Escape)[0]
 	}
 	if p.Comment != "" {
-		csvReader.Comment = []rune(p.Comment)[0]
+		csvReader.comment = []rune(p.Comment)[0]
 	}
-	csvReader.TrimLeadingSpace = p.TrimSpace
+	csvReader.commentPrefix = p.CommentPrefix
 	return csvReader, nil
 }
 
 func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
-	r := bytes.NewReader(buf)
+	metrics := make([]telegraf.Metric, 0)
+	err := p.ParseStream(bytes.NewReader(buf), func(m telegraf.Metric) error {
+		metrics = append(metrics, m)
+		return nil
+	})
+	return metrics, err
+}
+
+// ParseStream parses the records read from r and calls fn for each metric as
+// soon as it is parsed.
+func (p *Parser) ParseStream(r io.Reader, fn func(telegraf.Metric) error) error {
 	csvReader, err := p.compile(r)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	// skip first rows
 	for i := 0; i < p.SkipRows; i++ {
 		_, err := csvReader.Read()
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 	// if there is a header and we did not get DataColumns
@@ -117,7 +188,7 @@ func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 		for i := 0; i < p.HeaderRowCount; i++ {
 			header, err := csvReader.Read()
 			if err != nil {
-				return nil, err
+				return err
 			}
 			//concatenate header names
 			for i := range header {
@@ -138,31 +209,57 @@ func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 		for i := 0; i < p.HeaderRowCount; i++ {
 			_, err := csvReader.Read()
 			if err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
 
-	table, err := csvReader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
+	// records are held back until they can't be part of the footer, such
+	// as a row of totals
+	pending := make([][]string, 0, p.SkipFooterRows+1)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		pending = append(pending, record)
+		if len(pending) <= p.SkipFooterRows {
+			continue
+		}
+		record, pending = pending[0], pending[1:]
 
-	metrics := make([]telegraf.Metric, 0)
-	for _, record := range table {
 		m, err := p.parseRecord(record)
 		if err != nil {
-			return metrics, err
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
 		}
-		metrics = append(metrics, m)
 	}
-	return metrics, nil
 }
 
 // ParseLine does not use any information in header and assumes DataColumns is set
-// it will also not skip any rows
+// it will also not skip any header or footer rows.  Lines ending inside a quoted field are kept
+// until the record is completed by the following lines, no metric is returned
+// for them.  An error is returned and the lines are dropped when the record
+// grows beyond 1000 lines or 1MiB.
 func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
-	r := bytes.NewReader([]byte(line))
+	p.Lock()
+	defer p.Unlock()
+
+	lines := 1
+	if p.pending != "" {
+		line = p.pending + "\n" + line
+		lines += p.pendingLines
+		p.pending = ""
+		p.pendingLines = 0
+	}
+
+	r := strings.NewReader(line)
 	csvReader, err := p.compile(r)
 	if err != nil {
 		return nil, err
@@ -174,6 +271,14 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 	}
 
 	record, err := csvReader.Read()
+	if errors.Is(err, errUnterminatedQuote) {
+		if lines >= maxPendingLines || len(line) > maxPendingSize {
+			return nil, fmt.Errorf("quoted field not terminated after %d lines", lines)
+		}
+		p.pending = line
+		p.pendingLines = lines
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -205,6 +310,13 @@ outer:
 				}
 			}
 
+			// values representing a missing value are omitted
+			for _, s := range p.NullValues {
+				if strings.EqualFold(value, s) {
+					continue outer
+				}
+			}
+
 			for _, tagName := range p.TagColumns {
 				if tagName == fieldName {
 					tags[tagName] = value
@@ -218,6 +330,16 @@ outer:
 				continue
 			}
 
+			// Columns with an explicit type are always converted to that type.
+			if typ, ok := p.ColumnTypeMap[fieldName]; ok {
+				val, err := convertType(typ, value)
+				if err != nil {
+					return nil, fmt.Errorf("column %q: %v", fieldName, err)
+				}
+				recordFields[fieldName] = val
+				continue
+			}
+
 			// Try explicit conversion only when column types is defined.
 			if len(p.ColumnTypes) > 0 {
 				// Throw error if current column count exceeds defined types.
@@ -225,27 +347,9 @@ outer:
 					return nil, fmt.Errorf("column type: column count exceeded")
 				}
 
-				var val interface{}
-				var err error
-
-				switch p.ColumnTypes[i] {
-				case "int":
-					val, err = strconv.ParseInt(value, 10, 64)
-					if err != nil {
-						return nil, fmt.Errorf("column type: parse int error %s", err)
-					}
-				case "float":
-					val, err = strconv.ParseFloat(value, 64)
-					if err != nil {
-						return nil, fmt.Errorf("column type: parse float error %s", err)
-					}
-				case "bool":
-					val, err = strconv.ParseBool(value)
-					if err != nil {
-						return nil, fmt.Errorf("column type: parse bool error %s", err)
-					}
-				default:
-					val = value
+				val, err := convertType(p.ColumnTypes[i], value)
+				if err != nil {
+					return nil, err
 				}
 
 				recordFields[fieldName] = val
@@ -294,6 +398,33 @@ outer:
 	return m, nil
 }
 
+// convertType converts the value to the given column type, unknown types are
+// kept as string.
+func convertType(typ string, value string) (interface{}, error) {
+	switch typ {
+	case "int":
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column type: parse int error %s", err)
+		}
+		return val, nil
+	case "float":
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column type: parse float error %s", err)
+		}
+		return val, nil
+	case "bool":
+		val, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("column type: parse bool error %s", err)
+		}
+		return val, nil
+	default:
+		return value, nil
+	}
+}
+
 // ParseTimestamp return a timestamp, if there is no timestamp on the csv it
 // will be the current timestamp, else it will try to parse the time according
 // to the format.
@@ -667,3 +667,258 @@ func TestSkipSpecifiedStringValue(t *testing.T) {
 	}
 	testutil.RequireMetricsEqual(t, expected, metrics, testutil.IgnoreTime())
 }
+
+func TestMultilineQuotedField(t *testing.T) {
+	p, err := NewParser(
+		&Config{
+			MetricName:     "csv",
+			HeaderRowCount: 1,
+			TagColumns:     []string{"host"},
+			TimeFunc:       DefaultTime,
+		},
+	)
+	require.NoError(t, err)
+	testCSV := "host,message,count\r\n" +
+		"server01,\"disk full\r\non /var, \"\"cleanup\"\" required\",3\r\n" +
+		"server02,ok,1\r\n"
+	metrics, err := p.Parse([]byte(testCSV))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("csv",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{
+				"message": "disk full\non /var, \"cleanup\" required",
+				"count":   int64(3),
+			},
+			time.Unix(3600, 0),
+		),
+		testutil.MustMetric("csv",
+			map[string]string{"host": "server02"},
+			map[string]interface{}{
+				"message": "ok",
+				"count":   int64(1),
+			},
+			time.Unix(3600, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	_, err = p.Parse([]byte("host,message\nserver01,\"unterminated\n"))
+	require.EqualError(t, err, "line 2: unterminated quoted field")
+}
+
+func TestQuoteAndEscape(t *testing.T) {
+	p, err := NewParser(
+		&Config{
+			MetricName:  "csv",
+			ColumnNames: []string{"name", "path", "size"},
+			Delimiter:   ";",
+			Quote:       "'",
+			Escape:      `\`,
+			TimeFunc:    DefaultTime,
+		},
+	)
+	require.NoError(t, err)
+	testCSV := `'it\'s here';C:\\temp\;old;12
+"quoted";'a;b';7`
+	metrics, err := p.Parse([]byte(testCSV))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("csv",
+			map[string]string{},
+			map[string]interface{}{
+				"name": "it's here",
+				"path": `C:\temp;old`,
+				"size": int64(12),
+			},
+			time.Unix(3600, 0),
+		),
+		testutil.MustMetric("csv",
+			map[string]string{},
+			map[string]interface{}{
+				"name": `"quoted"`,
+				"path": "a;b",
+				"size": int64(7),
+			},
+			time.Unix(3600, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	_, err = p.Parse([]byte("'name'x;a;1"))
+	require.EqualError(t, err, `line 1: extraneous 'x' after quoted field`)
+}
+
+func TestParseLineMultiline(t *testing.T) {
+	p, err := NewParser(
+		&Config{
+			MetricName:  "csv",
+			ColumnNames: []string{"message", "count"},
+			TimeFunc:    DefaultTime,
+		},
+	)
+	require.NoError(t, err)
+
+	m, err := p.ParseLine(`"first line`)
+	require.NoError(t, err)
+	require.Nil(t, m)
+
+	m, err = p.ParseLine(`second line`)
+	require.NoError(t, err)
+	require.Nil(t, m)
+
+	m, err = p.ParseLine(`third line",5`)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			testutil.MustMetric("csv",
+				map[string]string{},
+				map[string]interface{}{
+					"message": "first line\nsecond line\nthird line",
+					"count":   int64(5),
+				},
+				time.Unix(3600, 0),
+			),
+		},
+		[]telegraf.Metric{m},
+	)
+
+	m, err = p.ParseLine(`single,6`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"message": "single", "count": int64(6)}, m.Fields())
+}
+
+func TestParseLineMultilineLimit(t *testing.T) {
+	p, err := NewParser(
+		&Config{
+			MetricName:  "csv",
+			ColumnNames: []string{"message", "count"},
+			TimeFunc:    DefaultTime,
+		},
+	)
+	require.NoError(t, err)
+
+	m, err := p.ParseLine(`"stray quote`)
+	require.NoError(t, err)
+	require.Nil(t, m)
+	for i := 2; i < maxPendingLines; i++ {
+		m, err = p.ParseLine(`line`)
+		require.NoError(t, err)
+		require.Nil(t, m)
+	}
+	_, err = p.ParseLine(`line`)
+	require.EqualError(t, err, "quoted field not terminated after 1000 lines")
+
+	// The lines are dropped and parsing continues.
+	m, err = p.ParseLine(`single,6`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"message": "single", "count": int64(6)}, m.Fields())
+}
+
+func TestQuoteConfigErrors(t *testing.T) {
+	_, err := NewParser(&Config{HeaderRowCount: 1, Quote: "''"})
+	require.EqualError(t, err, "csv_quote must be a single character, got: ''")
+
+	_, err = NewParser(&Config{HeaderRowCount: 1, Delimiter: "|", Escape: "|"})
+	require.EqualError(t, err, "csv_delimiter must differ from csv_quote and csv_escape")
+}
+
+func TestSkipFooterRowsAndCommentPrefix(t *testing.T) {
+	p, err := NewParser(
+		&Config{
+			MetricName:     "csv",
+			HeaderRowCount: 1,
+			SkipFooterRows: 2,
+			CommentPrefix:  "//",
+			TimeFunc:       DefaultTime,
+		},
+	)
+	require.NoError(t, err)
+	testCSV := `// exported by the billing system
+item,amount
+// first quarter
+apples,10
+/pears,20
+total,30
+rows,2`
+	metrics, err := p.Parse([]byte(testCSV))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("csv",
+			map[string]string{},
+			map[string]interface{}{
+				"item":   "apples",
+				"amount": int64(10),
+			},
+			time.Unix(3600, 0),
+		),
+		testutil.MustMetric("csv",
+			map[string]string{},
+			map[string]interface{}{
+				"item":   "/pears",
+				"amount": int64(20),
+			},
+			time.Unix(3600, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	metrics, err = p.Parse([]byte("item,amount\ntotal,0\n"))
+	require.NoError(t, err)
+	require.Empty(t, metrics)
+}
+
+func TestColumnTypeMapAndNullValues(t *testing.T) {
+	p, err := NewParser(
+		&Config{
+			MetricName:     "csv",
+			HeaderRowCount: 1,
+			ColumnTypeMap:  map[string]string{"reading": "float", "serial": "string"},
+			NullValues:     []string{"", "NA", "null"},
+			TimeFunc:       DefaultTime,
+		},
+	)
+	require.NoError(t, err)
+	testCSV := `serial,reading,status
+0042,12,ok
+0043,NULL,na
+0044,,`
+	metrics, err := p.Parse([]byte(testCSV))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("csv",
+			map[string]string{},
+			map[string]interface{}{
+				"serial":  "0042",
+				"reading": 12.0,
+				"status":  "ok",
+			},
+			time.Unix(3600, 0),
+		),
+		testutil.MustMetric("csv",
+			map[string]string{},
+			map[string]interface{}{
+				"serial": "0043",
+			},
+			time.Unix(3600, 0),
+		),
+		testutil.MustMetric("csv",
+			map[string]string{},
+			map[string]interface{}{
+				"serial": "0044",
+			},
+			time.Unix(3600, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	_, err = p.Parse([]byte("serial,reading\n0045,n/a"))
+	require.EqualError(t, err, `column "reading": column type: parse float error strconv.ParseFloat: parsing "n/a": invalid syntax`)
+
+	_, err = NewParser(&Config{HeaderRowCount: 1, ColumnTypeMap: map[string]string{"a": "integer"}})
+	require.EqualError(t, err, `csv_column_type_map: invalid type "integer" for column "a"`)
+}
@@ -0,0 +1,138 @@
+package csv
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// errUnterminatedQuote is returned when the input ends inside a quoted field.
+var errUnterminatedQuote = errors.New("unterminated quoted field")
+
+// reader splits CSV data into records as described in RFC 4180.  Unlike
+// encoding/csv the quote character is configurable and an escape character
+// can be used in addition to doubling the quote.  Quoted fields may span
+// multiple lines, the line breaks are kept as "\n".
+type reader struct {
+	r                *bufio.Reader
+	comma            rune
+	quote            rune
+	escape           rune
+	comment          rune
+	commentPrefix    string
+	trimLeadingSpace bool
+
+	line int
+}
+
+// Read returns the next record, empty lines and comments are skipped.
+func (r *reader) Read() ([]string, error) {
+	line, err := r.readLine()
+	for err == nil && (line == "" || r.isComment(line)) {
+		line, err = r.readLine()
+	}
+	if err != nil {
+		return nil, err
+	}
+	start := r.line
+
+	var record []string
+	var field strings.Builder
+	runes := []rune(line)
+	i := 0
+	for {
+		field.Reset()
+		if r.trimLeadingSpace {
+			for i < len(runes) && unicode.IsSpace(runes[i]) {
+				i++
+			}
+		}
+
+		if i < len(runes) && runes[i] == r.quote {
+			i++
+		quoted:
+			for {
+				if i >= len(runes) {
+					// The field continues on the next line.
+					next, err := r.readLine()
+					if err == io.EOF {
+						return nil, fmt.Errorf("line %d: %w", start, errUnterminatedQuote)
+					}
+					if err != nil {
+						return nil, err
+					}
+					field.WriteByte('\n')
+					runes, i = []rune(next), 0
+					continue
+				}
+
+				c := runes[i]
+				switch {
+				case r.escape != 0 && c == r.escape && r.escape != r.quote:
+					// An escape at the end of the line escapes the line break.
+					if i+1 < len(runes) {
+						field.WriteRune(runes[i+1])
+					}
+					i += 2
+				case c == r.quote && i+1 < len(runes) && runes[i+1] == r.quote:
+					field.WriteRune(c)
+					i += 2
+				case c == r.quote:
+					i++
+					break quoted
+				default:
+					field.WriteRune(c)
+					i++
+				}
+			}
+			if i < len(runes) && runes[i] != r.comma {
+				return nil, fmt.Errorf("line %d: extraneous %q after quoted field", r.line, runes[i])
+			}
+		} else {
+			for i < len(runes) && runes[i] != r.comma {
+				c := runes[i]
+				switch {
+				case r.escape != 0 && c == r.escape && r.escape != r.quote && i+1 < len(runes):
+					field.WriteRune(runes[i+1])
+					i += 2
+				case c == r.quote:
+					return nil, fmt.Errorf("line %d: bare %q in non-quoted field", r.line, c)
+				default:
+					field.WriteRune(c)
+					i++
+				}
+			}
+		}
+
+		record = append(record, field.String())
+		if i >= len(runes) {
+			return record, nil
+		}
+		// skip the delimiter
+		i++
+	}
+}
+
+func (r *reader) isComment(line string) bool {
+	if r.comment != 0 && strings.HasPrefix(line, string(r.comment)) {
+		return true
+	}
+	return r.commentPrefix != "" && strings.HasPrefix(line, r.commentPrefix)
+}
+
+// readLine returns the next line without the line break.
+func (r *reader) readLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	r.line++
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
@@ -0,0 +1,123 @@
+package csv
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestReaderMatchesEncodingCSV checks that the reader splits records the same
+// way as encoding/csv with the default quote and no escape character.
+func TestReaderMatchesEncodingCSV(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "plain",
+			input: "a,b,c\n1,2,3\n",
+		},
+		{
+			name:  "no final line break",
+			input: "a,b,c\n1,2,3",
+		},
+		{
+			name:  "quoted delimiter",
+			input: "a,\"b,c\",d\n",
+		},
+		{
+			name:  "doubled quotes",
+			input: "\"say \"\"hi\"\"\",x\n",
+		},
+		{
+			name:  "empty quoted field",
+			input: "\"\",a,\"\"\n",
+		},
+		{
+			name:  "crlf",
+			input: "a,b\r\n1,2\r\n",
+		},
+		{
+			name:  "empty trailing fields",
+			input: "a,b,\n1,,\n",
+		},
+		{
+			name:  "single empty field",
+			input: "a\n,\n",
+		},
+		{
+			name:  "quoted line break",
+			input: "a,\"b\nc\",d\n",
+		},
+		{
+			name:  "empty lines",
+			input: "a,b\n\n1,2\n\n",
+		},
+		{
+			name:  "unicode",
+			input: "héllo,wörld\n\"ünï\",\"cödé\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected, err := readAll(csv.NewReader(strings.NewReader(tt.input)))
+			require.NoError(t, err)
+
+			r := &reader{r: bufio.NewReader(strings.NewReader(tt.input)), comma: ',', quote: '"'}
+			actual, err := readAll(r)
+			require.NoError(t, err)
+			require.Equal(t, expected, actual)
+		})
+	}
+}
+
+// TestReaderEscape checks that an escaped quote reads like a doubled one.
+func TestReaderEscape(t *testing.T) {
+	expected, err := readAll(csv.NewReader(strings.NewReader("\"say \"\"hi\"\"\",x\n")))
+	require.NoError(t, err)
+
+	input := `"say \"hi\"",x` + "\n"
+	r := &reader{r: bufio.NewReader(strings.NewReader(input)), comma: ',', quote: '"', escape: '\\'}
+	actual, err := readAll(r)
+	require.NoError(t, err)
+	require.Equal(t, expected, actual)
+}
+
+// TestReaderErrors checks that input rejected by encoding/csv is rejected.
+func TestReaderErrors(t *testing.T) {
+	for _, input := range []string{
+		"a,\"b\n",
+		"a,b\"c\n",
+		"\"a\"b,c\n",
+	} {
+		_, err := readAll(csv.NewReader(strings.NewReader(input)))
+		require.Error(t, err, input)
+
+		r := &reader{r: bufio.NewReader(strings.NewReader(input)), comma: ',', quote: '"'}
+		_, err = readAll(r)
+		require.Error(t, err, input)
+	}
+}
+
+func readAll(r interface{ Read() ([]string, error) }) ([][]string, error) {
+	if r, ok := r.(*csv.Reader); ok {
+		// The number of fields is checked by the parser.
+		r.FieldsPerRecord = -1
+	}
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,56 @@
+package parsers
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// defaultsParser completes the metrics of the parser with default and
+// constant fields and tags.  Defaults are only added when the metric is
+// missing the key, constants replace parsed values.
+type defaultsParser struct {
+	Parser
+	defaultFields  map[string]interface{}
+	defaultTags    map[string]string
+	constantFields map[string]interface{}
+	constantTags   map[string]string
+}
+
+func (p *defaultsParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics, err := p.Parser.Parse(buf)
+	for _, m := range metrics {
+		p.apply(m)
+	}
+	return metrics, err
+}
+
+func (p *defaultsParser) ParseLine(line string) (telegraf.Metric, error) {
+	m, err := p.Parser.ParseLine(line)
+	if m != nil {
+		p.apply(m)
+	}
+	return m, err
+}
+
+// Unwrap returns the wrapped parser.
+func (p *defaultsParser) Unwrap() Parser {
+	return p.Parser
+}
+
+func (p *defaultsParser) apply(m telegraf.Metric) {
+	for k, v := range p.defaultFields {
+		if !m.HasField(k) {
+			m.AddField(k, v)
+		}
+	}
+	for k, v := range p.defaultTags {
+		if !m.HasTag(k) {
+			m.AddTag(k, v)
+		}
+	}
+	for k, v := range p.constantFields {
+		m.AddField(k, v)
+	}
+	for k, v := range p.constantTags {
+		m.AddTag(k, v)
+	}
+}
@@ -0,0 +1,45 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataDefaultsAndConstants(t *testing.T) {
+	parser, err := NewParser(&Config{
+		DataFormat:         "influx",
+		DataDefaultFields:  map[string]interface{}{"errors": int64(0), "status": "unknown"},
+		DataDefaultTags:    map[string]string{"region": "eu"},
+		DataConstantFields: map[string]interface{}{"schema": int64(2)},
+		DataConstantTags:   map[string]string{"source": "partner"},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse([]byte("orders,region=us,source=x count=3i,errors=1i,schema=1i 1600000000000000000\n" +
+		"orders count=5i 1600000000000000000\n"))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"orders",
+			map[string]string{"region": "us", "source": "partner"},
+			map[string]interface{}{"count": int64(3), "errors": int64(1), "status": "unknown", "schema": int64(2)},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"orders",
+			map[string]string{"region": "eu", "source": "partner"},
+			map[string]interface{}{"count": int64(5), "errors": int64(0), "status": "unknown", "schema": int64(2)},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	m, err := parser.ParseLine("orders count=1i 1600000000000000000")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"region": "eu", "source": "partner"}, m.Tags())
+}
@@ -0,0 +1,566 @@
+package evtx
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// The layout of the files, chunks, records and binary XML follows the
+// documentation of the libevtx project.
+const (
+	fileSignature  = "ElfFile\x00"
+	chunkSignature = "ElfChnk\x00"
+	recordMagic    = 0x00002a2a
+
+	fileHeaderSize   = 4096
+	chunkSize        = 65536
+	chunkHeaderSize  = 512
+	recordHeaderSize = 24
+
+	maxDepth = 64
+)
+
+// Binary XML tokens, the 0x40 flag marks elements with attributes and
+// attributes or values followed by more of them.
+const (
+	tokenEOF                  = 0x00
+	tokenOpenStartElement     = 0x01
+	tokenCloseStartElement    = 0x02
+	tokenCloseEmptyElement    = 0x03
+	tokenEndElement           = 0x04
+	tokenValue                = 0x05
+	tokenAttribute            = 0x06
+	tokenCDATA                = 0x07
+	tokenCharRef              = 0x08
+	tokenEntityRef            = 0x09
+	tokenPITarget             = 0x0a
+	tokenPIData               = 0x0b
+	tokenTemplateInstance     = 0x0c
+	tokenNormalSubstitution   = 0x0d
+	tokenOptionalSubstitution = 0x0e
+	tokenFragmentHeader       = 0x0f
+
+	tokenMoreFlag = 0x40
+)
+
+// Types of values and substitutions, the 0x80 flag marks arrays.
+const (
+	typeNull       = 0x00
+	typeString     = 0x01
+	typeAnsiString = 0x02
+	typeInt8       = 0x03
+	typeUInt8      = 0x04
+	typeInt16      = 0x05
+	typeUInt16     = 0x06
+	typeInt32      = 0x07
+	typeUInt32     = 0x08
+	typeInt64      = 0x09
+	typeUInt64     = 0x0a
+	typeReal32     = 0x0b
+	typeReal64     = 0x0c
+	typeBool       = 0x0d
+	typeBinary     = 0x0e
+	typeGUID       = 0x0f
+	typeSizeT      = 0x10
+	typeFileTime   = 0x11
+	typeSysTime    = 0x12
+	typeSID        = 0x13
+	typeHexInt32   = 0x14
+	typeHexInt64   = 0x15
+	typeBinXML     = 0x21
+
+	typeArrayFlag = 0x80
+)
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+// element is a decoded XML element.
+type element struct {
+	name     string
+	attrs    []attribute
+	children []*element
+	text     string
+}
+
+type attribute struct {
+	name  string
+	value string
+}
+
+// child returns the first child element with the given name.
+func (e *element) child(name string) *element {
+	if e == nil {
+		return nil
+	}
+	for _, c := range e.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// attr returns the value of the attribute with the given name.
+func (e *element) attr(name string) string {
+	if e == nil {
+		return ""
+	}
+	for _, a := range e.attrs {
+		if a.name == name {
+			return a.value
+		}
+	}
+	return ""
+}
+
+func (e *element) value() string {
+	if e == nil {
+		return ""
+	}
+	return strings.TrimSpace(e.text)
+}
+
+// substitution is a value of a template instance.
+type substitution struct {
+	typ    byte
+	offset int
+	data   []byte
+}
+
+// reader reads little endian values from a chunk, with offsets relative to
+// the start of the chunk.  Reading past the end sets err and returns zero
+// values.
+type reader struct {
+	chunk []byte
+	pos   int
+	end   int
+	err   error
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > r.end {
+		r.err = errUnexpectedEnd
+		return nil
+	}
+	b := r.chunk[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) u8() byte {
+	b := r.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (r *reader) u16() uint16 {
+	b := r.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *reader) u32() uint32 {
+	b := r.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+func (r *reader) peek() byte {
+	if r.err != nil {
+		return tokenEOF
+	}
+	if r.pos >= r.end {
+		r.err = errUnexpectedEnd
+		return tokenEOF
+	}
+	return r.chunk[r.pos]
+}
+
+// decoder decodes the binary XML of the records of a chunk.
+type decoder struct {
+	chunk []byte
+}
+
+// decode decodes a binary XML fragment from start to end, returning its root
+// element.
+func (d *decoder) decode(start, end int) (*element, error) {
+	r := &reader{chunk: d.chunk, pos: start, end: end}
+	root := &element{}
+	d.fragment(r, root, nil, 0)
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(root.children) == 0 {
+		return nil, errors.New("no event element")
+	}
+	return root.children[0], nil
+}
+
+// fragment decodes the elements of a fragment, adding them to parent.
+func (d *decoder) fragment(r *reader, parent *element, values []substitution, depth int) {
+	if depth > maxDepth {
+		r.err = errors.New("nested too deep")
+		return
+	}
+	for r.err == nil && r.pos < r.end {
+		switch token := r.u8(); token {
+		case tokenEOF:
+			return
+		case tokenFragmentHeader:
+			r.bytes(3)
+		case tokenTemplateInstance:
+			d.templateInstance(r, parent, depth)
+		case tokenOpenStartElement, tokenOpenStartElement | tokenMoreFlag:
+			d.element(r, token, parent, values, depth)
+		default:
+			r.err = fmt.Errorf("unexpected token 0x%02x at offset %d", token, r.pos-1)
+		}
+	}
+}
+
+// templateInstance decodes a template definition, inline or found earlier in
+// the chunk, with the substitution values following it.
+func (d *decoder) templateInstance(r *reader, parent *element, depth int) {
+	r.bytes(1 + 4)
+	offset := int(r.u32())
+	if r.err != nil {
+		return
+	}
+
+	// Skip the next template offset and the GUID to the size of the data.
+	t := &reader{chunk: d.chunk, pos: offset + 20, end: len(d.chunk)}
+	size := int(t.u32())
+	if t.err != nil {
+		r.err = t.err
+		return
+	}
+	if offset == r.pos {
+		r.bytes(24 + size)
+	}
+
+	count := int(r.u32())
+	descriptors := r.bytes(4 * count)
+	if r.err != nil {
+		return
+	}
+	values := make([]substitution, 0, count)
+	for i := 0; i < count; i++ {
+		size := int(binary.LittleEndian.Uint16(descriptors[4*i:]))
+		typ := descriptors[4*i+2]
+		pos := r.pos
+		data := r.bytes(size)
+		values = append(values, substitution{typ: typ, offset: pos, data: data})
+	}
+	if r.err != nil {
+		return
+	}
+
+	t.end = t.pos + size
+	if t.end > len(d.chunk) {
+		r.err = errUnexpectedEnd
+		return
+	}
+	d.fragment(t, parent, values, depth+1)
+	if t.err != nil {
+		r.err = t.err
+	}
+}
+
+func (d *decoder) element(r *reader, token byte, parent *element, values []substitution, depth int) {
+	// Skip the dependency identifier and size of the element.
+	r.bytes(2 + 4)
+	e := &element{name: d.name(r)}
+
+	if token&tokenMoreFlag != 0 {
+		r.bytes(4)
+		for r.err == nil {
+			next := r.peek()
+			if next&^tokenMoreFlag != tokenAttribute {
+				break
+			}
+			r.u8()
+			name := d.name(r)
+			value := d.content(r, nil, values, depth)
+			e.attrs = append(e.attrs, attribute{name: name, value: value})
+		}
+	}
+
+	switch token := r.u8(); token {
+	case tokenCloseEmptyElement:
+	case tokenCloseStartElement:
+		e.text = d.content(r, e, values, depth)
+		if r.u8() != tokenEndElement && r.err == nil {
+			r.err = fmt.Errorf("missing end of element %q", e.name)
+		}
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unexpected token 0x%02x in element %q", token, e.name)
+		}
+	}
+	parent.children = append(parent.children, e)
+}
+
+// content decodes the text and child elements of an element or the value of
+// an attribute when parent is nil, up to the next token ending them.
+func (d *decoder) content(r *reader, parent *element, values []substitution, depth int) string {
+	var text strings.Builder
+	for r.err == nil {
+		token := r.peek()
+		switch token {
+		case tokenValue, tokenValue | tokenMoreFlag:
+			r.u8()
+			r.u8()
+			text.WriteString(d.utf16(r, int(r.u16())))
+		case tokenCDATA:
+			r.u8()
+			text.WriteString(d.utf16(r, int(r.u16())))
+		case tokenCharRef:
+			r.u8()
+			text.WriteRune(rune(r.u16()))
+		case tokenEntityRef:
+			r.u8()
+			switch d.name(r) {
+			case "amp":
+				text.WriteByte('&')
+			case "lt":
+				text.WriteByte('<')
+			case "gt":
+				text.WriteByte('>')
+			case "quot":
+				text.WriteByte('"')
+			case "apos":
+				text.WriteByte('\'')
+			}
+		case tokenNormalSubstitution, tokenOptionalSubstitution:
+			r.u8()
+			id := int(r.u16())
+			r.u8()
+			if r.err != nil {
+				break
+			}
+			if id >= len(values) {
+				r.err = fmt.Errorf("missing substitution %d", id)
+				break
+			}
+			v := values[id]
+			if v.typ == typeBinXML && parent != nil {
+				s := &reader{chunk: d.chunk, pos: v.offset, end: v.offset + len(v.data)}
+				d.fragment(s, parent, nil, depth+1)
+				if s.err != nil {
+					r.err = s.err
+				}
+				break
+			}
+			text.WriteString(render(v.typ, v.data))
+		case tokenOpenStartElement, tokenOpenStartElement | tokenMoreFlag:
+			if parent == nil {
+				return text.String()
+			}
+			d.element(r, r.u8(), parent, values, depth)
+		case tokenPITarget:
+			r.u8()
+			d.name(r)
+		case tokenPIData:
+			r.u8()
+			d.utf16(r, int(r.u16()))
+		default:
+			return text.String()
+		}
+	}
+	return text.String()
+}
+
+// name returns the name at the offset read from r, skipping the name if it
+// is stored inline.
+func (d *decoder) name(r *reader) string {
+	offset := int(r.u32())
+	if r.err != nil {
+		return ""
+	}
+	n := &reader{chunk: d.chunk, pos: offset + 6, end: len(d.chunk)}
+	name := d.utf16(n, int(n.u16()))
+	if n.err != nil {
+		r.err = n.err
+		return ""
+	}
+	if offset == r.pos {
+		// Skip the name and its terminating null character.
+		r.pos = n.pos + 2
+	}
+	return name
+}
+
+func (d *decoder) utf16(r *reader, count int) string {
+	b := r.bytes(2 * count)
+	if b == nil {
+		return ""
+	}
+	return decodeUTF16(b)
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, binary.LittleEndian.Uint16(b[i:]))
+	}
+	for len(u) > 0 && u[len(u)-1] == 0 {
+		u = u[:len(u)-1]
+	}
+	return string(utf16.Decode(u))
+}
+
+// render formats a value the way the event viewer shows it in the XML view.
+func render(typ byte, b []byte) string {
+	if typ&typeArrayFlag != 0 {
+		return renderArray(typ&^typeArrayFlag, b)
+	}
+
+	switch typ {
+	case typeNull:
+		return ""
+	case typeString:
+		return decodeUTF16(b)
+	case typeAnsiString:
+		return strings.TrimRight(string(b), "\x00")
+	case typeInt8:
+		if len(b) >= 1 {
+			return strconv.FormatInt(int64(int8(b[0])), 10)
+		}
+	case typeUInt8:
+		if len(b) >= 1 {
+			return strconv.FormatUint(uint64(b[0]), 10)
+		}
+	case typeInt16:
+		if len(b) >= 2 {
+			return strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(b))), 10)
+		}
+	case typeUInt16:
+		if len(b) >= 2 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(b)), 10)
+		}
+	case typeInt32:
+		if len(b) >= 4 {
+			return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(b))), 10)
+		}
+	case typeUInt32:
+		if len(b) >= 4 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b)), 10)
+		}
+	case typeInt64:
+		if len(b) >= 8 {
+			return strconv.FormatInt(int64(binary.LittleEndian.Uint64(b)), 10)
+		}
+	case typeUInt64:
+		if len(b) >= 8 {
+			return strconv.FormatUint(binary.LittleEndian.Uint64(b), 10)
+		}
+	case typeReal32:
+		if len(b) >= 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), 'g', -1, 32)
+		}
+	case typeReal64:
+		if len(b) >= 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)), 'g', -1, 64)
+		}
+	case typeBool:
+		if len(b) >= 4 {
+			return strconv.FormatBool(binary.LittleEndian.Uint32(b) != 0)
+		}
+	case typeGUID:
+		if len(b) >= 16 {
+			return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}",
+				binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint16(b[4:]),
+				binary.LittleEndian.Uint16(b[6:]), b[8:10], b[10:16])
+		}
+	case typeSizeT, typeHexInt32, typeHexInt64:
+		switch len(b) {
+		case 4:
+			return fmt.Sprintf("0x%x", binary.LittleEndian.Uint32(b))
+		case 8:
+			return fmt.Sprintf("0x%x", binary.LittleEndian.Uint64(b))
+		}
+	case typeFileTime:
+		if len(b) >= 8 {
+			return fileTime(binary.LittleEndian.Uint64(b)).Format(time.RFC3339Nano)
+		}
+	case typeSysTime:
+		if len(b) >= 16 {
+			v := func(i int) int { return int(binary.LittleEndian.Uint16(b[2*i:])) }
+			return time.Date(v(0), time.Month(v(1)), v(3), v(4), v(5), v(6), v(7)*int(time.Millisecond), time.UTC).Format(time.RFC3339Nano)
+		}
+	case typeSID:
+		return sid(b)
+	}
+	return strings.ToUpper(hex.EncodeToString(b))
+}
+
+func renderArray(typ byte, b []byte) string {
+	var items []string
+	switch typ {
+	case typeString:
+		items = strings.Split(decodeUTF16(b), "\x00")
+	case typeAnsiString:
+		items = strings.Split(strings.TrimRight(string(b), "\x00"), "\x00")
+	default:
+		size := map[byte]int{
+			typeInt8: 1, typeUInt8: 1, typeInt16: 2, typeUInt16: 2,
+			typeInt32: 4, typeUInt32: 4, typeHexInt32: 4, typeReal32: 4, typeBool: 4,
+			typeInt64: 8, typeUInt64: 8, typeHexInt64: 8, typeReal64: 8, typeFileTime: 8,
+			typeGUID: 16, typeSysTime: 16,
+		}[typ]
+		if size == 0 {
+			return strings.ToUpper(hex.EncodeToString(b))
+		}
+		for i := 0; i+size <= len(b); i += size {
+			items = append(items, render(typ, b[i:i+size]))
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// fileTime converts a Windows FILETIME, in 100 nanosecond intervals since
+// 1601-01-01, to a time.
+func fileTime(v uint64) time.Time {
+	const epochDiff = 116444736000000000
+	if v < epochDiff {
+		return time.Unix(0, 0).UTC()
+	}
+	v -= epochDiff
+	return time.Unix(int64(v/1e7), int64(v%1e7)*100).UTC()
+}
+
+// sid formats a security identifier as S-R-I-S-S...
+func sid(b []byte) string {
+	if len(b) < 8 {
+		return strings.ToUpper(hex.EncodeToString(b))
+	}
+	count := int(b[1])
+	if len(b) < 8+4*count {
+		return strings.ToUpper(hex.EncodeToString(b))
+	}
+	var authority uint64
+	for _, c := range b[2:8] {
+		authority = authority<<8 | uint64(c)
+	}
+	s := fmt.Sprintf("S-%d-%d", b[0], authority)
+	for i := 0; i < count; i++ {
+		s += fmt.Sprintf("-%d", binary.LittleEndian.Uint32(b[8+4*i:]))
+	}
+	return s
+}
@@ -0,0 +1,283 @@
+package evtx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var (
+	DefaultEventTags = []string{"Source", "EventID", "Level", "LevelText", "Channel", "Computer"}
+
+	ErrLineNotSupported = errors.New("evtx files can't be parsed by line")
+)
+
+type Config struct {
+	MetricName    string
+	EventTags     []string
+	EventFields   []string
+	ExcludeFields []string
+	Separator     string
+	DefaultTags   map[string]string
+}
+
+// Parser decodes the events of Windows event log files (.evtx), as exported
+// from the event viewer or copied from the event log directory.
+type Parser struct {
+	metricName    string
+	eventTags     filter.Filter
+	eventFields   filter.Filter
+	excludeFields filter.Filter
+	separator     string
+	defaultTags   map[string]string
+}
+
+func New(config *Config) (*Parser, error) {
+	eventTags := config.EventTags
+	if eventTags == nil {
+		eventTags = DefaultEventTags
+	}
+	tagFilter, err := filter.Compile(eventTags)
+	if err != nil {
+		return nil, err
+	}
+	fieldFilter, err := filter.Compile(config.EventFields)
+	if err != nil {
+		return nil, err
+	}
+	excludeFilter, err := filter.Compile(config.ExcludeFields)
+	if err != nil {
+		return nil, err
+	}
+
+	separator := config.Separator
+	if separator == "" {
+		separator = "_"
+	}
+
+	return &Parser{
+		metricName:    config.MetricName,
+		eventTags:     tagFilter,
+		eventFields:   fieldFilter,
+		excludeFields: excludeFilter,
+		separator:     separator,
+		defaultTags:   config.DefaultTags,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < fileHeaderSize || !bytes.HasPrefix(buf, []byte(fileSignature)) {
+		return nil, errors.New("not an evtx file")
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	for offset := fileHeaderSize; offset+chunkSize <= len(buf); offset += chunkSize {
+		chunk := buf[offset : offset+chunkSize]
+		// Chunks not yet used by the event log are empty.
+		if !bytes.HasPrefix(chunk, []byte(chunkSignature)) {
+			continue
+		}
+		m, err := p.parseChunk(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("chunk at offset %d: %v", offset, err)
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	return nil, ErrLineNotSupported
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseChunk(chunk []byte) ([]telegraf.Metric, error) {
+	d := &decoder{chunk: chunk}
+	end := int(binary.LittleEndian.Uint32(chunk[48:]))
+	if end > len(chunk) {
+		end = len(chunk)
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	for pos := chunkHeaderSize; pos+recordHeaderSize <= end; {
+		if binary.LittleEndian.Uint32(chunk[pos:]) != recordMagic {
+			break
+		}
+		size := int(binary.LittleEndian.Uint32(chunk[pos+4:]))
+		id := binary.LittleEndian.Uint64(chunk[pos+8:])
+		written := fileTime(binary.LittleEndian.Uint64(chunk[pos+16:]))
+		if size < recordHeaderSize+4 || pos+size > end {
+			return nil, fmt.Errorf("record %d: invalid size %d", id, size)
+		}
+
+		event, err := d.decode(pos+recordHeaderSize, pos+size-4)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %v", id, err)
+		}
+		m, err := p.toMetric(event, written)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %v", id, err)
+		}
+		metrics = append(metrics, m)
+		pos += size
+	}
+	return metrics, nil
+}
+
+// toMetric converts an event to a metric with the same tags and fields as the
+// win_eventlog input.
+func (p *Parser) toMetric(event *element, written time.Time) (telegraf.Metric, error) {
+	system := event.child("System")
+	rendering := event.child("RenderingInfo")
+
+	level := system.child("Level").value()
+	levelText := rendering.child("Level").value()
+	if levelText == "" {
+		levelText = levelName(level)
+	}
+	message := rendering.child("Message").value()
+
+	values := []struct {
+		name  string
+		value interface{}
+	}{
+		{"Source", system.child("Provider").attr("Name")},
+		{"EventID", integer(system.child("EventID").value())},
+		{"Version", integer(system.child("Version").value())},
+		{"Level", integer(level)},
+		{"LevelText", levelText},
+		{"Task", integer(system.child("Task").value())},
+		{"Opcode", integer(system.child("Opcode").value())},
+		{"Keywords", system.child("Keywords").value()},
+		{"EventRecordID", integer(system.child("EventRecordID").value())},
+		{"ActivityID", system.child("Correlation").attr("ActivityID")},
+		{"RelatedActivityID", system.child("Correlation").attr("RelatedActivityID")},
+		{"ProcessID", integer(system.child("Execution").attr("ProcessID"))},
+		{"ThreadID", integer(system.child("Execution").attr("ThreadID"))},
+		{"Channel", system.child("Channel").value()},
+		{"Computer", system.child("Computer").value()},
+		{"UserID", system.child("Security").attr("UserID")},
+		{"Message", message},
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	add := func(name string, value interface{}) {
+		if value == nil || value == "" {
+			return
+		}
+		if p.eventTags != nil && p.eventTags.Match(name) {
+			tags[name] = fmt.Sprint(value)
+			return
+		}
+		if p.eventFields != nil && !p.eventFields.Match(name) {
+			return
+		}
+		if p.excludeFields != nil && p.excludeFields.Match(name) {
+			return
+		}
+		fields[name] = value
+	}
+	for _, v := range values {
+		add(v.name, v.value)
+	}
+	for _, data := range []*element{event.child("EventData"), event.child("UserData")} {
+		if data == nil {
+			continue
+		}
+		for _, f := range p.dataFields(data) {
+			add(f.name, f.value)
+		}
+	}
+
+	timestamp := written
+	if t, err := time.Parse(time.RFC3339Nano, system.child("TimeCreated").attr("SystemTime")); err == nil {
+		timestamp = t
+	}
+
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+type dataField struct {
+	name  string
+	value string
+}
+
+// dataFields flattens the elements of the event or user data, with the names
+// of the elements joined by the separator and the Name attribute appended to
+// the name of its element.  Names used more than once get a number suffix.
+func (p *Parser) dataFields(data *element) []dataField {
+	var fields []dataField
+	usage := make(map[string]int)
+
+	var walk func(e *element, parents []string)
+	walk = func(e *element, parents []string) {
+		name := e.name
+		if n := e.attr("Name"); n != "" {
+			name += p.separator + n
+		}
+		path := append(parents[:len(parents):len(parents)], name)
+		if v := e.value(); v != "" {
+			key := strings.Join(path, p.separator)
+			usage[key]++
+			fields = append(fields, dataField{name: key, value: v})
+		}
+		for _, c := range e.children {
+			walk(c, path)
+		}
+	}
+	for _, c := range data.children {
+		walk(c, nil)
+	}
+
+	counter := make(map[string]int)
+	for i, f := range fields {
+		if usage[f.name] > 1 {
+			counter[f.name]++
+			fields[i].name = f.name + p.separator + strconv.Itoa(counter[f.name])
+		}
+	}
+	return fields
+}
+
+// integer converts a value to an integer field, values that aren't integers
+// are skipped.
+func integer(v string) interface{} {
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return i
+}
+
+// levelName returns the name of the standard event levels, as shown by the
+// event viewer.
+func levelName(level string) string {
+	switch level {
+	case "0", "4":
+		return "Information"
+	case "1":
+		return "Critical"
+	case "2":
+		return "Error"
+	case "3":
+		return "Warning"
+	case "5":
+		return "Verbose"
+	}
+	return ""
+}
@@ -0,0 +1,350 @@
+package evtx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// builder writes binary XML at the given offset of a chunk.
+type builder struct {
+	base int
+	buf  bytes.Buffer
+}
+
+func (b *builder) pos() int { return b.base + b.buf.Len() }
+
+func (b *builder) u8(v byte) { b.buf.WriteByte(v) }
+
+func (b *builder) u16(v uint16) { binary.Write(&b.buf, binary.LittleEndian, v) }
+
+func (b *builder) u32(v uint32) { binary.Write(&b.buf, binary.LittleEndian, v) }
+
+func (b *builder) u64(v uint64) { binary.Write(&b.buf, binary.LittleEndian, v) }
+
+func (b *builder) chars(s string) {
+	for _, c := range utf16.Encode([]rune(s)) {
+		b.u16(c)
+	}
+}
+
+// name writes an inline name.
+func (b *builder) name(s string) {
+	b.u32(uint32(b.pos() + 4))
+	b.u32(0)
+	b.u16(0)
+	b.u16(uint16(len(s)))
+	b.chars(s)
+	b.u16(0)
+}
+
+func (b *builder) open(name string, attrs bool) {
+	if attrs {
+		b.u8(tokenOpenStartElement | tokenMoreFlag)
+	} else {
+		b.u8(tokenOpenStartElement)
+	}
+	b.u16(0xffff)
+	b.u32(0)
+	b.name(name)
+	if attrs {
+		b.u32(0)
+	}
+}
+
+func (b *builder) attr(name string) {
+	b.u8(tokenAttribute)
+	b.name(name)
+}
+
+func (b *builder) text(s string) {
+	b.u8(tokenValue)
+	b.u8(typeString)
+	b.u16(uint16(len(s)))
+	b.chars(s)
+}
+
+func (b *builder) sub(id uint16, typ byte) {
+	b.u8(tokenOptionalSubstitution)
+	b.u16(id)
+	b.u8(typ)
+}
+
+// element writes an element with a substitution as its content.
+func (b *builder) element(name string, id uint16, typ byte) {
+	b.open(name, false)
+	b.u8(tokenCloseStartElement)
+	b.sub(id, typ)
+	b.u8(tokenEndElement)
+}
+
+// template writes the definition of the event template.
+func (b *builder) template() {
+	b.u8(tokenFragmentHeader)
+	b.u8(1)
+	b.u8(1)
+	b.u8(0)
+	b.open("Event", false)
+	b.u8(tokenCloseStartElement)
+
+	b.open("System", false)
+	b.u8(tokenCloseStartElement)
+	b.open("Provider", true)
+	b.attr("Name")
+	b.sub(0, typeString)
+	b.u8(tokenCloseEmptyElement)
+	b.element("EventID", 1, typeUInt16)
+	b.element("Level", 2, typeUInt8)
+	b.element("Keywords", 3, typeHexInt64)
+	b.open("TimeCreated", true)
+	b.attr("SystemTime")
+	b.sub(4, typeFileTime)
+	b.u8(tokenCloseEmptyElement)
+	b.element("EventRecordID", 5, typeUInt64)
+	b.open("Execution", true)
+	b.u8(tokenAttribute | tokenMoreFlag)
+	b.name("ProcessID")
+	b.sub(6, typeUInt32)
+	b.attr("ThreadID")
+	b.sub(7, typeUInt32)
+	b.u8(tokenCloseEmptyElement)
+	b.element("Channel", 8, typeString)
+	b.element("Computer", 9, typeString)
+	b.open("Security", true)
+	b.attr("UserID")
+	b.sub(10, typeSID)
+	b.u8(tokenCloseEmptyElement)
+	b.u8(tokenEndElement)
+
+	b.open("EventData", false)
+	b.u8(tokenCloseStartElement)
+	b.sub(11, typeBinXML)
+	b.u8(tokenEndElement)
+
+	b.u8(tokenEndElement)
+	b.u8(tokenEOF)
+}
+
+type value struct {
+	typ  byte
+	data func(b *builder)
+}
+
+// record writes an event record using the template, inline unless its
+// offset is given.
+func (b *builder) record(id uint64, templateOffset int, values []value) {
+	start := b.buf.Len()
+	b.u32(recordMagic)
+	b.u32(0)
+	b.u64(id)
+	b.u64(132444736000000000)
+
+	b.u8(tokenFragmentHeader)
+	b.u8(1)
+	b.u8(1)
+	b.u8(0)
+	b.u8(tokenTemplateInstance)
+	b.u8(1)
+	b.u32(1)
+	if templateOffset == 0 {
+		offset := b.pos() + 4
+		b.u32(uint32(offset))
+		t := &builder{base: offset + 24}
+		t.template()
+		b.u32(0)
+		b.buf.Write(make([]byte, 16))
+		b.u32(uint32(t.buf.Len()))
+		b.buf.Write(t.buf.Bytes())
+	} else {
+		b.u32(uint32(templateOffset))
+	}
+
+	b.u32(uint32(len(values)))
+	data := &builder{base: b.pos() + 4*len(values)}
+	var sizes []int
+	for _, v := range values {
+		n := data.buf.Len()
+		if v.data != nil {
+			v.data(data)
+		}
+		sizes = append(sizes, data.buf.Len()-n)
+	}
+	for i, v := range values {
+		b.u16(uint16(sizes[i]))
+		b.u8(v.typ)
+		b.u8(0)
+	}
+	b.buf.Write(data.buf.Bytes())
+	b.u8(tokenEOF)
+
+	size := b.buf.Len() - start + 4
+	b.u32(uint32(size))
+	binary.LittleEndian.PutUint32(b.buf.Bytes()[start+4:], uint32(size))
+}
+
+func str(s string) value {
+	return value{typeString, func(b *builder) { b.chars(s) }}
+}
+
+func events() []byte {
+	chunk := &builder{}
+	chunk.buf.WriteString(chunkSignature)
+	chunk.buf.Write(make([]byte, chunkHeaderSize-len(chunkSignature)))
+
+	eventData := func(user string, logonType string) value {
+		return value{typeBinXML, func(b *builder) {
+			b.u8(tokenFragmentHeader)
+			b.u8(1)
+			b.u8(1)
+			b.u8(0)
+			for _, d := range [][2]string{{"TargetUserName", user}, {"LogonType", logonType}} {
+				b.open("Data", true)
+				b.attr("Name")
+				b.text(d[0])
+				b.u8(tokenCloseStartElement)
+				b.text(d[1])
+				b.u8(tokenEndElement)
+			}
+			b.u8(tokenEOF)
+		}}
+	}
+
+	// SystemTime of 2020-09-13T12:26:40.5Z.
+	filetime := value{typeFileTime, func(b *builder) { b.u64(132444736005000000) }}
+
+	chunk.record(1, 0, []value{
+		str("Microsoft-Windows-Security-Auditing"),
+		{typeUInt16, func(b *builder) { b.u16(4624) }},
+		{typeUInt8, func(b *builder) { b.u8(0) }},
+		{typeHexInt64, func(b *builder) { b.u64(0x8020000000000000) }},
+		filetime,
+		{typeUInt64, func(b *builder) { b.u64(1) }},
+		{typeUInt32, func(b *builder) { b.u32(628) }},
+		{typeUInt32, func(b *builder) { b.u32(4016) }},
+		str("Security"),
+		str("dc01.example.org"),
+		{typeSID, func(b *builder) { b.buf.Write([]byte{1, 1, 0, 0, 0, 0, 0, 5, 18, 0, 0, 0}) }},
+		eventData("admin", "2"),
+	})
+
+	// The template definition follows the record header, the fragment
+	// header, and the template instance token.
+	templateOffset := chunkHeaderSize + recordHeaderSize + 4 + 10
+	chunk.record(2, templateOffset, []value{
+		str("Service Control Manager"),
+		{typeUInt16, func(b *builder) { b.u16(7036) }},
+		{typeUInt8, func(b *builder) { b.u8(3) }},
+		{typeHexInt64, func(b *builder) { b.u64(0x8080000000000000) }},
+		filetime,
+		{typeUInt64, func(b *builder) { b.u64(2) }},
+		{typeUInt32, func(b *builder) { b.u32(700) }},
+		{typeUInt32, func(b *builder) { b.u32(800) }},
+		str("System"),
+		str("dc01.example.org"),
+		{typeNull, nil},
+		eventData("svc", "5"),
+	})
+
+	data := chunk.buf.Bytes()
+	binary.LittleEndian.PutUint32(data[48:], uint32(len(data)))
+
+	var file bytes.Buffer
+	file.WriteString(fileSignature)
+	file.Write(make([]byte, fileHeaderSize-len(fileSignature)))
+	file.Write(data)
+	file.Write(make([]byte, chunkSize-len(data)))
+	return file.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{MetricName: "win_eventlog"})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(events())
+	require.NoError(t, err)
+
+	ts := time.Date(2020, 9, 13, 12, 26, 40, 500000000, time.UTC)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"win_eventlog",
+			map[string]string{
+				"Source":    "Microsoft-Windows-Security-Auditing",
+				"EventID":   "4624",
+				"Level":     "0",
+				"LevelText": "Information",
+				"Channel":   "Security",
+				"Computer":  "dc01.example.org",
+			},
+			map[string]interface{}{
+				"Keywords":            "0x8020000000000000",
+				"EventRecordID":       int64(1),
+				"ProcessID":           int64(628),
+				"ThreadID":            int64(4016),
+				"UserID":              "S-1-5-18",
+				"Data_TargetUserName": "admin",
+				"Data_LogonType":      "2",
+			},
+			ts,
+		),
+		testutil.MustMetric(
+			"win_eventlog",
+			map[string]string{
+				"Source":    "Service Control Manager",
+				"EventID":   "7036",
+				"Level":     "3",
+				"LevelText": "Warning",
+				"Channel":   "System",
+				"Computer":  "dc01.example.org",
+			},
+			map[string]interface{}{
+				"Keywords":            "0x8080000000000000",
+				"EventRecordID":       int64(2),
+				"ProcessID":           int64(700),
+				"ThreadID":            int64(800),
+				"Data_TargetUserName": "svc",
+				"Data_LogonType":      "5",
+			},
+			ts,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSelectFields(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:    "win_eventlog",
+		EventTags:     []string{"EventID"},
+		EventFields:   []string{"Data_*", "Computer"},
+		ExcludeFields: []string{"Data_LogonType"},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(events())
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]string{"EventID": "4624"}, metrics[0].Tags())
+	require.Equal(t, map[string]interface{}{
+		"Computer":            "dc01.example.org",
+		"Data_TargetUserName": "admin",
+	}, metrics[0].Fields())
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser, err := New(&Config{MetricName: "win_eventlog"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("not an evtx file"))
+	require.EqualError(t, err, "not an evtx file")
+
+	// Truncate the first record in the middle of its template.
+	data := events()
+	binary.LittleEndian.PutUint32(data[fileHeaderSize+chunkHeaderSize+4:], 64)
+	_, err = parser.Parse(data)
+	require.EqualError(t, err, "chunk at offset 4096: record 1: unexpected end of data")
+}
@@ -0,0 +1,199 @@
+package execd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+)
+
+const (
+	defaultRestartDelay = 10 * time.Second
+	defaultTimeout      = 5 * time.Second
+)
+
+type Config struct {
+	Command      []string
+	RestartDelay time.Duration
+	Timeout      time.Duration
+	DefaultTags  map[string]string
+	Log          telegraf.Logger
+}
+
+// Parser passes the data to a long-running external program and parses the
+// line protocol written in response.  Each request is the length of the data
+// in bytes on a line of its own followed by the data, the response ends with
+// an empty line.
+type Parser struct {
+	command      []string
+	restartDelay time.Duration
+	timeout      time.Duration
+	log          telegraf.Logger
+
+	sync.Mutex
+	process   *process.Process
+	responses chan []byte
+	parser    *influx.Parser
+}
+
+func New(config *Config) (*Parser, error) {
+	if len(config.Command) == 0 {
+		return nil, errors.New("no command specified")
+	}
+
+	restartDelay := config.RestartDelay
+	if restartDelay == 0 {
+		restartDelay = defaultRestartDelay
+	}
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	parser := influx.NewParser(influx.NewMetricHandler())
+	parser.SetDefaultTags(config.DefaultTags)
+
+	return &Parser{
+		command:      config.Command,
+		restartDelay: restartDelay,
+		timeout:      timeout,
+		log:          config.Log,
+		responses:    make(chan []byte, 1),
+		parser:       parser,
+	}, nil
+}
+
+// Parse sends the data to the program, which is started on first use, and
+// waits for its response.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.process == nil {
+		if err := p.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Discard a late response to a request that timed out.
+	select {
+	case <-p.responses:
+	default:
+	}
+
+	request := append([]byte(strconv.Itoa(len(buf))+"\n"), buf...)
+	if _, err := p.process.Stdin.Write(request); err != nil {
+		return nil, fmt.Errorf("error writing to process stdin: %w", err)
+	}
+
+	select {
+	case response := <-p.responses:
+		return p.parser.Parse(response)
+	case <-time.After(p.timeout):
+		return nil, fmt.Errorf("no response from %s within %s", p.command[0], p.timeout)
+	}
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("can not parse the line: %s, for data format: execd", line)
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.parser.SetDefaultTags(tags)
+}
+
+// Stop terminates the program if it was started.
+func (p *Parser) Stop() {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.process != nil {
+		p.process.Stop()
+		p.process = nil
+	}
+}
+
+func (p *Parser) start() error {
+	proc, err := process.New(p.command)
+	if err != nil {
+		return fmt.Errorf("error creating new process: %w", err)
+	}
+	proc.Log = p.log
+	proc.RestartDelay = p.restartDelay
+	proc.ReadStdoutFn = p.readStdout
+	proc.ReadStderrFn = p.readStderr
+
+	if err := proc.Start(); err != nil {
+		// if there was only one argument, and it contained spaces, warn the user
+		// that they may have configured it wrong.
+		if len(p.command) == 1 && strings.Contains(p.command[0], " ") {
+			p.log.Warn("The execd parser command contained spaces but no arguments. " +
+				"This setting expects the program and arguments as an array of strings, " +
+				"not as a space-delimited string.")
+		}
+		return fmt.Errorf("failed to start process %s: %w", p.command, err)
+	}
+	p.process = proc
+	return nil
+}
+
+// readStdout collects the lines of each response until the empty line ending
+// it.
+func (p *Parser) readStdout(out io.Reader) {
+	reader := bufio.NewReader(out)
+	var response bytes.Buffer
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			if err != io.EOF {
+				p.log.Errorf("Error reading stdout: %s", err)
+			}
+			return
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			response.Write(line)
+			continue
+		}
+
+		data := make([]byte, response.Len())
+		copy(data, response.Bytes())
+		response.Reset()
+
+		select {
+		case p.responses <- data:
+		default:
+			p.log.Warn("Discarding response without a pending request")
+		}
+	}
+}
+
+func (p *Parser) readStderr(out io.Reader) {
+	scanner := bufio.NewScanner(out)
+
+	for scanner.Scan() {
+		p.log.Errorf("stderr: %q", scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		p.log.Errorf("Error reading stderr: %s", err)
+	}
+}
@@ -0,0 +1,131 @@
+// +build !windows
+
+package execd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestParser(t *testing.T) *Parser {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	parser, err := New(&Config{
+		Command: []string{exe, "-external"},
+		Timeout: time.Second,
+		Log:     testutil.Logger{},
+	})
+	require.NoError(t, err)
+	return parser
+}
+
+func TestParse(t *testing.T) {
+	parser := newTestParser(t)
+	defer parser.Stop()
+	parser.SetDefaultTags(map[string]string{"host": "localhost"})
+
+	metrics, err := parser.Parse([]byte("pump 1600000000\nflow=3.5\npressure=2\n"))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"pump",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"flow": 3.5, "pressure": 2.0},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	m, err := parser.ParseLine("valve 1600000060")
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			testutil.MustMetric(
+				"valve",
+				map[string]string{"host": "localhost"},
+				map[string]interface{}{"bytes": 16.0},
+				time.Unix(1600000060, 0),
+			),
+		},
+		[]telegraf.Metric{m},
+	)
+}
+
+func TestParseTimeout(t *testing.T) {
+	parser := newTestParser(t)
+	defer parser.Stop()
+	parser.timeout = 100 * time.Millisecond
+
+	_, err := parser.Parse([]byte("sleep"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no response from")
+
+	// the late response is discarded
+	time.Sleep(300 * time.Millisecond)
+	parser.timeout = time.Second
+	metrics, err := parser.Parse([]byte("pump 1600000000"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "pump", metrics[0].Name())
+}
+
+var external = flag.Bool("external", false,
+	"if true, run externalProcess instead of tests")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *external {
+		externalProcess()
+		os.Exit(0)
+	}
+	code := m.Run()
+	os.Exit(code)
+}
+
+// externalProcess decodes requests with a first line of the measurement and
+// timestamp, followed by key=value lines of fields.  Without fields the
+// length of the request is used.
+func externalProcess() {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(header))
+		if err != nil {
+			os.Exit(1)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			os.Exit(1)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if lines[0] == "sleep" {
+			time.Sleep(200 * time.Millisecond)
+			fmt.Print("sleep value=1\n\n")
+			continue
+		}
+
+		head := strings.Fields(lines[0])
+		fields := lines[1:]
+		if len(fields) == 0 {
+			fields = []string{fmt.Sprintf("bytes=%d", length)}
+		}
+		fmt.Printf("%s %s %s000000000\n\n", head[0], strings.Join(fields, ","), head[1])
+	}
+}
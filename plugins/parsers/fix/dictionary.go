@@ -0,0 +1,107 @@
+package fix
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// field describes a FIX tag.
+type field struct {
+	name string
+	typ  string
+}
+
+// defaultDictionary holds the session and common application tags of FIX
+// 4.2 and 4.4, used for the tags not found in a configured dictionary.
+var defaultDictionary = map[int]field{
+	1:   {"Account", "STRING"},
+	6:   {"AvgPx", "PRICE"},
+	7:   {"BeginSeqNo", "SEQNUM"},
+	8:   {"BeginString", "STRING"},
+	9:   {"BodyLength", "LENGTH"},
+	10:  {"CheckSum", "STRING"},
+	11:  {"ClOrdID", "STRING"},
+	14:  {"CumQty", "QTY"},
+	15:  {"Currency", "CURRENCY"},
+	16:  {"EndSeqNo", "SEQNUM"},
+	17:  {"ExecID", "STRING"},
+	18:  {"ExecInst", "MULTIPLEVALUESTRING"},
+	21:  {"HandlInst", "CHAR"},
+	31:  {"LastPx", "PRICE"},
+	32:  {"LastQty", "QTY"},
+	34:  {"MsgSeqNum", "SEQNUM"},
+	35:  {"MsgType", "STRING"},
+	36:  {"NewSeqNo", "SEQNUM"},
+	37:  {"OrderID", "STRING"},
+	38:  {"OrderQty", "QTY"},
+	39:  {"OrdStatus", "CHAR"},
+	40:  {"OrdType", "CHAR"},
+	41:  {"OrigClOrdID", "STRING"},
+	43:  {"PossDupFlag", "BOOLEAN"},
+	44:  {"Price", "PRICE"},
+	45:  {"RefSeqNum", "SEQNUM"},
+	48:  {"SecurityID", "STRING"},
+	49:  {"SenderCompID", "STRING"},
+	50:  {"SenderSubID", "STRING"},
+	52:  {"SendingTime", "UTCTIMESTAMP"},
+	54:  {"Side", "CHAR"},
+	55:  {"Symbol", "STRING"},
+	56:  {"TargetCompID", "STRING"},
+	57:  {"TargetSubID", "STRING"},
+	58:  {"Text", "STRING"},
+	59:  {"TimeInForce", "CHAR"},
+	60:  {"TransactTime", "UTCTIMESTAMP"},
+	97:  {"PossResend", "BOOLEAN"},
+	98:  {"EncryptMethod", "INT"},
+	99:  {"StopPx", "PRICE"},
+	100: {"ExDestination", "EXCHANGE"},
+	102: {"CxlRejReason", "INT"},
+	103: {"OrdRejReason", "INT"},
+	108: {"HeartBtInt", "INT"},
+	112: {"TestReqID", "STRING"},
+	122: {"OrigSendingTime", "UTCTIMESTAMP"},
+	126: {"ExpireTime", "UTCTIMESTAMP"},
+	141: {"ResetSeqNumFlag", "BOOLEAN"},
+	150: {"ExecType", "CHAR"},
+	151: {"LeavesQty", "QTY"},
+	167: {"SecurityType", "STRING"},
+	207: {"SecurityExchange", "EXCHANGE"},
+	371: {"RefTagID", "INT"},
+	372: {"RefMsgType", "STRING"},
+	373: {"SessionRejectReason", "INT"},
+	434: {"CxlRejResponseTo", "CHAR"},
+}
+
+// loadDictionary reads the fields of a QuickFIX data dictionary.
+func loadDictionary(path string) (map[int]field, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Fields []struct {
+			Number string `xml:"number,attr"`
+			Name   string `xml:"name,attr"`
+			Type   string `xml:"type,attr"`
+		} `xml:"fields>field"`
+	}
+	if err := xml.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("parsing dictionary %q: %v", path, err)
+	}
+
+	dictionary := make(map[int]field, len(defaultDictionary)+len(doc.Fields))
+	for tag, f := range defaultDictionary {
+		dictionary[tag] = f
+	}
+	for _, f := range doc.Fields {
+		tag, err := strconv.Atoi(f.Number)
+		if err != nil {
+			return nil, fmt.Errorf("parsing dictionary %q: invalid number of field %q", path, f.Name)
+		}
+		dictionary[tag] = field{name: f.Name, typ: f.Type}
+	}
+	return dictionary, nil
+}
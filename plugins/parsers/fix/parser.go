@@ -0,0 +1,195 @@
+package fix
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const timestampLayout = "20060102-15:04:05.999999999"
+
+var (
+	DefaultTagKeys = []string{"MsgType", "SenderCompID", "TargetCompID"}
+
+	ErrNoMetric = errors.New("no FIX message in line")
+
+	// delimiters are tried in order when the delimiter isn't configured, SOH
+	// is often logged as ^A.
+	delimiters = []string{"\x01", "^A", "|"}
+)
+
+type Config struct {
+	MetricName  string
+	Dictionary  string
+	Delimiter   string
+	TagKeys     []string
+	DefaultTags map[string]string
+}
+
+// Parser decodes FIX messages from log files, one message per line.  Any text
+// before the BeginString of a message, such as the timestamp added by the
+// logger, is ignored.
+type Parser struct {
+	metricName  string
+	dictionary  map[int]field
+	delimiter   string
+	tagKeys     filter.Filter
+	defaultTags map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	dictionary := defaultDictionary
+	if config.Dictionary != "" {
+		var err error
+		dictionary, err = loadDictionary(config.Dictionary)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tagKeys := config.TagKeys
+	if tagKeys == nil {
+		tagKeys = DefaultTagKeys
+	}
+	tagFilter, err := filter.Compile(tagKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Parser{
+		metricName:  config.MetricName,
+		dictionary:  dictionary,
+		delimiter:   config.Delimiter,
+		tagKeys:     tagFilter,
+		defaultTags: config.DefaultTags,
+		TimeFunc:    time.Now,
+	}, nil
+}
+
+// Parse parses the FIX messages of the lines, lines without a message are
+// skipped.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for i, line := range strings.Split(string(buf), "\n") {
+		m, err := p.ParseLine(line)
+		if err == ErrNoMetric {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	start := strings.Index(line, "8=FIX")
+	if start < 0 {
+		return nil, ErrNoMetric
+	}
+	line = strings.TrimRight(line[start:], "\r\n")
+
+	delimiter := p.delimiter
+	if delimiter == "" {
+		delimiter = delimiters[len(delimiters)-1]
+		for _, d := range delimiters {
+			if strings.Contains(line, d) {
+				delimiter = d
+				break
+			}
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	timestamp := p.TimeFunc()
+	seen := make(map[string]int)
+
+	for _, pair := range strings.Split(line, delimiter) {
+		if pair == "" {
+			continue
+		}
+		i := strings.IndexByte(pair, '=')
+		if i < 1 {
+			return nil, fmt.Errorf("invalid field %q", pair)
+		}
+		tag, err := strconv.Atoi(pair[:i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag in field %q", pair)
+		}
+		value := pair[i+1:]
+
+		f, ok := p.dictionary[tag]
+		if !ok {
+			f = field{name: pair[:i]}
+		}
+
+		// Tags repeated in groups are numbered from the second occurrence.
+		name := f.name
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name += "_" + strconv.Itoa(n)
+		}
+
+		if tag == 52 && seen[f.name] == 1 {
+			if t, err := time.Parse(timestampLayout, value); err == nil {
+				timestamp = t
+			}
+		}
+
+		if p.tagKeys.Match(name) {
+			tags[name] = value
+			continue
+		}
+		fields[name] = convert(f.typ, value)
+	}
+
+	if len(fields) == 0 && len(tags) == 0 {
+		return nil, ErrNoMetric
+	}
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+// convert returns the value in the type of the field, timestamps are
+// converted to Unix nanoseconds.  Values not matching their type are kept as
+// strings.
+func convert(typ string, value string) interface{} {
+	switch typ {
+	case "INT", "SEQNUM", "LENGTH", "NUMINGROUP", "TAGNUM", "DAYOFMONTH":
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return v
+		}
+	case "FLOAT", "PRICE", "QTY", "AMT", "PRICEOFFSET", "PERCENTAGE":
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			return v
+		}
+	case "BOOLEAN":
+		switch value {
+		case "Y":
+			return true
+		case "N":
+			return false
+		}
+	case "UTCTIMESTAMP":
+		if t, err := time.Parse(timestampLayout, value); err == nil {
+			return t.UnixNano()
+		}
+	}
+	return value
+}
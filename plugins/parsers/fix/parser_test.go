@@ -0,0 +1,107 @@
+package fix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{MetricName: "fix"})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	data := "2020-09-13 12:26:40.123 INFO  Session FIX.4.4:GW->EXCH started\n" +
+		"2020-09-13 12:26:40.130 OUT 8=FIX.4.4\x019=122\x0135=D\x0134=215\x0149=GW\x0156=EXCH\x0152=20200913-12:26:40.130\x01" +
+		"11=ORD-1\x0155=MSFT\x0154=1\x0138=100\x0144=212.5\x0140=2\x0160=20200913-12:26:40.125\x0143=N\x0110=072\x01\n" +
+		"2020-09-13 12:26:40.131 IN  8=FIX.4.4|9=80|35=8|34=87|49=EXCH|56=GW|52=20200913-12:26:40|37=X1|150=0|39=0|14=0|151=100|10=011|\n"
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"fix",
+			map[string]string{"MsgType": "D", "SenderCompID": "GW", "TargetCompID": "EXCH"},
+			map[string]interface{}{
+				"BeginString":  "FIX.4.4",
+				"BodyLength":   int64(122),
+				"MsgSeqNum":    int64(215),
+				"SendingTime":  int64(1600000000130000000),
+				"ClOrdID":      "ORD-1",
+				"Symbol":       "MSFT",
+				"Side":         "1",
+				"OrderQty":     100.0,
+				"Price":        212.5,
+				"OrdType":      "2",
+				"TransactTime": int64(1600000000125000000),
+				"PossDupFlag":  false,
+				"CheckSum":     "072",
+			},
+			time.Unix(1600000000, 130000000),
+		),
+		testutil.MustMetric(
+			"fix",
+			map[string]string{"MsgType": "8", "SenderCompID": "EXCH", "TargetCompID": "GW"},
+			map[string]interface{}{
+				"BeginString": "FIX.4.4",
+				"BodyLength":  int64(80),
+				"MsgSeqNum":   int64(87),
+				"SendingTime": int64(1600000000000000000),
+				"OrderID":     "X1",
+				"ExecType":    "0",
+				"OrdStatus":   "0",
+				"CumQty":      0.0,
+				"LeavesQty":   100.0,
+				"CheckSum":    "011",
+			},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseDictionary(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "fix",
+		Dictionary: "testdata/dictionary.xml",
+		Delimiter:  "^A",
+		TagKeys:    []string{"MsgType", "GatewayRegion"},
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	m, err := parser.ParseLine("8=FIX.4.4^A35=0^A9001=17^A9002=eu^A9003=x^A447=D^A447=P^A")
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"fix",
+		map[string]string{"MsgType": "0", "GatewayRegion": "eu"},
+		map[string]interface{}{
+			"BeginString":    "FIX.4.4",
+			"GatewayLatency": int64(17),
+			"9003":           "x",
+			"447":            "D",
+			"447_2":          "P",
+		},
+		time.Unix(42, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+}
+
+func TestParseErrors(t *testing.T) {
+	parser, err := New(&Config{MetricName: "fix"})
+	require.NoError(t, err)
+
+	_, err = parser.ParseLine("session logon")
+	require.Equal(t, ErrNoMetric, err)
+
+	_, err = parser.Parse([]byte("8=FIX.4.2|35=0\n8=FIX.4.2|35|10=000\n"))
+	require.EqualError(t, err, `line 2: invalid field "35"`)
+
+	_, err = New(&Config{Dictionary: "testdata/missing.xml"})
+	require.Error(t, err)
+}
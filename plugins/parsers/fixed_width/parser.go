@@ -0,0 +1,243 @@
+package fixed_width
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrNoMetric = errors.New("no metric in line")
+
+type Config struct {
+	MetricName        string
+	ColumnNames       []string
+	ColumnWidths      []int
+	ColumnOffsets     []int
+	ColumnTypes       []string
+	TagColumns        []string
+	MeasurementColumn string
+	TimestampColumn   string
+	TimestampFormat   string
+	Timezone          string
+	SkipRows          int
+	SkipValues        []string
+	DefaultTags       map[string]string
+}
+
+type column struct {
+	name   string
+	offset int
+	width  int
+	typ    string
+	tag    bool
+}
+
+// Parser decodes records with columns at fixed byte offsets, one record per
+// line.
+type Parser struct {
+	metricName        string
+	columns           []column
+	measurementColumn string
+	timestampColumn   string
+	timestampFormat   string
+	timezone          string
+	skipRows          int
+	skipValues        map[string]bool
+	defaultTags       map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if len(config.ColumnNames) == 0 {
+		return nil, errors.New("column names must be specified")
+	}
+	if len(config.ColumnWidths) != len(config.ColumnNames) {
+		return nil, errors.New("column widths must be specified for all columns")
+	}
+	if len(config.ColumnOffsets) > 0 && len(config.ColumnOffsets) != len(config.ColumnNames) {
+		return nil, errors.New("column offsets must be specified for all columns, or none")
+	}
+	if len(config.ColumnTypes) > 0 && len(config.ColumnTypes) != len(config.ColumnNames) {
+		return nil, errors.New("column types must be specified for all columns, or none")
+	}
+	if config.TimestampColumn != "" && config.TimestampFormat == "" {
+		return nil, errors.New("timestamp format must be specified")
+	}
+
+	tags := make(map[string]bool)
+	for _, name := range config.TagColumns {
+		tags[name] = true
+	}
+
+	// Without offsets the columns follow each other.
+	columns := make([]column, 0, len(config.ColumnNames))
+	offset := 0
+	for i, name := range config.ColumnNames {
+		if len(config.ColumnOffsets) > 0 {
+			offset = config.ColumnOffsets[i]
+		}
+		c := column{
+			name:   name,
+			offset: offset,
+			width:  config.ColumnWidths[i],
+			tag:    tags[name],
+		}
+		if c.offset < 0 || c.width <= 0 {
+			return nil, fmt.Errorf("invalid offset or width of column %q", name)
+		}
+		if len(config.ColumnTypes) > 0 {
+			c.typ = config.ColumnTypes[i]
+			switch c.typ {
+			case "", "int", "float", "bool", "string":
+			default:
+				return nil, fmt.Errorf("invalid type %q for column %q", c.typ, name)
+			}
+		}
+		columns = append(columns, c)
+		offset += c.width
+	}
+
+	skipValues := make(map[string]bool)
+	for _, v := range config.SkipValues {
+		skipValues[v] = true
+	}
+
+	return &Parser{
+		metricName:        config.MetricName,
+		columns:           columns,
+		measurementColumn: config.MeasurementColumn,
+		timestampColumn:   config.TimestampColumn,
+		timestampFormat:   config.TimestampFormat,
+		timezone:          config.Timezone,
+		skipRows:          config.SkipRows,
+		skipValues:        skipValues,
+		defaultTags:       config.DefaultTags,
+		TimeFunc:          time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	lines := strings.Split(string(buf), "\n")
+	if p.skipRows < len(lines) {
+		lines = lines[p.skipRows:]
+	} else {
+		lines = nil
+	}
+
+	metrics := make([]telegraf.Metric, 0)
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m, err := p.parseRecord(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", p.skipRows+i+1, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a single record, the rows to skip only apply to Parse.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	if strings.TrimSpace(line) == "" {
+		return nil, ErrNoMetric
+	}
+	return p.parseRecord(strings.TrimRight(line, "\r\n"))
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseRecord(line string) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	name := p.metricName
+	timestamp := p.TimeFunc()
+
+	for _, c := range p.columns {
+		// Short records lack the trailing columns.
+		if c.offset >= len(line) {
+			continue
+		}
+		end := c.offset + c.width
+		if end > len(line) {
+			end = len(line)
+		}
+		value := strings.TrimSpace(line[c.offset:end])
+		if value == "" || p.skipValues[value] {
+			continue
+		}
+
+		switch {
+		case c.name == p.measurementColumn:
+			name = value
+		case c.name == p.timestampColumn:
+			var err error
+			timestamp, err = internal.ParseTimestamp(p.timestampFormat, value, p.timezone)
+			if err != nil {
+				return nil, err
+			}
+		case c.tag:
+			tags[c.name] = value
+		default:
+			v, err := convert(c, value)
+			if err != nil {
+				return nil, err
+			}
+			fields[c.name] = v
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// convert returns the value in the type of the column, or in the first
+// matching type of int, float and bool for columns without a type.
+func convert(c column, value string) (interface{}, error) {
+	switch c.typ {
+	case "int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %v", c.name, err)
+		}
+		return v, nil
+	case "float":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %v", c.name, err)
+		}
+		return v, nil
+	case "bool":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %v", c.name, err)
+		}
+		return v, nil
+	case "string":
+		return value, nil
+	}
+
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v, nil
+	}
+	if v, err := strconv.ParseBool(value); err == nil {
+		return v, nil
+	}
+	return value, nil
+}
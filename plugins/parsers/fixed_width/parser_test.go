@@ -0,0 +1,142 @@
+package fixed_width
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:      "fixed_width",
+		ColumnNames:     []string{"time", "caller", "callee", "trunk", "duration", "charge", "code"},
+		ColumnWidths:    []int{14, 12, 12, 4, 6, 8, 3},
+		ColumnTypes:     []string{"", "string", "string", "", "int", "float", "string"},
+		TagColumns:      []string{"trunk"},
+		TimestampColumn: "time",
+		TimestampFormat: "20060102150405",
+		SkipRows:        2,
+		SkipValues:      []string{"N/A"},
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	data := "TIME          CALLER      CALLEE      TRNKDUR   CHARGE  CD\n" +
+		"------------------------------------------------------------\n" +
+		"202009131226404915112345674930765432  T01    125    0.25007\n" +
+		"20200913122641491511234567N/A         T02      3    0.00\n"
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"fixed_width",
+			map[string]string{"trunk": "T01"},
+			map[string]interface{}{
+				"caller":   "491511234567",
+				"callee":   "4930765432",
+				"duration": int64(125),
+				"charge":   0.25,
+				"code":     "007",
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"fixed_width",
+			map[string]string{"trunk": "T02"},
+			map[string]interface{}{
+				"caller":   "491511234567",
+				"duration": int64(3),
+				"charge":   0.0,
+			},
+			time.Unix(1600000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseOffsets(t *testing.T) {
+	// Columns can be given in any order and skip parts of the record.
+	parser, err := New(&Config{
+		MetricName:        "fixed_width",
+		ColumnNames:       []string{"value", "host", "kind"},
+		ColumnOffsets:     []int{10, 0, 6},
+		ColumnWidths:      []int{5, 5, 3},
+		MeasurementColumn: "kind",
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	m, err := parser.ParseLine("srv01 cpu 12.5 ignored")
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"host": "srv01", "value": 12.5},
+		time.Unix(42, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		err    string
+	}{
+		{
+			name:   "no columns",
+			config: &Config{},
+			err:    "column names must be specified",
+		},
+		{
+			name:   "missing widths",
+			config: &Config{ColumnNames: []string{"a", "b"}, ColumnWidths: []int{1}},
+			err:    "column widths must be specified for all columns",
+		},
+		{
+			name:   "missing offsets",
+			config: &Config{ColumnNames: []string{"a", "b"}, ColumnWidths: []int{1, 1}, ColumnOffsets: []int{0}},
+			err:    "column offsets must be specified for all columns, or none",
+		},
+		{
+			name:   "invalid width",
+			config: &Config{ColumnNames: []string{"a"}, ColumnWidths: []int{0}},
+			err:    `invalid offset or width of column "a"`,
+		},
+		{
+			name:   "invalid type",
+			config: &Config{ColumnNames: []string{"a"}, ColumnWidths: []int{1}, ColumnTypes: []string{"number"}},
+			err:    `invalid type "number" for column "a"`,
+		},
+		{
+			name:   "missing timestamp format",
+			config: &Config{ColumnNames: []string{"a"}, ColumnWidths: []int{1}, TimestampColumn: "a"},
+			err:    "timestamp format must be specified",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.config)
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestParseTypeError(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:   "fixed_width",
+		ColumnNames:  []string{"count"},
+		ColumnWidths: []int{4},
+		ColumnTypes:  []string{"int"},
+	})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("  12\n 1.5\n"))
+	require.EqualError(t, err, `line 2: column "count": strconv.ParseInt: parsing "1.5": invalid syntax`)
+}
@@ -4,14 +4,19 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal/globpath"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/vjeantet/grok"
 )
@@ -77,6 +82,10 @@ type Parser struct {
 	Measurement        string
 	DefaultTags        map[string]string
 
+	// TagKeys are the names of captures without a modifier to add as tags.
+	TagKeys []string
+	tagKeys filter.Filter
+
 	// Timezone is an optional component to help render log dates to
 	// your chosen zone.
 	// Default: "" which renders UTC
@@ -87,6 +96,13 @@ type Parser struct {
 	Timezone string
 	loc      *time.Location
 
+	// Timezones overrides the Timezone of the patterns, by the name of the
+	// custom pattern used as pattern.
+	//   ie, {"NGINX_ACCESS": "Europe/Berlin"} applies to "%{NGINX_ACCESS}"
+	Timezones map[string]string
+	// locs is a map of the internal names of patterns -> location.
+	locs map[string]*time.Location
+
 	// UniqueTimestamp when set to "disable", timestamp will not incremented if there is a duplicate.
 	UniqueTimestamp string
 
@@ -142,9 +158,21 @@ func (p *Parser) Compile() error {
 		p.UniqueTimestamp = "auto"
 	}
 
+	p.loc, err = time.LoadLocation(p.Timezone)
+	if err != nil {
+		log.Printf("W! improper timezone supplied (%s), setting loc to UTC", p.Timezone)
+		p.loc, _ = time.LoadLocation("UTC")
+	}
+
+	p.tagKeys, err = filter.Compile(p.TagKeys)
+	if err != nil {
+		return err
+	}
+
 	// Give Patterns fake names so that they can be treated as named
 	// "custom patterns"
 	p.NamedPatterns = make([]string, 0, len(p.Patterns))
+	p.locs = make(map[string]*time.Location)
 	for i, pattern := range p.Patterns {
 		pattern = strings.TrimSpace(pattern)
 		if pattern == "" {
@@ -153,6 +181,17 @@ func (p *Parser) Compile() error {
 		name := fmt.Sprintf("GROK_INTERNAL_PATTERN_%d", i)
 		p.CustomPatterns += "\n" + name + " " + pattern + "\n"
 		p.NamedPatterns = append(p.NamedPatterns, "%{"+name+"}")
+
+		for patternName, tz := range p.Timezones {
+			if pattern != "%{"+patternName+"}" {
+				continue
+			}
+			loc, err := time.LoadLocation(tz)
+			if err != nil {
+				return fmt.Errorf("invalid timezone for pattern %q: %v", patternName, err)
+			}
+			p.locs["%{"+name+"}"] = loc
+		}
 	}
 
 	if len(p.NamedPatterns) == 0 {
@@ -167,21 +206,18 @@ func (p *Parser) Compile() error {
 		p.addCustomPatterns(scanner)
 	}
 
-	// Parse any custom pattern files supplied.
-	for _, filename := range p.CustomPatternFiles {
-		file, fileErr := os.Open(filename)
-		if fileErr != nil {
-			return fileErr
+	// Parse any custom pattern files supplied, later files override the
+	// patterns of earlier ones.
+	for _, entry := range p.CustomPatternFiles {
+		filenames, err := patternFiles(entry)
+		if err != nil {
+			return err
+		}
+		for _, filename := range filenames {
+			if err := p.addCustomPatternFile(filename); err != nil {
+				return err
+			}
 		}
-
-		scanner := bufio.NewScanner(bufio.NewReader(file))
-		p.addCustomPatterns(scanner)
-	}
-
-	p.loc, err = time.LoadLocation(p.Timezone)
-	if err != nil {
-		log.Printf("W! improper timezone supplied (%s), setting loc to UTC", p.Timezone)
-		p.loc, _ = time.LoadLocation("UTC")
 	}
 
 	if p.timeFunc == nil {
@@ -221,6 +257,11 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 		tags[k] = v
 	}
 
+	loc := p.loc
+	if l, ok := p.locs[patternName]; ok {
+		loc = l
+	}
+
 	timestamp := time.Now()
 	for k, v := range values {
 		if k == "" || v == "" {
@@ -242,8 +283,12 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 			}
 		}
 		// if we didn't find a type OR timestamp modifier, assume string
+		// unless the capture is listed as tag
 		if t == "" {
 			t = STRING
+			if p.tagKeys != nil && p.tagKeys.Match(k) {
+				t = TAG
+			}
 		}
 
 		switch t {
@@ -314,7 +359,7 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 				timestamp = time.Unix(0, iv)
 			}
 		case SYSLOG_TIMESTAMP:
-			ts, err := time.ParseInLocation(time.Stamp, v, p.loc)
+			ts, err := time.ParseInLocation(time.Stamp, v, loc)
 			if err == nil {
 				if ts.Year() == 0 {
 					ts = ts.AddDate(timestamp.Year(), 0, 0)
@@ -327,7 +372,7 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 			var foundTs bool
 			// first try timestamp layouts that we've already found
 			for _, layout := range p.foundTsLayouts {
-				ts, err := time.ParseInLocation(layout, v, p.loc)
+				ts, err := time.ParseInLocation(layout, v, loc)
 				if err == nil {
 					timestamp = ts
 					foundTs = true
@@ -338,7 +383,7 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 			// layouts.
 			if !foundTs {
 				for _, layout := range timeLayouts {
-					ts, err := time.ParseInLocation(layout, v, p.loc)
+					ts, err := time.ParseInLocation(layout, v, loc)
 					if err == nil {
 						timestamp = ts
 						foundTs = true
@@ -357,7 +402,7 @@ func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 		// goodbye!
 		default:
 			v = strings.Replace(v, ",", ".", -1)
-			ts, err := time.ParseInLocation(t, v, p.loc)
+			ts, err := time.ParseInLocation(t, v, loc)
 			if err == nil {
 				if ts.Year() == 0 {
 					ts = ts.AddDate(timestamp.Year(), 0, 0)
@@ -401,13 +446,73 @@ func (p *Parser) SetDefaultTags(tags map[string]string) {
 	p.DefaultTags = tags
 }
 
+// patternFiles returns the pattern files of a file, a directory or a glob
+// pattern, in lexical order.
+func patternFiles(entry string) ([]string, error) {
+	g, err := globpath.Compile(entry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern file glob %q: %v", entry, err)
+	}
+	matches := g.Match()
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no pattern files match %q", entry)
+	}
+	sort.Strings(matches)
+
+	var filenames []string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			filenames = append(filenames, match)
+			continue
+		}
+
+		// Directories are only read when given explicitly, as the
+		// patterns_dir of logstash.
+		if filepath.Clean(match) != filepath.Clean(entry) {
+			continue
+		}
+		infos, err := ioutil.ReadDir(match)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if info.Mode().IsRegular() {
+				filenames = append(filenames, filepath.Join(match, info.Name()))
+			}
+		}
+	}
+	return filenames, nil
+}
+
+func (p *Parser) addCustomPatternFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(bufio.NewReader(file))
+	p.addCustomPatterns(scanner)
+	return scanner.Err()
+}
+
 func (p *Parser) addCustomPatterns(scanner *bufio.Scanner) {
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
-		if len(line) > 0 && line[0] != '#' {
-			names := strings.SplitN(line, " ", 2)
-			p.patterns[names[0]] = names[1]
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		// logstash pattern files may separate the name by tabs or several
+		// spaces
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			continue
 		}
+		p.patterns[line[:i]] = strings.TrimSpace(line[i:])
 	}
 }
 
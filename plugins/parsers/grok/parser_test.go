@@ -1115,3 +1115,44 @@ func TestTrimRegression(t *testing.T) {
 	)
 	require.Equal(t, expected, actual)
 }
+
+func TestCustomPatternDirectory(t *testing.T) {
+	for _, files := range [][]string{{"./testdata/patterns"}, {"./testdata/patterns/*"}} {
+		p := &Parser{
+			Patterns:           []string{"%{APP_LOG}", "%{OTHER_LOG}"},
+			CustomPatternFiles: files,
+			TagKeys:            []string{"level", "comp*"},
+			Timezones:          map[string]string{"APP_LOG": "Europe/Berlin"},
+		}
+		require.NoError(t, p.Compile())
+
+		m, err := p.ParseLine("2016-06-04 12:41:45 INFO db 1.5")
+		require.NoError(t, err)
+		require.NotNil(t, m)
+		require.Equal(t, map[string]string{"level": "INFO", "component": "db"}, m.Tags())
+		require.Equal(t, map[string]interface{}{"latency": 1.5}, m.Fields())
+		require.Equal(t, int64(1465036905000000000), m.Time().UnixNano())
+
+		// patterns without an override use the default timezone
+		m, err = p.ParseLine("2016-06-04 12:41:45 other 5")
+		require.NoError(t, err)
+		require.NotNil(t, m)
+		require.Equal(t, map[string]interface{}{"value": int64(5)}, m.Fields())
+		require.Equal(t, int64(1465044105000000000), m.Time().UnixNano())
+	}
+}
+
+func TestCustomPatternErrors(t *testing.T) {
+	p := &Parser{
+		Patterns:           []string{"%{APP_LOG}"},
+		CustomPatternFiles: []string{"./testdata/missing/*"},
+	}
+	require.EqualError(t, p.Compile(), `no pattern files match "./testdata/missing/*"`)
+
+	p = &Parser{
+		Patterns:           []string{"%{APP_LOG}"},
+		CustomPatternFiles: []string{"./testdata/patterns"},
+		Timezones:          map[string]string{"APP_LOG": "Mars/Olympus_Mons"},
+	}
+	require.Error(t, p.Compile())
+}
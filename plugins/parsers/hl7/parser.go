@@ -0,0 +1,367 @@
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrNoMetric = errors.New("no metric in line")
+
+// DefaultTags are the addresses of the tags added to every message, unless
+// the tag is configured with another address.
+var DefaultTags = map[string]string{
+	"message_type":          "MSH-9.1",
+	"trigger_event":         "MSH-9.2",
+	"sending_application":   "MSH-3.1",
+	"sending_facility":      "MSH-4.1",
+	"receiving_application": "MSH-5.1",
+	"receiving_facility":    "MSH-6.1",
+}
+
+type Config struct {
+	MetricName  string
+	Tags        map[string]string
+	Fields      map[string]string
+	Timezone    string
+	DefaultTags map[string]string
+}
+
+// address identifies a value of a message, such as PID-3.1 for the first
+// component of the third field of the first PID segment.
+type address struct {
+	segment      string
+	field        int
+	component    int
+	subcomponent int
+}
+
+// Parser decodes HL7 version 2 messages, with one segment per line.  A
+// message starts at each MSH segment.
+type Parser struct {
+	metricName  string
+	tags        map[string]address
+	fields      map[string]address
+	location    *time.Location
+	defaultTags map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	tags := make(map[string]address)
+	for name, s := range DefaultTags {
+		if _, ok := config.Fields[name]; ok {
+			continue
+		}
+		a, _ := parseAddress(s)
+		tags[name] = a
+	}
+	for name, s := range config.Tags {
+		a, err := parseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("tag %q: %v", name, err)
+		}
+		tags[name] = a
+	}
+
+	fields := make(map[string]address)
+	for name, s := range config.Fields {
+		if _, ok := config.Tags[name]; ok {
+			return nil, fmt.Errorf("%q is configured as tag and field", name)
+		}
+		a, err := parseAddress(s)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", name, err)
+		}
+		fields[name] = a
+	}
+
+	location := time.UTC
+	if config.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Parser{
+		metricName:  config.MetricName,
+		tags:        tags,
+		fields:      fields,
+		location:    location,
+		defaultTags: config.DefaultTags,
+		TimeFunc:    time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	data := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(string(buf))
+
+	metrics := make([]telegraf.Metric, 0)
+	var segments []string
+	start := 0
+	flush := func() error {
+		if len(segments) == 0 {
+			return nil
+		}
+		m, err := p.parseMessage(segments)
+		if err != nil {
+			return fmt.Errorf("message at line %d: %v", start, err)
+		}
+		metrics = append(metrics, m)
+		segments = nil
+		return nil
+	}
+
+	for i, line := range strings.Split(data, "\n") {
+		// Dumps of MLLP connections keep the block characters around the
+		// messages.
+		line = strings.Trim(line, " \t\x0b\x1c")
+		if len(line) < 3 {
+			continue
+		}
+		switch line[:3] {
+		case "MSH":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			start = i + 1
+		case "FHS", "FTS", "BHS", "BTS":
+			// Batch headers and trailers only wrap the messages.
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		default:
+			if segments == nil {
+				return nil, fmt.Errorf("line %d: segment %s outside of a message", i+1, line[:3])
+			}
+		}
+		segments = append(segments, line)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a message consisting of only the MSH segment.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, ErrNoMetric
+	}
+	if !strings.HasPrefix(line, "MSH") {
+		return nil, errors.New("message must start with an MSH segment")
+	}
+	return p.parseMessage([]string{line})
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseMessage(segments []string) (telegraf.Metric, error) {
+	msg, err := newMessage(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for name, a := range p.tags {
+		if v := msg.get(a); v != "" {
+			tags[name] = v
+		}
+	}
+
+	fields := map[string]interface{}{
+		"segments": len(segments),
+	}
+	if v := msg.get(address{segment: "MSH", field: 10}); v != "" {
+		fields["control_id"] = v
+	}
+	if v := msg.get(address{segment: "MSH", field: 12, component: 1}); v != "" {
+		fields["version"] = v
+	}
+	for name, a := range p.fields {
+		if v := msg.get(a); v != "" {
+			fields[name] = v
+		}
+	}
+
+	timestamp := p.TimeFunc()
+	if v := msg.get(address{segment: "MSH", field: 7, component: 1}); v != "" {
+		timestamp, err = parseTimestamp(v, p.location)
+		if err != nil {
+			return nil, fmt.Errorf("MSH-7: %v", err)
+		}
+	}
+
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+func parseAddress(s string) (address, error) {
+	var a address
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) == 1 {
+		return a, fmt.Errorf("missing field in %q", s)
+	}
+	a.segment = parts[0]
+	if len(a.segment) != 3 {
+		return a, fmt.Errorf("invalid segment in %q", s)
+	}
+
+	numbers := strings.Split(parts[1], ".")
+	if len(numbers) > 3 {
+		return a, fmt.Errorf("invalid address %q", s)
+	}
+	targets := []*int{&a.field, &a.component, &a.subcomponent}
+	for i, n := range numbers {
+		v, err := strconv.Atoi(n)
+		if err != nil || v < 1 {
+			return a, fmt.Errorf("invalid address %q", s)
+		}
+		*targets[i] = v
+	}
+	return a, nil
+}
+
+type message struct {
+	segments     [][]string
+	separator    string
+	component    string
+	repetition   string
+	escape       string
+	subcomponent string
+}
+
+func newMessage(segments []string) (*message, error) {
+	msh := segments[0]
+	if len(msh) < 8 {
+		return nil, errors.New("invalid MSH segment")
+	}
+
+	m := &message{
+		separator:    msh[3:4],
+		component:    msh[4:5],
+		repetition:   msh[5:6],
+		escape:       msh[6:7],
+		subcomponent: msh[7:8],
+	}
+	for _, s := range segments {
+		m.segments = append(m.segments, strings.Split(s, m.separator))
+	}
+	return m, nil
+}
+
+// get returns the first repetition of the value at the address of the first
+// matching segment, or an empty string if missing.
+func (m *message) get(a address) string {
+	for _, s := range m.segments {
+		if s[0] != a.segment {
+			continue
+		}
+
+		// The separator is the first field of the MSH segment, so its
+		// fields are shifted by one.
+		i := a.field
+		if a.segment == "MSH" {
+			if a.field == 1 {
+				return m.separator
+			}
+			i--
+		}
+		if i >= len(s) {
+			return ""
+		}
+		value := s[i]
+		if a.segment == "MSH" && a.field == 2 {
+			return value
+		}
+
+		value = strings.SplitN(value, m.repetition, 2)[0]
+		if a.component > 0 {
+			components := strings.Split(value, m.component)
+			if a.component > len(components) {
+				return ""
+			}
+			value = components[a.component-1]
+			if a.subcomponent > 0 {
+				subcomponents := strings.Split(value, m.subcomponent)
+				if a.subcomponent > len(subcomponents) {
+					return ""
+				}
+				value = subcomponents[a.subcomponent-1]
+			}
+		}
+		return m.unescape(value)
+	}
+	return ""
+}
+
+// unescape replaces the escape sequences of the delimiters.
+func (m *message) unescape(value string) string {
+	if !strings.Contains(value, m.escape) {
+		return value
+	}
+	return strings.NewReplacer(
+		m.escape+"F"+m.escape, m.separator,
+		m.escape+"S"+m.escape, m.component,
+		m.escape+"R"+m.escape, m.repetition,
+		m.escape+"T"+m.escape, m.subcomponent,
+		m.escape+"E"+m.escape, m.escape,
+	).Replace(value)
+}
+
+// parseTimestamp parses the HL7 timestamp format YYYY[MM[DD[HH[MM[SS[.S...]]]]]]
+// with an optional +/-ZZZZ offset, using the given location without offset.
+func parseTimestamp(value string, location *time.Location) (time.Time, error) {
+	if i := strings.IndexAny(value, "+-"); i >= 0 {
+		offset := value[i:]
+		value = value[:i]
+		if len(offset) != 5 {
+			return time.Time{}, fmt.Errorf("invalid timezone offset %q", offset)
+		}
+		hours, err1 := strconv.Atoi(offset[1:3])
+		minutes, err2 := strconv.Atoi(offset[3:5])
+		if err1 != nil || err2 != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone offset %q", offset)
+		}
+		seconds := hours*3600 + minutes*60
+		if offset[0] == '-' {
+			seconds = -seconds
+		}
+		location = time.FixedZone("", seconds)
+	}
+
+	layouts := map[int]string{
+		4:  "2006",
+		6:  "200601",
+		8:  "20060102",
+		10: "2006010215",
+		12: "200601021504",
+		14: "20060102150405",
+	}
+	digits := value
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		digits = value[:i]
+	}
+	layout, ok := layouts[len(digits)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+	}
+	if len(digits) < len(value) {
+		layout += "." + strings.Repeat("9", len(value)-len(digits)-1)
+	}
+	return time.ParseInLocation(layout, value, location)
+}
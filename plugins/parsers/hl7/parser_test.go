@@ -0,0 +1,124 @@
+package hl7
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const batch = "BHS|^~\\&|LAB|HOSP\r" +
+	"MSH|^~\\&|ADT1|GOOD HEALTH HOSPITAL|LAB|HOSP|20200913142640+0200||ADT^A01^ADT_A01|MSG00001|P|2.5.1\r" +
+	"EVN|A01|20200913142600\r" +
+	"PID|1||PATID1234^5^M11^ADT1^MR~123456789^^^USSSA^SS||EVERYMAN^ADAM^A^III||19610615|M\r" +
+	"PV1|1|I|2000^2012^01||||004777^ATTEND^AARON^A|||SUR\r" +
+	"MSH|^~\\&|ADT1|GOOD HEALTH HOSPITAL|LAB|HOSP|202009131227.5||ORU^R01|MSG00002|P|2.3\r" +
+	"OBX|1|ST|1234^Glucose \\T\\ Sugar||182|mg/dl\r" +
+	"BTS|2\r"
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "hl7",
+		Tags:       map[string]string{"patient_class": "PV1-2"},
+		Fields: map[string]string{
+			"patient_id":  "PID-3.1",
+			"observation": "OBX-3.2",
+		},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse([]byte(batch))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"hl7",
+			map[string]string{
+				"message_type":          "ADT",
+				"trigger_event":         "A01",
+				"sending_application":   "ADT1",
+				"sending_facility":      "GOOD HEALTH HOSPITAL",
+				"receiving_application": "LAB",
+				"receiving_facility":    "HOSP",
+				"patient_class":         "I",
+			},
+			map[string]interface{}{
+				"segments":   4,
+				"control_id": "MSG00001",
+				"version":    "2.5.1",
+				"patient_id": "PATID1234",
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"hl7",
+			map[string]string{
+				"message_type":          "ORU",
+				"trigger_event":         "R01",
+				"sending_application":   "ADT1",
+				"sending_facility":      "GOOD HEALTH HOSPITAL",
+				"receiving_application": "LAB",
+				"receiving_facility":    "HOSP",
+			},
+			map[string]interface{}{
+				"segments":    2,
+				"control_id":  "MSG00002",
+				"version":     "2.3",
+				"observation": "Glucose & Sugar",
+			},
+			time.Date(2020, 9, 13, 12, 27, 0, 500000000, time.UTC),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseTimestamp(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{"2020", time.Date(2020, 1, 1, 0, 0, 0, 0, berlin)},
+		{"20200913", time.Date(2020, 9, 13, 0, 0, 0, 0, berlin)},
+		{"20200913142640", time.Unix(1600000000, 0)},
+		{"20200913142640.1234", time.Unix(1600000000, 123400000)},
+		{"20200913122640-0000", time.Unix(1600000000, 0)},
+		{"202009130826-0400", time.Unix(1599999960, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			actual, err := parseTimestamp(tt.value, berlin)
+			require.NoError(t, err)
+			require.True(t, tt.expected.Equal(actual), "expected %v, got %v", tt.expected, actual)
+		})
+	}
+
+	_, err = parseTimestamp("20200913T1426", berlin)
+	require.Error(t, err)
+}
+
+func TestConfigErrors(t *testing.T) {
+	_, err := New(&Config{Tags: map[string]string{"facility": "MSH4"}})
+	require.EqualError(t, err, `tag "facility": missing field in "MSH4"`)
+
+	_, err = New(&Config{Fields: map[string]string{"id": "PID-3.x"}})
+	require.EqualError(t, err, `field "id": invalid address "PID-3.x"`)
+
+	_, err = New(&Config{
+		Tags:   map[string]string{"id": "PID-3"},
+		Fields: map[string]string{"id": "PID-3"},
+	})
+	require.EqualError(t, err, `"id" is configured as tag and field`)
+}
+
+func TestParseOutsideOfMessage(t *testing.T) {
+	parser, err := New(&Config{MetricName: "hl7"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("PID|1||PATID1234\n"))
+	require.EqualError(t, err, "line 1: segment PID outside of a message")
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"strings"
 	"sync"
 	"time"
@@ -125,6 +126,66 @@ func (p *Parser) Parse(input []byte) ([]telegraf.Metric, error) {
 	return metrics, nil
 }
 
+// ParseStream parses the line protocol read from r and calls fn for each
+// metric as soon as it is parsed.  The parser is not locked while fn runs, so
+// fn may use the parser.
+func (p *Parser) ParseStream(r io.Reader, fn func(telegraf.Metric) error) error {
+	// The stream machine only accepts complete lines, series are parsed at
+	// once.
+	if p.machine.initState == LineProtocol_en_series {
+		input, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		metrics, err := p.Parse(input)
+		if err != nil {
+			return err
+		}
+		for _, m := range metrics {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	parser := &StreamParser{
+		machine: NewStreamMachine(r, p.handler),
+		handler: p.handler,
+	}
+
+	for {
+		metric, err := p.next(parser)
+		if err == EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if metric == nil {
+			continue
+		}
+
+		if err := fn(metric); err != nil {
+			return err
+		}
+	}
+}
+
+// next parses the next line of the stream with the handler of the parser
+// locked.
+func (p *Parser) next(parser *StreamParser) (telegraf.Metric, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	metric, err := parser.Next()
+	if err == nil && metric != nil {
+		p.applyDefaultTagsSingle(metric)
+	}
+	return metric, err
+}
+
 func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
 	metrics, err := p.Parse([]byte(line))
 	if err != nil {
@@ -927,3 +927,48 @@ func TestStreamParserProducesAllAvailableMetrics(t *testing.T) {
 	_, err = parser.Next()
 	require.NoError(t, err)
 }
+
+func TestParserParseStream(t *testing.T) {
+	handler := NewMetricHandler()
+	handler.SetTimeFunc(DefaultTime)
+	parser := NewParser(handler)
+	parser.SetDefaultTags(map[string]string{"host": "localhost"})
+
+	var metrics []telegraf.Metric
+	err := parser.ParseStream(strings.NewReader("cpu value=1\n\n# comment\nmem value=2 1600000000000000000\n"),
+		func(m telegraf.Metric) error {
+			metrics = append(metrics, m)
+			return nil
+		})
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, "cpu", metrics[0].Name())
+	require.Equal(t, map[string]string{"host": "localhost"}, metrics[0].Tags())
+	require.Equal(t, DefaultTime(), metrics[0].Time())
+	require.Equal(t, "mem", metrics[1].Name())
+	require.Equal(t, time.Unix(1600000000, 0), metrics[1].Time())
+
+	stop := errors.New("stop")
+	var count int
+	err = parser.ParseStream(strings.NewReader("cpu value=1\ncpu value=2\n"),
+		func(m telegraf.Metric) error {
+			count++
+			return stop
+		})
+	require.Equal(t, stop, err)
+	require.Equal(t, 1, count)
+
+	err = parser.ParseStream(strings.NewReader("cpu value=\n"), func(m telegraf.Metric) error { return nil })
+	require.Error(t, err)
+
+	// The parser is not locked while the callback runs.
+	var parsed []telegraf.Metric
+	err = parser.ParseStream(strings.NewReader("cpu value=1\n"),
+		func(m telegraf.Metric) error {
+			parsed, err = parser.Parse([]byte("mem value=2\n"))
+			return err
+		})
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+	require.Equal(t, "mem", parsed[0].Name())
+}
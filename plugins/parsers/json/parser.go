@@ -2,13 +2,17 @@ package json
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"time"
 
+	"github.com/PaesslerAG/gval"
+	"github.com/PaesslerAG/jsonpath"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
@@ -27,9 +31,12 @@ type Config struct {
 	NameKey      string
 	StringFields []string
 	Query        string
+	Path         string
 	TimeKey      string
+	TimePath     string
 	TimeFormat   string
 	Timezone     string
+	ExpandArrays []string
 	DefaultTags  map[string]string
 	Strict       bool
 }
@@ -40,9 +47,12 @@ type Parser struct {
 	stringFields filter.Filter
 	nameKey      string
 	query        string
+	path         gval.Evaluable
 	timeKey      string
+	timePath     gval.Evaluable
 	timeFormat   string
 	timezone     string
+	expandArrays filter.Filter
 	defaultTags  map[string]string
 	strict       bool
 }
@@ -58,15 +68,48 @@ func New(config *Config) (*Parser, error) {
 		return nil, err
 	}
 
+	expandFilter, err := filter.Compile(config.ExpandArrays)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Query != "" && config.Path != "" {
+		return nil, errors.New("only one of 'json_query' and 'json_path' can be used")
+	}
+	var path gval.Evaluable
+	if config.Path != "" {
+		path, err = jsonpath.New(config.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'json_path': %v", err)
+		}
+	}
+
+	if config.TimeKey != "" && config.TimePath != "" {
+		return nil, errors.New("only one of 'json_time_key' and 'json_time_path' can be used")
+	}
+	var timePath gval.Evaluable
+	if config.TimePath != "" {
+		if config.TimeFormat == "" {
+			return nil, errors.New("use of 'json_time_path' requires 'json_time_format'")
+		}
+		timePath, err = jsonpath.New(config.TimePath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'json_time_path': %v", err)
+		}
+	}
+
 	return &Parser{
 		metricName:   config.MetricName,
 		tagKeys:      tagKeyFilter,
 		nameKey:      config.NameKey,
 		stringFields: stringFilter,
 		query:        config.Query,
+		path:         path,
 		timeKey:      config.TimeKey,
+		timePath:     timePath,
 		timeFormat:   config.TimeFormat,
 		timezone:     config.Timezone,
+		expandArrays: expandFilter,
 		defaultTags:  config.DefaultTags,
 		strict:       config.Strict,
 	}, nil
@@ -96,6 +139,72 @@ func (p *Parser) parseArray(data []interface{}, timestamp time.Time) ([]telegraf
 }
 
 func (p *Parser) parseObject(data map[string]interface{}, timestamp time.Time) ([]telegraf.Metric, error) {
+	records := []map[string]interface{}{data}
+	if p.expandArrays != nil {
+		records = p.expand(data)
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(records))
+	for _, record := range records {
+		m, err := p.parseRecord(record, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// expand returns a record for each object of the arrays to expand, with the
+// other keys of the parent object inherited by each record.  Keys of the
+// objects take precedence over the inherited keys.  Objects without arrays to
+// expand are returned as is.
+func (p *Parser) expand(data map[string]interface{}) []map[string]interface{} {
+	parent := make(map[string]interface{}, len(data))
+	arrays := make(map[string][]interface{})
+	keys := make([]string, 0)
+	for k, v := range data {
+		if arr, ok := v.([]interface{}); ok && p.expandArrays.Match(k) && isObjectArray(arr) {
+			arrays[k] = arr
+			keys = append(keys, k)
+			continue
+		}
+		parent[k] = v
+	}
+	if len(keys) == 0 {
+		return []map[string]interface{}{data}
+	}
+	sort.Strings(keys)
+
+	records := make([]map[string]interface{}, 0)
+	for _, k := range keys {
+		for _, item := range arrays[k] {
+			record := make(map[string]interface{}, len(parent))
+			for pk, pv := range parent {
+				record[pk] = pv
+			}
+			for ik, iv := range item.(map[string]interface{}) {
+				record[ik] = iv
+			}
+			records = append(records, p.expand(record)...)
+		}
+	}
+	return records
+}
+
+func isObjectArray(arr []interface{}) bool {
+	if len(arr) == 0 {
+		return false
+	}
+	for _, item := range arr {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Parser) parseRecord(data map[string]interface{}, timestamp time.Time) (telegraf.Metric, error) {
 	tags := make(map[string]string)
 	for k, v := range p.defaultTags {
 		tags[k] = v
@@ -109,6 +218,26 @@ func (p *Parser) parseObject(data map[string]interface{}, timestamp time.Time) (
 
 	name := p.metricName
 
+	// the time path addresses the object rather than the flattened fields
+	if p.timePath != nil {
+		value, err := p.timePath(context.Background(), data)
+		if err != nil {
+			return nil, fmt.Errorf("JSON time path could not be found: %v", err)
+		}
+		// paths selecting several values use the first one
+		if values, ok := value.([]interface{}); ok {
+			if len(values) == 0 {
+				return nil, fmt.Errorf("JSON time path could not be found")
+			}
+			value = values[0]
+		}
+
+		timestamp, err = internal.ParseTimestamp(p.timeFormat, value, p.timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// checks if json_name_key is set
 	if p.nameKey != "" {
 		switch field := f.Fields[p.nameKey].(type) {
@@ -143,11 +272,7 @@ func (p *Parser) parseObject(data map[string]interface{}, timestamp time.Time) (
 	}
 
 	tags, nFields := p.switchFieldToTag(tags, f.Fields)
-	metric, err := metric.New(name, tags, nFields, timestamp)
-	if err != nil {
-		return nil, err
-	}
-	return []telegraf.Metric{metric}, nil
+	return metric.New(name, tags, nFields, timestamp)
 }
 
 // will take in field map with strings and bools,
@@ -215,6 +340,13 @@ func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 		return nil, err
 	}
 
+	if p.path != nil {
+		data, err = p.path(context.Background(), data)
+		if err != nil {
+			return nil, fmt.Errorf("JSON path could not be evaluated: %v", err)
+		}
+	}
+
 	timestamp := time.Now().UTC()
 	switch v := data.(type) {
 	case map[string]interface{}:
@@ -1359,3 +1359,147 @@ func TestParseArrayWithWildcardTagKeys(t *testing.T) {
 	}
 
 }
+
+const nestedVendorJSON = `
+{
+  "site": "plant-1",
+  "exported": "2020-09-13T12:26:40Z",
+  "devices": [
+    {
+      "device": "pump-1",
+      "online": true,
+      "readings": [
+        {"sensor": "pressure", "value": 2.5, "ts": 1600000000},
+        {"sensor": "flow", "value": 12, "ts": 1600000001}
+      ]
+    },
+    {
+      "device": "pump-2",
+      "online": false,
+      "readings": [
+        {"sensor": "pressure", "value": 0, "ts": 1600000002}
+      ]
+    }
+  ]
+}
+`
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *Config
+		expected []telegraf.Metric
+	}{
+		{
+			name: "filter expression",
+			config: &Config{
+				MetricName: "json",
+				Path:       "$.devices[?(@.online == true)]",
+				TagKeys:    []string{"device"},
+				TimePath:   "$.readings[0].ts",
+				TimeFormat: "unix",
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric(
+					"json",
+					map[string]string{"device": "pump-1"},
+					map[string]interface{}{
+						"readings_0_value": 2.5,
+						"readings_0_ts":    float64(1600000000),
+						"readings_1_value": float64(12),
+						"readings_1_ts":    float64(1600000001),
+					},
+					time.Unix(1600000000, 0),
+				),
+			},
+		},
+		{
+			name: "expand arrays with time path",
+			config: &Config{
+				MetricName:   "json",
+				ExpandArrays: []string{"devices", "readings"},
+				TagKeys:      []string{"site", "device", "sensor"},
+				TimePath:     "$.ts",
+				TimeFormat:   "unix",
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric(
+					"json",
+					map[string]string{"site": "plant-1", "device": "pump-1", "sensor": "pressure"},
+					map[string]interface{}{"value": 2.5, "ts": float64(1600000000)},
+					time.Unix(1600000000, 0),
+				),
+				testutil.MustMetric(
+					"json",
+					map[string]string{"site": "plant-1", "device": "pump-1", "sensor": "flow"},
+					map[string]interface{}{"value": float64(12), "ts": float64(1600000001)},
+					time.Unix(1600000001, 0),
+				),
+				testutil.MustMetric(
+					"json",
+					map[string]string{"site": "plant-1", "device": "pump-2", "sensor": "pressure"},
+					map[string]interface{}{"value": float64(0), "ts": float64(1600000002)},
+					time.Unix(1600000002, 0),
+				),
+			},
+		},
+		{
+			name: "time path inherited from the parent",
+			config: &Config{
+				MetricName:   "json",
+				ExpandArrays: []string{"devices", "readings"},
+				TagKeys:      []string{"device", "sensor"},
+				TimePath:     "$.exported",
+				TimeFormat:   "2006-01-02T15:04:05Z07:00",
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric(
+					"json",
+					map[string]string{"device": "pump-1", "sensor": "pressure"},
+					map[string]interface{}{"value": 2.5, "ts": float64(1600000000)},
+					time.Unix(1600000000, 0),
+				),
+				testutil.MustMetric(
+					"json",
+					map[string]string{"device": "pump-1", "sensor": "flow"},
+					map[string]interface{}{"value": float64(12), "ts": float64(1600000001)},
+					time.Unix(1600000000, 0),
+				),
+				testutil.MustMetric(
+					"json",
+					map[string]string{"device": "pump-2", "sensor": "pressure"},
+					map[string]interface{}{"value": float64(0), "ts": float64(1600000002)},
+					time.Unix(1600000000, 0),
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := New(tt.config)
+			require.NoError(t, err)
+
+			actual, err := parser.Parse([]byte(nestedVendorJSON))
+			require.NoError(t, err)
+
+			testutil.RequireMetricsEqual(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	_, err := New(&Config{Query: "devices", Path: "$.devices"})
+	require.EqualError(t, err, "only one of 'json_query' and 'json_path' can be used")
+
+	_, err = New(&Config{TimePath: "$.ts"})
+	require.EqualError(t, err, "use of 'json_time_path' requires 'json_time_format'")
+
+	_, err = New(&Config{Path: "$.devices[?("})
+	require.Error(t, err)
+
+	parser, err := New(&Config{MetricName: "json", Path: "$.missing"})
+	require.NoError(t, err)
+	_, err = parser.Parse([]byte(nestedVendorJSON))
+	require.Error(t, err)
+}
@@ -0,0 +1,237 @@
+package leef
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrNoMetric = errors.New("no metric in line")
+
+// Names of the header fields following the version.
+var headerNames = []string{
+	"device_vendor",
+	"device_product",
+	"device_version",
+	"event_id",
+}
+
+const defaultDelimiter = "\t"
+
+type Config struct {
+	MetricName      string
+	Delimiter       string
+	TagKeys         []string
+	TimestampKey    string
+	TimestampFormat string
+	Timezone        string
+	DefaultTags     map[string]string
+}
+
+// Parser decodes messages in the IBM Log Event Extended Format, versions 1.0
+// and 2.0, one message per line.
+type Parser struct {
+	metricName      string
+	delimiter       string
+	tagKeys         filter.Filter
+	timestampKey    string
+	timestampFormat string
+	timezone        string
+	defaultTags     map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.TimestampKey != "" && config.TimestampFormat == "" {
+		return nil, errors.New("timestamp format must be specified")
+	}
+
+	var delimiter string
+	if config.Delimiter != "" {
+		var err error
+		delimiter, err = parseDelimiter(config.Delimiter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tagKeys, err := filter.Compile(config.TagKeys)
+	if err != nil {
+		return nil, fmt.Errorf("compiling tag key filter: %v", err)
+	}
+
+	return &Parser{
+		metricName:      config.MetricName,
+		delimiter:       delimiter,
+		tagKeys:         tagKeys,
+		timestampKey:    config.TimestampKey,
+		timestampFormat: config.TimestampFormat,
+		timezone:        config.Timezone,
+		defaultTags:     config.DefaultTags,
+		TimeFunc:        time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for i, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m, err := p.parseMessage(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseMessage(line string) (telegraf.Metric, error) {
+	// Messages are often prefixed with a syslog header, which is skipped.
+	start := strings.Index(line, "LEEF:")
+	if start < 0 {
+		return nil, errors.New("not a LEEF message")
+	}
+	line = line[start+len("LEEF:"):]
+
+	parts := strings.SplitN(line, "|", len(headerNames)+2)
+	if len(parts) < len(headerNames)+2 {
+		return nil, fmt.Errorf("expected %d header fields, got %d", len(headerNames)+1, len(parts))
+	}
+	version := strings.TrimSpace(parts[0])
+	attributes := parts[len(parts)-1]
+
+	delimiter := defaultDelimiter
+	switch version {
+	case "1.0":
+	case "2.0":
+		// The delimiter is an additional header field, which is empty when
+		// using the default.
+		i := strings.Index(attributes, "|")
+		if i < 0 {
+			return nil, errors.New("missing delimiter header field")
+		}
+		if i > 0 {
+			var err error
+			delimiter, err = parseDelimiter(attributes[:i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		attributes = attributes[i+1:]
+	default:
+		return nil, fmt.Errorf("unsupported version %q", version)
+	}
+	if p.delimiter != "" {
+		delimiter = p.delimiter
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for i, name := range headerNames {
+		if value := parts[i+1]; value != "" {
+			tags[name] = value
+		}
+	}
+	fields := map[string]interface{}{
+		"leef_version": version,
+	}
+
+	values := make(map[string]string)
+	for _, attribute := range strings.Split(attributes, delimiter) {
+		if strings.TrimSpace(attribute) == "" {
+			continue
+		}
+		kv := strings.SplitN(attribute, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid attribute %q", attribute)
+		}
+		values[kv[0]] = kv[1]
+	}
+
+	timestamp := p.TimeFunc()
+	if p.timestampKey != "" {
+		// Not all events of a device carry the same attributes, so events
+		// without a timestamp use the current time.
+		if v, ok := values[p.timestampKey]; ok {
+			var err error
+			timestamp, err = internal.ParseTimestamp(p.timestampFormat, v, p.timezone)
+			if err != nil {
+				return nil, err
+			}
+			delete(values, p.timestampKey)
+		}
+	}
+
+	for k, v := range values {
+		if p.tagKeys != nil && p.tagKeys.Match(k) {
+			tags[k] = v
+			continue
+		}
+		fields[k] = convert(v)
+	}
+
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+// parseDelimiter returns the delimiter given as a single character, or as a
+// hex code such as "x09" or "0x09".
+func parseDelimiter(s string) (string, error) {
+	if len(s) == 1 {
+		return s, nil
+	}
+
+	var hex string
+	switch lower := strings.ToLower(s); {
+	case strings.HasPrefix(lower, "0x"):
+		hex = lower[2:]
+	case strings.HasPrefix(lower, "x"):
+		hex = lower[1:]
+	default:
+		return "", fmt.Errorf("invalid delimiter %q", s)
+	}
+	c, err := strconv.ParseUint(hex, 16, 8)
+	if err != nil || c == 0 {
+		return "", fmt.Errorf("invalid delimiter %q", s)
+	}
+	return string(rune(c)), nil
+}
+
+// convert returns integers and floats as such, and other values as strings.
+func convert(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v
+	}
+	return value
+}
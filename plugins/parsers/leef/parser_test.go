@@ -0,0 +1,129 @@
+package leef
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:      "leef",
+		TagKeys:         []string{"src", "dst"},
+		TimestampKey:    "devTime",
+		TimestampFormat: "Jan 02 2006 15:04:05",
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	data := "<13>Sep 13 12:26:40 fw01 LEEF:1.0|Lancope|StealthWatch|1.0|41|src=10.0.1.8\tdst=10.0.0.5\tsev=5\tcat=anomaly\tdevTime=Sep 13 2020 12:26:40\n" +
+		"LEEF:2.0|Vendor|Product|2.1|login|^|src=10.0.0.1^usrName=admin^ratio=0.5^msg=a=b\n" +
+		"LEEF:2.0|Vendor|Product|2.1|logout||src=10.0.0.1\tusrName=admin\n"
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"leef",
+			map[string]string{
+				"device_vendor":  "Lancope",
+				"device_product": "StealthWatch",
+				"device_version": "1.0",
+				"event_id":       "41",
+				"src":            "10.0.1.8",
+				"dst":            "10.0.0.5",
+			},
+			map[string]interface{}{
+				"leef_version": "1.0",
+				"sev":          int64(5),
+				"cat":          "anomaly",
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"leef",
+			map[string]string{
+				"device_vendor":  "Vendor",
+				"device_product": "Product",
+				"device_version": "2.1",
+				"event_id":       "login",
+				"src":            "10.0.0.1",
+			},
+			map[string]interface{}{
+				"leef_version": "2.0",
+				"usrName":      "admin",
+				"ratio":        0.5,
+				"msg":          "a=b",
+			},
+			time.Unix(42, 0),
+		),
+		testutil.MustMetric(
+			"leef",
+			map[string]string{
+				"device_vendor":  "Vendor",
+				"device_product": "Product",
+				"device_version": "2.1",
+				"event_id":       "logout",
+				"src":            "10.0.0.1",
+			},
+			map[string]interface{}{
+				"leef_version": "2.0",
+				"usrName":      "admin",
+			},
+			time.Unix(42, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseDelimiter(t *testing.T) {
+	for _, delimiter := range []string{"x7C", "0x7c", "|"} {
+		// A delimiter in the header is overridden by the configured one.
+		parser, err := New(&Config{MetricName: "leef", Delimiter: delimiter})
+		require.NoError(t, err)
+		parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+		m, err := parser.ParseLine("LEEF:2.0|V|P|1|e|^|a=1|b=2")
+		require.NoError(t, err)
+
+		expected := testutil.MustMetric(
+			"leef",
+			map[string]string{"device_vendor": "V", "device_product": "P", "device_version": "1", "event_id": "e"},
+			map[string]interface{}{"leef_version": "2.0", "a": int64(1), "b": int64(2)},
+			time.Unix(42, 0),
+		)
+		testutil.RequireMetricEqual(t, expected, m)
+	}
+
+	for _, delimiter := range []string{"09", "tab", "x100", "x00"} {
+		_, err := New(&Config{MetricName: "leef", Delimiter: delimiter})
+		require.Error(t, err, delimiter)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := New(&Config{MetricName: "leef", TimestampKey: "devTime"})
+	require.EqualError(t, err, "timestamp format must be specified")
+
+	parser, err := New(&Config{MetricName: "leef"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("CEF:0|V|P|1|1|Event|3|src=a"))
+	require.EqualError(t, err, "line 1: not a LEEF message")
+
+	_, err = parser.Parse([]byte("LEEF:1.0|V|P|1|e|a=1\nLEEF:1.0|V|P|1"))
+	require.EqualError(t, err, "line 2: expected 5 header fields, got 4")
+
+	_, err = parser.Parse([]byte("LEEF:3.0|V|P|1|e|a=1"))
+	require.EqualError(t, err, `line 1: unsupported version "3.0"`)
+
+	_, err = parser.Parse([]byte("LEEF:2.0|V|P|1|e|a=1"))
+	require.EqualError(t, err, "line 1: missing delimiter header field")
+
+	_, err = parser.Parse([]byte("LEEF:1.0|V|P|1|e|a=1\tnovalue"))
+	require.EqualError(t, err, `line 1: invalid attribute "novalue"`)
+}
@@ -0,0 +1,176 @@
+package ltsv
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrNoMetric = errors.New("no metric in line")
+
+type Config struct {
+	MetricName      string
+	TagKeys         []string
+	FieldTypes      map[string]string
+	TimestampLabel  string
+	TimestampFormat string
+	Timezone        string
+	DefaultTags     map[string]string
+}
+
+// Parser decodes Labeled Tab-Separated Values, one record per line.
+type Parser struct {
+	metricName      string
+	tagKeys         filter.Filter
+	fieldTypes      map[string]string
+	timestampLabel  string
+	timestampFormat string
+	timezone        string
+	defaultTags     map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.TimestampLabel != "" && config.TimestampFormat == "" {
+		return nil, errors.New("timestamp format must be specified")
+	}
+
+	for label, typ := range config.FieldTypes {
+		switch typ {
+		case "int", "float", "bool", "string":
+		default:
+			return nil, fmt.Errorf("invalid type %q for label %q", typ, label)
+		}
+	}
+
+	tagKeys, err := filter.Compile(config.TagKeys)
+	if err != nil {
+		return nil, fmt.Errorf("compiling tag key filter: %v", err)
+	}
+
+	return &Parser{
+		metricName:      config.MetricName,
+		tagKeys:         tagKeys,
+		fieldTypes:      config.FieldTypes,
+		timestampLabel:  config.TimestampLabel,
+		timestampFormat: config.TimestampFormat,
+		timezone:        config.Timezone,
+		defaultTags:     config.DefaultTags,
+		TimeFunc:        time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for i, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m, err := p.parseRecord(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseRecord(line string) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	timestamp := p.TimeFunc()
+
+	for _, item := range strings.Split(line, "\t") {
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid item %q", item)
+		}
+		label, value := kv[0], kv[1]
+
+		// A dash is used for values that are not available.
+		if value == "" || value == "-" {
+			continue
+		}
+
+		switch {
+		case label == p.timestampLabel:
+			var err error
+			timestamp, err = internal.ParseTimestamp(p.timestampFormat, value, p.timezone)
+			if err != nil {
+				return nil, err
+			}
+		case p.tagKeys != nil && p.tagKeys.Match(label):
+			tags[label] = value
+		default:
+			v, err := p.convert(label, value)
+			if err != nil {
+				return nil, err
+			}
+			fields[label] = v
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+// convert returns the value in the type hinted for the label, or as a string
+// without a hint.
+func (p *Parser) convert(label, value string) (interface{}, error) {
+	switch p.fieldTypes[label] {
+	case "int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("converting %q to int: %v", label, err)
+		}
+		return v, nil
+	case "float":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("converting %q to float: %v", label, err)
+		}
+		return v, nil
+	case "bool":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("converting %q to bool: %v", label, err)
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
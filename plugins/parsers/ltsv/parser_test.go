@@ -0,0 +1,97 @@
+package ltsv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "ltsv",
+		TagKeys:    []string{"host", "method"},
+		FieldTypes: map[string]string{
+			"status":  "int",
+			"size":    "int",
+			"reqtime": "float",
+			"cache":   "bool",
+		},
+		TimestampLabel:  "time",
+		TimestampFormat: "[02/Jan/2006:15:04:05 -0700]",
+	})
+	require.NoError(t, err)
+
+	data := "host:127.0.0.1\tident:-\tuser:frank\ttime:[13/Sep/2020:14:26:40 +0200]\tmethod:GET\turi:/apache_pb.gif\tstatus:200\tsize:2326\treqtime:0.013\tcache:true\n" +
+		"\n" +
+		"host:127.0.0.2\ttime:[13/Sep/2020:12:26:41 +0000]\tmethod:POST\turi:/login\tstatus:302\tsize:-\treqtime:1.5\tcache:false\n"
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"ltsv",
+			map[string]string{"host": "127.0.0.1", "method": "GET"},
+			map[string]interface{}{
+				"user":    "frank",
+				"uri":     "/apache_pb.gif",
+				"status":  int64(200),
+				"size":    int64(2326),
+				"reqtime": 0.013,
+				"cache":   true,
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"ltsv",
+			map[string]string{"host": "127.0.0.2", "method": "POST"},
+			map[string]interface{}{
+				"uri":     "/login",
+				"status":  int64(302),
+				"reqtime": 1.5,
+				"cache":   false,
+			},
+			time.Unix(1600000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseDefaults(t *testing.T) {
+	parser, err := New(&Config{MetricName: "ltsv"})
+	require.NoError(t, err)
+	parser.SetDefaultTags(map[string]string{"source": "web01"})
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	// Values without a type hint are kept as strings.
+	m, err := parser.ParseLine("status:200\tvalue:a:b")
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"ltsv",
+		map[string]string{"source": "web01"},
+		map[string]interface{}{"status": "200", "value": "a:b"},
+		time.Unix(42, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := New(&Config{MetricName: "ltsv", TimestampLabel: "time"})
+	require.EqualError(t, err, "timestamp format must be specified")
+
+	_, err = New(&Config{MetricName: "ltsv", FieldTypes: map[string]string{"status": "integer"}})
+	require.EqualError(t, err, `invalid type "integer" for label "status"`)
+
+	parser, err := New(&Config{MetricName: "ltsv", FieldTypes: map[string]string{"status": "int"}})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("status:200\nstatus:OK"))
+	require.EqualError(t, err, `line 2: converting "status" to int: strconv.ParseInt: parsing "OK": invalid syntax`)
+
+	_, err = parser.Parse([]byte("status:200\tnolabel"))
+	require.EqualError(t, err, `line 1: invalid item "nolabel"`)
+}
@@ -0,0 +1,235 @@
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/vmihailenco/msgpack"
+)
+
+var ErrWrongType = errors.New("must be a map or an array of maps")
+
+type Config struct {
+	MetricName   string
+	TagKeys      []string
+	NameKey      string
+	StringFields []string
+	TimeKey      string
+	TimeFormat   string
+	Timezone     string
+	DefaultTags  map[string]string
+	Strict       bool
+}
+
+// Parser decodes MessagePack maps, arrays of maps, or streams of either, with
+// the same key mapping as the JSON parser.
+type Parser struct {
+	metricName   string
+	tagKeys      filter.Filter
+	stringFields filter.Filter
+	nameKey      string
+	timeKey      string
+	timeFormat   string
+	timezone     string
+	defaultTags  map[string]string
+	strict       bool
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	stringFilter, err := filter.Compile(config.StringFields)
+	if err != nil {
+		return nil, err
+	}
+
+	tagKeyFilter, err := filter.Compile(config.TagKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Parser{
+		metricName:   config.MetricName,
+		tagKeys:      tagKeyFilter,
+		stringFields: stringFilter,
+		nameKey:      config.NameKey,
+		timeKey:      config.TimeKey,
+		timeFormat:   config.TimeFormat,
+		timezone:     config.Timezone,
+		defaultTags:  config.DefaultTags,
+		strict:       config.Strict,
+		TimeFunc:     time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	dec := msgpack.NewDecoder(bytes.NewReader(buf)).UseDecodeInterfaceLoose(true)
+
+	timestamp := p.TimeFunc().UTC()
+	metrics := make([]telegraf.Metric, 0)
+	for {
+		data, err := dec.DecodeInterfaceLoose()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := data.(type) {
+		case []interface{}:
+			for _, item := range v {
+				m, err := p.parseItem(item, timestamp)
+				if err != nil {
+					if p.strict {
+						return nil, err
+					}
+					continue
+				}
+				metrics = append(metrics, m)
+			}
+		default:
+			m, err := p.parseItem(v, timestamp)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseItem(item interface{}, timestamp time.Time) (telegraf.Metric, error) {
+	if item == nil || reflect.TypeOf(item).Kind() != reflect.Map {
+		return nil, ErrWrongType
+	}
+
+	fields := make(map[string]interface{})
+	flatten("", item, fields)
+
+	name := p.metricName
+	if p.nameKey != "" {
+		if v, ok := fields[p.nameKey].(string); ok {
+			name = v
+		}
+	}
+
+	if p.timeKey != "" {
+		v, ok := fields[p.timeKey]
+		if !ok {
+			return nil, errors.New("msgpack time key could not be found")
+		}
+		if t, ok := v.(time.Time); ok {
+			timestamp = t
+		} else {
+			if p.timeFormat == "" {
+				return nil, errors.New("use of 'msgpack_time_key' requires 'msgpack_time_format'")
+			}
+			var err error
+			timestamp, err = internal.ParseTimestamp(p.timeFormat, v, p.timezone)
+			if err != nil {
+				return nil, err
+			}
+		}
+		delete(fields, p.timeKey)
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for k, v := range fields {
+		if p.tagKeys != nil && p.tagKeys.Match(k) {
+			tags[k] = toString(v)
+			delete(fields, k)
+			continue
+		}
+
+		// Like with JSON, strings and booleans are only kept if selected.
+		switch v := v.(type) {
+		case string, bool:
+			if p.stringFields == nil || !p.stringFields.Match(k) {
+				delete(fields, k)
+			}
+		case time.Time:
+			fields[k] = v.UnixNano()
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// flatten adds the values of nested maps and arrays to fields, joining the
+// keys and indexes with "_".
+func flatten(prefix string, value interface{}, fields map[string]interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "_" + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			flatten(join(k), item, fields)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(join(strconv.Itoa(i)), item, fields)
+		}
+	case []byte:
+		fields[prefix] = string(v)
+	case *time.Time:
+		fields[prefix] = *v
+	case int64, uint64, float64, string, bool:
+		fields[prefix] = v
+	default:
+		// Maps with keys other than strings are decoded into maps typed
+		// after their first key and value.
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Map {
+			iter := rv.MapRange()
+			for iter.Next() {
+				flatten(join(toString(iter.Key().Interface())), iter.Value().Interface(), fields)
+			}
+		}
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,139 @@
+package msgpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack"
+)
+
+func encode(t *testing.T, values ...interface{}) []byte {
+	var buf []byte
+	for _, v := range values {
+		b, err := msgpack.Marshal(v)
+		require.NoError(t, err)
+		buf = append(buf, b...)
+	}
+	return buf
+}
+
+func TestParseObject(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:   "msgpack",
+		TagKeys:      []string{"host", "location_*"},
+		NameKey:      "kind",
+		StringFields: []string{"status"},
+		TimeKey:      "time",
+		TimeFormat:   "unix",
+	})
+	require.NoError(t, err)
+
+	buf := encode(t, map[string]interface{}{
+		"host":     "a",
+		"kind":     "cpu",
+		"time":     1600000000,
+		"usage":    42.5,
+		"count":    uint64(3),
+		"errors":   -2,
+		"status":   "ok",
+		"comment":  "dropped",
+		"ok":       true,
+		"raw":      []byte("bytes"),
+		"location": map[string]interface{}{"site": "dc1"},
+		"samples":  []interface{}{1, 2.5},
+		"codes":    map[int]int{404: 7},
+	})
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "location_site": "dc1"},
+			map[string]interface{}{
+				"usage":     42.5,
+				"count":     uint64(3),
+				"errors":    int64(-2),
+				"status":    "ok",
+				"samples_0": int64(1),
+				"samples_1": 2.5,
+				"codes_404": int64(7),
+			},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseStream(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "msgpack",
+		TagKeys:    []string{"host"},
+		TimeKey:    "time",
+	})
+	require.NoError(t, err)
+
+	// An array of objects followed by a single object, with timestamps
+	// using the msgpack timestamp extension.
+	buf := encode(t,
+		[]interface{}{
+			map[string]interface{}{"host": "a", "value": 1, "time": time.Unix(1600000000, 0)},
+			map[string]interface{}{"host": "b", "value": 2, "time": time.Unix(1600000001, 0)},
+		},
+		map[string]interface{}{"host": "c", "value": 3, "time": time.Unix(1600000002, 0)},
+	)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("msgpack", map[string]string{"host": "a"}, map[string]interface{}{"value": int64(1)}, time.Unix(1600000000, 0)),
+		testutil.MustMetric("msgpack", map[string]string{"host": "b"}, map[string]interface{}{"value": int64(2)}, time.Unix(1600000001, 0)),
+		testutil.MustMetric("msgpack", map[string]string{"host": "c"}, map[string]interface{}{"value": int64(3)}, time.Unix(1600000002, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	// A truncated stream is an error.
+	_, err = parser.Parse(buf[:len(buf)-1])
+	require.Error(t, err)
+}
+
+func TestParseErrors(t *testing.T) {
+	parser, err := New(&Config{MetricName: "msgpack"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse(encode(t, 42))
+	require.Equal(t, ErrWrongType, err)
+
+	// Items other than maps are skipped, unless strict.
+	metrics, err := parser.Parse(encode(t, []interface{}{42, map[string]interface{}{"value": 1}}))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	parser, err = New(&Config{MetricName: "msgpack", Strict: true})
+	require.NoError(t, err)
+	_, err = parser.Parse(encode(t, []interface{}{42, map[string]interface{}{"value": 1}}))
+	require.Equal(t, ErrWrongType, err)
+
+	parser, err = New(&Config{MetricName: "msgpack", TimeKey: "time"})
+	require.NoError(t, err)
+	_, err = parser.Parse(encode(t, map[string]interface{}{"time": 1600000000}))
+	require.EqualError(t, err, "use of 'msgpack_time_key' requires 'msgpack_time_format'")
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	parser, err := New(&Config{MetricName: "msgpack"})
+	require.NoError(t, err)
+	parser.SetDefaultTags(map[string]string{"source": "export"})
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	m, err := parser.ParseLine(string(encode(t, map[string]interface{}{"value": 1.5})))
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric("msgpack", map[string]string{"source": "export"}, map[string]interface{}{"value": 1.5}, time.Unix(42, 0))
+	testutil.RequireMetricEqual(t, expected, m)
+}
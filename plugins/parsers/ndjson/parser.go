@@ -0,0 +1,121 @@
+package ndjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/json"
+)
+
+const defaultMaxLineBytes = 1024 * 1024
+
+var ErrNoMetric = errors.New("no metric in line")
+
+type Config struct {
+	MetricName   string
+	TagKeys      []string
+	NameKey      string
+	StringFields []string
+	TimeKey      string
+	TimeFormat   string
+	Timezone     string
+	MaxLineBytes int
+	Strict       bool
+	DefaultTags  map[string]string
+}
+
+// Parser decodes newline delimited JSON one line at a time, so that only a
+// single record is decoded at once and invalid lines don't affect the other
+// records.
+type Parser struct {
+	json         *json.Parser
+	maxLineBytes int
+	strict       bool
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.TimeKey != "" && config.TimeFormat == "" {
+		return nil, errors.New("use of 'ndjson_time_key' requires 'ndjson_time_format'")
+	}
+
+	parser, err := json.New(&json.Config{
+		MetricName:   config.MetricName,
+		TagKeys:      config.TagKeys,
+		NameKey:      config.NameKey,
+		StringFields: config.StringFields,
+		TimeKey:      config.TimeKey,
+		TimeFormat:   config.TimeFormat,
+		Timezone:     config.Timezone,
+		DefaultTags:  config.DefaultTags,
+		Strict:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	maxLineBytes := config.MaxLineBytes
+	if maxLineBytes <= 0 {
+		maxLineBytes = defaultMaxLineBytes
+	}
+
+	return &Parser{
+		json:         parser,
+		maxLineBytes: maxLineBytes,
+		strict:       config.Strict,
+	}, nil
+}
+
+// Parse parses each line of the buffer as a JSON object or array of objects.
+// Invalid lines are logged and skipped, unless strict is set in which case
+// the first invalid line is returned as an error.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for n := 1; len(buf) > 0; n++ {
+		var line []byte
+		if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+			line, buf = buf[:i], buf[i+1:]
+		} else {
+			line, buf = buf, nil
+		}
+
+		m, err := p.parseLine(line)
+		if err != nil {
+			if p.strict {
+				return nil, fmt.Errorf("line %d: %v", n, err)
+			}
+			log.Printf("W! [parsers.ndjson] Skipping line %d: %v", n, err)
+			continue
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.parseLine([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.json.SetDefaultTags(tags)
+}
+
+func (p *Parser) parseLine(line []byte) ([]telegraf.Metric, error) {
+	if len(line) > p.maxLineBytes {
+		return nil, fmt.Errorf("line exceeds %d bytes", p.maxLineBytes)
+	}
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil, nil
+	}
+	return p.json.Parse(line)
+}
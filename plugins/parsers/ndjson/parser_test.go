@@ -0,0 +1,96 @@
+package ndjson
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "ndjson",
+		TagKeys:    []string{"host"},
+		TimeKey:    "time",
+		TimeFormat: "unix",
+	})
+	require.NoError(t, err)
+
+	data := `{"host": "a", "time": 1600000000, "cpu": {"user": 1.5, "system": 0.5}}
+
+{"host": "b", "time": 1600000001, "cpu": {"user": 2}}` + "\r\n" +
+		`[{"host": "c", "time": 1600000002, "cpu": {"user": 3}}]`
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"ndjson",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"cpu_user": 1.5, "cpu_system": 0.5},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"ndjson",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"cpu_user": 2.0},
+			time.Unix(1600000001, 0),
+		),
+		testutil.MustMetric(
+			"ndjson",
+			map[string]string{"host": "c"},
+			map[string]interface{}{"cpu_user": 3.0},
+			time.Unix(1600000002, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseInvalidLines(t *testing.T) {
+	data := `{"value": 1}
+{"value": 2
+42
+{"value": 3}
+{"value": "` + strings.Repeat("x", 64) + `"}
+`
+
+	parser, err := New(&Config{MetricName: "ndjson", MaxLineBytes: 32})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]interface{}{"value": 1.0}, metrics[0].Fields())
+	require.Equal(t, map[string]interface{}{"value": 3.0}, metrics[1].Fields())
+
+	parser, err = New(&Config{MetricName: "ndjson", MaxLineBytes: 32, Strict: true})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte(data))
+	require.EqualError(t, err, "line 2: unexpected end of JSON input")
+
+	_, err = parser.ParseLine(`{"value": "` + strings.Repeat("x", 64) + `"}`)
+	require.EqualError(t, err, "line exceeds 32 bytes")
+}
+
+func TestParseLine(t *testing.T) {
+	parser, err := New(&Config{MetricName: "ndjson", DefaultTags: map[string]string{"source": "test"}})
+	require.NoError(t, err)
+
+	m, err := parser.ParseLine(`{"value": 42}`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"source": "test"}, m.Tags())
+	require.Equal(t, map[string]interface{}{"value": 42.0}, m.Fields())
+
+	_, err = parser.ParseLine(" ")
+	require.Equal(t, ErrNoMetric, err)
+}
+
+func TestTimeKeyRequiresFormat(t *testing.T) {
+	_, err := New(&Config{MetricName: "ndjson", TimeKey: "time"})
+	require.EqualError(t, err, "use of 'ndjson_time_key' requires 'ndjson_time_format'")
+}
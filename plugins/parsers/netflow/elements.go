@@ -0,0 +1,111 @@
+package netflow
+
+// elementType is the abstract data type of an information element, deciding
+// how its value is converted to a field.
+type elementType int
+
+const (
+	typeUnsigned elementType = iota
+	typeIPv4
+	typeIPv6
+	typeMAC
+	typeString
+	typeOctets
+)
+
+// paddingOctets is the element used to align records, it is never emitted.
+const paddingOctets = 210
+
+type element struct {
+	name string
+	typ  elementType
+}
+
+// elements are the commonly exported information elements of the IANA IPFIX
+// registry, NetFlow v9 field types share the same numbers.  Elements missing
+// here are named after their number.
+var elements = map[uint16]element{
+	1:   {"octet_delta_count", typeUnsigned},
+	2:   {"packet_delta_count", typeUnsigned},
+	4:   {"protocol_identifier", typeUnsigned},
+	5:   {"ip_class_of_service", typeUnsigned},
+	6:   {"tcp_control_bits", typeUnsigned},
+	7:   {"source_transport_port", typeUnsigned},
+	8:   {"source_ipv4_address", typeIPv4},
+	9:   {"source_ipv4_prefix_length", typeUnsigned},
+	10:  {"ingress_interface", typeUnsigned},
+	11:  {"destination_transport_port", typeUnsigned},
+	12:  {"destination_ipv4_address", typeIPv4},
+	13:  {"destination_ipv4_prefix_length", typeUnsigned},
+	14:  {"egress_interface", typeUnsigned},
+	15:  {"ip_next_hop_ipv4_address", typeIPv4},
+	16:  {"bgp_source_as_number", typeUnsigned},
+	17:  {"bgp_destination_as_number", typeUnsigned},
+	18:  {"bgp_next_hop_ipv4_address", typeIPv4},
+	21:  {"flow_end_sys_up_time", typeUnsigned},
+	22:  {"flow_start_sys_up_time", typeUnsigned},
+	27:  {"source_ipv6_address", typeIPv6},
+	28:  {"destination_ipv6_address", typeIPv6},
+	29:  {"source_ipv6_prefix_length", typeUnsigned},
+	30:  {"destination_ipv6_prefix_length", typeUnsigned},
+	31:  {"flow_label_ipv6", typeUnsigned},
+	32:  {"icmp_type_code_ipv4", typeUnsigned},
+	56:  {"source_mac_address", typeMAC},
+	57:  {"post_destination_mac_address", typeMAC},
+	58:  {"vlan_id", typeUnsigned},
+	60:  {"ip_version", typeUnsigned},
+	61:  {"flow_direction", typeUnsigned},
+	62:  {"ip_next_hop_ipv6_address", typeIPv6},
+	63:  {"bgp_next_hop_ipv6_address", typeIPv6},
+	80:  {"destination_mac_address", typeMAC},
+	81:  {"post_source_mac_address", typeMAC},
+	82:  {"interface_name", typeString},
+	83:  {"interface_description", typeString},
+	85:  {"octet_total_count", typeUnsigned},
+	86:  {"packet_total_count", typeUnsigned},
+	136: {"flow_end_reason", typeUnsigned},
+	139: {"icmp_type_code_ipv6", typeUnsigned},
+	148: {"flow_id", typeUnsigned},
+	150: {"flow_start_seconds", typeUnsigned},
+	151: {"flow_end_seconds", typeUnsigned},
+	152: {"flow_start_milliseconds", typeUnsigned},
+	153: {"flow_end_milliseconds", typeUnsigned},
+	160: {"system_init_time_milliseconds", typeUnsigned},
+	176: {"icmp_type_ipv4", typeUnsigned},
+	177: {"icmp_code_ipv4", typeUnsigned},
+	178: {"icmp_type_ipv6", typeUnsigned},
+	179: {"icmp_code_ipv6", typeUnsigned},
+	225: {"post_nat_source_ipv4_address", typeIPv4},
+	226: {"post_nat_destination_ipv4_address", typeIPv4},
+	227: {"post_napt_source_transport_port", typeUnsigned},
+	228: {"post_napt_destination_transport_port", typeUnsigned},
+	234: {"ingress_vrf_id", typeUnsigned},
+	235: {"egress_vrf_id", typeUnsigned},
+}
+
+// v5Template describes the fixed records of NetFlow v5 in terms of the
+// information elements.
+var v5Template = &template{
+	fields: []templateField{
+		{id: 8, length: 4},
+		{id: 12, length: 4},
+		{id: 15, length: 4},
+		{id: 10, length: 2},
+		{id: 14, length: 2},
+		{id: 2, length: 4},
+		{id: 1, length: 4},
+		{id: 22, length: 4},
+		{id: 21, length: 4},
+		{id: 7, length: 2},
+		{id: 11, length: 2},
+		{id: paddingOctets, length: 1},
+		{id: 6, length: 1},
+		{id: 4, length: 1},
+		{id: 5, length: 1},
+		{id: 16, length: 2},
+		{id: 17, length: 2},
+		{id: 9, length: 1},
+		{id: 13, length: 1},
+		{id: paddingOctets, length: 2},
+	},
+}
@@ -0,0 +1,463 @@
+package netflow
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	v5HeaderLength    = 24
+	v5RecordLength    = 48
+	v9HeaderLength    = 20
+	ipfixHeaderLength = 16
+
+	// variableLength marks IPFIX elements prefixed by their length.
+	variableLength = 65535
+)
+
+var (
+	ErrLineNotSupported = errors.New("flow files can't be parsed by line")
+
+	errTruncated = errors.New("truncated message")
+)
+
+type Config struct {
+	MetricName  string
+	TagKeys     []string
+	DefaultTags map[string]string
+}
+
+// Parser decodes files of concatenated NetFlow v5, NetFlow v9 and IPFIX
+// messages, such as the IPFIX files of RFC 5655, into a metric per flow
+// record.  Templates are read from the file itself, data sets of templates
+// not in the file are skipped.
+type Parser struct {
+	metricName  string
+	tagKeys     filter.Filter
+	defaultTags map[string]string
+}
+
+type templateField struct {
+	id         uint16
+	enterprise uint32
+	length     uint16
+}
+
+type template struct {
+	fields []templateField
+	// options templates describe the exporter rather than flows, their
+	// records are not emitted.
+	options bool
+}
+
+type templateKey struct {
+	version string
+	domain  uint32
+	id      uint16
+}
+
+// header is the part of the message header shared by its records.
+type header struct {
+	version    string
+	ipfix      bool
+	domain     uint32
+	hasDomain  bool
+	exportTime time.Time
+	// sysUptime is the uptime of the exporter in milliseconds at export time,
+	// NetFlow reports the time of flows relative to it.
+	sysUptime uint32
+	hasUptime bool
+}
+
+func New(config *Config) (*Parser, error) {
+	tagKeys, err := filter.Compile(config.TagKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Parser{
+		metricName:  config.MetricName,
+		tagKeys:     tagKeys,
+		defaultTags: config.DefaultTags,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	templates := make(map[templateKey]*template)
+	metrics := make([]telegraf.Metric, 0)
+	for n := 1; len(buf) > 0; n++ {
+		ms, rest, err := p.parseMessage(buf, templates)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %v", n, err)
+		}
+		metrics = append(metrics, ms...)
+		buf = rest
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	return nil, ErrLineNotSupported
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+// parseMessage parses the message at the start of the buffer, returning the
+// remainder of the buffer.
+func (p *Parser) parseMessage(buf []byte, templates map[templateKey]*template) ([]telegraf.Metric, []byte, error) {
+	if len(buf) < 2 {
+		return nil, nil, errTruncated
+	}
+
+	switch version := binary.BigEndian.Uint16(buf); version {
+	case 5:
+		return p.parseV5(buf)
+	case 9:
+		return p.parseV9(buf, templates)
+	case 10:
+		return p.parseIPFIX(buf, templates)
+	default:
+		return nil, nil, fmt.Errorf("unsupported version %d", version)
+	}
+}
+
+func (p *Parser) parseV5(buf []byte) ([]telegraf.Metric, []byte, error) {
+	if len(buf) < v5HeaderLength {
+		return nil, nil, errTruncated
+	}
+	h := header{
+		version:    "netflow_v5",
+		sysUptime:  binary.BigEndian.Uint32(buf[4:]),
+		hasUptime:  true,
+		exportTime: time.Unix(int64(binary.BigEndian.Uint32(buf[8:])), int64(binary.BigEndian.Uint32(buf[12:]))),
+	}
+
+	count := int(binary.BigEndian.Uint16(buf[2:]))
+	end := v5HeaderLength + count*v5RecordLength
+	if len(buf) < end {
+		return nil, nil, errTruncated
+	}
+
+	metrics := make([]telegraf.Metric, 0, count)
+	for off := v5HeaderLength; off < end; off += v5RecordLength {
+		fields, raw, _, err := decodeRecord(v5Template, buf[off:off+v5RecordLength])
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := p.metric(h, fields, raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, buf[end:], nil
+}
+
+func (p *Parser) parseV9(buf []byte, templates map[templateKey]*template) ([]telegraf.Metric, []byte, error) {
+	if len(buf) < v9HeaderLength {
+		return nil, nil, errTruncated
+	}
+	h := header{
+		version:    "netflow_v9",
+		sysUptime:  binary.BigEndian.Uint32(buf[4:]),
+		hasUptime:  true,
+		exportTime: time.Unix(int64(binary.BigEndian.Uint32(buf[8:])), 0),
+		domain:     binary.BigEndian.Uint32(buf[16:]),
+		hasDomain:  true,
+	}
+
+	// NetFlow v9 packets carry no length, their flowsets are read up to the
+	// next message as flowset IDs never collide with the version numbers.
+	var metrics []telegraf.Metric
+	off := v9HeaderLength
+	for len(buf)-off >= 4 {
+		id := binary.BigEndian.Uint16(buf[off:])
+		if id > 1 && id < 256 {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(buf[off+2:]))
+		if length < 4 || off+length > len(buf) {
+			return nil, nil, fmt.Errorf("invalid flowset length %d", length)
+		}
+		ms, err := p.parseSet(h, id, buf[off+4:off+length], templates)
+		if err != nil {
+			return nil, nil, err
+		}
+		metrics = append(metrics, ms...)
+		off += length
+	}
+	return metrics, buf[off:], nil
+}
+
+func (p *Parser) parseIPFIX(buf []byte, templates map[templateKey]*template) ([]telegraf.Metric, []byte, error) {
+	if len(buf) < ipfixHeaderLength {
+		return nil, nil, errTruncated
+	}
+	length := int(binary.BigEndian.Uint16(buf[2:]))
+	if length < ipfixHeaderLength || length > len(buf) {
+		return nil, nil, fmt.Errorf("invalid message length %d", length)
+	}
+	h := header{
+		version:    "ipfix",
+		ipfix:      true,
+		exportTime: time.Unix(int64(binary.BigEndian.Uint32(buf[4:])), 0),
+		domain:     binary.BigEndian.Uint32(buf[12:]),
+		hasDomain:  true,
+	}
+
+	var metrics []telegraf.Metric
+	off := ipfixHeaderLength
+	for off < length {
+		if length-off < 4 {
+			return nil, nil, errTruncated
+		}
+		id := binary.BigEndian.Uint16(buf[off:])
+		setLength := int(binary.BigEndian.Uint16(buf[off+2:]))
+		if setLength < 4 || off+setLength > length {
+			return nil, nil, fmt.Errorf("invalid set length %d", setLength)
+		}
+		ms, err := p.parseSet(h, id, buf[off+4:off+setLength], templates)
+		if err != nil {
+			return nil, nil, err
+		}
+		metrics = append(metrics, ms...)
+		off += setLength
+	}
+	return metrics, buf[length:], nil
+}
+
+func (p *Parser) parseSet(h header, id uint16, body []byte, templates map[templateKey]*template) ([]telegraf.Metric, error) {
+	templateSet, optionsSet := uint16(0), uint16(1)
+	if h.ipfix {
+		templateSet, optionsSet = 2, 3
+	}
+
+	switch {
+	case id == templateSet:
+		return nil, parseTemplates(h, body, templates, false)
+	case id == optionsSet:
+		return nil, parseTemplates(h, body, templates, true)
+	case id < 256:
+		// Reserved sets are skipped.
+		return nil, nil
+	}
+
+	t, ok := templates[templateKey{h.version, h.domain, id}]
+	if !ok {
+		log.Printf("D! [parsers.netflow] Skipping data set %d without template", id)
+		return nil, nil
+	}
+	minLength := 0
+	for _, f := range t.fields {
+		if f.length == variableLength {
+			minLength++
+		} else {
+			minLength += int(f.length)
+		}
+	}
+	if minLength == 0 {
+		return nil, nil
+	}
+
+	// Anything shorter than a record is padding.
+	var metrics []telegraf.Metric
+	for len(body) >= minLength {
+		fields, raw, n, err := decodeRecord(t, body)
+		if err != nil {
+			return nil, err
+		}
+		body = body[n:]
+		if t.options {
+			continue
+		}
+
+		m, err := p.metric(h, fields, raw)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func parseTemplates(h header, body []byte, templates map[templateKey]*template, options bool) error {
+	for len(body) >= 4 {
+		id := binary.BigEndian.Uint16(body)
+		count := int(binary.BigEndian.Uint16(body[2:]))
+		body = body[4:]
+		key := templateKey{h.version, h.domain, id}
+
+		// IPFIX withdraws templates with an empty template record.
+		if h.ipfix && count == 0 {
+			delete(templates, key)
+			continue
+		}
+
+		if options {
+			if len(body) < 2 {
+				return fmt.Errorf("truncated template %d", id)
+			}
+			if !h.ipfix {
+				// NetFlow v9 gives the lengths of the scope and option
+				// fields in bytes instead of the field count.
+				count = (count + int(binary.BigEndian.Uint16(body))) / 4
+			}
+			body = body[2:]
+		}
+
+		fields := make([]templateField, 0, count)
+		for i := 0; i < count; i++ {
+			if len(body) < 4 {
+				return fmt.Errorf("truncated template %d", id)
+			}
+			f := templateField{
+				id:     binary.BigEndian.Uint16(body),
+				length: binary.BigEndian.Uint16(body[2:]),
+			}
+			body = body[4:]
+			if h.ipfix && f.id&0x8000 != 0 {
+				if len(body) < 4 {
+					return fmt.Errorf("truncated template %d", id)
+				}
+				f.id &= 0x7fff
+				f.enterprise = binary.BigEndian.Uint32(body)
+				body = body[4:]
+			}
+			fields = append(fields, f)
+		}
+		templates[key] = &template{fields: fields, options: options}
+	}
+	return nil
+}
+
+// decodeRecord decodes the record at the start of data, returning the fields,
+// the unsigned values of the IANA elements for time calculations and the
+// length of the record.
+func decodeRecord(t *template, data []byte) (map[string]interface{}, map[uint16]uint64, int, error) {
+	fields := make(map[string]interface{}, len(t.fields))
+	raw := make(map[uint16]uint64)
+	off := 0
+	for _, f := range t.fields {
+		length := int(f.length)
+		if f.length == variableLength {
+			if off >= len(data) {
+				return nil, nil, 0, errors.New("truncated record")
+			}
+			length = int(data[off])
+			off++
+			if length == 255 {
+				if off+2 > len(data) {
+					return nil, nil, 0, errors.New("truncated record")
+				}
+				length = int(binary.BigEndian.Uint16(data[off:]))
+				off += 2
+			}
+		}
+		if off+length > len(data) {
+			return nil, nil, 0, errors.New("truncated record")
+		}
+		value := data[off : off+length]
+		off += length
+
+		if f.enterprise != 0 {
+			fields[fmt.Sprintf("ie%d_%d", f.enterprise, f.id)] = convert(typeUnsigned, value)
+			continue
+		}
+		if f.id == paddingOctets {
+			continue
+		}
+		if length <= 8 {
+			raw[f.id] = unsigned(value)
+		}
+
+		e, ok := elements[f.id]
+		if !ok {
+			e = element{name: "ie" + strconv.Itoa(int(f.id)), typ: typeUnsigned}
+		}
+		fields[e.name] = convert(e.typ, value)
+	}
+	return fields, raw, off, nil
+}
+
+func (p *Parser) metric(h header, fields map[string]interface{}, raw map[uint16]uint64) (telegraf.Metric, error) {
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	tags["version"] = h.version
+	if h.hasDomain {
+		tags["observation_domain_id"] = strconv.FormatUint(uint64(h.domain), 10)
+	}
+
+	if p.tagKeys != nil {
+		for name, value := range fields {
+			if p.tagKeys.Match(name) {
+				tags[name] = fmt.Sprint(value)
+				delete(fields, name)
+			}
+		}
+	}
+
+	return metric.New(p.metricName, tags, fields, flowStart(h, raw))
+}
+
+// flowStart returns the start time of the flow, or the export time if the
+// record doesn't have one.
+func flowStart(h header, raw map[uint16]uint64) time.Time {
+	if ms, ok := raw[152]; ok {
+		return time.Unix(0, int64(ms)*int64(time.Millisecond))
+	}
+	if s, ok := raw[150]; ok {
+		return time.Unix(int64(s), 0)
+	}
+	if uptime, ok := raw[22]; ok {
+		if h.hasUptime {
+			return h.exportTime.Add(time.Duration(int64(uptime)-int64(h.sysUptime)) * time.Millisecond)
+		}
+		if init, ok := raw[160]; ok {
+			return time.Unix(0, int64(init+uptime)*int64(time.Millisecond))
+		}
+	}
+	return h.exportTime
+}
+
+// convert returns the value of an element, values not matching the length of
+// their type are kept as hex strings.
+func convert(typ elementType, value []byte) interface{} {
+	switch {
+	case typ == typeIPv4 && len(value) == net.IPv4len:
+		return net.IP(value).String()
+	case typ == typeIPv6 && len(value) == net.IPv6len:
+		return net.IP(value).String()
+	case typ == typeMAC && len(value) == 6:
+		return net.HardwareAddr(value).String()
+	case typ == typeString:
+		return strings.TrimRight(string(value), "\x00")
+	case typ == typeUnsigned && len(value) <= 8:
+		return int64(unsigned(value))
+	}
+	return hex.EncodeToString(value)
+}
+
+// unsigned decodes big endian unsigned integers of up to 8 bytes, IPFIX
+// allows sending them with fewer bytes than their type.
+func unsigned(value []byte) uint64 {
+	var v uint64
+	for _, b := range value {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
@@ -0,0 +1,187 @@
+package netflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// build concatenates the big endian encoding of the values.
+func build(values ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range values {
+		if err := binary.Write(&buf, binary.BigEndian, v); err != nil {
+			panic(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func set(id uint16, body ...[]byte) []byte {
+	data := bytes.Join(body, nil)
+	return append(build(id, uint16(len(data)+4)), data...)
+}
+
+func ipfixMessage(sets ...[]byte) []byte {
+	data := bytes.Join(sets, nil)
+	header := build(uint16(10), uint16(len(data)+16), uint32(1600000000), uint32(0), uint32(1))
+	return append(header, data...)
+}
+
+func TestParseIPFIX(t *testing.T) {
+	parser, err := New(&Config{MetricName: "netflow", TagKeys: []string{"protocol_identifier"}})
+	require.NoError(t, err)
+
+	templates := set(2,
+		build(uint16(256), uint16(7),
+			uint16(8), uint16(4), uint16(12), uint16(4),
+			uint16(7), uint16(2), uint16(11), uint16(2),
+			uint16(4), uint16(1), uint16(1), uint16(8), uint16(152), uint16(8)),
+		build(uint16(257), uint16(2),
+			uint16(82), uint16(65535), uint16(0x8000|100), uint16(2), uint32(29305)),
+	)
+	options := set(3,
+		build(uint16(258), uint16(2), uint16(1), uint16(149), uint16(4), uint16(41), uint16(8)),
+	)
+	flows := set(256,
+		build([]byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}, uint16(40000), uint16(443), uint8(6), uint64(1500), uint64(1600000000250)),
+		build([]byte{10, 0, 0, 2}, []byte{10, 0, 0, 1}, uint16(443), uint16(40000), uint8(6), uint64(9000), uint64(1600000000260)),
+		// padding
+		build(uint16(0)),
+	)
+	interfaces := set(257, build(uint8(4), []byte("eth0"), uint16(7)))
+	exporter := set(258, build(uint32(1), uint64(12345)))
+	unknown := set(300, build(uint32(0)))
+
+	data := append(ipfixMessage(templates, options), ipfixMessage(flows, interfaces, exporter, unknown)...)
+	metrics, err := parser.Parse(data)
+	require.NoError(t, err)
+
+	tags := map[string]string{"version": "ipfix", "observation_domain_id": "1"}
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"netflow",
+			map[string]string{"version": "ipfix", "observation_domain_id": "1", "protocol_identifier": "6"},
+			map[string]interface{}{
+				"source_ipv4_address":        "10.0.0.1",
+				"destination_ipv4_address":   "10.0.0.2",
+				"source_transport_port":      int64(40000),
+				"destination_transport_port": int64(443),
+				"octet_delta_count":          int64(1500),
+				"flow_start_milliseconds":    int64(1600000000250),
+			},
+			time.Unix(1600000000, 250000000),
+		),
+		testutil.MustMetric(
+			"netflow",
+			map[string]string{"version": "ipfix", "observation_domain_id": "1", "protocol_identifier": "6"},
+			map[string]interface{}{
+				"source_ipv4_address":        "10.0.0.2",
+				"destination_ipv4_address":   "10.0.0.1",
+				"source_transport_port":      int64(443),
+				"destination_transport_port": int64(40000),
+				"octet_delta_count":          int64(9000),
+				"flow_start_milliseconds":    int64(1600000000260),
+			},
+			time.Unix(1600000000, 260000000),
+		),
+		testutil.MustMetric(
+			"netflow",
+			tags,
+			map[string]interface{}{
+				"interface_name": "eth0",
+				"ie29305_100":    int64(7),
+			},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseNetFlow(t *testing.T) {
+	parser, err := New(&Config{MetricName: "netflow"})
+	require.NoError(t, err)
+
+	v9 := build(uint16(9), uint16(2), uint32(10000), uint32(1600000000), uint32(0), uint32(5))
+	v9 = append(v9, set(0,
+		build(uint16(260), uint16(5),
+			uint16(8), uint16(4), uint16(12), uint16(4), uint16(2), uint16(4),
+			uint16(22), uint16(4), uint16(82), uint16(4)),
+	)...)
+	v9 = append(v9, set(260,
+		build([]byte{192, 168, 0, 1}, []byte{192, 168, 0, 2}, uint32(3), uint32(9000), []byte("eth0")),
+		build(uint16(0)),
+	)...)
+
+	v5 := build(uint16(5), uint16(1), uint32(10000), uint32(1600000000), uint32(0), uint32(0), uint16(0), uint16(0))
+	v5 = append(v5, build(
+		[]byte{172, 16, 0, 1}, []byte{172, 16, 0, 2}, []byte{0, 0, 0, 0},
+		uint16(1), uint16(2), uint32(10), uint32(840), uint32(8000), uint32(9500),
+		uint16(53000), uint16(53), uint8(0), uint8(0), uint8(17), uint8(0),
+		uint16(64512), uint16(64513), uint8(24), uint8(24), uint16(0),
+	)...)
+
+	metrics, err := parser.Parse(append(v9, v5...))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"netflow",
+			map[string]string{"version": "netflow_v9", "observation_domain_id": "5"},
+			map[string]interface{}{
+				"source_ipv4_address":      "192.168.0.1",
+				"destination_ipv4_address": "192.168.0.2",
+				"packet_delta_count":       int64(3),
+				"flow_start_sys_up_time":   int64(9000),
+				"interface_name":           "eth0",
+			},
+			time.Unix(1599999999, 0),
+		),
+		testutil.MustMetric(
+			"netflow",
+			map[string]string{"version": "netflow_v5"},
+			map[string]interface{}{
+				"source_ipv4_address":            "172.16.0.1",
+				"destination_ipv4_address":       "172.16.0.2",
+				"ip_next_hop_ipv4_address":       "0.0.0.0",
+				"ingress_interface":              int64(1),
+				"egress_interface":               int64(2),
+				"packet_delta_count":             int64(10),
+				"octet_delta_count":              int64(840),
+				"flow_start_sys_up_time":         int64(8000),
+				"flow_end_sys_up_time":           int64(9500),
+				"source_transport_port":          int64(53000),
+				"destination_transport_port":     int64(53),
+				"tcp_control_bits":               int64(0),
+				"protocol_identifier":            int64(17),
+				"ip_class_of_service":            int64(0),
+				"bgp_source_as_number":           int64(64512),
+				"bgp_destination_as_number":      int64(64513),
+				"source_ipv4_prefix_length":      int64(24),
+				"destination_ipv4_prefix_length": int64(24),
+			},
+			time.Unix(1599999998, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseErrors(t *testing.T) {
+	parser, err := New(&Config{MetricName: "netflow"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("not a flow file"))
+	require.EqualError(t, err, "message 1: unsupported version 28271")
+
+	data := ipfixMessage(set(2, build(uint16(256), uint16(1), uint16(8), uint16(4))))
+	_, err = parser.Parse(append(data, data[:10]...))
+	require.EqualError(t, err, "message 2: truncated message")
+
+	_, err = parser.Parse(ipfixMessage(set(2, build(uint16(256), uint16(2), uint16(8), uint16(4)))))
+	require.EqualError(t, err, "message 1: truncated template 256")
+}
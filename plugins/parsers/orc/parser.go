@@ -0,0 +1,216 @@
+package orc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/scritchley/orc"
+)
+
+const nestedSeparator = "_"
+
+type Config struct {
+	MetricName        string
+	Columns           []string
+	TagColumns        []string
+	MeasurementColumn string
+	TimestampColumn   string
+	TimestampFormat   string
+	Timezone          string
+	DefaultTags       map[string]string
+}
+
+// Parser reads ORC files one stripe at a time, creating a metric for each
+// row.
+type Parser struct {
+	metricName        string
+	columns           []string
+	tagColumns        map[string]bool
+	measurementColumn string
+	timestampColumn   string
+	timestampFormat   string
+	timezone          string
+	defaultTags       map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	p := &Parser{
+		metricName:        config.MetricName,
+		tagColumns:        make(map[string]bool),
+		measurementColumn: config.MeasurementColumn,
+		timestampColumn:   config.TimestampColumn,
+		timestampFormat:   config.TimestampFormat,
+		timezone:          config.Timezone,
+		defaultTags:       config.DefaultTags,
+		TimeFunc:          time.Now,
+	}
+
+	for _, name := range config.TagColumns {
+		p.tagColumns[name] = true
+	}
+
+	// Columns used for tags, the measurement and the timestamp are always
+	// read.
+	if len(config.Columns) > 0 {
+		selected := make(map[string]bool)
+		for _, names := range [][]string{config.Columns, config.TagColumns, {p.measurementColumn, p.timestampColumn}} {
+			for _, name := range names {
+				if name != "" && !selected[name] {
+					selected[name] = true
+					p.columns = append(p.columns, name)
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *Parser) Parse(buf []byte) (metrics []telegraf.Metric, err error) {
+	// The reader panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			metrics, err = nil, fmt.Errorf("invalid orc file: %v", r)
+		}
+	}()
+
+	reader, err := orc.NewReader(bytes.NewReader(buf))
+	if err != nil {
+		return nil, fmt.Errorf("reading footer: %v", err)
+	}
+	defer reader.Close()
+
+	columns := p.columns
+	if columns == nil {
+		columns = reader.Schema().Columns()
+	}
+
+	cursor := reader.Select(columns...)
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	metrics = make([]telegraf.Metric, 0, reader.NumRows())
+	for cursor.Stripes() {
+		for cursor.Next() {
+			m, err := p.createMetric(columns, cursor.Row())
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) createMetric(columns []string, row []interface{}) (telegraf.Metric, error) {
+	values := make(map[string]interface{})
+	for i, name := range columns {
+		flatten(name, row[i], values)
+	}
+
+	name := p.metricName
+	if p.measurementColumn != "" {
+		if v, ok := values[p.measurementColumn]; ok {
+			name = fmt.Sprint(v)
+			delete(values, p.measurementColumn)
+		}
+	}
+
+	timestamp := p.TimeFunc()
+	if p.timestampColumn != "" {
+		v, ok := values[p.timestampColumn]
+		if !ok {
+			return nil, fmt.Errorf("timestamp column %q not found", p.timestampColumn)
+		}
+		var err error
+		timestamp, err = p.parseTimestamp(v)
+		if err != nil {
+			return nil, err
+		}
+		delete(values, p.timestampColumn)
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	for k, v := range values {
+		if p.tagColumns[k] {
+			tags[k] = fmt.Sprint(v)
+			continue
+		}
+		if t, ok := v.(time.Time); ok {
+			v = t.UnixNano()
+		}
+		fields[k] = v
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// parseTimestamp uses the value of timestamp and date columns, and the
+// configured format for other columns.
+func (p *Parser) parseTimestamp(v interface{}) (time.Time, error) {
+	if ts, ok := v.(time.Time); ok {
+		return ts, nil
+	}
+	if p.timestampFormat == "" {
+		return time.Time{}, errors.New("timestamp format must be specified")
+	}
+	return internal.ParseTimestamp(p.timestampFormat, v, p.timezone)
+}
+
+// flatten adds the value of a column to values, naming the fields of structs
+// by joining the names with the separator and unwrapping unions.  Lists and
+// maps can't be mapped to a single field and are skipped.
+func flatten(name string, value interface{}, values map[string]interface{}) {
+	switch v := value.(type) {
+	case nil, []interface{}, []orc.MapEntry:
+	case orc.Struct:
+		for k, inner := range v {
+			flatten(name+nestedSeparator+k, inner, values)
+		}
+	case orc.UnionValue:
+		flatten(name, v.Value, values)
+	case int8:
+		values[name] = int64(v)
+	case orc.Float:
+		values[name] = float64(v)
+	case orc.Double:
+		values[name] = float64(v)
+	case []byte:
+		values[name] = string(v)
+	case orc.Decimal:
+		values[name] = v.Float64()
+	case orc.Date:
+		values[name] = v.Time
+	default:
+		values[name] = v
+	}
+}
@@ -0,0 +1,127 @@
+package orc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/scritchley/orc"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = "struct<host:string,kind:string,time:timestamp,epoch:bigint,value:double,count:int,ok:boolean,location:struct<site:string,rack:int>,labels:array<string>>"
+
+func writeFile(t *testing.T, rows ...[]interface{}) []byte {
+	schema, err := orc.ParseSchema(testSchema)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := orc.NewWriter(&buf, orc.SetSchema(schema))
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, w.Write(row...))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	buf := writeFile(t,
+		[]interface{}{"a", "cpu", time.Unix(1600000000, 0), int64(1500000000), 1.5, int64(3), true, []interface{}{"dc1", int64(7)}, []interface{}{"x"}},
+		[]interface{}{"b", "mem", time.Unix(1600000001, 0), int64(1500000001), 2.5, int64(4), false, []interface{}{nil, nil}, nil},
+	)
+
+	parser, err := New(&Config{
+		MetricName:        "orc",
+		TagColumns:        []string{"host"},
+		MeasurementColumn: "kind",
+		TimestampColumn:   "time",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"epoch":         int64(1500000000),
+				"value":         1.5,
+				"count":         int64(3),
+				"ok":            true,
+				"location_site": "dc1",
+				"location_rack": int64(7),
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "b"},
+			map[string]interface{}{
+				"epoch": int64(1500000001),
+				"value": 2.5,
+				"count": int64(4),
+				"ok":    false,
+			},
+			time.Unix(1600000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestColumnProjectionAndTimestampFormat(t *testing.T) {
+	buf := writeFile(t,
+		[]interface{}{"a", "cpu", nil, int64(1500000000), 1.5, int64(3), true, []interface{}{nil, nil}, nil},
+		[]interface{}{"b", "cpu", nil, int64(1500000001), 2.5, int64(4), true, []interface{}{nil, nil}, nil},
+	)
+
+	parser, err := New(&Config{
+		MetricName:      "orc",
+		Columns:         []string{"value"},
+		TagColumns:      []string{"host"},
+		TimestampColumn: "epoch",
+		TimestampFormat: "unix",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("orc", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.5}, time.Unix(1500000000, 0)),
+		testutil.MustMetric("orc", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.5}, time.Unix(1500000001, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestTimestampFormatRequired(t *testing.T) {
+	buf := writeFile(t, []interface{}{"a", "cpu", nil, int64(1500000000), 1.5, int64(3), true, []interface{}{nil, nil}, nil})
+
+	parser, err := New(&Config{MetricName: "orc", TimestampColumn: "epoch"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse(buf)
+	require.EqualError(t, err, "timestamp format must be specified")
+}
+
+func TestMissingTimestampColumn(t *testing.T) {
+	buf := writeFile(t, []interface{}{"a", "cpu", nil, int64(1500000000), 1.5, int64(3), true, []interface{}{nil, nil}, nil})
+
+	parser, err := New(&Config{MetricName: "orc", TimestampColumn: "missing"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse(buf)
+	require.EqualError(t, err, `timestamp column "missing" not found`)
+}
+
+func TestInvalidFile(t *testing.T) {
+	parser, err := New(&Config{MetricName: "orc"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("not an orc file"))
+	require.Error(t, err)
+}
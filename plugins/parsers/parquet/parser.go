@@ -0,0 +1,353 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+const nestedSeparator = "_"
+
+// julianDayOfEpoch is the Julian day number of 1970-01-01, used by INT96
+// timestamps.
+const julianDayOfEpoch = 2440588
+
+type Config struct {
+	MetricName        string
+	Columns           []string
+	TagColumns        []string
+	MeasurementColumn string
+	TimestampColumn   string
+	TimestampFormat   string
+	Timezone          string
+	DefaultTags       map[string]string
+}
+
+// Parser reads Parquet files one row group at a time, creating a metric for
+// each row.
+type Parser struct {
+	metricName        string
+	columns           map[string]bool
+	tagColumns        map[string]bool
+	measurementColumn string
+	timestampColumn   string
+	timestampFormat   string
+	timezone          string
+	defaultTags       map[string]string
+
+	TimeFunc func() time.Time
+}
+
+// column is a leaf column of the file schema that is read.
+type column struct {
+	name   string
+	path   string
+	schema *parquet.SchemaElement
+}
+
+func New(config *Config) (*Parser, error) {
+	p := &Parser{
+		metricName:        config.MetricName,
+		tagColumns:        make(map[string]bool),
+		measurementColumn: config.MeasurementColumn,
+		timestampColumn:   config.TimestampColumn,
+		timestampFormat:   config.TimestampFormat,
+		timezone:          config.Timezone,
+		defaultTags:       config.DefaultTags,
+		TimeFunc:          time.Now,
+	}
+
+	for _, name := range config.TagColumns {
+		p.tagColumns[name] = true
+	}
+
+	// Columns used for tags, the measurement and the timestamp are always
+	// read.
+	if len(config.Columns) > 0 {
+		p.columns = make(map[string]bool)
+		for _, name := range config.Columns {
+			p.columns[name] = true
+		}
+		for name := range p.tagColumns {
+			p.columns[name] = true
+		}
+		for _, name := range []string{p.measurementColumn, p.timestampColumn} {
+			if name != "" {
+				p.columns[name] = true
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *Parser) Parse(buf []byte) (metrics []telegraf.Metric, err error) {
+	// The reader panics on some malformed files.
+	defer func() {
+		if r := recover(); r != nil {
+			metrics, err = nil, fmt.Errorf("invalid parquet file: %v", r)
+		}
+	}()
+
+	pr, err := reader.NewParquetColumnReader(newBufferFile(buf), 1)
+	if err != nil {
+		return nil, fmt.Errorf("reading footer: %v", err)
+	}
+
+	columns, err := p.selectColumns(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics = make([]telegraf.Metric, 0, pr.GetNumRows())
+	for _, rowGroup := range pr.Footer.GetRowGroups() {
+		rows := rowGroup.GetNumRows()
+		if rows == 0 {
+			continue
+		}
+
+		values := make([][]interface{}, len(columns))
+		for i, c := range columns {
+			values[i], _, _, err = pr.ReadColumnByPath(c.path, rows)
+			if err != nil {
+				return nil, fmt.Errorf("reading column %q: %v", c.name, err)
+			}
+			if int64(len(values[i])) != rows {
+				return nil, fmt.Errorf("reading column %q: expected %d values, got %d", c.name, rows, len(values[i]))
+			}
+		}
+
+		for row := int64(0); row < rows; row++ {
+			m, err := p.createMetric(columns, values, row)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+// selectColumns returns the projected leaf columns of the file.  Repeated
+// columns can't be mapped to a single field and are skipped.
+func (p *Parser) selectColumns(pr *reader.ParquetReader) ([]column, error) {
+	handler := pr.SchemaHandler
+
+	var columns []column
+	found := make(map[string]bool)
+	for _, path := range handler.ValueColumns {
+		index := handler.MapIndex[path]
+		exPath := common.StrToPath(handler.InPathToExPath[path])
+		name := strings.Join(exPath[1:], nestedSeparator)
+
+		if p.columns != nil && !p.columns[name] {
+			continue
+		}
+		level, err := handler.MaxRepetitionLevel(common.StrToPath(path))
+		if err != nil {
+			return nil, err
+		}
+		if level > 0 {
+			continue
+		}
+
+		found[name] = true
+		columns = append(columns, column{
+			name:   name,
+			path:   path,
+			schema: handler.SchemaElements[index],
+		})
+	}
+
+	if p.timestampColumn != "" && !found[p.timestampColumn] {
+		return nil, fmt.Errorf("timestamp column %q not found", p.timestampColumn)
+	}
+	return columns, nil
+}
+
+func (p *Parser) createMetric(columns []column, values [][]interface{}, row int64) (telegraf.Metric, error) {
+	name := p.metricName
+	timestamp := p.TimeFunc()
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+
+	for i, c := range columns {
+		raw := values[i][row]
+		if raw == nil {
+			continue
+		}
+
+		switch c.name {
+		case p.timestampColumn:
+			var err error
+			timestamp, err = p.parseTimestamp(c.schema, raw)
+			if err != nil {
+				return nil, fmt.Errorf("parsing timestamp of row %d: %v", row, err)
+			}
+			continue
+		case p.measurementColumn:
+			name = fmt.Sprint(convertValue(c.schema, raw))
+			continue
+		}
+
+		value := convertValue(c.schema, raw)
+		if p.tagColumns[c.name] {
+			tags[c.name] = fmt.Sprint(value)
+			continue
+		}
+		fields[c.name] = value
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// parseTimestamp uses the type of timestamp columns, and the configured
+// format for other columns.
+func (p *Parser) parseTimestamp(schema *parquet.SchemaElement, raw interface{}) (time.Time, error) {
+	if schema.GetType() == parquet.Type_INT96 {
+		return int96ToTime(raw.(string)), nil
+	}
+	if schema.ConvertedType != nil {
+		switch schema.GetConvertedType() {
+		case parquet.ConvertedType_TIMESTAMP_MILLIS:
+			return time.Unix(0, toInt64(raw)*int64(time.Millisecond)).UTC(), nil
+		case parquet.ConvertedType_TIMESTAMP_MICROS:
+			return time.Unix(0, toInt64(raw)*int64(time.Microsecond)).UTC(), nil
+		case parquet.ConvertedType_DATE:
+			return time.Unix(toInt64(raw)*24*60*60, 0).UTC(), nil
+		}
+	}
+
+	if p.timestampFormat == "" {
+		return time.Time{}, errors.New("timestamp format must be specified")
+	}
+	return internal.ParseTimestamp(p.timestampFormat, convertValue(schema, raw), p.timezone)
+}
+
+// convertValue converts a value read from a column to a field value according
+// to the column type.
+func convertValue(schema *parquet.SchemaElement, raw interface{}) interface{} {
+	if schema.GetType() == parquet.Type_INT96 {
+		return int96ToTime(raw.(string)).UnixNano()
+	}
+
+	if schema.ConvertedType != nil {
+		switch schema.GetConvertedType() {
+		case parquet.ConvertedType_DECIMAL:
+			return decimalToFloat(raw, schema.GetScale())
+		case parquet.ConvertedType_TIMESTAMP_MILLIS:
+			return toInt64(raw) * int64(time.Millisecond)
+		case parquet.ConvertedType_TIMESTAMP_MICROS:
+			return toInt64(raw) * int64(time.Microsecond)
+		case parquet.ConvertedType_UINT_64:
+			return uint64(toInt64(raw))
+		case parquet.ConvertedType_UINT_32:
+			return int64(uint32(toInt64(raw)))
+		}
+	}
+
+	switch v := raw.(type) {
+	case int32:
+		return int64(v)
+	case float32:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+func toInt64(raw interface{}) int64 {
+	switch v := raw.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return 0
+}
+
+// decimalToFloat converts a decimal stored as an integer or as a big-endian
+// two's complement byte array.
+func decimalToFloat(raw interface{}, scale int32) float64 {
+	unscaled := new(big.Int)
+	switch v := raw.(type) {
+	case int32, int64:
+		unscaled.SetInt64(toInt64(v))
+	case string:
+		unscaled.SetBytes([]byte(v))
+		if len(v) > 0 && v[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(v)*8)))
+		}
+	}
+	f, _ := new(big.Float).SetInt(unscaled).Float64()
+	return f / math.Pow10(int(scale))
+}
+
+// int96ToTime converts a legacy INT96 timestamp, the nanoseconds of the day
+// followed by the Julian day.
+func int96ToTime(raw string) time.Time {
+	b := []byte(raw)
+	if len(b) != 12 {
+		return time.Time{}
+	}
+	nanos := int64(binary.LittleEndian.Uint64(b[:8]))
+	days := int64(binary.LittleEndian.Uint32(b[8:]))
+	return time.Unix((days-julianDayOfEpoch)*24*60*60, nanos).UTC()
+}
+
+// bufferFile provides the file interface used by the reader for a buffer.
+type bufferFile struct {
+	*bytes.Reader
+	buf []byte
+}
+
+func newBufferFile(buf []byte) *bufferFile {
+	return &bufferFile{Reader: bytes.NewReader(buf), buf: buf}
+}
+
+func (f *bufferFile) Open(_ string) (source.ParquetFile, error) {
+	return newBufferFile(f.buf), nil
+}
+
+func (f *bufferFile) Create(_ string) (source.ParquetFile, error) {
+	return nil, errors.New("read only")
+}
+
+func (f *bufferFile) Write(_ []byte) (int, error) {
+	return 0, errors.New("read only")
+}
+
+func (f *bufferFile) Close() error {
+	return nil
+}
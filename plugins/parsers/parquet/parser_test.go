@@ -0,0 +1,167 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+type reading struct {
+	Host    string  `parquet:"name=host, type=UTF8, encoding=PLAIN_DICTIONARY"`
+	Kind    string  `parquet:"name=kind, type=UTF8"`
+	Time    int64   `parquet:"name=time, type=TIMESTAMP_MILLIS"`
+	Epoch   int64   `parquet:"name=epoch, type=INT64"`
+	Value   float64 `parquet:"name=value, type=DOUBLE"`
+	Count   int32   `parquet:"name=count, type=INT32"`
+	Ok      bool    `parquet:"name=ok, type=BOOLEAN"`
+	Price   int64   `parquet:"name=price, type=DECIMAL, scale=2, precision=10, basetype=INT64"`
+	Comment *string `parquet:"name=comment, type=UTF8, repetitiontype=OPTIONAL"`
+}
+
+// writeBuffer collects a file written by the parquet writer.
+type writeBuffer struct {
+	bytes.Buffer
+}
+
+func (b *writeBuffer) Seek(_ int64, _ int) (int64, error) {
+	return 0, errors.New("not supported")
+}
+
+func (b *writeBuffer) Open(_ string) (source.ParquetFile, error) {
+	return nil, errors.New("not supported")
+}
+
+func (b *writeBuffer) Create(_ string) (source.ParquetFile, error) {
+	return nil, errors.New("not supported")
+}
+
+func (b *writeBuffer) Close() error {
+	return nil
+}
+
+func writeFile(t *testing.T, rowGroupSize int64, rows ...reading) []byte {
+	var buf writeBuffer
+	pw, err := writer.NewParquetWriter(&buf, new(reading), 1)
+	require.NoError(t, err)
+	pw.RowGroupSize = rowGroupSize
+
+	for _, row := range rows {
+		require.NoError(t, pw.Write(row))
+		if rowGroupSize == 1 {
+			require.NoError(t, pw.Flush(true))
+		}
+	}
+	require.NoError(t, pw.WriteStop())
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	comment := "restarted"
+	buf := writeFile(t, 128*1024*1024,
+		reading{Host: "a", Kind: "cpu", Time: 1600000000000, Epoch: 1500000000, Value: 1.5, Count: 3, Ok: true, Price: 1234, Comment: &comment},
+		reading{Host: "b", Kind: "mem", Time: 1600000001000, Epoch: 1500000001, Value: 2.5, Count: 4, Ok: false, Price: -50},
+	)
+
+	parser, err := New(&Config{
+		MetricName:        "parquet",
+		TagColumns:        []string{"host"},
+		MeasurementColumn: "kind",
+		TimestampColumn:   "time",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{
+				"epoch":   int64(1500000000),
+				"value":   1.5,
+				"count":   int64(3),
+				"ok":      true,
+				"price":   12.34,
+				"comment": "restarted",
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "b"},
+			map[string]interface{}{
+				"epoch": int64(1500000001),
+				"value": 2.5,
+				"count": int64(4),
+				"ok":    false,
+				"price": -0.5,
+			},
+			time.Unix(1600000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestColumnProjectionAndTimestampFormat(t *testing.T) {
+	buf := writeFile(t, 1,
+		reading{Host: "a", Epoch: 1500000000, Value: 1.5},
+		reading{Host: "b", Epoch: 1500000001, Value: 2.5},
+		reading{Host: "c", Epoch: 1500000002, Value: 3.5},
+	)
+
+	parser, err := New(&Config{
+		MetricName:      "parquet",
+		Columns:         []string{"value"},
+		TagColumns:      []string{"host"},
+		TimestampColumn: "epoch",
+		TimestampFormat: "unix",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("parquet", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.5}, time.Unix(1500000000, 0)),
+		testutil.MustMetric("parquet", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.5}, time.Unix(1500000001, 0)),
+		testutil.MustMetric("parquet", map[string]string{"host": "c"}, map[string]interface{}{"value": 3.5}, time.Unix(1500000002, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestMissingTimestampColumn(t *testing.T) {
+	buf := writeFile(t, 1024, reading{Host: "a"})
+
+	parser, err := New(&Config{MetricName: "parquet", TimestampColumn: "missing"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse(buf)
+	require.EqualError(t, err, `timestamp column "missing" not found`)
+}
+
+func TestInvalidFile(t *testing.T) {
+	parser, err := New(&Config{MetricName: "parquet"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("not a parquet file"))
+	require.Error(t, err)
+}
+
+func TestInt96ToTime(t *testing.T) {
+	expected := time.Date(2020, 9, 13, 12, 26, 40, 500000000, time.UTC)
+	midnight := time.Date(2020, 9, 13, 0, 0, 0, 0, time.UTC)
+
+	raw := make([]byte, 12)
+	binary.LittleEndian.PutUint64(raw[:8], uint64(expected.Sub(midnight).Nanoseconds()))
+	binary.LittleEndian.PutUint32(raw[8:], uint32(julianDayOfEpoch+midnight.Unix()/(24*60*60)))
+	require.Equal(t, expected, int96ToTime(string(raw)))
+}
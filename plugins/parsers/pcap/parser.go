@@ -0,0 +1,280 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	ModePacket = "packet"
+	ModeFlow   = "flow"
+)
+
+var ErrLineNotSupported = errors.New("pcap files can't be parsed by line")
+
+type Config struct {
+	MetricName  string
+	Mode        string
+	DefaultTags map[string]string
+}
+
+// Parser decodes the packets of pcap and pcapng capture files, either into
+// a metric per packet or aggregated into a metric per flow.
+type Parser struct {
+	metricName  string
+	mode        string
+	defaultTags map[string]string
+}
+
+// packetReader reads the packets of pcap and pcapng files.
+type packetReader interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+}
+
+// packet is the summary of a decoded packet.
+type packet struct {
+	timestamp time.Time
+	length    int
+	captured  int
+	protocol  string
+	srcIP     string
+	dstIP     string
+	srcPort   int
+	dstPort   int
+}
+
+type flow struct {
+	protocol string
+	srcIP    string
+	dstIP    string
+	srcPort  int
+	dstPort  int
+	first    time.Time
+	last     time.Time
+	packets  int64
+	bytes    int64
+}
+
+func New(config *Config) (*Parser, error) {
+	mode := config.Mode
+	switch mode {
+	case "":
+		mode = ModePacket
+	case ModePacket, ModeFlow:
+	default:
+		return nil, fmt.Errorf("unknown mode %q", mode)
+	}
+
+	return &Parser{
+		metricName:  config.MetricName,
+		mode:        mode,
+		defaultTags: config.DefaultTags,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < 4 {
+		return nil, errors.New("not a pcap or pcapng file")
+	}
+
+	var reader packetReader
+	var linkType func(ci gopacket.CaptureInfo) layers.LinkType
+	if binary.LittleEndian.Uint32(buf) == 0x0a0d0d0a {
+		r, err := pcapgo.NewNgReader(bytes.NewReader(buf), pcapgo.DefaultNgReaderOptions)
+		if err != nil {
+			return nil, err
+		}
+		reader = r
+		linkType = func(ci gopacket.CaptureInfo) layers.LinkType {
+			iface, err := r.Interface(ci.InterfaceIndex)
+			if err != nil {
+				return r.LinkType()
+			}
+			return iface.LinkType
+		}
+	} else {
+		r, err := pcapgo.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return nil, err
+		}
+		reader = r
+		linkType = func(gopacket.CaptureInfo) layers.LinkType {
+			return r.LinkType()
+		}
+	}
+
+	var packets []packet
+	for {
+		data, ci, err := reader.ReadPacketData()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("packet %d: %v", len(packets)+1, err)
+		}
+		packets = append(packets, decode(data, ci, linkType(ci)))
+	}
+
+	if p.mode == ModeFlow {
+		return p.flowMetrics(packets)
+	}
+	return p.packetMetrics(packets)
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	return nil, ErrLineNotSupported
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) packetMetrics(packets []packet) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0, len(packets))
+	for _, pkt := range packets {
+		tags := p.tags()
+		tags["protocol"] = pkt.protocol
+
+		fields := map[string]interface{}{
+			"bytes":          pkt.length,
+			"captured_bytes": pkt.captured,
+		}
+		if pkt.srcIP != "" {
+			fields["src_ip"] = pkt.srcIP
+			fields["dst_ip"] = pkt.dstIP
+		}
+		if pkt.srcPort != 0 || pkt.dstPort != 0 {
+			fields["src_port"] = pkt.srcPort
+			fields["dst_port"] = pkt.dstPort
+		}
+
+		m, err := metric.New(p.metricName, tags, fields, pkt.timestamp)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// flowMetrics aggregates the packets by protocol and endpoints, in the order
+// the flows were first seen.
+func (p *Parser) flowMetrics(packets []packet) ([]telegraf.Metric, error) {
+	type key struct {
+		protocol, srcIP, dstIP string
+		srcPort, dstPort       int
+	}
+	flows := make(map[key]*flow)
+	var order []*flow
+	for _, pkt := range packets {
+		k := key{pkt.protocol, pkt.srcIP, pkt.dstIP, pkt.srcPort, pkt.dstPort}
+		f, ok := flows[k]
+		if !ok {
+			f = &flow{
+				protocol: pkt.protocol,
+				srcIP:    pkt.srcIP,
+				dstIP:    pkt.dstIP,
+				srcPort:  pkt.srcPort,
+				dstPort:  pkt.dstPort,
+				first:    pkt.timestamp,
+			}
+			flows[k] = f
+			order = append(order, f)
+		}
+		if pkt.timestamp.Before(f.first) {
+			f.first = pkt.timestamp
+		}
+		if pkt.timestamp.After(f.last) {
+			f.last = pkt.timestamp
+		}
+		f.packets++
+		f.bytes += int64(pkt.length)
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(order))
+	for _, f := range order {
+		tags := p.tags()
+		tags["protocol"] = f.protocol
+		if f.srcIP != "" {
+			tags["src_ip"] = f.srcIP
+			tags["dst_ip"] = f.dstIP
+		}
+		if f.srcPort != 0 || f.dstPort != 0 {
+			tags["src_port"] = fmt.Sprint(f.srcPort)
+			tags["dst_port"] = fmt.Sprint(f.dstPort)
+		}
+
+		fields := map[string]interface{}{
+			"packets":     f.packets,
+			"bytes":       f.bytes,
+			"duration_ns": f.last.Sub(f.first).Nanoseconds(),
+		}
+
+		m, err := metric.New(p.metricName, tags, fields, f.first)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) tags() map[string]string {
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	return tags
+}
+
+// decode summarizes a packet, packets that can't be fully decoded keep the
+// parts that could.
+func decode(data []byte, ci gopacket.CaptureInfo, linkType layers.LinkType) packet {
+	pkt := packet{
+		timestamp: ci.Timestamp,
+		length:    ci.Length,
+		captured:  ci.CaptureLength,
+		protocol:  "unknown",
+	}
+
+	decoded := gopacket.NewPacket(data, linkType, gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+	if network := decoded.NetworkLayer(); network != nil {
+		switch l := network.(type) {
+		case *layers.IPv4:
+			pkt.srcIP, pkt.dstIP = l.SrcIP.String(), l.DstIP.String()
+			pkt.protocol = strings.ToLower(l.Protocol.String())
+		case *layers.IPv6:
+			pkt.srcIP, pkt.dstIP = l.SrcIP.String(), l.DstIP.String()
+			pkt.protocol = strings.ToLower(l.NextHeader.String())
+		default:
+			pkt.protocol = strings.ToLower(network.LayerType().String())
+		}
+	} else if ls := decoded.Layers(); len(ls) > 1 {
+		// Packets without a network layer, such as ARP.
+		pkt.protocol = strings.ToLower(ls[1].LayerType().String())
+	}
+
+	switch l := decoded.TransportLayer().(type) {
+	case *layers.TCP:
+		pkt.protocol = "tcp"
+		pkt.srcPort, pkt.dstPort = int(l.SrcPort), int(l.DstPort)
+	case *layers.UDP:
+		pkt.protocol = "udp"
+		pkt.srcPort, pkt.dstPort = int(l.SrcPort), int(l.DstPort)
+	case *layers.SCTP:
+		pkt.protocol = "sctp"
+		pkt.srcPort, pkt.dstPort = int(l.SrcPort), int(l.DstPort)
+	}
+	return pkt
+}
@@ -0,0 +1,210 @@
+package pcap
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testPacket struct {
+	offset time.Duration
+	data   []byte
+}
+
+func udpPacket(t *testing.T, src, dst string, srcPort, dstPort int, payload []byte) []byte {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		DstMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 6},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolUDP,
+		SrcIP:    net.ParseIP(src),
+		DstIP:    net.ParseIP(dst),
+	}
+	udp := &layers.UDP{SrcPort: layers.UDPPort(srcPort), DstPort: layers.UDPPort(dstPort)}
+	require.NoError(t, udp.SetNetworkLayerForChecksum(ip))
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	require.NoError(t, gopacket.SerializeLayers(buf, opts, eth, ip, udp, gopacket.Payload(payload)))
+	return buf.Bytes()
+}
+
+func arpPacket(t *testing.T) []byte {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		DstMAC:       net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		EthernetType: layers.EthernetTypeARP,
+	}
+	arp := &layers.ARP{
+		AddrType:          layers.LinkTypeEthernet,
+		Protocol:          layers.EthernetTypeIPv4,
+		HwAddressSize:     6,
+		ProtAddressSize:   4,
+		Operation:         layers.ARPRequest,
+		SourceHwAddress:   []byte{0, 1, 2, 3, 4, 5},
+		SourceProtAddress: []byte{10, 0, 0, 1},
+		DstHwAddress:      []byte{0, 0, 0, 0, 0, 0},
+		DstProtAddress:    []byte{10, 0, 0, 2},
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	require.NoError(t, gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, eth, arp))
+	return buf.Bytes()
+}
+
+// packets returns the test packets, Ethernet pads frames to 60 bytes.
+func packets(t *testing.T) []testPacket {
+	return []testPacket{
+		{0, udpPacket(t, "10.0.0.1", "10.0.0.53", 40000, 53, make([]byte, 30))},
+		{500 * time.Millisecond, arpPacket(t)},
+		{time.Second, udpPacket(t, "10.0.0.1", "10.0.0.53", 40000, 53, make([]byte, 10))},
+	}
+}
+
+func pcapFile(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w := pcapgo.NewWriterNanos(&buf)
+	require.NoError(t, w.WriteFileHeader(65536, layers.LinkTypeEthernet))
+	for _, p := range packets(t) {
+		ci := gopacket.CaptureInfo{
+			Timestamp:     time.Unix(1600000000, 0).Add(p.offset),
+			CaptureLength: len(p.data),
+			Length:        len(p.data),
+		}
+		require.NoError(t, w.WritePacket(ci, p.data))
+	}
+	return buf.Bytes()
+}
+
+func pcapngFile(t *testing.T) []byte {
+	var buf bytes.Buffer
+	w, err := pcapgo.NewNgWriter(&buf, layers.LinkTypeEthernet)
+	require.NoError(t, err)
+	for _, p := range packets(t) {
+		ci := gopacket.CaptureInfo{
+			Timestamp:     time.Unix(1600000000, 0).Add(p.offset),
+			CaptureLength: len(p.data),
+			Length:        len(p.data),
+		}
+		require.NoError(t, w.WritePacket(ci, p.data))
+	}
+	require.NoError(t, w.Flush())
+	return buf.Bytes()
+}
+
+func TestParsePackets(t *testing.T) {
+	parser, err := New(&Config{MetricName: "pcap"})
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"pcap",
+			map[string]string{"protocol": "udp"},
+			map[string]interface{}{
+				"bytes":          72,
+				"captured_bytes": 72,
+				"src_ip":         "10.0.0.1",
+				"dst_ip":         "10.0.0.53",
+				"src_port":       40000,
+				"dst_port":       53,
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"pcap",
+			map[string]string{"protocol": "arp"},
+			map[string]interface{}{
+				"bytes":          60,
+				"captured_bytes": 60,
+			},
+			time.Unix(1600000000, 500000000),
+		),
+		testutil.MustMetric(
+			"pcap",
+			map[string]string{"protocol": "udp"},
+			map[string]interface{}{
+				"bytes":          60,
+				"captured_bytes": 60,
+				"src_ip":         "10.0.0.1",
+				"dst_ip":         "10.0.0.53",
+				"src_port":       40000,
+				"dst_port":       53,
+			},
+			time.Unix(1600000001, 0),
+		),
+	}
+
+	for name, data := range map[string][]byte{"pcap": pcapFile(t), "pcapng": pcapngFile(t)} {
+		t.Run(name, func(t *testing.T) {
+			metrics, err := parser.Parse(data)
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, expected, metrics)
+		})
+	}
+}
+
+func TestParseFlows(t *testing.T) {
+	parser, err := New(&Config{MetricName: "pcap", Mode: ModeFlow})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(pcapFile(t))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"pcap",
+			map[string]string{
+				"protocol": "udp",
+				"src_ip":   "10.0.0.1",
+				"dst_ip":   "10.0.0.53",
+				"src_port": "40000",
+				"dst_port": "53",
+			},
+			map[string]interface{}{
+				"packets":     int64(2),
+				"bytes":       int64(132),
+				"duration_ns": int64(time.Second),
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"pcap",
+			map[string]string{"protocol": "arp"},
+			map[string]interface{}{
+				"packets":     int64(1),
+				"bytes":       int64(60),
+				"duration_ns": int64(0),
+			},
+			time.Unix(1600000000, 500000000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser, err := New(&Config{MetricName: "pcap"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("not a capture"))
+	require.Error(t, err)
+
+	// Cut the last packet short.
+	data := pcapFile(t)
+	_, err = parser.Parse(data[:len(data)-10])
+	require.EqualError(t, err, "packet 3: unexpected EOF")
+
+	_, err = New(&Config{Mode: "sessions"})
+	require.EqualError(t, err, `unknown mode "sessions"`)
+}
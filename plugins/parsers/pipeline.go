@@ -0,0 +1,134 @@
+package parsers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/encoding"
+	"github.com/tidwall/gjson"
+)
+
+// DataTransform is a step applied to the data before it is parsed, such as
+// decompressing or decoding it.
+type DataTransform struct {
+	// Type is one of gzip, zlib, base64, charset or json_envelope.
+	Type string `toml:"type"`
+	// Encoding is the character encoding of the charset transform.
+	Encoding string `toml:"encoding"`
+	// Path is the GJSON path of the payload of the json_envelope transform.
+	Path string `toml:"path"`
+}
+
+type transformFunc func([]byte) ([]byte, error)
+
+// pipelineParser applies the transforms in order and passes the result to
+// the parser.
+type pipelineParser struct {
+	Parser
+	transforms []transformFunc
+}
+
+func newPipelineParser(parser Parser, transforms []DataTransform) (Parser, error) {
+	funcs := make([]transformFunc, 0, len(transforms))
+	for i, t := range transforms {
+		fn, err := newTransform(t)
+		if err != nil {
+			return nil, fmt.Errorf("data_transform %d: %v", i+1, err)
+		}
+		funcs = append(funcs, fn)
+	}
+	return &pipelineParser{Parser: parser, transforms: funcs}, nil
+}
+
+func (p *pipelineParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	data, err := p.transform(buf)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parser.Parse(data)
+}
+
+func (p *pipelineParser) ParseLine(line string) (telegraf.Metric, error) {
+	data, err := p.transform([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	return p.Parser.ParseLine(string(data))
+}
+
+// Unwrap returns the wrapped parser.
+func (p *pipelineParser) Unwrap() Parser {
+	return p.Parser
+}
+
+func (p *pipelineParser) transform(data []byte) ([]byte, error) {
+	var err error
+	for i, fn := range p.transforms {
+		data, err = fn(data)
+		if err != nil {
+			return nil, fmt.Errorf("data_transform %d: %v", i+1, err)
+		}
+	}
+	return data, nil
+}
+
+func newTransform(t DataTransform) (transformFunc, error) {
+	switch t.Type {
+	case "gzip":
+		return func(data []byte) ([]byte, error) {
+			decoder, err := internal.NewGzipDecoder()
+			if err != nil {
+				return nil, err
+			}
+			return decoder.Decode(data)
+		}, nil
+	case "zlib":
+		return func(data []byte) ([]byte, error) {
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+			return ioutil.ReadAll(r)
+		}, nil
+	case "base64":
+		return func(data []byte) ([]byte, error) {
+			return base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+		}, nil
+	case "charset":
+		// Check the encoding, decoders keep state and are created for
+		// each call.
+		if _, err := encoding.NewDecoder(t.Encoding); err != nil {
+			return nil, fmt.Errorf("%v %q", err, t.Encoding)
+		}
+		return func(data []byte) ([]byte, error) {
+			decoder, err := encoding.NewDecoder(t.Encoding)
+			if err != nil {
+				return nil, err
+			}
+			return decoder.Bytes(data)
+		}, nil
+	case "json_envelope":
+		if t.Path == "" {
+			return nil, fmt.Errorf("path must be specified")
+		}
+		return func(data []byte) ([]byte, error) {
+			result := gjson.GetBytes(data, t.Path)
+			if !result.Exists() {
+				return nil, fmt.Errorf("path %q not found", t.Path)
+			}
+			// Strings are unquoted, other values are passed as JSON.
+			if result.Type == gjson.String {
+				return []byte(result.Str), nil
+			}
+			return []byte(result.Raw), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid type %q", t.Type)
+	}
+}
@@ -0,0 +1,62 @@
+package parsers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataTransforms(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte("cpu,host=h\xe9 value=42 1600000000000000000\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	envelope := `{"id": 1, "payload": "` + base64.StdEncoding.EncodeToString(buf.Bytes()) + `"}`
+
+	parser, err := NewParser(&Config{
+		DataFormat: "influx",
+		DataTransforms: []DataTransform{
+			{Type: "json_envelope", Path: "payload"},
+			{Type: "base64"},
+			{Type: "gzip"},
+			{Type: "charset", Encoding: "latin-1"},
+		},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse([]byte(envelope))
+	require.NoError(t, err)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "hé"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	_, err = parser.Parse([]byte(`{"id": 2}`))
+	require.EqualError(t, err, `data_transform 1: path "payload" not found`)
+}
+
+func TestDataTransformErrors(t *testing.T) {
+	_, err := NewParser(&Config{
+		DataFormat:     "influx",
+		DataTransforms: []DataTransform{{Type: "base64"}, {Type: "rot13"}},
+	})
+	require.EqualError(t, err, `data_transform 2: invalid type "rot13"`)
+
+	_, err = NewParser(&Config{
+		DataFormat:     "influx",
+		DataTransforms: []DataTransform{{Type: "charset", Encoding: "ebcdic"}},
+	})
+	require.EqualError(t, err, `data_transform 1: unknown character encoding "ebcdic"`)
+}
@@ -0,0 +1,341 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+const defaultFieldSeparator = "_"
+
+// Framing of the messages in the parsed data.
+const (
+	FramingSingle          = "single"
+	FramingLengthDelimited = "length_delimited"
+)
+
+type Config struct {
+	MetricName       string
+	DescriptorSet    string
+	Files            []string
+	ImportPaths      []string
+	MessageType      string
+	Framing          string
+	MeasurementField string
+	Tags             []string
+	Fields           []string
+	TimestampField   string
+	TimestampFormat  string
+	Timezone         string
+	FieldSeparator   string
+	DefaultTags      map[string]string
+}
+
+// Parser decodes protobuf messages using descriptors loaded at runtime,
+// either from a compiled FileDescriptorSet or from .proto files.
+type Parser struct {
+	metricName       string
+	message          *desc.MessageDescriptor
+	framing          string
+	measurementField string
+	tags             map[string]bool
+	fields           filter.Filter
+	timestampField   string
+	timestampFormat  string
+	timezone         string
+	separator        string
+	defaultTags      map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.MessageType == "" {
+		return nil, errors.New("message type must be specified")
+	}
+
+	framing := config.Framing
+	switch framing {
+	case "":
+		framing = FramingSingle
+	case FramingSingle, FramingLengthDelimited:
+	default:
+		return nil, fmt.Errorf("unknown framing %q", framing)
+	}
+
+	files, err := loadFiles(config)
+	if err != nil {
+		return nil, err
+	}
+	var message *desc.MessageDescriptor
+	for _, fd := range files {
+		if message = fd.FindMessage(config.MessageType); message != nil {
+			break
+		}
+	}
+	if message == nil {
+		return nil, fmt.Errorf("message type %q not found", config.MessageType)
+	}
+
+	fields, err := filter.Compile(config.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("compiling field filter: %v", err)
+	}
+
+	p := &Parser{
+		metricName:       config.MetricName,
+		message:          message,
+		framing:          framing,
+		measurementField: config.MeasurementField,
+		tags:             make(map[string]bool),
+		fields:           fields,
+		timestampField:   config.TimestampField,
+		timestampFormat:  config.TimestampFormat,
+		timezone:         config.Timezone,
+		separator:        config.FieldSeparator,
+		defaultTags:      config.DefaultTags,
+		TimeFunc:         time.Now,
+	}
+	if p.separator == "" {
+		p.separator = defaultFieldSeparator
+	}
+	for _, tag := range config.Tags {
+		p.tags[tag] = true
+	}
+	return p, nil
+}
+
+// loadFiles returns the descriptors of the FileDescriptorSet, or of the .proto
+// files compiled with their imports.
+func loadFiles(config *Config) ([]*desc.FileDescriptor, error) {
+	switch {
+	case config.DescriptorSet != "" && len(config.Files) > 0:
+		return nil, errors.New("only one of descriptor set and proto files can be given")
+	case config.DescriptorSet != "":
+		buf, err := ioutil.ReadFile(config.DescriptorSet)
+		if err != nil {
+			return nil, err
+		}
+		var set dpb.FileDescriptorSet
+		if err := proto.Unmarshal(buf, &set); err != nil {
+			return nil, fmt.Errorf("decoding descriptor set: %v", err)
+		}
+		byName, err := desc.CreateFileDescriptorsFromSet(&set)
+		if err != nil {
+			return nil, fmt.Errorf("loading descriptor set: %v", err)
+		}
+		files := make([]*desc.FileDescriptor, 0, len(byName))
+		for _, fd := range byName {
+			files = append(files, fd)
+		}
+		return files, nil
+	case len(config.Files) > 0:
+		parser := protoparse.Parser{ImportPaths: config.ImportPaths}
+		files, err := parser.ParseFiles(config.Files...)
+		if err != nil {
+			return nil, fmt.Errorf("compiling proto files: %v", err)
+		}
+		return files, nil
+	}
+	return nil, errors.New("descriptor set or proto files must be specified")
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if p.framing == FramingSingle {
+		m, err := p.parseMessage(buf)
+		if err != nil {
+			return nil, err
+		}
+		return []telegraf.Metric{m}, nil
+	}
+
+	var metrics []telegraf.Metric
+	for len(buf) > 0 {
+		size, n := binary.Uvarint(buf)
+		if n <= 0 || uint64(len(buf)-n) < size {
+			return nil, fmt.Errorf("invalid length prefix of message %d", len(metrics)+1)
+		}
+		buf = buf[n:]
+
+		m, err := p.parseMessage(buf[:size])
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %v", len(metrics)+1, err)
+		}
+		metrics = append(metrics, m)
+		buf = buf[size:]
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseMessage(buf []byte) (telegraf.Metric, error) {
+	msg := dynamic.NewMessage(p.message)
+	if err := msg.Unmarshal(buf); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	p.flattenMessage("", msg, values)
+
+	name := p.metricName
+	if p.measurementField != "" {
+		if v, ok := values[p.measurementField]; ok {
+			name = fmt.Sprint(v)
+			delete(values, p.measurementField)
+		}
+	}
+
+	timestamp := p.TimeFunc()
+	if p.timestampField != "" {
+		v, ok := values[p.timestampField]
+		if !ok {
+			return nil, fmt.Errorf("timestamp field %q not found", p.timestampField)
+		}
+		var err error
+		timestamp, err = p.parseTimestamp(v)
+		if err != nil {
+			return nil, err
+		}
+		delete(values, p.timestampField)
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	for k, v := range values {
+		if p.tags[k] {
+			tags[k] = fmt.Sprint(v)
+			continue
+		}
+		if p.fields != nil && !p.fields.Match(k) {
+			continue
+		}
+		if t, ok := v.(time.Time); ok {
+			v = t.UnixNano()
+		}
+		fields[k] = v
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// parseTimestamp uses the value of google.protobuf.Timestamp fields, and the
+// configured format for other fields.
+func (p *Parser) parseTimestamp(v interface{}) (time.Time, error) {
+	if ts, ok := v.(time.Time); ok {
+		return ts, nil
+	}
+	if p.timestampFormat == "" {
+		return time.Time{}, errors.New("timestamp format must be specified")
+	}
+	return internal.ParseTimestamp(p.timestampFormat, v, p.timezone)
+}
+
+// flattenMessage adds the fields of a message to values.  Names of nested
+// messages and map keys are joined with the separator, and the elements of
+// repeated fields are suffixed with their index.
+func (p *Parser) flattenMessage(prefix string, msg *dynamic.Message, values map[string]interface{}) {
+	for _, fd := range msg.GetKnownFields() {
+		name := p.join(prefix, fd.GetName())
+		switch {
+		case fd.IsMap():
+			valueField := fd.GetMapValueType()
+			msg.ForEachMapFieldEntry(fd, func(key, value interface{}) bool {
+				p.flattenValue(p.join(name, fmt.Sprint(key)), valueField, value, values)
+				return true
+			})
+		case fd.IsRepeated():
+			for i, value := range msg.GetField(fd).([]interface{}) {
+				p.flattenValue(p.join(name, strconv.Itoa(i)), fd, value, values)
+			}
+		case fd.GetMessageType() != nil && !msg.HasField(fd):
+			// Unset messages are skipped, scalars always have a value.
+		default:
+			p.flattenValue(name, fd, msg.GetField(fd), values)
+		}
+	}
+}
+
+func (p *Parser) flattenValue(name string, fd *desc.FieldDescriptor, value interface{}, values map[string]interface{}) {
+	// Well-known types are decoded into their generated types.
+	if msg, ok := value.(proto.Message); ok {
+		if _, ok := msg.(*dynamic.Message); !ok {
+			dm, err := dynamic.AsDynamicMessage(msg)
+			if err != nil {
+				return
+			}
+			value = dm
+		}
+	}
+
+	switch v := value.(type) {
+	case *dynamic.Message:
+		switch v.GetMessageDescriptor().GetFullyQualifiedName() {
+		case "google.protobuf.Timestamp":
+			seconds, _ := v.GetFieldByName("seconds").(int64)
+			nanos, _ := v.GetFieldByName("nanos").(int32)
+			values[name] = time.Unix(seconds, int64(nanos)).UTC()
+		case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+			"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+			"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+			"google.protobuf.BoolValue", "google.protobuf.StringValue",
+			"google.protobuf.BytesValue":
+			valueField := v.GetMessageDescriptor().FindFieldByName("value")
+			p.flattenValue(name, valueField, v.GetField(valueField), values)
+		default:
+			p.flattenMessage(name, v, values)
+		}
+	case int32:
+		if enum := fd.GetEnumType(); enum != nil {
+			if ev := enum.FindValueByNumber(v); ev != nil {
+				values[name] = ev.GetName()
+				return
+			}
+		}
+		values[name] = int64(v)
+	case uint32:
+		values[name] = uint64(v)
+	case float32:
+		values[name] = float64(v)
+	case []byte:
+		values[name] = string(v)
+	default:
+		values[name] = v
+	}
+}
+
+func (p *Parser) join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + p.separator + name
+}
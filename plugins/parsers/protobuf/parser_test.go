@@ -0,0 +1,181 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/require"
+)
+
+func readingDescriptor(t *testing.T) *desc.MessageDescriptor {
+	parser := protoparse.Parser{ImportPaths: []string{"testdata"}}
+	files, err := parser.ParseFiles("reading.proto")
+	require.NoError(t, err)
+	return files[0].FindMessage("telegraf.test.Reading")
+}
+
+func encodeReading(t *testing.T, md *desc.MessageDescriptor, host string, seconds int64, value float64) []byte {
+	ts := dynamic.NewMessage(md.FindFieldByName("time").GetMessageType())
+	ts.SetFieldByName("seconds", seconds)
+
+	location := dynamic.NewMessage(md.FindFieldByName("location").GetMessageType())
+	location.SetFieldByName("site", "dc1")
+	location.SetFieldByName("rack", int32(7))
+
+	msg := dynamic.NewMessage(md)
+	msg.SetFieldByName("host", host)
+	msg.SetFieldByName("kind", "cpu")
+	msg.SetFieldByName("time", ts)
+	msg.SetFieldByName("epoch", int64(1500000000))
+	msg.SetFieldByName("value", value)
+	msg.SetFieldByName("count", uint32(3))
+	msg.SetFieldByName("status", int32(2))
+	msg.SetFieldByName("location", location)
+	msg.AddRepeatedFieldByName("samples", float32(0.5))
+	msg.AddRepeatedFieldByName("samples", float32(1.5))
+	msg.PutMapFieldByName("counters", "errors", int32(4))
+
+	buf, err := msg.Marshal()
+	require.NoError(t, err)
+	return buf
+}
+
+func TestParseSingle(t *testing.T) {
+	md := readingDescriptor(t)
+
+	parser, err := New(&Config{
+		MetricName:       "protobuf",
+		Files:            []string{"reading.proto"},
+		ImportPaths:      []string{"testdata"},
+		MessageType:      "telegraf.test.Reading",
+		MeasurementField: "kind",
+		Tags:             []string{"host", "location_site"},
+		TimestampField:   "time",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(encodeReading(t, md, "a", 1600000000, 1.5))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "location_site": "dc1"},
+			map[string]interface{}{
+				"epoch":           int64(1500000000),
+				"value":           1.5,
+				"count":           uint64(3),
+				"status":          "FAILED",
+				"location_rack":   int64(7),
+				"samples_0":       0.5,
+				"samples_1":       1.5,
+				"counters_errors": int64(4),
+			},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseLengthDelimited(t *testing.T) {
+	md := readingDescriptor(t)
+
+	// Write the descriptor set as created by
+	// protoc --include_imports --descriptor_set_out
+	set := desc.ToFileDescriptorSet(md.GetFile())
+	setBuf, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "protobuf")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	setFile := filepath.Join(dir, "reading.pb")
+	require.NoError(t, ioutil.WriteFile(setFile, setBuf, 0644))
+
+	parser, err := New(&Config{
+		MetricName:      "protobuf",
+		DescriptorSet:   setFile,
+		MessageType:     "telegraf.test.Reading",
+		Framing:         FramingLengthDelimited,
+		Tags:            []string{"host"},
+		Fields:          []string{"value"},
+		TimestampField:  "epoch",
+		TimestampFormat: "unix",
+	})
+	require.NoError(t, err)
+
+	var buf []byte
+	for i, host := range []string{"a", "b", "c"} {
+		msg := encodeReading(t, md, host, 1600000000, float64(i))
+		buf = append(buf, proto.EncodeVarint(uint64(len(msg)))...)
+		buf = append(buf, msg...)
+	}
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("protobuf", map[string]string{"host": "a"}, map[string]interface{}{"value": 0.0}, time.Unix(1500000000, 0)),
+		testutil.MustMetric("protobuf", map[string]string{"host": "b"}, map[string]interface{}{"value": 1.0}, time.Unix(1500000000, 0)),
+		testutil.MustMetric("protobuf", map[string]string{"host": "c"}, map[string]interface{}{"value": 2.0}, time.Unix(1500000000, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	// A truncated message is an error.
+	_, err = parser.Parse(buf[:len(buf)-1])
+	require.Error(t, err)
+
+	// So is a length prefix exceeding the data.
+	truncated := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(truncated, 1000)
+	_, err = parser.Parse(truncated[:n])
+	require.Error(t, err)
+}
+
+func TestConfigErrors(t *testing.T) {
+	_, err := New(&Config{MetricName: "protobuf", Files: []string{"reading.proto"}, ImportPaths: []string{"testdata"}})
+	require.EqualError(t, err, "message type must be specified")
+
+	_, err = New(&Config{MetricName: "protobuf", MessageType: "telegraf.test.Reading"})
+	require.EqualError(t, err, "descriptor set or proto files must be specified")
+
+	_, err = New(&Config{
+		MetricName:  "protobuf",
+		Files:       []string{"reading.proto"},
+		ImportPaths: []string{"testdata"},
+		MessageType: "telegraf.test.Missing",
+	})
+	require.EqualError(t, err, `message type "telegraf.test.Missing" not found`)
+
+	_, err = New(&Config{
+		MetricName:  "protobuf",
+		Files:       []string{"reading.proto"},
+		ImportPaths: []string{"testdata"},
+		MessageType: "telegraf.test.Reading",
+		Framing:     "fixed32",
+	})
+	require.EqualError(t, err, `unknown framing "fixed32"`)
+}
+
+func TestInvalidMessage(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:  "protobuf",
+		Files:       []string{"reading.proto"},
+		ImportPaths: []string{"testdata"},
+		MessageType: "telegraf.test.Reading",
+	})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte{0x0a, 0xff})
+	require.Error(t, err)
+}
@@ -2,21 +2,48 @@ package parsers
 
 import (
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/parsers/access_log"
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
+	"github.com/influxdata/telegraf/plugins/parsers/binary"
+	"github.com/influxdata/telegraf/plugins/parsers/cbor"
+	"github.com/influxdata/telegraf/plugins/parsers/cef"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
+	"github.com/influxdata/telegraf/plugins/parsers/evtx"
+	"github.com/influxdata/telegraf/plugins/parsers/execd"
+	"github.com/influxdata/telegraf/plugins/parsers/fix"
+	"github.com/influxdata/telegraf/plugins/parsers/fixed_width"
 	"github.com/influxdata/telegraf/plugins/parsers/form_urlencoded"
 	"github.com/influxdata/telegraf/plugins/parsers/graphite"
 	"github.com/influxdata/telegraf/plugins/parsers/grok"
+	"github.com/influxdata/telegraf/plugins/parsers/hl7"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
+	"github.com/influxdata/telegraf/plugins/parsers/leef"
 	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
+	"github.com/influxdata/telegraf/plugins/parsers/ltsv"
+	"github.com/influxdata/telegraf/plugins/parsers/msgpack"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/ndjson"
+	"github.com/influxdata/telegraf/plugins/parsers/netflow"
+	"github.com/influxdata/telegraf/plugins/parsers/orc"
+	"github.com/influxdata/telegraf/plugins/parsers/parquet"
+	"github.com/influxdata/telegraf/plugins/parsers/pcap"
 	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
+	"github.com/influxdata/telegraf/plugins/parsers/syslog"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
+	"github.com/influxdata/telegraf/plugins/parsers/w3c"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
+	"github.com/influxdata/telegraf/plugins/parsers/xlsx"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
+	"github.com/influxdata/telegraf/plugins/parsers/yaml"
 )
 
 type ParserFunc func() (Parser, error)
@@ -57,12 +84,46 @@ type Parser interface {
 	SetDefaultTags(tags map[string]string)
 }
 
+// StreamParser is an optional interface for parsers able to read their input
+// incrementally instead of requiring all of the data in memory.
+type StreamParser interface {
+	// ParseStream parses the data read from r and calls fn for each metric
+	// as soon as it is parsed.  Parsing stops at the first error, including
+	// errors returned by fn.
+	//
+	// Must be thread-safe.
+	ParseStream(r io.Reader, fn func(telegraf.Metric) error) error
+}
+
+// Unwrap returns the parser wrapped by the options common to all parsers,
+// such as the data transforms and selfstats, or the parser itself if it is
+// not wrapped.
+func Unwrap(parser Parser) Parser {
+	for {
+		w, ok := parser.(interface{ Unwrap() Parser })
+		if !ok {
+			return parser
+		}
+		parser = w.Unwrap()
+	}
+}
+
 // Config is a struct that covers the data types needed for all parser types,
 // and can be used to instantiate _any_ of the parsers.
 type Config struct {
 	// Dataformat can be one of: json, influx, graphite, value, nagios
 	DataFormat string `toml:"data_format"`
 
+	// DataTransforms are applied in order to the data before parsing it
+	DataTransforms []DataTransform `toml:"data_transform"`
+
+	// Fields and tags added to the parsed metrics if they are missing
+	DataDefaultFields map[string]interface{} `toml:"data_default_fields"`
+	DataDefaultTags   map[string]string      `toml:"data_default_tags"`
+	// Fields and tags replacing the parsed values
+	DataConstantFields map[string]interface{} `toml:"data_constant_fields"`
+	DataConstantTags   map[string]string      `toml:"data_constant_tags"`
+
 	// Separator only applied to Graphite data.
 	Separator string `toml:"separator"`
 	// Templates only apply to Graphite data.
@@ -80,9 +141,15 @@ type Config struct {
 	// holds a gjson path for json parser
 	JSONQuery string `toml:"json_query"`
 
+	// holds a JSONPath expression for json parser
+	JSONPath string `toml:"json_path"`
+
 	// key of time
 	JSONTimeKey string `toml:"json_time_key"`
 
+	// JSONPath of time, relative to each object
+	JSONTimePath string `toml:"json_time_path"`
+
 	// time format
 	JSONTimeFormat string `toml:"json_time_format"`
 
@@ -92,6 +159,9 @@ type Config struct {
 	// Whether to continue if a JSON object can't be coerced
 	JSONStrict bool `toml:"json_strict"`
 
+	// keys of arrays of objects to expand into a metric per object
+	JSONExpandArrays []string `toml:"json_expand_arrays"`
+
 	// Authentication file for collectd
 	CollectdAuthFile string `toml:"collectd_auth_file"`
 	// One of none (default), sign, or encrypt
@@ -105,6 +175,11 @@ type Config struct {
 	// DataType only applies to value, this will be the type to parse value to
 	DataType string `toml:"data_type"`
 
+	// ValueFieldNames and ValueSeparator only apply to value, they allow
+	// several named values per line
+	ValueFieldNames []string `toml:"value_field_names"`
+	ValueSeparator  string   `toml:"value_separator"`
+
 	// DefaultTags are the default tags that will be added to all parsed metrics.
 	DefaultTags map[string]string `toml:"default_tags"`
 
@@ -125,21 +200,27 @@ type Config struct {
 	DropwizardTagPathsMap map[string]string `toml:"dropwizard_tag_paths_map"`
 
 	//grok patterns
-	GrokPatterns           []string `toml:"grok_patterns"`
-	GrokNamedPatterns      []string `toml:"grok_named_patterns"`
-	GrokCustomPatterns     string   `toml:"grok_custom_patterns"`
-	GrokCustomPatternFiles []string `toml:"grok_custom_pattern_files"`
-	GrokTimezone           string   `toml:"grok_timezone"`
-	GrokUniqueTimestamp    string   `toml:"grok_unique_timestamp"`
+	GrokPatterns           []string          `toml:"grok_patterns"`
+	GrokNamedPatterns      []string          `toml:"grok_named_patterns"`
+	GrokCustomPatterns     string            `toml:"grok_custom_patterns"`
+	GrokCustomPatternFiles []string          `toml:"grok_custom_pattern_files"`
+	GrokTimezone           string            `toml:"grok_timezone"`
+	GrokTimezones          map[string]string `toml:"grok_timezones"`
+	GrokTagKeys            []string          `toml:"grok_tag_keys"`
+	GrokUniqueTimestamp    string            `toml:"grok_unique_timestamp"`
 
 	//csv configuration
 	CSVColumnNames       []string `toml:"csv_column_names"`
 	CSVColumnTypes       []string `toml:"csv_column_types"`
 	CSVComment           string   `toml:"csv_comment"`
+	CSVCommentPrefix     string   `toml:"csv_comment_prefix"`
 	CSVDelimiter         string   `toml:"csv_delimiter"`
+	CSVEscape            string   `toml:"csv_escape"`
 	CSVHeaderRowCount    int      `toml:"csv_header_row_count"`
 	CSVMeasurementColumn string   `toml:"csv_measurement_column"`
+	CSVQuote             string   `toml:"csv_quote"`
 	CSVSkipColumns       int      `toml:"csv_skip_columns"`
+	CSVSkipFooterRows    int      `toml:"csv_skip_footer_rows"`
 	CSVSkipRows          int      `toml:"csv_skip_rows"`
 	CSVTagColumns        []string `toml:"csv_tag_columns"`
 	CSVTimestampColumn   string   `toml:"csv_timestamp_column"`
@@ -148,8 +229,218 @@ type Config struct {
 	CSVTrimSpace         bool     `toml:"csv_trim_space"`
 	CSVSkipValues        []string `toml:"csv_skip_values"`
 
+	CSVColumnTypeMap map[string]string `toml:"csv_column_type_map"`
+	CSVNullValues    []string          `toml:"csv_null_values"`
+
 	// FormData configuration
 	FormUrlencodedTagKeys []string `toml:"form_urlencoded_tag_keys"`
+
+	// Avro configuration
+	AvroSchema           string   `toml:"avro_schema"`
+	AvroSchemaFile       string   `toml:"avro_schema_file"`
+	AvroSchemaRegistry   string   `toml:"avro_schema_registry"`
+	AvroMeasurementField string   `toml:"avro_measurement_field"`
+	AvroTags             []string `toml:"avro_tags"`
+	AvroFields           []string `toml:"avro_fields"`
+	AvroTimestampField   string   `toml:"avro_timestamp_field"`
+	AvroTimestampFormat  string   `toml:"avro_timestamp_format"`
+	AvroTimezone         string   `toml:"avro_timezone"`
+	AvroFieldSeparator   string   `toml:"avro_field_separator"`
+
+	// Parquet configuration
+	ParquetColumns           []string `toml:"parquet_columns"`
+	ParquetTagColumns        []string `toml:"parquet_tag_columns"`
+	ParquetMeasurementColumn string   `toml:"parquet_measurement_column"`
+	ParquetTimestampColumn   string   `toml:"parquet_timestamp_column"`
+	ParquetTimestampFormat   string   `toml:"parquet_timestamp_format"`
+	ParquetTimezone          string   `toml:"parquet_timezone"`
+
+	// ORC configuration
+	OrcColumns           []string `toml:"orc_columns"`
+	OrcTagColumns        []string `toml:"orc_tag_columns"`
+	OrcMeasurementColumn string   `toml:"orc_measurement_column"`
+	OrcTimestampColumn   string   `toml:"orc_timestamp_column"`
+	OrcTimestampFormat   string   `toml:"orc_timestamp_format"`
+	OrcTimezone          string   `toml:"orc_timezone"`
+
+	// XLSX configuration
+	XLSXSheets            []string `toml:"xlsx_sheets"`
+	XLSXSheetTag          string   `toml:"xlsx_sheet_tag"`
+	XLSXHeaderRow         int      `toml:"xlsx_header_row"`
+	XLSXSkipRows          int      `toml:"xlsx_skip_rows"`
+	XLSXColumnNames       []string `toml:"xlsx_column_names"`
+	XLSXColumnTypes       []string `toml:"xlsx_column_types"`
+	XLSXTagColumns        []string `toml:"xlsx_tag_columns"`
+	XLSXMeasurementColumn string   `toml:"xlsx_measurement_column"`
+	XLSXTimestampColumn   string   `toml:"xlsx_timestamp_column"`
+	XLSXTimestampFormat   string   `toml:"xlsx_timestamp_format"`
+	XLSXTimezone          string   `toml:"xlsx_timezone"`
+
+	// Protobuf configuration
+	ProtobufDescriptorSet    string   `toml:"protobuf_descriptor_set"`
+	ProtobufFiles            []string `toml:"protobuf_files"`
+	ProtobufImportPaths      []string `toml:"protobuf_import_paths"`
+	ProtobufMessageType      string   `toml:"protobuf_message_type"`
+	ProtobufFraming          string   `toml:"protobuf_framing"`
+	ProtobufMeasurementField string   `toml:"protobuf_measurement_field"`
+	ProtobufTags             []string `toml:"protobuf_tags"`
+	ProtobufFields           []string `toml:"protobuf_fields"`
+	ProtobufTimestampField   string   `toml:"protobuf_timestamp_field"`
+	ProtobufTimestampFormat  string   `toml:"protobuf_timestamp_format"`
+	ProtobufTimezone         string   `toml:"protobuf_timezone"`
+	ProtobufFieldSeparator   string   `toml:"protobuf_field_separator"`
+
+	// XML configuration
+	XMLConfig []XMLConfig `toml:"xml"`
+
+	// MessagePack configuration
+	MsgpackTagKeys      []string `toml:"msgpack_tag_keys"`
+	MsgpackNameKey      string   `toml:"msgpack_name_key"`
+	MsgpackStringFields []string `toml:"msgpack_string_fields"`
+	MsgpackTimeKey      string   `toml:"msgpack_time_key"`
+	MsgpackTimeFormat   string   `toml:"msgpack_time_format"`
+	MsgpackTimezone     string   `toml:"msgpack_timezone"`
+	MsgpackStrict       bool     `toml:"msgpack_strict"`
+
+	// CBOR configuration
+	CBORTagKeys      []string `toml:"cbor_tag_keys"`
+	CBORNameKey      string   `toml:"cbor_name_key"`
+	CBORStringFields []string `toml:"cbor_string_fields"`
+	CBORTimeKey      string   `toml:"cbor_time_key"`
+	CBORTimeFormat   string   `toml:"cbor_time_format"`
+	CBORTimezone     string   `toml:"cbor_timezone"`
+	CBORStrict       bool     `toml:"cbor_strict"`
+
+	// CEF configuration
+	CEFTagKeys         []string `toml:"cef_tag_keys"`
+	CEFResolveLabels   bool     `toml:"cef_resolve_labels"`
+	CEFTimestampKey    string   `toml:"cef_timestamp_key"`
+	CEFTimestampFormat string   `toml:"cef_timestamp_format"`
+	CEFTimezone        string   `toml:"cef_timezone"`
+
+	// LEEF configuration
+	LEEFDelimiter       string   `toml:"leef_delimiter"`
+	LEEFTagKeys         []string `toml:"leef_tag_keys"`
+	LEEFTimestampKey    string   `toml:"leef_timestamp_key"`
+	LEEFTimestampFormat string   `toml:"leef_timestamp_format"`
+	LEEFTimezone        string   `toml:"leef_timezone"`
+
+	// LTSV configuration
+	LTSVTagKeys         []string          `toml:"ltsv_tag_keys"`
+	LTSVFieldTypes      map[string]string `toml:"ltsv_field_types"`
+	LTSVTimestampLabel  string            `toml:"ltsv_timestamp_label"`
+	LTSVTimestampFormat string            `toml:"ltsv_timestamp_format"`
+	LTSVTimezone        string            `toml:"ltsv_timezone"`
+
+	// W3C extended log format configuration
+	W3CFields   []string `toml:"w3c_fields"`
+	W3CTagKeys  []string `toml:"w3c_tag_keys"`
+	W3CTimezone string   `toml:"w3c_timezone"`
+
+	// Access log configuration
+	AccessLogFormat string `toml:"access_log_format"`
+
+	// Fixed-width configuration
+	FixedWidthColumnNames       []string `toml:"fixed_width_column_names"`
+	FixedWidthColumnWidths      []int    `toml:"fixed_width_column_widths"`
+	FixedWidthColumnOffsets     []int    `toml:"fixed_width_column_offsets"`
+	FixedWidthColumnTypes       []string `toml:"fixed_width_column_types"`
+	FixedWidthTagColumns        []string `toml:"fixed_width_tag_columns"`
+	FixedWidthMeasurementColumn string   `toml:"fixed_width_measurement_column"`
+	FixedWidthTimestampColumn   string   `toml:"fixed_width_timestamp_column"`
+	FixedWidthTimestampFormat   string   `toml:"fixed_width_timestamp_format"`
+	FixedWidthTimezone          string   `toml:"fixed_width_timezone"`
+	FixedWidthSkipRows          int      `toml:"fixed_width_skip_rows"`
+	FixedWidthSkipValues        []string `toml:"fixed_width_skip_values"`
+
+	// NDJSON configuration
+	NDJSONTagKeys      []string `toml:"ndjson_tag_keys"`
+	NDJSONNameKey      string   `toml:"ndjson_name_key"`
+	NDJSONStringFields []string `toml:"ndjson_string_fields"`
+	NDJSONTimeKey      string   `toml:"ndjson_time_key"`
+	NDJSONTimeFormat   string   `toml:"ndjson_time_format"`
+	NDJSONTimezone     string   `toml:"ndjson_timezone"`
+	NDJSONMaxLineBytes int      `toml:"ndjson_max_line_bytes"`
+	NDJSONStrict       bool     `toml:"ndjson_strict"`
+
+	// YAML configuration
+	YAMLTagKeys      []string `toml:"yaml_tag_keys"`
+	YAMLNameKey      string   `toml:"yaml_name_key"`
+	YAMLStringFields []string `toml:"yaml_string_fields"`
+	YAMLTimeKey      string   `toml:"yaml_time_key"`
+	YAMLTimeFormat   string   `toml:"yaml_time_format"`
+	YAMLTimezone     string   `toml:"yaml_timezone"`
+	YAMLStrict       bool     `toml:"yaml_strict"`
+
+	// Syslog configuration
+	SyslogTimezone         string `toml:"syslog_timezone"`
+	SyslogBestEffort       bool   `toml:"syslog_best_effort"`
+	SyslogSDParamSeparator string `toml:"syslog_sdparam_separator"`
+
+	// Windows event log file configuration
+	EVTXEventTags     []string `toml:"evtx_event_tags"`
+	EVTXEventFields   []string `toml:"evtx_event_fields"`
+	EVTXExcludeFields []string `toml:"evtx_exclude_fields"`
+	EVTXSeparator     string   `toml:"evtx_separator"`
+
+	// HL7 configuration
+	HL7Tags     map[string]string `toml:"hl7_tags"`
+	HL7Fields   map[string]string `toml:"hl7_fields"`
+	HL7Timezone string            `toml:"hl7_timezone"`
+
+	// FIX configuration
+	FIXDictionary string   `toml:"fix_dictionary"`
+	FIXDelimiter  string   `toml:"fix_delimiter"`
+	FIXTagKeys    []string `toml:"fix_tag_keys"`
+
+	// pcap configuration
+	PcapMode string `toml:"pcap_mode"`
+
+	// NetFlow configuration
+	NetFlowTagKeys []string `toml:"netflow_tag_keys"`
+
+	// Binary configuration
+	BinaryEndianness       string        `toml:"binary_endianness"`
+	BinaryHeaderLength     int           `toml:"binary_header_length"`
+	BinaryRecordLength     int           `toml:"binary_record_length"`
+	BinaryFields           []BinaryField `toml:"binary_field"`
+	BinaryTagFields        []string      `toml:"binary_tag_fields"`
+	BinaryMeasurementField string        `toml:"binary_measurement_field"`
+	BinaryTimestampField   string        `toml:"binary_timestamp_field"`
+	BinaryTimestampFormat  string        `toml:"binary_timestamp_format"`
+	BinaryTimezone         string        `toml:"binary_timezone"`
+
+	// Execd configuration
+	ExecdCommand      []string      `toml:"execd_command"`
+	ExecdRestartDelay time.Duration `toml:"execd_restart_delay"`
+	ExecdTimeout      time.Duration `toml:"execd_timeout"`
+}
+
+// XMLConfig describes how the metrics are built from an XML document.
+type XMLConfig struct {
+	MetricName      string            `toml:"metric_name"`
+	Selection       string            `toml:"metric_selection"`
+	Timestamp       string            `toml:"timestamp"`
+	TimestampFormat string            `toml:"timestamp_format"`
+	Timezone        string            `toml:"timezone"`
+	Tags            map[string]string `toml:"tags"`
+	Fields          map[string]string `toml:"fields"`
+	FieldsInt       map[string]string `toml:"fields_int"`
+
+	FieldSelection  string `toml:"field_selection"`
+	FieldName       string `toml:"field_name"`
+	FieldValue      string `toml:"field_value"`
+	FieldNameExpand bool   `toml:"field_name_expansion"`
+}
+
+// BinaryField describes a value of the records of the binary data format.
+type BinaryField struct {
+	Name       string `toml:"name"`
+	Type       string `toml:"type"`
+	Offset     int    `toml:"offset"`
+	Length     int    `toml:"length"`
+	Count      int    `toml:"count"`
+	Endianness string `toml:"endianness"`
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -165,16 +456,24 @@ func NewParser(config *Config) (Parser, error) {
 				NameKey:      config.JSONNameKey,
 				StringFields: config.JSONStringFields,
 				Query:        config.JSONQuery,
+				Path:         config.JSONPath,
 				TimeKey:      config.JSONTimeKey,
+				TimePath:     config.JSONTimePath,
 				TimeFormat:   config.JSONTimeFormat,
 				Timezone:     config.JSONTimezone,
+				ExpandArrays: config.JSONExpandArrays,
 				DefaultTags:  config.DefaultTags,
 				Strict:       config.JSONStrict,
 			},
 		)
 	case "value":
-		parser, err = NewValueParser(config.MetricName,
-			config.DataType, config.DefaultTags)
+		parser = &value.ValueParser{
+			MetricName:  config.MetricName,
+			DataType:    config.DataType,
+			DefaultTags: config.DefaultTags,
+			FieldNames:  config.ValueFieldNames,
+			Separator:   config.ValueSeparator,
+		}
 	case "influx":
 		parser, err = NewInfluxParser()
 	case "nagios":
@@ -205,6 +504,8 @@ func NewParser(config *Config) (Parser, error) {
 			config.GrokCustomPatterns,
 			config.GrokCustomPatternFiles,
 			config.GrokTimezone,
+			config.GrokTimezones,
+			config.GrokTagKeys,
 			config.GrokUniqueTimestamp)
 	case "csv":
 		config := &csv.Config{
@@ -212,8 +513,12 @@ func NewParser(config *Config) (Parser, error) {
 			HeaderRowCount:    config.CSVHeaderRowCount,
 			SkipRows:          config.CSVSkipRows,
 			SkipColumns:       config.CSVSkipColumns,
+			SkipFooterRows:    config.CSVSkipFooterRows,
 			Delimiter:         config.CSVDelimiter,
 			Comment:           config.CSVComment,
+			CommentPrefix:     config.CSVCommentPrefix,
+			Quote:             config.CSVQuote,
+			Escape:            config.CSVEscape,
 			TrimSpace:         config.CSVTrimSpace,
 			ColumnNames:       config.CSVColumnNames,
 			ColumnTypes:       config.CSVColumnTypes,
@@ -224,6 +529,8 @@ func NewParser(config *Config) (Parser, error) {
 			Timezone:          config.CSVTimezone,
 			DefaultTags:       config.DefaultTags,
 			SkipValues:        config.CSVSkipValues,
+			ColumnTypeMap:     config.CSVColumnTypeMap,
+			NullValues:        config.CSVNullValues,
 		}
 
 		return csv.NewParser(config)
@@ -237,16 +544,364 @@ func NewParser(config *Config) (Parser, error) {
 		)
 	case "prometheus":
 		parser, err = NewPrometheusParser(config.DefaultTags)
+	case "avro":
+		parser, err = avro.New(
+			&avro.Config{
+				MetricName:       config.MetricName,
+				Schema:           config.AvroSchema,
+				SchemaFile:       config.AvroSchemaFile,
+				SchemaRegistry:   config.AvroSchemaRegistry,
+				MeasurementField: config.AvroMeasurementField,
+				Tags:             config.AvroTags,
+				Fields:           config.AvroFields,
+				TimestampField:   config.AvroTimestampField,
+				TimestampFormat:  config.AvroTimestampFormat,
+				Timezone:         config.AvroTimezone,
+				FieldSeparator:   config.AvroFieldSeparator,
+				DefaultTags:      config.DefaultTags,
+			},
+		)
+	case "parquet":
+		parser, err = parquet.New(
+			&parquet.Config{
+				MetricName:        config.MetricName,
+				Columns:           config.ParquetColumns,
+				TagColumns:        config.ParquetTagColumns,
+				MeasurementColumn: config.ParquetMeasurementColumn,
+				TimestampColumn:   config.ParquetTimestampColumn,
+				TimestampFormat:   config.ParquetTimestampFormat,
+				Timezone:          config.ParquetTimezone,
+				DefaultTags:       config.DefaultTags,
+			},
+		)
+	case "orc":
+		parser, err = orc.New(
+			&orc.Config{
+				MetricName:        config.MetricName,
+				Columns:           config.OrcColumns,
+				TagColumns:        config.OrcTagColumns,
+				MeasurementColumn: config.OrcMeasurementColumn,
+				TimestampColumn:   config.OrcTimestampColumn,
+				TimestampFormat:   config.OrcTimestampFormat,
+				Timezone:          config.OrcTimezone,
+				DefaultTags:       config.DefaultTags,
+			},
+		)
+	case "xlsx":
+		parser, err = xlsx.New(
+			&xlsx.Config{
+				MetricName:        config.MetricName,
+				Sheets:            config.XLSXSheets,
+				SheetTag:          config.XLSXSheetTag,
+				HeaderRow:         config.XLSXHeaderRow,
+				SkipRows:          config.XLSXSkipRows,
+				ColumnNames:       config.XLSXColumnNames,
+				ColumnTypes:       config.XLSXColumnTypes,
+				TagColumns:        config.XLSXTagColumns,
+				MeasurementColumn: config.XLSXMeasurementColumn,
+				TimestampColumn:   config.XLSXTimestampColumn,
+				TimestampFormat:   config.XLSXTimestampFormat,
+				Timezone:          config.XLSXTimezone,
+				DefaultTags:       config.DefaultTags,
+			},
+		)
+	case "protobuf":
+		parser, err = protobuf.New(
+			&protobuf.Config{
+				MetricName:       config.MetricName,
+				DescriptorSet:    config.ProtobufDescriptorSet,
+				Files:            config.ProtobufFiles,
+				ImportPaths:      config.ProtobufImportPaths,
+				MessageType:      config.ProtobufMessageType,
+				Framing:          config.ProtobufFraming,
+				MeasurementField: config.ProtobufMeasurementField,
+				Tags:             config.ProtobufTags,
+				Fields:           config.ProtobufFields,
+				TimestampField:   config.ProtobufTimestampField,
+				TimestampFormat:  config.ProtobufTimestampFormat,
+				Timezone:         config.ProtobufTimezone,
+				FieldSeparator:   config.ProtobufFieldSeparator,
+				DefaultTags:      config.DefaultTags,
+			},
+		)
+	case "xml":
+		parser, err = newXMLParser(config.MetricName, config.XMLConfig, config.DefaultTags)
+	case "msgpack":
+		parser, err = msgpack.New(
+			&msgpack.Config{
+				MetricName:   config.MetricName,
+				TagKeys:      config.MsgpackTagKeys,
+				NameKey:      config.MsgpackNameKey,
+				StringFields: config.MsgpackStringFields,
+				TimeKey:      config.MsgpackTimeKey,
+				TimeFormat:   config.MsgpackTimeFormat,
+				Timezone:     config.MsgpackTimezone,
+				DefaultTags:  config.DefaultTags,
+				Strict:       config.MsgpackStrict,
+			},
+		)
+	case "cbor":
+		parser, err = cbor.New(
+			&cbor.Config{
+				MetricName:   config.MetricName,
+				TagKeys:      config.CBORTagKeys,
+				NameKey:      config.CBORNameKey,
+				StringFields: config.CBORStringFields,
+				TimeKey:      config.CBORTimeKey,
+				TimeFormat:   config.CBORTimeFormat,
+				Timezone:     config.CBORTimezone,
+				DefaultTags:  config.DefaultTags,
+				Strict:       config.CBORStrict,
+			},
+		)
+	case "cef":
+		parser, err = cef.New(
+			&cef.Config{
+				MetricName:      config.MetricName,
+				TagKeys:         config.CEFTagKeys,
+				ResolveLabels:   config.CEFResolveLabels,
+				TimestampKey:    config.CEFTimestampKey,
+				TimestampFormat: config.CEFTimestampFormat,
+				Timezone:        config.CEFTimezone,
+				DefaultTags:     config.DefaultTags,
+			},
+		)
+	case "leef":
+		parser, err = leef.New(
+			&leef.Config{
+				MetricName:      config.MetricName,
+				Delimiter:       config.LEEFDelimiter,
+				TagKeys:         config.LEEFTagKeys,
+				TimestampKey:    config.LEEFTimestampKey,
+				TimestampFormat: config.LEEFTimestampFormat,
+				Timezone:        config.LEEFTimezone,
+				DefaultTags:     config.DefaultTags,
+			},
+		)
+	case "ltsv":
+		parser, err = ltsv.New(
+			&ltsv.Config{
+				MetricName:      config.MetricName,
+				TagKeys:         config.LTSVTagKeys,
+				FieldTypes:      config.LTSVFieldTypes,
+				TimestampLabel:  config.LTSVTimestampLabel,
+				TimestampFormat: config.LTSVTimestampFormat,
+				Timezone:        config.LTSVTimezone,
+				DefaultTags:     config.DefaultTags,
+			},
+		)
+	case "w3c":
+		parser, err = w3c.New(
+			&w3c.Config{
+				MetricName:  config.MetricName,
+				Fields:      config.W3CFields,
+				TagKeys:     config.W3CTagKeys,
+				Timezone:    config.W3CTimezone,
+				DefaultTags: config.DefaultTags,
+			},
+		)
+	case "access_log":
+		parser, err = access_log.New(
+			&access_log.Config{
+				MetricName:  config.MetricName,
+				Format:      config.AccessLogFormat,
+				DefaultTags: config.DefaultTags,
+			},
+		)
+	case "fixed_width":
+		parser, err = fixed_width.New(
+			&fixed_width.Config{
+				MetricName:        config.MetricName,
+				ColumnNames:       config.FixedWidthColumnNames,
+				ColumnWidths:      config.FixedWidthColumnWidths,
+				ColumnOffsets:     config.FixedWidthColumnOffsets,
+				ColumnTypes:       config.FixedWidthColumnTypes,
+				TagColumns:        config.FixedWidthTagColumns,
+				MeasurementColumn: config.FixedWidthMeasurementColumn,
+				TimestampColumn:   config.FixedWidthTimestampColumn,
+				TimestampFormat:   config.FixedWidthTimestampFormat,
+				Timezone:          config.FixedWidthTimezone,
+				SkipRows:          config.FixedWidthSkipRows,
+				SkipValues:        config.FixedWidthSkipValues,
+				DefaultTags:       config.DefaultTags,
+			},
+		)
+	case "ndjson":
+		parser, err = ndjson.New(
+			&ndjson.Config{
+				MetricName:   config.MetricName,
+				TagKeys:      config.NDJSONTagKeys,
+				NameKey:      config.NDJSONNameKey,
+				StringFields: config.NDJSONStringFields,
+				TimeKey:      config.NDJSONTimeKey,
+				TimeFormat:   config.NDJSONTimeFormat,
+				Timezone:     config.NDJSONTimezone,
+				MaxLineBytes: config.NDJSONMaxLineBytes,
+				Strict:       config.NDJSONStrict,
+				DefaultTags:  config.DefaultTags,
+			},
+		)
+	case "yaml":
+		parser, err = yaml.New(
+			&yaml.Config{
+				MetricName:   config.MetricName,
+				TagKeys:      config.YAMLTagKeys,
+				NameKey:      config.YAMLNameKey,
+				StringFields: config.YAMLStringFields,
+				TimeKey:      config.YAMLTimeKey,
+				TimeFormat:   config.YAMLTimeFormat,
+				Timezone:     config.YAMLTimezone,
+				Strict:       config.YAMLStrict,
+				DefaultTags:  config.DefaultTags,
+			},
+		)
+	case "syslog":
+		parser, err = syslog.New(
+			&syslog.Config{
+				MetricName:  config.MetricName,
+				Timezone:    config.SyslogTimezone,
+				BestEffort:  config.SyslogBestEffort,
+				Separator:   config.SyslogSDParamSeparator,
+				DefaultTags: config.DefaultTags,
+			},
+		)
+	case "evtx":
+		parser, err = evtx.New(
+			&evtx.Config{
+				MetricName:    config.MetricName,
+				EventTags:     config.EVTXEventTags,
+				EventFields:   config.EVTXEventFields,
+				ExcludeFields: config.EVTXExcludeFields,
+				Separator:     config.EVTXSeparator,
+				DefaultTags:   config.DefaultTags,
+			},
+		)
+	case "hl7":
+		parser, err = hl7.New(
+			&hl7.Config{
+				MetricName:  config.MetricName,
+				Tags:        config.HL7Tags,
+				Fields:      config.HL7Fields,
+				Timezone:    config.HL7Timezone,
+				DefaultTags: config.DefaultTags,
+			},
+		)
+	case "fix":
+		parser, err = fix.New(
+			&fix.Config{
+				MetricName:  config.MetricName,
+				Dictionary:  config.FIXDictionary,
+				Delimiter:   config.FIXDelimiter,
+				TagKeys:     config.FIXTagKeys,
+				DefaultTags: config.DefaultTags,
+			},
+		)
+	case "pcap":
+		parser, err = pcap.New(
+			&pcap.Config{
+				MetricName:  config.MetricName,
+				Mode:        config.PcapMode,
+				DefaultTags: config.DefaultTags,
+			},
+		)
+	case "netflow":
+		parser, err = netflow.New(
+			&netflow.Config{
+				MetricName:  config.MetricName,
+				TagKeys:     config.NetFlowTagKeys,
+				DefaultTags: config.DefaultTags,
+			},
+		)
+	case "binary":
+		parser, err = newBinaryParser(config)
+	case "execd":
+		parser, err = execd.New(
+			&execd.Config{
+				Command:      config.ExecdCommand,
+				RestartDelay: config.ExecdRestartDelay,
+				Timeout:      config.ExecdTimeout,
+				DefaultTags:  config.DefaultTags,
+				Log:          models.NewLogger("parsers", "execd", ""),
+			},
+		)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
+	if err == nil && (len(config.DataDefaultFields) > 0 || len(config.DataDefaultTags) > 0 ||
+		len(config.DataConstantFields) > 0 || len(config.DataConstantTags) > 0) {
+		parser = &defaultsParser{
+			Parser:         parser,
+			defaultFields:  config.DataDefaultFields,
+			defaultTags:    config.DataDefaultTags,
+			constantFields: config.DataConstantFields,
+			constantTags:   config.DataConstantTags,
+		}
+	}
+	if err == nil && len(config.DataTransforms) > 0 {
+		parser, err = newPipelineParser(parser, config.DataTransforms)
+	}
 	return parser, err
 }
 
+func newXMLParser(metricName string, xmlConfigs []XMLConfig, defaultTags map[string]string) (Parser, error) {
+	queries := make([]xml.Query, 0, len(xmlConfigs))
+	for _, cfg := range xmlConfigs {
+		queries = append(queries, xml.Query{
+			MetricName:      cfg.MetricName,
+			Selection:       cfg.Selection,
+			Timestamp:       cfg.Timestamp,
+			TimestampFormat: cfg.TimestampFormat,
+			Timezone:        cfg.Timezone,
+			Tags:            cfg.Tags,
+			Fields:          cfg.Fields,
+			FieldsInt:       cfg.FieldsInt,
+			FieldSelection:  cfg.FieldSelection,
+			FieldName:       cfg.FieldName,
+			FieldValue:      cfg.FieldValue,
+			FieldNameExpand: cfg.FieldNameExpand,
+		})
+	}
+
+	return xml.New(&xml.Config{
+		MetricName:  metricName,
+		Queries:     queries,
+		DefaultTags: defaultTags,
+	})
+}
+
+func newBinaryParser(config *Config) (Parser, error) {
+	fields := make([]binary.Field, 0, len(config.BinaryFields))
+	for _, f := range config.BinaryFields {
+		fields = append(fields, binary.Field{
+			Name:       f.Name,
+			Type:       f.Type,
+			Offset:     f.Offset,
+			Length:     f.Length,
+			Count:      f.Count,
+			Endianness: f.Endianness,
+		})
+	}
+
+	return binary.New(&binary.Config{
+		MetricName:       config.MetricName,
+		Endianness:       config.BinaryEndianness,
+		HeaderLength:     config.BinaryHeaderLength,
+		RecordLength:     config.BinaryRecordLength,
+		Fields:           fields,
+		TagFields:        config.BinaryTagFields,
+		MeasurementField: config.BinaryMeasurementField,
+		TimestampField:   config.BinaryTimestampField,
+		TimestampFormat:  config.BinaryTimestampFormat,
+		Timezone:         config.BinaryTimezone,
+		DefaultTags:      config.DefaultTags,
+	})
+}
+
 func newGrokParser(metricName string,
 	patterns []string, nPatterns []string,
 	cPatterns string, cPatternFiles []string,
-	tZone string, uniqueTimestamp string) (Parser, error) {
+	tZone string, tZones map[string]string,
+	tagKeys []string, uniqueTimestamp string) (Parser, error) {
 	parser := grok.Parser{
 		Measurement:        metricName,
 		Patterns:           patterns,
@@ -254,6 +909,8 @@ func newGrokParser(metricName string,
 		CustomPatterns:     cPatterns,
 		CustomPatternFiles: cPatternFiles,
 		Timezone:           tZone,
+		Timezones:          tZones,
+		TagKeys:            tagKeys,
 		UniqueTimestamp:    uniqueTimestamp,
 	}
 
@@ -0,0 +1,112 @@
+package parsers
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// statsParser records the outcome of parsing in selfstats, reported by the
+// internal input as the parser measurement.
+type statsParser struct {
+	Parser
+
+	parseSuccesses selfstat.Stat
+	parseErrors    selfstat.Stat
+	recordsSkipped selfstat.Stat
+	metricsParsed  selfstat.Stat
+}
+
+// NewStatsParser wraps the parser to count successful and failed parse
+// calls, calls returning no metrics and the parsed metrics.  Parsers with the
+// same tags share their stats.
+func NewStatsParser(parser Parser, tags map[string]string) Parser {
+	return &statsParser{
+		Parser:         parser,
+		parseSuccesses: selfstat.Register("parser", "parse_successes", tags),
+		parseErrors:    selfstat.Register("parser", "parse_errors", tags),
+		recordsSkipped: selfstat.Register("parser", "records_skipped", tags),
+		metricsParsed:  selfstat.Register("parser", "metrics_parsed", tags),
+	}
+}
+
+func (p *statsParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics, err := p.Parser.Parse(buf)
+	p.record(len(metrics), err)
+	return metrics, err
+}
+
+func (p *statsParser) ParseLine(line string) (telegraf.Metric, error) {
+	m, err := p.Parser.ParseLine(line)
+	if m != nil {
+		p.record(1, err)
+	} else {
+		p.record(0, err)
+	}
+	return m, err
+}
+
+// ParseStream passes the stream to the parser if it supports streams, the
+// data is read completely otherwise.
+func (p *statsParser) ParseStream(r io.Reader, fn func(telegraf.Metric) error) error {
+	parser, ok := p.Parser.(StreamParser)
+	if !ok {
+		buf, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		metrics, err := p.Parse(buf)
+		if err != nil {
+			return err
+		}
+		for _, m := range metrics {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var count int
+	err := parser.ParseStream(r, func(m telegraf.Metric) error {
+		count++
+		return fn(m)
+	})
+	p.record(count, err)
+	return err
+}
+
+// InfluxStreamable returns true if the parser is the line protocol parser,
+// either bare or only wrapped to record selfstats, so callers can read the
+// data with the streaming line protocol parser instead.  Other wrappers, such
+// as data transforms and defaults, require the parser.  The returned function
+// records the result of each parsed line in the selfstats.
+func InfluxStreamable(parser Parser) (func(metrics int, err error), bool) {
+	record := func(int, error) {}
+	if p, ok := parser.(*statsParser); ok {
+		record = p.record
+		parser = p.Parser
+	}
+	_, ok := parser.(*influx.Parser)
+	return record, ok
+}
+
+// Unwrap returns the wrapped parser.
+func (p *statsParser) Unwrap() Parser {
+	return p.Parser
+}
+
+func (p *statsParser) record(metrics int, err error) {
+	p.metricsParsed.Incr(int64(metrics))
+	switch {
+	case err != nil:
+		p.parseErrors.Incr(1)
+	case metrics == 0:
+		p.recordsSkipped.Incr(1)
+	default:
+		p.parseSuccesses.Incr(1)
+	}
+}
@@ -0,0 +1,60 @@
+package parsers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsParser(t *testing.T) {
+	p, err := NewParser(&Config{DataFormat: "influx"})
+	require.NoError(t, err)
+	parser := NewStatsParser(p, map[string]string{"input": "test_stats"}).(*statsParser)
+
+	_, err = parser.Parse([]byte("cpu value=1\ncpu value=2\n"))
+	require.NoError(t, err)
+	_, err = parser.Parse([]byte("cpu value=\n"))
+	require.Error(t, err)
+	_, err = parser.Parse([]byte("# only a comment\n"))
+	require.NoError(t, err)
+	_, err = parser.ParseLine("mem value=3")
+	require.NoError(t, err)
+
+	err = parser.ParseStream(strings.NewReader("cpu value=4\n"), func(telegraf.Metric) error { return nil })
+	require.NoError(t, err)
+
+	require.Equal(t, int64(3), parser.parseSuccesses.Get())
+	require.Equal(t, int64(1), parser.parseErrors.Get())
+	require.Equal(t, int64(1), parser.recordsSkipped.Get())
+	require.Equal(t, int64(4), parser.metricsParsed.Get())
+
+	require.Equal(t, p, Unwrap(parser))
+}
+
+func TestInfluxStreamable(t *testing.T) {
+	p, err := NewParser(&Config{DataFormat: "influx"})
+	require.NoError(t, err)
+	_, ok := InfluxStreamable(p)
+	require.True(t, ok)
+
+	parser := NewStatsParser(p, map[string]string{"input": "test_streamable"})
+	record, ok := InfluxStreamable(parser)
+	require.True(t, ok)
+	record(1, nil)
+	require.Equal(t, int64(1), parser.(*statsParser).metricsParsed.Get())
+
+	p, err = NewParser(&Config{
+		DataFormat:       "influx",
+		DataConstantTags: map[string]string{"source": "test"},
+	})
+	require.NoError(t, err)
+	_, ok = InfluxStreamable(NewStatsParser(p, map[string]string{"input": "test_streamable"}))
+	require.False(t, ok)
+
+	p, err = NewParser(&Config{DataFormat: "json"})
+	require.NoError(t, err)
+	_, ok = InfluxStreamable(p)
+	require.False(t, ok)
+}
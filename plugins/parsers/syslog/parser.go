@@ -0,0 +1,289 @@
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/go-syslog/v2"
+	"github.com/influxdata/go-syslog/v2/rfc5424"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrNoMetric = errors.New("no metric in line")
+
+type Config struct {
+	MetricName  string
+	Timezone    string
+	BestEffort  bool
+	Separator   string
+	DefaultTags map[string]string
+}
+
+// Parser decodes syslog messages as written to log files, one message per
+// line, in the RFC5424 format or the BSD format of RFC3164.
+type Parser struct {
+	metricName string
+	location   *time.Location
+	bestEffort bool
+	separator  string
+
+	// The mutex guards the RFC5424 machine, which keeps the state of the
+	// message it parses, and the default tags.
+	sync.Mutex
+	rfc5424     syslog.Machine
+	defaultTags map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	location := time.UTC
+	if config.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	separator := config.Separator
+	if separator == "" {
+		separator = "_"
+	}
+
+	var options []syslog.MachineOption
+	if config.BestEffort {
+		options = append(options, rfc5424.WithBestEffort())
+	}
+
+	return &Parser{
+		metricName:  config.MetricName,
+		location:    location,
+		bestEffort:  config.BestEffort,
+		separator:   separator,
+		rfc5424:     rfc5424.NewParser(options...),
+		defaultTags: config.DefaultTags,
+		TimeFunc:    time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for i, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m, err := p.parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.TrimSpace(line) == "" {
+		return nil, ErrNoMetric
+	}
+	return p.parseLine(line)
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.Lock()
+	defer p.Unlock()
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseLine(line string) (telegraf.Metric, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+
+	pri, rest, hasPri := priority(line)
+	var timestamp time.Time
+	if hasPri && isRFC5424(rest) {
+		msg, err := p.rfc5424.Parse([]byte(line))
+		if msg == nil {
+			return nil, err
+		}
+		timestamp = p.fromRFC5424(msg, tags, fields)
+	} else {
+		if hasPri {
+			setPriority(pri, tags, fields)
+		}
+		var err error
+		timestamp, err = p.fromRFC3164(rest, tags, fields)
+		if err != nil {
+			if !p.bestEffort {
+				return nil, err
+			}
+			fields["message"] = strings.TrimRightFunc(rest, unicode.IsSpace)
+			timestamp = p.TimeFunc()
+		}
+	}
+
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+// fromRFC5424 adds the tags and fields of the message in the same way as the
+// syslog input, and returns the time of the message.
+func (p *Parser) fromRFC5424(msg syslog.Message, tags map[string]string, fields map[string]interface{}) time.Time {
+	if msg.Severity() != nil {
+		tags["severity"] = *msg.SeverityShortLevel()
+		fields["severity_code"] = int(*msg.Severity())
+	}
+	if msg.Facility() != nil {
+		tags["facility"] = *msg.FacilityLevel()
+		fields["facility_code"] = int(*msg.Facility())
+	}
+	if msg.Hostname() != nil {
+		tags["hostname"] = *msg.Hostname()
+	}
+	if msg.Appname() != nil {
+		tags["appname"] = *msg.Appname()
+	}
+
+	fields["version"] = msg.Version()
+	if msg.ProcID() != nil {
+		fields["procid"] = *msg.ProcID()
+	}
+	if msg.MsgID() != nil {
+		fields["msgid"] = *msg.MsgID()
+	}
+	if msg.Message() != nil {
+		fields["message"] = strings.TrimRightFunc(*msg.Message(), unicode.IsSpace)
+	}
+	if msg.StructuredData() != nil {
+		for sdid, sdparams := range *msg.StructuredData() {
+			if len(sdparams) == 0 {
+				fields[sdid] = true
+				continue
+			}
+			for name, value := range sdparams {
+				fields[sdid+p.separator+name] = value
+			}
+		}
+	}
+
+	if msg.Timestamp() != nil {
+		return *msg.Timestamp()
+	}
+	return p.TimeFunc()
+}
+
+// fromRFC3164 adds the tags and fields of a BSD syslog message following the
+// priority, "Mmm dd hh:mm:ss hostname tag[pid]: message", and returns the
+// time of the message.  High precision RFC3339 timestamps, as written by
+// rsyslog, are accepted as well.
+func (p *Parser) fromRFC3164(line string, tags map[string]string, fields map[string]interface{}) (time.Time, error) {
+	timestamp, rest, err := p.parseTimestamp(line)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	rest = strings.TrimLeft(rest, " ")
+	i := strings.IndexByte(rest, ' ')
+	if i < 0 {
+		return time.Time{}, errors.New("missing hostname")
+	}
+	tags["hostname"] = rest[:i]
+	rest = rest[i+1:]
+
+	// The tag ends at the first character not allowed in a program name,
+	// without a tag the rest is the message.
+	end := strings.IndexAny(rest, ":[ ")
+	if end > 0 && rest[end] != ' ' {
+		appname := rest[:end]
+		rest = rest[end:]
+		if rest[0] == '[' {
+			if j := strings.IndexByte(rest, ']'); j > 0 {
+				fields["procid"] = rest[1:j]
+				rest = rest[j+1:]
+			}
+		}
+		tags["appname"] = appname
+		rest = strings.TrimPrefix(rest, ":")
+		rest = strings.TrimPrefix(rest, " ")
+	}
+
+	if message := strings.TrimRightFunc(rest, unicode.IsSpace); message != "" {
+		fields["message"] = message
+	}
+	return timestamp, nil
+}
+
+func (p *Parser) parseTimestamp(line string) (time.Time, string, error) {
+	if len(line) >= len(time.Stamp) {
+		t, err := time.ParseInLocation(time.Stamp, line[:len(time.Stamp)], p.location)
+		if err == nil {
+			// The year isn't logged, use the current one unless this puts
+			// the message in the future, such as December messages read in
+			// January.
+			now := p.TimeFunc().In(p.location)
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, line[len(time.Stamp):], nil
+		}
+	}
+
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		t, err := time.ParseInLocation(time.RFC3339Nano, line[:i], p.location)
+		if err == nil {
+			return t, line[i:], nil
+		}
+	}
+
+	return time.Time{}, "", errors.New("invalid timestamp")
+}
+
+// priority returns the value of the "<PRI>" prefix of the line and the rest
+// of the line.
+func priority(line string) (uint8, string, bool) {
+	if !strings.HasPrefix(line, "<") {
+		return 0, line, false
+	}
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 {
+		return 0, line, false
+	}
+	pri, err := strconv.ParseUint(line[1:end], 10, 8)
+	if err != nil || pri > 191 {
+		return 0, line, false
+	}
+	return uint8(pri), line[end+1:], true
+}
+
+// isRFC5424 reports whether the part of the line following the priority
+// starts with a version number.
+func isRFC5424(rest string) bool {
+	i := strings.IndexByte(rest, ' ')
+	if i < 1 || i > 3 {
+		return false
+	}
+	_, err := strconv.ParseUint(rest[:i], 10, 16)
+	return err == nil
+}
+
+func setPriority(pri uint8, tags map[string]string, fields map[string]interface{}) {
+	msg := (&rfc5424.SyslogMessage{}).SetPriority(pri)
+	tags["severity"] = *msg.SeverityShortLevel()
+	tags["facility"] = *msg.FacilityLevel()
+	fields["severity_code"] = int(*msg.Severity())
+	fields["facility_code"] = int(*msg.Facility())
+}
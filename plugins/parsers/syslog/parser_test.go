@@ -0,0 +1,144 @@
+package syslog
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{MetricName: "syslog"})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC) }
+
+	data := `<34>1 2020-09-13T12:26:40Z mymachine su - ID47 [exampleSDID@32473 iut="3"] 'su root' failed for lonvick on /dev/pts/8
+<13>Dec 31 23:59:59 server01 CRON[1234]: (root) CMD (run-parts /etc/cron.hourly)
+Jan  1 23:00:00 server01 kernel: eth0: link up
+2020-09-13T12:26:40.5+02:00 server02 sshd[42]: Accepted publickey for admin
+`
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"syslog",
+			map[string]string{
+				"severity": "crit",
+				"facility": "auth",
+				"hostname": "mymachine",
+				"appname":  "su",
+			},
+			map[string]interface{}{
+				"version":               uint64(1),
+				"severity_code":         2,
+				"facility_code":         4,
+				"msgid":                 "ID47",
+				"message":               "'su root' failed for lonvick on /dev/pts/8",
+				"exampleSDID@32473_iut": "3",
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"syslog",
+			map[string]string{
+				"severity": "notice",
+				"facility": "user",
+				"hostname": "server01",
+				"appname":  "CRON",
+			},
+			map[string]interface{}{
+				"severity_code": 5,
+				"facility_code": 1,
+				"procid":        "1234",
+				"message":       "(root) CMD (run-parts /etc/cron.hourly)",
+			},
+			time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC),
+		),
+		testutil.MustMetric(
+			"syslog",
+			map[string]string{
+				"hostname": "server01",
+				"appname":  "kernel",
+			},
+			map[string]interface{}{
+				"message": "eth0: link up",
+			},
+			time.Date(2021, 1, 1, 23, 0, 0, 0, time.UTC),
+		),
+		testutil.MustMetric(
+			"syslog",
+			map[string]string{
+				"hostname": "server02",
+				"appname":  "sshd",
+			},
+			map[string]interface{}{
+				"procid":  "42",
+				"message": "Accepted publickey for admin",
+			},
+			time.Date(2020, 9, 13, 10, 26, 40, 500000000, time.UTC),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseConcurrent(t *testing.T) {
+	parser, err := New(&Config{MetricName: "syslog"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				host := fmt.Sprintf("host%d", i)
+				m, err := parser.ParseLine("<34>1 2020-09-13T12:26:40Z " + host + " su - ID47 - message")
+				require.NoError(t, err)
+				require.Equal(t, host, m.Tags()["hostname"])
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestParseTimezone(t *testing.T) {
+	parser, err := New(&Config{MetricName: "syslog", Timezone: "Europe/Berlin"})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Date(2020, 9, 14, 0, 0, 0, 0, time.UTC) }
+
+	m, err := parser.ParseLine("Sep 13 14:26:40 server01 app: hello")
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1600000000, 0).UTC(), m.Time().UTC())
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser, err := New(&Config{MetricName: "syslog"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("Sep 13 12:26:40 server01 app: hello\nnot a syslog message\n"))
+	require.EqualError(t, err, "line 2: invalid timestamp")
+
+	parser, err = New(&Config{MetricName: "syslog", BestEffort: true})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	m, err := parser.ParseLine("<13>not a syslog message")
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"syslog",
+		map[string]string{"severity": "notice", "facility": "user"},
+		map[string]interface{}{
+			"severity_code": 5,
+			"facility_code": 1,
+			"message":       "not a syslog message",
+		},
+		time.Unix(42, 0),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+}
@@ -15,9 +15,18 @@ type ValueParser struct {
 	MetricName  string
 	DataType    string
 	DefaultTags map[string]string
+
+	// FieldNames are the names of the values of a line, when given each line
+	// is split at the Separator, or at whitespace if no separator is set.
+	FieldNames []string
+	Separator  string
 }
 
 func (v *ValueParser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if len(v.FieldNames) > 0 {
+		return v.parseFields(buf)
+	}
+
 	vStr := string(bytes.TrimSpace(bytes.Trim(buf, "\x00")))
 
 	// unless it's a string, separate out any fields in the buffer,
@@ -30,18 +39,7 @@ func (v *ValueParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 		vStr = string(values[len(values)-1])
 	}
 
-	var value interface{}
-	var err error
-	switch v.DataType {
-	case "", "int", "integer":
-		value, err = strconv.Atoi(vStr)
-	case "float", "long":
-		value, err = strconv.ParseFloat(vStr, 64)
-	case "str", "string":
-		value = vStr
-	case "bool", "boolean":
-		value, err = strconv.ParseBool(vStr)
-	}
+	value, err := v.parseValue(vStr)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +54,58 @@ func (v *ValueParser) Parse(buf []byte) ([]telegraf.Metric, error) {
 	return []telegraf.Metric{metric}, nil
 }
 
+// parseFields creates a metric with the named values of each line.
+func (v *ValueParser) parseFields(buf []byte) ([]telegraf.Metric, error) {
+	now := time.Now().UTC()
+	metrics := make([]telegraf.Metric, 0)
+	for _, line := range strings.Split(string(bytes.Trim(buf, "\x00")), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		var values []string
+		if v.Separator == "" {
+			values = strings.Fields(line)
+		} else {
+			values = strings.Split(line, v.Separator)
+		}
+		if len(values) != len(v.FieldNames) {
+			return nil, fmt.Errorf("expected %d values, got %d in line: %s", len(v.FieldNames), len(values), line)
+		}
+
+		fields := make(map[string]interface{}, len(values))
+		for i, vStr := range values {
+			value, err := v.parseValue(strings.TrimSpace(vStr))
+			if err != nil {
+				return nil, err
+			}
+			fields[v.FieldNames[i]] = value
+		}
+
+		m, err := metric.New(v.MetricName, v.DefaultTags, fields, now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+func (v *ValueParser) parseValue(vStr string) (interface{}, error) {
+	switch v.DataType {
+	case "", "int", "integer":
+		return strconv.Atoi(vStr)
+	case "float", "long":
+		return strconv.ParseFloat(vStr, 64)
+	case "str", "string":
+		return vStr, nil
+	case "bool", "boolean":
+		return strconv.ParseBool(vStr)
+	}
+	return nil, nil
+}
+
 func (v *ValueParser) ParseLine(line string) (telegraf.Metric, error) {
 	metrics, err := v.Parse([]byte(line))
 
@@ -251,3 +251,39 @@ func TestParseValuesWithNullCharacter(t *testing.T) {
 	}, metrics[0].Fields())
 	assert.Equal(t, map[string]string{}, metrics[0].Tags())
 }
+
+func TestParseNamedValues(t *testing.T) {
+	parser := ValueParser{
+		MetricName: "value_test",
+		DataType:   "float",
+		FieldNames: []string{"temperature", "humidity"},
+	}
+	metrics, err := parser.Parse([]byte("21.5 40\n\n22\t41.5\n"))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 2)
+	assert.Equal(t, map[string]interface{}{
+		"temperature": 21.5,
+		"humidity":    float64(40),
+	}, metrics[0].Fields())
+	assert.Equal(t, map[string]interface{}{
+		"temperature": float64(22),
+		"humidity":    41.5,
+	}, metrics[1].Fields())
+
+	parser = ValueParser{
+		MetricName: "value_test",
+		DataType:   "integer",
+		FieldNames: []string{"rx", "tx", "errors"},
+		Separator:  ";",
+	}
+	metric, err := parser.ParseLine("100; 200;0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"rx":     int64(100),
+		"tx":     int64(200),
+		"errors": int64(0),
+	}, metric.Fields())
+
+	_, err = parser.ParseLine("100;200")
+	assert.EqualError(t, err, "expected 3 values, got 2 in line: 100;200")
+}
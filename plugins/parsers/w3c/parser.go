@@ -0,0 +1,245 @@
+package w3c
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+var ErrNoMetric = errors.New("no metric in line")
+
+type Config struct {
+	MetricName  string
+	Fields      []string
+	TagKeys     []string
+	Timezone    string
+	DefaultTags map[string]string
+}
+
+// Parser decodes logs in the W3C extended log file format.  The columns are
+// taken from the #Fields directive.  Each call of Parse starts over with the
+// configured fields, while ParseLine keeps the directives between calls so
+// that logs can be parsed line by line.
+type Parser struct {
+	metricName  string
+	tagKeys     filter.Filter
+	location    *time.Location
+	defaultTags map[string]string
+	fields      []string
+
+	// The mutex guards the directives of the lines given to ParseLine.
+	sync.Mutex
+	header header
+
+	TimeFunc func() time.Time
+}
+
+// header is the state of the directives preceding an entry.
+type header struct {
+	fields []string
+	date   string
+}
+
+func New(config *Config) (*Parser, error) {
+	location := time.UTC
+	if config.Timezone != "" {
+		var err error
+		location, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tagKeys, err := filter.Compile(config.TagKeys)
+	if err != nil {
+		return nil, fmt.Errorf("compiling tag key filter: %v", err)
+	}
+
+	return &Parser{
+		metricName:  config.MetricName,
+		tagKeys:     tagKeys,
+		location:    location,
+		defaultTags: config.DefaultTags,
+		fields:      config.Fields,
+		header:      header{fields: config.Fields},
+		TimeFunc:    time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	return p.parse(buf, &header{fields: p.fields})
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	metrics, err := p.parse([]byte(line), &p.header)
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parse(buf []byte, h *header) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for i, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			h.parseDirective(line)
+			continue
+		}
+		m, err := p.parseEntry(line, h)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// parseDirective keeps the columns of #Fields and the date of #Date, other
+// directives are ignored.
+func (h *header) parseDirective(line string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return
+	}
+	value := strings.TrimSpace(line[i+1:])
+	switch line[1:i] {
+	case "Fields":
+		h.fields = strings.Fields(value)
+	case "Date":
+		if date := strings.Fields(value); len(date) > 0 {
+			h.date = date[0]
+		}
+	}
+}
+
+func (p *Parser) parseEntry(line string, h *header) (telegraf.Metric, error) {
+	if len(h.fields) == 0 {
+		return nil, errors.New("entry without #Fields directive")
+	}
+
+	values, err := splitEntry(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(h.fields) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(h.fields), len(values))
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	var date, clock string
+	for i, name := range h.fields {
+		value := values[i]
+		// A dash is used for values that are not available.
+		if value == "-" {
+			continue
+		}
+
+		switch {
+		case name == "date":
+			date = value
+		case name == "time":
+			clock = value
+		case p.tagKeys != nil && p.tagKeys.Match(name):
+			tags[name] = value
+		default:
+			fields[name] = convert(value)
+		}
+	}
+
+	// Entries without a date use the date of the #Date directive.
+	if date == "" && clock != "" {
+		date = h.date
+	}
+	timestamp := p.TimeFunc()
+	if date != "" && clock != "" {
+		timestamp, err = internal.ParseTimestamp("2006-01-02 15:04:05", date+" "+clock, p.location.String())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return metric.New(p.metricName, tags, fields, timestamp)
+}
+
+// splitEntry splits the entry into values.  Values are separated by tabs when
+// the entry contains any, as done by some CDNs, and by spaces otherwise.
+// Values in double quotes may contain spaces, with quotes escaped by doubling
+// them.
+func splitEntry(line string) ([]string, error) {
+	if strings.Contains(line, "\t") {
+		return strings.Split(line, "\t"), nil
+	}
+
+	var values []string
+	for i := 0; i < len(line); {
+		switch line[i] {
+		case ' ':
+			i++
+		case '"':
+			var b strings.Builder
+			j := i + 1
+			for {
+				k := strings.IndexByte(line[j:], '"')
+				if k < 0 {
+					return nil, errors.New("unterminated quoted value")
+				}
+				b.WriteString(line[j : j+k])
+				j += k + 1
+				if j < len(line) && line[j] == '"' {
+					b.WriteByte('"')
+					j++
+					continue
+				}
+				break
+			}
+			values = append(values, b.String())
+			i = j
+		default:
+			j := strings.IndexByte(line[i:], ' ')
+			if j < 0 {
+				j = len(line) - i
+			}
+			values = append(values, line[i:i+j])
+			i += j
+		}
+	}
+	return values, nil
+}
+
+// convert returns integers and floats as such, and other values as strings.
+func convert(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil && !math.IsNaN(v) && !math.IsInf(v, 0) {
+		return v
+	}
+	return value
+}
@@ -0,0 +1,151 @@
+package w3c
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIIS(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "w3c",
+		TagKeys:    []string{"s-ip", "cs-method"},
+	})
+	require.NoError(t, err)
+
+	data := `#Software: Microsoft Internet Information Services 10.0
+#Version: 1.0
+#Date: 2020-09-13 12:26:40
+#Fields: date time s-ip cs-method cs-uri-stem cs-uri-query s-port c-ip cs(User-Agent) sc-status time-taken
+2020-09-13 12:26:40 10.0.0.1 GET /index.html - 443 192.168.1.5 Mozilla/5.0+(Windows+NT+10.0) 200 15
+2020-09-13 12:26:41 10.0.0.1 POST /login a=1 443 192.168.1.6 - 302 7
+`
+
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"w3c",
+			map[string]string{"s-ip": "10.0.0.1", "cs-method": "GET"},
+			map[string]interface{}{
+				"cs-uri-stem":    "/index.html",
+				"s-port":         int64(443),
+				"c-ip":           "192.168.1.5",
+				"cs(User-Agent)": "Mozilla/5.0+(Windows+NT+10.0)",
+				"sc-status":      int64(200),
+				"time-taken":     int64(15),
+			},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"w3c",
+			map[string]string{"s-ip": "10.0.0.1", "cs-method": "POST"},
+			map[string]interface{}{
+				"cs-uri-stem":  "/login",
+				"cs-uri-query": "a=1",
+				"s-port":       int64(443),
+				"c-ip":         "192.168.1.6",
+				"sc-status":    int64(302),
+				"time-taken":   int64(7),
+			},
+			time.Unix(1600000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseLineByLine(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "w3c",
+		Timezone:   "Europe/Berlin",
+	})
+	require.NoError(t, err)
+
+	// Directives are kept between calls, and entries without a date use the
+	// date of the #Date directive.
+	lines := []string{
+		"#Version: 1.0",
+		"#Date: 2020-09-13 14:00:00",
+		"#Fields: time cs-uri sc-status x-comment",
+		`14:26:40.5 /a 200 "quoted ""value"" with spaces"`,
+		"14:26:41\t/b c\t404\tplain",
+	}
+	var metrics []telegraf.Metric
+	for _, line := range lines {
+		m, err := parser.ParseLine(line)
+		if err == ErrNoMetric {
+			continue
+		}
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"w3c",
+			map[string]string{},
+			map[string]interface{}{"cs-uri": "/a", "sc-status": int64(200), "x-comment": `quoted "value" with spaces`},
+			time.Unix(1600000000, 500000000),
+		),
+		testutil.MustMetric(
+			"w3c",
+			map[string]string{},
+			map[string]interface{}{"cs-uri": "/b c", "sc-status": int64(404), "x-comment": "plain"},
+			time.Unix(1600000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseHeaderPerCall(t *testing.T) {
+	parser, err := New(&Config{MetricName: "w3c"})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse([]byte("#Fields: date time sc-status\n2020-09-13 12:26:40 200\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	// The directives of a file don't apply to the next one.
+	metrics, err = parser.Parse([]byte("#Fields: date time cs-uri\n2020-09-13 12:26:40 /a\n"))
+	require.NoError(t, err)
+	expected := testutil.MustMetric("w3c", map[string]string{}, map[string]interface{}{"cs-uri": "/a"}, time.Unix(1600000000, 0))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, metrics)
+
+	_, err = parser.Parse([]byte("2020-09-13 12:26:40 200"))
+	require.EqualError(t, err, "line 1: entry without #Fields directive")
+}
+
+func TestParseConfiguredFields(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "w3c",
+		Fields:     []string{"date", "time", "sc-status"},
+	})
+	require.NoError(t, err)
+
+	m, err := parser.ParseLine("2020-09-13 12:26:40 200")
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric("w3c", map[string]string{}, map[string]interface{}{"sc-status": int64(200)}, time.Unix(1600000000, 0))
+	testutil.RequireMetricEqual(t, expected, m)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := New(&Config{MetricName: "w3c", Timezone: "Nowhere/Special"})
+	require.Error(t, err)
+
+	parser, err := New(&Config{MetricName: "w3c"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("2020-09-13 12:26:40 200"))
+	require.EqualError(t, err, "line 1: entry without #Fields directive")
+
+	_, err = parser.Parse([]byte("#Fields: date time sc-status\n2020-09-13 12:26:40"))
+	require.EqualError(t, err, "line 2: expected 3 values, got 2")
+
+	_, err = parser.Parse([]byte("#Fields: date time sc-status\n2020-09-13 12:26:40 \"200"))
+	require.EqualError(t, err, "line 2: unterminated quoted value")
+}
@@ -0,0 +1,347 @@
+package xlsx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/tealeg/xlsx"
+)
+
+type Config struct {
+	MetricName        string
+	Sheets            []string
+	SheetTag          string
+	HeaderRow         int
+	SkipRows          int
+	ColumnNames       []string
+	ColumnTypes       []string
+	TagColumns        []string
+	MeasurementColumn string
+	TimestampColumn   string
+	TimestampFormat   string
+	Timezone          string
+	DefaultTags       map[string]string
+}
+
+// Parser reads the rows of the selected sheets of an Excel workbook, creating
+// a metric for each row.
+type Parser struct {
+	metricName        string
+	sheets            filter.Filter
+	sheetTag          string
+	headerRow         int
+	skipRows          int
+	columnNames       []string
+	columnTypes       []string
+	tagColumns        map[string]bool
+	measurementColumn string
+	timestampColumn   string
+	timestampFormat   string
+	location          *time.Location
+	defaultTags       map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.HeaderRow < 0 {
+		return nil, errors.New("header row must not be negative")
+	}
+	if config.HeaderRow == 0 && len(config.ColumnNames) == 0 {
+		return nil, errors.New("`xlsx_header_row` must be defined if `xlsx_column_names` is not specified")
+	}
+	if len(config.ColumnNames) > 0 && len(config.ColumnTypes) > 0 && len(config.ColumnNames) != len(config.ColumnTypes) {
+		return nil, errors.New("xlsx_column_names field count doesn't match with xlsx_column_types")
+	}
+	for _, t := range config.ColumnTypes {
+		switch t {
+		case "", "int", "float", "bool", "string", "time":
+		default:
+			return nil, fmt.Errorf("unknown column type %q", t)
+		}
+	}
+
+	sheets, err := filter.Compile(config.Sheets)
+	if err != nil {
+		return nil, fmt.Errorf("compiling sheet filter: %v", err)
+	}
+
+	location := time.UTC
+	if config.Timezone != "" {
+		location, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Parser{
+		metricName:        config.MetricName,
+		sheets:            sheets,
+		sheetTag:          config.SheetTag,
+		headerRow:         config.HeaderRow,
+		skipRows:          config.SkipRows,
+		columnNames:       config.ColumnNames,
+		columnTypes:       config.ColumnTypes,
+		tagColumns:        make(map[string]bool),
+		measurementColumn: config.MeasurementColumn,
+		timestampColumn:   config.TimestampColumn,
+		timestampFormat:   config.TimestampFormat,
+		location:          location,
+		defaultTags:       config.DefaultTags,
+		TimeFunc:          time.Now,
+	}
+	for _, name := range config.TagColumns {
+		p.tagColumns[name] = true
+	}
+	return p, nil
+}
+
+func (p *Parser) Parse(buf []byte) (metrics []telegraf.Metric, err error) {
+	// The reader panics on some malformed workbooks.
+	defer func() {
+		if r := recover(); r != nil {
+			metrics, err = nil, fmt.Errorf("invalid xlsx file: %v", r)
+		}
+	}()
+
+	file, err := xlsx.OpenBinary(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	var found bool
+	for i, sheet := range file.Sheets {
+		// Only the first sheet is read by default.
+		if p.sheets == nil && i > 0 {
+			break
+		}
+		if p.sheets != nil && !p.sheets.Match(sheet.Name) {
+			continue
+		}
+		found = true
+
+		m, err := p.parseSheet(sheet, file.Date1904)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %v", sheet.Name, err)
+		}
+		metrics = append(metrics, m...)
+	}
+	if !found {
+		return nil, errors.New("no matching sheet found")
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseSheet(sheet *xlsx.Sheet, date1904 bool) ([]telegraf.Metric, error) {
+	rows := sheet.Rows
+	if p.skipRows >= len(rows) {
+		return nil, nil
+	}
+	rows = rows[p.skipRows:]
+
+	// The header row is counted from the first row after the skipped rows,
+	// starting at one.
+	columns := p.columnNames
+	if p.headerRow > 0 {
+		if p.headerRow > len(rows) {
+			return nil, nil
+		}
+		if len(columns) == 0 {
+			for _, cell := range rows[p.headerRow-1].Cells {
+				columns = append(columns, strings.TrimSpace(cell.Value))
+			}
+		}
+		rows = rows[p.headerRow:]
+	}
+
+	var metrics []telegraf.Metric
+	for i, row := range rows {
+		if row == nil || len(row.Cells) == 0 {
+			continue
+		}
+		m, err := p.parseRow(sheet.Name, columns, row, date1904)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", p.skipRows+p.headerRow+i+1, err)
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseRow(sheetName string, columns []string, row *xlsx.Row, date1904 bool) (telegraf.Metric, error) {
+	values := make(map[string]interface{})
+	for i, cell := range row.Cells {
+		if i >= len(columns) || columns[i] == "" {
+			continue
+		}
+		columnType := ""
+		if i < len(p.columnTypes) {
+			columnType = p.columnTypes[i]
+		}
+
+		value, err := p.cellValue(cell, columnType, date1904)
+		if err != nil {
+			return nil, fmt.Errorf("column %q: %v", columns[i], err)
+		}
+		if value != nil {
+			values[columns[i]] = value
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	name := p.metricName
+	if p.measurementColumn != "" {
+		if v, ok := values[p.measurementColumn]; ok {
+			name = fmt.Sprint(v)
+			delete(values, p.measurementColumn)
+		}
+	}
+
+	timestamp := p.TimeFunc()
+	if p.timestampColumn != "" {
+		v, ok := values[p.timestampColumn]
+		if !ok {
+			return nil, fmt.Errorf("timestamp column %q not found", p.timestampColumn)
+		}
+		var err error
+		timestamp, err = p.parseTimestamp(v)
+		if err != nil {
+			return nil, err
+		}
+		delete(values, p.timestampColumn)
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	if p.sheetTag != "" {
+		tags[p.sheetTag] = sheetName
+	}
+	fields := make(map[string]interface{})
+	for k, v := range values {
+		if p.tagColumns[k] {
+			tags[k] = fmt.Sprint(v)
+			continue
+		}
+		if t, ok := v.(time.Time); ok {
+			v = t.UnixNano()
+		}
+		fields[k] = v
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// cellValue converts a cell to the configured column type, or to a value
+// matching the type of the cell if no type is configured.  Empty and error
+// cells are skipped.
+func (p *Parser) cellValue(cell *xlsx.Cell, columnType string, date1904 bool) (interface{}, error) {
+	if cell.Value == "" || cell.Type() == xlsx.CellTypeError {
+		return nil, nil
+	}
+
+	switch columnType {
+	case "int":
+		if v, err := strconv.ParseInt(cell.Value, 10, 64); err == nil {
+			return v, nil
+		}
+		v, err := strconv.ParseFloat(cell.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse int error %s", err)
+		}
+		return int64(v), nil
+	case "float":
+		v, err := strconv.ParseFloat(cell.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse float error %s", err)
+		}
+		return v, nil
+	case "bool":
+		v, err := strconv.ParseBool(cell.Value)
+		if err != nil {
+			return nil, fmt.Errorf("parse bool error %s", err)
+		}
+		return v, nil
+	case "string":
+		return cell.String(), nil
+	case "time":
+		return p.cellTime(cell, date1904)
+	}
+
+	switch cell.Type() {
+	case xlsx.CellTypeBool:
+		return cell.Value == "1", nil
+	case xlsx.CellTypeNumeric:
+		if cell.IsTime() {
+			return p.cellTime(cell, date1904)
+		}
+		if v, err := strconv.ParseInt(cell.Value, 10, 64); err == nil {
+			return v, nil
+		}
+		if v, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+			return v, nil
+		}
+	case xlsx.CellTypeDate:
+		return p.cellTime(cell, date1904)
+	}
+	return cell.Value, nil
+}
+
+// cellTime converts a date cell.  Excel stores dates without a timezone, so
+// they are interpreted in the configured timezone.
+func (p *Parser) cellTime(cell *xlsx.Cell, date1904 bool) (time.Time, error) {
+	var t time.Time
+	var err error
+	if cell.Type() == xlsx.CellTypeDate {
+		t, err = time.Parse("2006-01-02T15:04:05", strings.TrimSuffix(cell.Value, "Z"))
+	} else {
+		t, err = cell.GetTime(date1904)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse time error %s", err)
+	}
+
+	// Excel only has millisecond precision, so drop the rounding error of
+	// the conversion from days.
+	t = t.Round(time.Millisecond)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), p.location), nil
+}
+
+// parseTimestamp uses the value of date cells, and the configured format for
+// other cells.
+func (p *Parser) parseTimestamp(v interface{}) (time.Time, error) {
+	if ts, ok := v.(time.Time); ok {
+		return ts, nil
+	}
+	if p.timestampFormat == "" {
+		return time.Time{}, errors.New("timestamp format must be specified")
+	}
+	return internal.ParseTimestamp(p.timestampFormat, v, p.location.String())
+}
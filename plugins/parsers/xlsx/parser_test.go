@@ -0,0 +1,206 @@
+package xlsx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tealeg/xlsx"
+)
+
+// writeWorkbook creates a workbook with a sheet for each entry of sheets,
+// using the cell values of the rows.
+func writeWorkbook(t *testing.T, names []string, sheets ...[][]interface{}) []byte {
+	file := xlsx.NewFile()
+	for i, rows := range sheets {
+		sheet, err := file.AddSheet(names[i])
+		require.NoError(t, err)
+		for _, values := range rows {
+			row := sheet.AddRow()
+			for _, v := range values {
+				cell := row.AddCell()
+				switch v := v.(type) {
+				case bool:
+					cell.SetBool(v)
+				case nil:
+				default:
+					cell.SetValue(v)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, file.Write(&buf))
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	buf := writeWorkbook(t, []string{"report", "other"},
+		[][]interface{}{
+			{"host", "kind", "time", "usage", "count", "ok"},
+			{"a", "cpu", time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC), 42.5, 3, true},
+			{},
+			{"b", "mem", time.Date(2020, 9, 13, 12, 26, 41, 0, time.UTC), 17.25, 4, false},
+		},
+		[][]interface{}{
+			{"host", "usage"},
+			{"c", 1.0},
+		},
+	)
+
+	parser, err := New(&Config{
+		MetricName:        "xlsx",
+		HeaderRow:         1,
+		TagColumns:        []string{"host"},
+		MeasurementColumn: "kind",
+		TimestampColumn:   "time",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3), "ok": true},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"usage": 17.25, "count": int64(4), "ok": false},
+			time.Unix(1600000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestSheetSelection(t *testing.T) {
+	buf := writeWorkbook(t, []string{"summary", "site-1", "site-2"},
+		[][]interface{}{
+			{"total"},
+			{10},
+		},
+		[][]interface{}{
+			{"Report generated by the building management system"},
+			{"host", "usage"},
+			{"a", 1},
+		},
+		[][]interface{}{
+			{"Report generated by the building management system"},
+			{"host", "usage"},
+			{"b", 2},
+		},
+	)
+
+	parser, err := New(&Config{
+		MetricName: "xlsx",
+		Sheets:     []string{"site-*"},
+		SheetTag:   "sheet",
+		SkipRows:   1,
+		HeaderRow:  1,
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(0, 0) }
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("xlsx", map[string]string{"sheet": "site-1"}, map[string]interface{}{"host": "a", "usage": int64(1)}, time.Unix(0, 0)),
+		testutil.MustMetric("xlsx", map[string]string{"sheet": "site-2"}, map[string]interface{}{"host": "b", "usage": int64(2)}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	parser, err = New(&Config{MetricName: "xlsx", Sheets: []string{"missing"}, HeaderRow: 1})
+	require.NoError(t, err)
+	_, err = parser.Parse(buf)
+	require.EqualError(t, err, "no matching sheet found")
+}
+
+func TestColumnTypes(t *testing.T) {
+	buf := writeWorkbook(t, []string{"report"},
+		[][]interface{}{
+			{"1600000000", "3", 4.5, "yes", "true", 12},
+		},
+	)
+
+	parser, err := New(&Config{
+		MetricName:      "xlsx",
+		ColumnNames:     []string{"time", "count", "ratio", "label", "ok", "code"},
+		ColumnTypes:     []string{"int", "int", "int", "string", "bool", "string"},
+		TimestampColumn: "time",
+		TimestampFormat: "unix",
+		Timezone:        "Europe/Berlin",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"xlsx",
+			map[string]string{},
+			map[string]interface{}{"count": int64(3), "ratio": int64(4), "label": "yes", "ok": true, "code": "12"},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	parser, err = New(&Config{
+		MetricName:  "xlsx",
+		ColumnNames: []string{"time", "count", "ratio", "label", "ok", "code"},
+		ColumnTypes: []string{"", "", "", "float", "", ""},
+	})
+	require.NoError(t, err)
+	_, err = parser.Parse(buf)
+	require.Error(t, err)
+}
+
+func TestTimezone(t *testing.T) {
+	buf := writeWorkbook(t, []string{"report"},
+		[][]interface{}{
+			{"time", "value"},
+			{time.Date(2020, 9, 13, 12, 0, 0, 0, time.UTC), 1},
+		},
+	)
+
+	parser, err := New(&Config{
+		MetricName:      "xlsx",
+		HeaderRow:       1,
+		TimestampColumn: "time",
+		Timezone:        "America/New_York",
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, time.Date(2020, 9, 13, 16, 0, 0, 0, time.UTC), metrics[0].Time().UTC())
+}
+
+func TestConfigErrors(t *testing.T) {
+	_, err := New(&Config{MetricName: "xlsx"})
+	require.Error(t, err)
+
+	_, err = New(&Config{MetricName: "xlsx", ColumnNames: []string{"a", "b"}, ColumnTypes: []string{"int"}})
+	require.Error(t, err)
+
+	_, err = New(&Config{MetricName: "xlsx", HeaderRow: 1, ColumnTypes: []string{"number"}})
+	require.Error(t, err)
+}
+
+func TestInvalidFile(t *testing.T) {
+	parser, err := New(&Config{MetricName: "xlsx", HeaderRow: 1})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("not a workbook"))
+	require.Error(t, err)
+}
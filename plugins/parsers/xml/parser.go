@@ -0,0 +1,333 @@
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	defaultFieldName  = "name()"
+	defaultFieldValue = "."
+	nameSeparator     = "_"
+)
+
+type Config struct {
+	MetricName  string
+	Queries     []Query
+	DefaultTags map[string]string
+}
+
+// Query describes how the metrics are built from a document.  All values are
+// XPath expressions evaluated relative to the selected node, or to the root
+// of the document if they start with a slash.
+type Query struct {
+	MetricName      string
+	Selection       string
+	Timestamp       string
+	TimestampFormat string
+	Timezone        string
+	Tags            map[string]string
+	Fields          map[string]string
+	FieldsInt       map[string]string
+
+	FieldSelection  string
+	FieldName       string
+	FieldValue      string
+	FieldNameExpand bool
+}
+
+// Parser creates metrics from XML documents, using XPath expressions to
+// select the nodes and their values.
+type Parser struct {
+	metricName  string
+	queries     []Query
+	defaultTags map[string]string
+
+	TimeFunc func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if len(config.Queries) == 0 {
+		return nil, errors.New("no xml configuration found")
+	}
+
+	// Compile all expressions up front to catch syntax errors early.
+	for _, q := range config.Queries {
+		exprs := []string{q.MetricName, q.Selection, q.Timestamp, q.FieldSelection, q.FieldName, q.FieldValue}
+		for _, m := range []map[string]string{q.Tags, q.Fields, q.FieldsInt} {
+			for _, expr := range m {
+				exprs = append(exprs, expr)
+			}
+		}
+		for _, expr := range exprs {
+			if expr == "" {
+				continue
+			}
+			if _, err := xpath.Compile(expr); err != nil {
+				return nil, fmt.Errorf("invalid xpath %q: %v", expr, err)
+			}
+		}
+	}
+
+	return &Parser{
+		metricName:  config.MetricName,
+		queries:     config.Queries,
+		defaultTags: config.DefaultTags,
+		TimeFunc:    time.Now,
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	doc, err := xmlquery.Parse(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.TimeFunc()
+	var metrics []telegraf.Metric
+	for _, q := range p.queries {
+		selection := q.Selection
+		if selection == "" {
+			selection = "/"
+		}
+		nodes, err := xmlquery.QueryAll(doc, selection)
+		if err != nil {
+			return nil, fmt.Errorf("selecting metrics: %v", err)
+		}
+
+		for _, node := range nodes {
+			m, err := p.parseNode(now, doc, node, q)
+			if err != nil {
+				return nil, err
+			}
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, errors.New("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) parseNode(now time.Time, doc, node *xmlquery.Node, q Query) (telegraf.Metric, error) {
+	name := p.metricName
+	if q.MetricName != "" {
+		v, err := query(doc, node, q.MetricName)
+		if err != nil {
+			return nil, fmt.Errorf("querying metric name: %v", err)
+		}
+		if s := toString(v); s != "" {
+			name = s
+		}
+	}
+
+	timestamp := now
+	if q.Timestamp != "" {
+		v, err := query(doc, node, q.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("querying timestamp: %v", err)
+		}
+		if q.TimestampFormat == "" {
+			return nil, errors.New("timestamp format must be specified")
+		}
+		timestamp, err = internal.ParseTimestamp(q.TimestampFormat, v, q.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for k, expr := range q.Tags {
+		v, err := query(doc, node, expr)
+		if err != nil {
+			return nil, fmt.Errorf("querying tag %q: %v", k, err)
+		}
+		if s := toString(v); s != "" {
+			tags[k] = s
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for k, expr := range q.FieldsInt {
+		v, err := query(doc, node, expr)
+		if err != nil {
+			return nil, fmt.Errorf("querying field %q: %v", k, err)
+		}
+		if v == "" {
+			continue
+		}
+		i, err := toInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", k, err)
+		}
+		fields[k] = i
+	}
+	for k, expr := range q.Fields {
+		v, err := query(doc, node, expr)
+		if err != nil {
+			return nil, fmt.Errorf("querying field %q: %v", k, err)
+		}
+		if v == "" {
+			continue
+		}
+		fields[k] = v
+	}
+
+	if q.FieldSelection != "" {
+		if err := p.selectFields(doc, node, q, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// selectFields adds a field for each node selected by the field selection,
+// converting the values to the best matching type.
+func (p *Parser) selectFields(doc, node *xmlquery.Node, q Query, fields map[string]interface{}) error {
+	nameExpr := q.FieldName
+	if nameExpr == "" {
+		nameExpr = defaultFieldName
+	}
+	valueExpr := q.FieldValue
+	if valueExpr == "" {
+		valueExpr = defaultFieldValue
+	}
+
+	selected, err := xmlquery.QueryAll(node, q.FieldSelection)
+	if err != nil {
+		return fmt.Errorf("selecting fields: %v", err)
+	}
+	for _, field := range selected {
+		// Expressions can't be evaluated on attributes, so the name and value
+		// of attributes are used unless other expressions are given, which
+		// are then evaluated on the element of the attribute.
+		context := field
+		if field.Type == xmlquery.AttributeNode {
+			context = field.Parent
+		}
+
+		var name string
+		if field.Type == xmlquery.AttributeNode && q.FieldName == "" {
+			name = field.Data
+		} else {
+			v, err := query(doc, context, nameExpr)
+			if err != nil {
+				return fmt.Errorf("querying field name: %v", err)
+			}
+			name = toString(v)
+		}
+		if name == "" {
+			continue
+		}
+		if q.FieldNameExpand {
+			for parent := field.Parent; parent != nil && parent != node; parent = parent.Parent {
+				name = parent.Data + nameSeparator + name
+			}
+		}
+
+		var v interface{} = strings.TrimSpace(field.InnerText())
+		if field.Type != xmlquery.AttributeNode || q.FieldValue != "" {
+			v, err = query(doc, context, valueExpr)
+			if err != nil {
+				return fmt.Errorf("querying field %q: %v", name, err)
+			}
+		}
+		if s, ok := v.(string); ok {
+			v = convertType(s)
+		}
+		fields[name] = v
+	}
+	return nil
+}
+
+// query evaluates an expression and returns a float64, string or bool.  For
+// expressions selecting nodes the value of the first node is used.
+func query(doc, node *xmlquery.Node, expr string) (interface{}, error) {
+	root := node
+	if strings.HasPrefix(expr, "/") {
+		root = doc
+	}
+
+	compiled, err := xpath.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := compiled.Evaluate(xmlquery.CreateXPathNavigator(root)).(type) {
+	case *xpath.NodeIterator:
+		if v.MoveNext() {
+			return strings.TrimSpace(v.Current().Value()), nil
+		}
+		return "", nil
+	case float64, string, bool:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unexpected result type %T", v)
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return ""
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case float64:
+		return int64(v), nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected type %T", v)
+}
+
+func convertType(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseBool(value); err == nil {
+		return v
+	}
+	return value
+}
@@ -0,0 +1,175 @@
+package xml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const gateway = `<?xml version="1.0"?>
+<Gateway>
+  <Name>Main Gateway</Name>
+  <Timestamp>2020-09-13T12:26:40Z</Timestamp>
+  <Sequence>12</Sequence>
+  <Status>ok</Status>
+  <Bus>
+    <Sensor name="Sensor Facility A">
+      <Variable temperature="20.0"/>
+      <Variable power="123.4"/>
+      <Variable frequency="49.78"/>
+      <Variable consumers="3"/>
+      <Mode>busy</Mode>
+    </Sensor>
+    <Sensor name="Sensor Facility B">
+      <Variable temperature="23.1"/>
+      <Variable power="14.3"/>
+      <Variable frequency="49.78"/>
+      <Variable consumers="1"/>
+      <Mode>standby</Mode>
+    </Sensor>
+  </Bus>
+</Gateway>
+`
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "xml",
+		Queries: []Query{
+			{
+				MetricName:      "string('sensors')",
+				Selection:       "/Gateway/Bus/Sensor",
+				Timestamp:       "/Gateway/Timestamp",
+				TimestampFormat: "2006-01-02T15:04:05Z",
+				Tags: map[string]string{
+					"name":    "substring-after(@name, ' ')",
+					"gateway": "/Gateway/Name",
+				},
+				Fields: map[string]string{
+					"temperature": "number(Variable/@temperature)",
+					"power":       "number(Variable/@power)",
+					"mode":        "Mode",
+					"missing":     "Missing",
+				},
+				FieldsInt: map[string]string{
+					"consumers": "Variable/@consumers",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse([]byte(gateway))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"sensors",
+			map[string]string{"name": "Facility A", "gateway": "Main Gateway"},
+			map[string]interface{}{"temperature": 20.0, "power": 123.4, "mode": "busy", "consumers": int64(3)},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"sensors",
+			map[string]string{"name": "Facility B", "gateway": "Main Gateway"},
+			map[string]interface{}{"temperature": 23.1, "power": 14.3, "mode": "standby", "consumers": int64(1)},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestFieldSelection(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "xml",
+		Queries: []Query{
+			{
+				Selection:      "/Gateway",
+				FieldSelection: "child::*[not(*)]",
+			},
+			{
+				Selection:      "/Gateway/Bus/Sensor",
+				Tags:           map[string]string{"name": "@name"},
+				FieldSelection: "Variable/@*",
+			},
+		},
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(0, 0) }
+
+	metrics, err := parser.Parse([]byte(gateway))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"xml",
+			map[string]string{},
+			map[string]interface{}{"Name": "Main Gateway", "Timestamp": "2020-09-13T12:26:40Z", "Sequence": int64(12), "Status": "ok"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"xml",
+			map[string]string{"name": "Sensor Facility A"},
+			map[string]interface{}{"temperature": 20.0, "power": 123.4, "frequency": 49.78, "consumers": int64(3)},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"xml",
+			map[string]string{"name": "Sensor Facility B"},
+			map[string]interface{}{"temperature": 23.1, "power": 14.3, "frequency": 49.78, "consumers": int64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestFieldNameExpansion(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "xml",
+		Queries: []Query{
+			{
+				Selection:       "/Gateway",
+				FieldSelection:  "descendant::Mode",
+				FieldNameExpand: true,
+			},
+		},
+	})
+	require.NoError(t, err)
+	parser.TimeFunc = func() time.Time { return time.Unix(0, 0) }
+
+	metrics, err := parser.Parse([]byte(gateway))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	// Both sensors expand to the same name, so the last one wins.
+	require.Equal(t, map[string]interface{}{"Bus_Sensor_Mode": "standby"}, metrics[0].Fields())
+}
+
+func TestTimestampFormatRequired(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "xml",
+		Queries:    []Query{{Selection: "/Gateway", Timestamp: "Sequence"}},
+	})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte(gateway))
+	require.EqualError(t, err, "timestamp format must be specified")
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := New(&Config{MetricName: "xml"})
+	require.Error(t, err)
+
+	_, err = New(&Config{MetricName: "xml", Queries: []Query{{Selection: "/Gateway["}}})
+	require.Error(t, err)
+}
+
+func TestInvalidDocument(t *testing.T) {
+	parser, err := New(&Config{MetricName: "xml", Queries: []Query{{Selection: "/Gateway"}}})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("<Gateway><Name>"))
+	require.Error(t, err)
+}
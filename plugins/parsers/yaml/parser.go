@@ -0,0 +1,140 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/influxdata/telegraf"
+	jsonparser "github.com/influxdata/telegraf/plugins/parsers/json"
+	"gopkg.in/yaml.v2"
+)
+
+var ErrNoMetric = errors.New("no metric in document")
+
+type Config struct {
+	MetricName   string
+	TagKeys      []string
+	NameKey      string
+	StringFields []string
+	TimeKey      string
+	TimeFormat   string
+	Timezone     string
+	Strict       bool
+	DefaultTags  map[string]string
+}
+
+// Parser decodes YAML documents and maps their keys to metrics the same way
+// as the JSON parser.
+type Parser struct {
+	json *jsonparser.Parser
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.TimeKey != "" && config.TimeFormat == "" {
+		return nil, errors.New("use of 'yaml_time_key' requires 'yaml_time_format'")
+	}
+
+	parser, err := jsonparser.New(&jsonparser.Config{
+		MetricName:   config.MetricName,
+		TagKeys:      config.TagKeys,
+		NameKey:      config.NameKey,
+		StringFields: config.StringFields,
+		TimeKey:      config.TimeKey,
+		TimeFormat:   config.TimeFormat,
+		Timezone:     config.Timezone,
+		DefaultTags:  config.DefaultTags,
+		Strict:       config.Strict,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Parser{json: parser}, nil
+}
+
+// Parse parses a single document or a stream of documents separated by
+// "---", each holding a mapping or a sequence of mappings.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(buf))
+	for n := 1; ; n++ {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			continue
+		}
+
+		// Documents are handed to the JSON parser to share its flattening
+		// and key selection.
+		data, err := json.Marshal(normalize(doc))
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", n, err)
+		}
+		m, err := p.json.Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", n, err)
+		}
+		metrics = append(metrics, m...)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.json.SetDefaultTags(tags)
+}
+
+// normalize converts the decoded YAML values to the types decoded from JSON,
+// with string keys and float numbers.
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = normalize(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, 0, len(t))
+		for _, v := range t {
+			s = append(s, normalize(v))
+		}
+		return s
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case uint64:
+		return float64(t)
+	case float64:
+		// JSON has no representation of NaN and infinity, so these are
+		// skipped like null values.
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return nil
+		}
+		return t
+	default:
+		return v
+	}
+}
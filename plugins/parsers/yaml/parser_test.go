@@ -0,0 +1,92 @@
+package yaml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:   "yaml",
+		TagKeys:      []string{"host"},
+		StringFields: []string{"status"},
+		TimeKey:      "time",
+		TimeFormat:   "2006-01-02T15:04:05Z07:00",
+	})
+	require.NoError(t, err)
+
+	data := `
+host: server01
+time: "2020-09-13T12:26:40Z"
+status: ok
+healthy: true
+disk:
+  used: 42
+  free: 1.5e3
+  mounts: [1, 2]
+latency: .nan
+`
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"yaml",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{
+				"status":        "ok",
+				"disk_used":     42.0,
+				"disk_free":     1500.0,
+				"disk_mounts_0": 1.0,
+				"disk_mounts_1": 2.0,
+			},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseMultiDocument(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:   "yaml",
+		NameKey:      "name",
+		StringFields: []string{"name"},
+	})
+	require.NoError(t, err)
+
+	data := `---
+name: cpu
+usage: 12
+---
+- name: mem
+  used: 1024
+- name: swap
+  used: 0
+---
+`
+	metrics, err := parser.Parse([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+	require.Equal(t, "cpu", metrics[0].Name())
+	require.Equal(t, map[string]interface{}{"name": "cpu", "usage": 12.0}, metrics[0].Fields())
+	require.Equal(t, "mem", metrics[1].Name())
+	require.Equal(t, "swap", metrics[2].Name())
+}
+
+func TestParseErrors(t *testing.T) {
+	parser, err := New(&Config{MetricName: "yaml"})
+	require.NoError(t, err)
+
+	_, err = parser.Parse([]byte("value: 1\n---\n42\n"))
+	require.EqualError(t, err, "document 2: must be an object or an array of objects")
+
+	_, err = parser.Parse([]byte("value: [1\n"))
+	require.Error(t, err)
+
+	_, err = New(&Config{MetricName: "yaml", TimeKey: "time"})
+	require.EqualError(t, err, "use of 'yaml_time_key' requires 'yaml_time_format'")
+}
@@ -1,6 +1,7 @@
 package regex
 
 import (
+	"fmt"
 	"regexp"
 
 	"github.com/influxdata/telegraf"
@@ -66,6 +67,20 @@ func (r *Regex) Description() string {
 	return "Transforms tag and field values with regex pattern"
 }
 
+// Init compiles the patterns, so invalid ones are reported at startup.
+func (r *Regex) Init() error {
+	for _, converters := range [][]converter{r.Tags, r.Fields} {
+		for _, c := range converters {
+			regex, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q: %v", c.Pattern, err)
+			}
+			r.regexCache[c.Pattern] = regex
+		}
+	}
+	return nil
+}
+
 func (r *Regex) Apply(in ...telegraf.Metric) []telegraf.Metric {
 	for _, metric := range in {
 		for _, converter := range r.Tags {
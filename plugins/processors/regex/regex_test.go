@@ -261,6 +261,19 @@ func TestNoMatches(t *testing.T) {
 	}
 }
 
+func TestInvalidPattern(t *testing.T) {
+	regex := NewRegex()
+	regex.Fields = []converter{
+		{
+			Key:     "request",
+			Pattern: "^/users/(\\d+/$",
+		},
+	}
+	err := regex.Init()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid pattern")
+}
+
 func BenchmarkConversions(b *testing.B) {
 	regex := NewRegex()
 	regex.Tags = []converter{
@@ -0,0 +1,505 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/linkedin/goavro/v2"
+)
+
+const (
+	registryTimeout     = 10 * time.Second
+	registryContentType = "application/vnd.schemaregistry.v1+json"
+)
+
+// Encodings of the serialized data.
+const (
+	encodingBinary       = "binary"
+	encodingContainer    = "ocf"
+	encodingSingleObject = "single_object"
+	encodingConfluent    = "confluent"
+)
+
+type Config struct {
+	Schema           string
+	SchemaFile       string
+	SchemaRegistry   string
+	SchemaSubject    string
+	SchemaRegister   bool
+	Encoding         string
+	MeasurementField string
+	TimestampField   string
+	TimestampFormat  string
+	Timezone         string
+}
+
+// Serializer encodes metrics as records of an Avro schema, filling the
+// fields of the record with the tags and fields of the same name.
+type Serializer struct {
+	schema           string
+	registry         string
+	subject          string
+	register         bool
+	encoding         string
+	measurementField string
+	timestampField   string
+	timestampFormat  string
+	location         *time.Location
+	client           *http.Client
+
+	codec    *goavro.Codec
+	fields   []recordField
+	schemaID uint32
+}
+
+// recordField is a field of the record schema with the type values are
+// converted to.
+type recordField struct {
+	name string
+	// typ is the primitive type or the logical type, such as
+	// "timestamp-millis", or "enum".
+	typ string
+	// union is the name of the type in a union with null, empty if the field
+	// is not nullable.
+	union string
+}
+
+func NewSerializer(config *Config) (*Serializer, error) {
+	if config.Schema != "" && config.SchemaFile != "" {
+		return nil, errors.New("only one of avro_schema and avro_schema_file can be set")
+	}
+
+	schema := config.Schema
+	if config.SchemaFile != "" {
+		b, err := ioutil.ReadFile(config.SchemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading avro_schema_file: %v", err)
+		}
+		schema = string(b)
+	}
+
+	encoding := config.Encoding
+	if encoding == "" {
+		encoding = encodingBinary
+		if config.SchemaRegistry != "" {
+			encoding = encodingConfluent
+		}
+	}
+	switch encoding {
+	case encodingBinary, encodingContainer, encodingSingleObject:
+		if schema == "" && config.SchemaRegistry == "" {
+			return nil, errors.New("avro_schema, avro_schema_file or avro_schema_registry required")
+		}
+	case encodingConfluent:
+		if config.SchemaRegistry == "" {
+			return nil, errors.New("avro_schema_registry required for confluent encoding")
+		}
+	default:
+		return nil, fmt.Errorf("unknown avro encoding %q", encoding)
+	}
+	if config.SchemaRegistry != "" && config.SchemaSubject == "" {
+		return nil, errors.New("avro_schema_subject required with avro_schema_registry")
+	}
+	if config.SchemaRegister && schema == "" {
+		return nil, errors.New("avro_schema or avro_schema_file required to register the schema")
+	}
+
+	// LoadLocation returns UTC if timezone is the empty string.
+	location, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Serializer{
+		schema:           schema,
+		registry:         strings.TrimSuffix(config.SchemaRegistry, "/"),
+		subject:          config.SchemaSubject,
+		register:         config.SchemaRegister,
+		encoding:         encoding,
+		measurementField: config.MeasurementField,
+		timestampField:   config.TimestampField,
+		timestampFormat:  config.TimestampFormat,
+		location:         location,
+		client:           &http.Client{Timeout: registryTimeout},
+	}
+	if s.timestampField == "" {
+		s.timestampField = "timestamp"
+	}
+	if s.timestampFormat == "" {
+		s.timestampFormat = "unix"
+	}
+
+	// Without a registry the schema is checked right away.
+	if s.registry == "" {
+		if err := s.setSchema(schema); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// Joinable reports whether the output can be concatenated, object container
+// files can't.
+func (s *Serializer) Joinable(_ bool) bool {
+	return s.encoding != encodingContainer
+}
+
+// SerializeBatch encodes the metrics one after another, or as a single
+// object container file.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if s.codec == nil {
+		if err := s.resolveSchema(); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]interface{}, 0, len(metrics))
+	for _, metric := range metrics {
+		record, err := s.record(metric)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	if s.encoding == encodingContainer {
+		var buf bytes.Buffer
+		ocf, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &buf, Codec: s.codec})
+		if err != nil {
+			return nil, err
+		}
+		if err := ocf.Append(records); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var buf []byte
+	for _, record := range records {
+		var err error
+		switch s.encoding {
+		case encodingSingleObject:
+			buf, err = s.codec.SingleFromNative(buf, record)
+		case encodingConfluent:
+			var header [5]byte
+			binary.BigEndian.PutUint32(header[1:], s.schemaID)
+			buf, err = s.codec.BinaryFromNative(append(buf, header[:]...), record)
+		default:
+			buf, err = s.codec.BinaryFromNative(buf, record)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// resolveSchema registers the configured schema or looks up its ID, or
+// fetches the latest schema of the subject if none is configured.  Failed
+// attempts are repeated with the next metrics.
+func (s *Serializer) resolveSchema() error {
+	request := struct {
+		Schema string `json:"schema"`
+	}{Schema: s.schema}
+	var response struct {
+		ID     uint32 `json:"id"`
+		Schema string `json:"schema"`
+	}
+
+	subject := s.registry + "/subjects/" + url.PathEscape(s.subject)
+	var err error
+	switch {
+	case s.register:
+		err = s.call(http.MethodPost, subject+"/versions", request, &response)
+	case s.schema != "":
+		err = s.call(http.MethodPost, subject, request, &response)
+	default:
+		err = s.call(http.MethodGet, subject+"/versions/latest", nil, &response)
+	}
+	if err != nil {
+		return fmt.Errorf("resolving schema of subject %q: %v", s.subject, err)
+	}
+
+	schema := s.schema
+	if schema == "" {
+		schema = response.Schema
+	}
+	if err := s.setSchema(schema); err != nil {
+		return err
+	}
+	s.schemaID = response.ID
+	return nil
+}
+
+func (s *Serializer) call(method, address string, request, response interface{}) error {
+	var body io.Reader
+	if request != nil {
+		b, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, address, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", registryContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", registryContentType)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, address, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+func (s *Serializer) setSchema(schema string) error {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %v", err)
+	}
+	fields, err := parseFields(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %v", err)
+	}
+	s.codec = codec
+	s.fields = fields
+	return nil
+}
+
+// parseFields returns the fields of a record schema.  Only fields of
+// primitive types, timestamps and enums, optionally in a union with null,
+// are supported.
+func parseFields(schema string) ([]recordField, error) {
+	var record struct {
+		Type      string `json:"type"`
+		Namespace string `json:"namespace"`
+		Fields    []struct {
+			Name string      `json:"name"`
+			Type interface{} `json:"type"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(schema), &record); err != nil {
+		return nil, err
+	}
+	if record.Type != "record" {
+		return nil, errors.New("schema must be a record")
+	}
+
+	fields := make([]recordField, 0, len(record.Fields))
+	for _, f := range record.Fields {
+		field := recordField{name: f.Name}
+		typ := f.Type
+		union, isUnion := typ.([]interface{})
+		if isUnion {
+			typ = nil
+			for _, member := range union {
+				if member != "null" {
+					if typ != nil {
+						return nil, fmt.Errorf("field %q: only unions with null are supported", f.Name)
+					}
+					typ = member
+				}
+			}
+			if typ == nil {
+				return nil, fmt.Errorf("field %q: unsupported type", f.Name)
+			}
+		}
+
+		var name string
+		switch t := typ.(type) {
+		case string:
+			field.typ, name = t, t
+		case map[string]interface{}:
+			base, _ := t["type"].(string)
+			field.typ, name = base, base
+			if logical, ok := t["logicalType"].(string); ok {
+				field.typ, name = logical, base+"."+logical
+			}
+			if base == "enum" {
+				name, _ = t["name"].(string)
+				namespace, ok := t["namespace"].(string)
+				if !ok {
+					namespace = record.Namespace
+				}
+				if !strings.Contains(name, ".") && namespace != "" {
+					name = namespace + "." + name
+				}
+			}
+		}
+		switch field.typ {
+		case "boolean", "int", "long", "float", "double", "string", "bytes",
+			"timestamp-millis", "timestamp-micros", "enum":
+		default:
+			return nil, fmt.Errorf("field %q: unsupported type", f.Name)
+		}
+		if isUnion {
+			field.union = name
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// record returns the native record of the metric.  Missing values are left
+// out so that the default of the field is used.
+func (s *Serializer) record(metric telegraf.Metric) (map[string]interface{}, error) {
+	record := make(map[string]interface{}, len(s.fields))
+	for _, f := range s.fields {
+		var value interface{}
+		switch f.name {
+		case s.timestampField:
+			value = s.timestamp(f, metric.Time())
+		case s.measurementField:
+			value = metric.Name()
+		default:
+			v, ok := metric.GetField(f.name)
+			if !ok {
+				tag, ok := metric.GetTag(f.name)
+				if !ok {
+					if f.union != "" {
+						record[f.name] = nil
+					}
+					continue
+				}
+				v = tag
+			}
+			var err error
+			value, err = convert(f.typ, v)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %v", f.name, err)
+			}
+		}
+
+		if f.union != "" {
+			value = goavro.Union(f.union, value)
+		}
+		record[f.name] = value
+	}
+	return record, nil
+}
+
+func (s *Serializer) timestamp(f recordField, t time.Time) interface{} {
+	var unix int64
+	switch s.timestampFormat {
+	case "unix":
+		unix = t.Unix()
+	case "unix_ms":
+		unix = t.UnixNano() / int64(time.Millisecond)
+	case "unix_us":
+		unix = t.UnixNano() / int64(time.Microsecond)
+	case "unix_ns":
+		unix = t.UnixNano()
+	default:
+		if f.typ == "string" {
+			return t.In(s.location).Format(s.timestampFormat)
+		}
+		unix = t.Unix()
+	}
+
+	switch f.typ {
+	case "timestamp-millis", "timestamp-micros":
+		return t
+	case "int":
+		return int32(unix)
+	case "float":
+		return float32(unix)
+	case "double":
+		return float64(unix)
+	case "string":
+		return strconv.FormatInt(unix, 10)
+	default:
+		return unix
+	}
+}
+
+// convert converts a tag or field value to the native type of the Avro type.
+func convert(typ string, v interface{}) (interface{}, error) {
+	switch typ {
+	case "boolean":
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(v)
+		}
+	case "int", "long":
+		var i int64
+		switch v := v.(type) {
+		case int64:
+			i = v
+		case uint64:
+			i = int64(v)
+		case float64:
+			i = int64(v)
+		case string:
+			var err error
+			if i, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("can't convert %T to %s", v, typ)
+		}
+		if typ == "int" {
+			return int32(i), nil
+		}
+		return i, nil
+	case "float", "double":
+		var f float64
+		switch v := v.(type) {
+		case float64:
+			f = v
+		case int64:
+			f = float64(v)
+		case uint64:
+			f = float64(v)
+		case string:
+			var err error
+			if f, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("can't convert %T to %s", v, typ)
+		}
+		if typ == "float" {
+			return float32(f), nil
+		}
+		return f, nil
+	case "string", "enum":
+		return fmt.Sprint(v), nil
+	case "bytes":
+		return []byte(fmt.Sprint(v)), nil
+	case "timestamp-millis", "timestamp-micros":
+		if i, ok := v.(int64); ok {
+			if typ == "timestamp-millis" {
+				return time.Unix(0, i*int64(time.Millisecond)), nil
+			}
+			return time.Unix(0, i*int64(time.Microsecond)), nil
+		}
+	}
+	return nil, fmt.Errorf("can't convert %T to %s", v, typ)
+}
@@ -0,0 +1,221 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	parser "github.com/influxdata/telegraf/plugins/parsers/avro"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `
+{
+  "type": "record",
+  "name": "Reading",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "sensor", "type": "string"},
+    {"name": "value", "type": "double"},
+    {"name": "count", "type": ["null", "int"]},
+    {"name": "state", "type": {"type": "enum", "name": "State", "symbols": ["OK", "FAILED"]}},
+    {"name": "unit", "type": "string", "default": "celsius"}
+  ]
+}`
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"reading",
+			map[string]string{"sensor": "a"},
+			map[string]interface{}{"value": 1.5, "count": int64(3), "state": "OK"},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"reading",
+			map[string]string{"sensor": "b"},
+			map[string]interface{}{"value": int64(2), "state": "FAILED", "ignored": true},
+			time.Unix(1600000010, 0),
+		),
+	}
+}
+
+func newParser(t *testing.T, config *parser.Config) *parser.Parser {
+	config.MeasurementField = "name"
+	config.TimestampField = "time"
+	config.Tags = []string{"sensor"}
+	p, err := parser.New(config)
+	require.NoError(t, err)
+	return p
+}
+
+func expectedMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"reading",
+			map[string]string{"sensor": "a"},
+			map[string]interface{}{"value": 1.5, "count": int64(3), "state": "OK", "unit": "celsius"},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"reading",
+			map[string]string{"sensor": "b"},
+			map[string]interface{}{"value": 2.0, "state": "FAILED", "unit": "celsius"},
+			time.Unix(1600000010, 0),
+		),
+	}
+}
+
+func TestSerializeEncodings(t *testing.T) {
+	for _, encoding := range []string{"binary", "ocf", "single_object"} {
+		t.Run(encoding, func(t *testing.T) {
+			s, err := NewSerializer(&Config{
+				Schema:           testSchema,
+				Encoding:         encoding,
+				MeasurementField: "name",
+				TimestampField:   "time",
+			})
+			require.NoError(t, err)
+
+			buf, err := s.SerializeBatch(testMetrics())
+			require.NoError(t, err)
+
+			p := newParser(t, &parser.Config{Schema: testSchema})
+			metrics, err := p.Parse(buf)
+			require.NoError(t, err)
+			testutil.RequireMetricsEqual(t, expectedMetrics(), metrics, testutil.IgnoreTime())
+			for i, m := range metrics {
+				require.True(t, expectedMetrics()[i].Time().Equal(m.Time()))
+			}
+		})
+	}
+}
+
+func TestTimestampFormat(t *testing.T) {
+	schema := `{"type": "record", "name": "R", "fields": [{"name": "ts", "type": "long"}]}`
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+
+	m := testutil.MustMetric("r", map[string]string{}, map[string]interface{}{}, time.Unix(1600000000, 5000000))
+
+	for format, expected := range map[string]int64{"unix": 1600000000, "unix_ms": 1600000000005} {
+		s, err := NewSerializer(&Config{Schema: schema, TimestampField: "ts", TimestampFormat: format})
+		require.NoError(t, err)
+		buf, err := s.Serialize(m)
+		require.NoError(t, err)
+
+		record, _, err := codec.NativeFromBinary(buf)
+		require.NoError(t, err)
+		require.Equal(t, expected, record.(map[string]interface{})["ts"])
+	}
+}
+
+func TestSchemaRegistry(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/subjects/readings-value/versions":
+			var request map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			require.JSONEq(t, testSchema, request["schema"])
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 42})
+		case "/subjects/readings-value/versions/latest":
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 43, "schema": testSchema})
+		case "/schemas/ids/42", "/schemas/ids/43":
+			json.NewEncoder(w).Encode(map[string]string{"schema": testSchema})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	tests := []struct {
+		name   string
+		config *Config
+		id     uint32
+		path   string
+	}{
+		{
+			name:   "register",
+			config: &Config{Schema: testSchema, SchemaRegister: true},
+			id:     42,
+			path:   "POST /subjects/readings-value/versions",
+		},
+		{
+			name:   "latest",
+			config: &Config{},
+			id:     43,
+			path:   "GET /subjects/readings-value/versions/latest",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths = nil
+			tt.config.SchemaRegistry = ts.URL
+			tt.config.SchemaSubject = "readings-value"
+			tt.config.MeasurementField = "name"
+			tt.config.TimestampField = "time"
+			s, err := NewSerializer(tt.config)
+			require.NoError(t, err)
+
+			p := newParser(t, &parser.Config{SchemaRegistry: ts.URL})
+			for i, m := range testMetrics() {
+				buf, err := s.Serialize(m)
+				require.NoError(t, err)
+				require.Equal(t, byte(0), buf[0])
+				require.Equal(t, tt.id, binary.BigEndian.Uint32(buf[1:5]))
+
+				metrics, err := p.Parse(buf)
+				require.NoError(t, err)
+				testutil.RequireMetricsEqual(t, expectedMetrics()[i:i+1], metrics, testutil.IgnoreTime())
+			}
+			// the schema is resolved once
+			require.Equal(t, tt.path, paths[0])
+			require.NotContains(t, paths[1:], tt.path)
+		})
+	}
+}
+
+func TestSchemaRegistryError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	s, err := NewSerializer(&Config{SchemaRegistry: ts.URL, SchemaSubject: "missing"})
+	require.NoError(t, err)
+
+	_, err = s.Serialize(testMetrics()[0])
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `resolving schema of subject "missing"`)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{name: "no schema", config: &Config{}},
+		{name: "unknown encoding", config: &Config{Schema: testSchema, Encoding: "json"}},
+		{name: "confluent without registry", config: &Config{Schema: testSchema, Encoding: "confluent"}},
+		{name: "registry without subject", config: &Config{SchemaRegistry: "http://localhost:8081"}},
+		{name: "not a record", config: &Config{Schema: `"string"`}},
+		{name: "nested record", config: &Config{Schema: `{"type": "record", "name": "R", "fields": [
+			{"name": "inner", "type": {"type": "record", "name": "I", "fields": []}}]}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSerializer(tt.config)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	columnTimestamp   = "timestamp"
+	columnMeasurement = "measurement"
+	prefixTag         = "tag."
+	prefixField       = "field."
+)
+
+type Config struct {
+	// Separator is the field delimiter, defaults to a comma.
+	Separator string
+	// Header enables writing a header row with the column names.
+	Header bool
+	// Columns is the ordered list of columns; "timestamp", "measurement",
+	// "tag.<key>" or "field.<key>".  When empty the columns are taken from
+	// the first metric.
+	Columns []string
+	// TimestampFormat is one of "unix", "unix_ms", "unix_us", "unix_ns" or a
+	// Go time layout.
+	TimestampFormat string
+	// Timezone is the location of timestamps formatted with a Go layout.
+	Timezone string
+}
+
+type column struct {
+	kind string
+	key  string
+}
+
+type Serializer struct {
+	separator       rune
+	header          bool
+	columns         []column
+	timestampFormat string
+	location        *time.Location
+
+	headerWritten bool
+}
+
+func NewSerializer(config *Config) (*Serializer, error) {
+	separator := ','
+	if config.Separator != "" {
+		if utf8.RuneCountInString(config.Separator) != 1 {
+			return nil, fmt.Errorf("csv_separator must be a single character, got %q", config.Separator)
+		}
+		separator, _ = utf8.DecodeRuneInString(config.Separator)
+	}
+
+	columns := make([]column, 0, len(config.Columns))
+	for _, name := range config.Columns {
+		c, err := parseColumn(name)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, c)
+	}
+
+	timestampFormat := config.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = "unix"
+	}
+
+	// LoadLocation returns UTC if timezone is the empty string.
+	location, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Serializer{
+		separator:       separator,
+		header:          config.Header,
+		columns:         columns,
+		timestampFormat: timestampFormat,
+		location:        location,
+	}, nil
+}
+
+func parseColumn(name string) (column, error) {
+	switch {
+	case name == columnTimestamp || name == columnMeasurement:
+		return column{kind: name}, nil
+	case strings.HasPrefix(name, prefixTag) && len(name) > len(prefixTag):
+		return column{kind: prefixTag, key: name[len(prefixTag):]}, nil
+	case strings.HasPrefix(name, prefixField) && len(name) > len(prefixField):
+		return column{kind: prefixField, key: name[len(prefixField):]}, nil
+	default:
+		return column{}, fmt.Errorf("invalid csv column %q", name)
+	}
+}
+
+// Serialize returns a row for the metric, preceded by the header for the
+// first metric.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	if len(s.columns) == 0 {
+		s.columns = columnsOf([]telegraf.Metric{metric})
+	}
+
+	var buf bytes.Buffer
+	w := s.newWriter(&buf)
+	if s.header && !s.headerWritten {
+		if err := w.Write(s.headerRow(s.columns)); err != nil {
+			return nil, err
+		}
+		s.headerWritten = true
+	}
+	if err := w.Write(s.row(s.columns, metric)); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Joinable reports whether rows can be appended to earlier output, which
+// isn't the case for batches with a header as each repeats it.
+func (s *Serializer) Joinable(batch bool) bool {
+	return !(s.header && batch)
+}
+
+// SerializeBatch returns a complete document for the metrics, starting with
+// the header.  Without configured columns, the columns are the union of the
+// tags and fields of the batch.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	columns := s.columns
+	if len(columns) == 0 {
+		columns = columnsOf(metrics)
+	}
+
+	var buf bytes.Buffer
+	w := s.newWriter(&buf)
+	if s.header {
+		if err := w.Write(s.headerRow(columns)); err != nil {
+			return nil, err
+		}
+	}
+	for _, metric := range metrics {
+		if err := w.Write(s.row(columns, metric)); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func (s *Serializer) newWriter(buf *bytes.Buffer) *csv.Writer {
+	w := csv.NewWriter(buf)
+	w.Comma = s.separator
+	return w
+}
+
+// columnsOf returns the timestamp and measurement columns followed by the
+// sorted tag and field keys of the metrics.
+func columnsOf(metrics []telegraf.Metric) []column {
+	tags := make(map[string]bool)
+	fields := make(map[string]bool)
+	for _, metric := range metrics {
+		for _, tag := range metric.TagList() {
+			tags[tag.Key] = true
+		}
+		for _, field := range metric.FieldList() {
+			fields[field.Key] = true
+		}
+	}
+
+	columns := []column{{kind: columnTimestamp}, {kind: columnMeasurement}}
+	columns = append(columns, sortedColumns(prefixTag, tags)...)
+	columns = append(columns, sortedColumns(prefixField, fields)...)
+	return columns
+}
+
+func sortedColumns(kind string, keys map[string]bool) []column {
+	columns := make([]column, 0, len(keys))
+	for key := range keys {
+		columns = append(columns, column{kind: kind, key: key})
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].key < columns[j].key
+	})
+	return columns
+}
+
+func (s *Serializer) headerRow(columns []column) []string {
+	row := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if c.key != "" {
+			row = append(row, c.key)
+		} else {
+			row = append(row, c.kind)
+		}
+	}
+	return row
+}
+
+// row returns the values of the metric, missing tags and fields are left
+// empty.
+func (s *Serializer) row(columns []column, metric telegraf.Metric) []string {
+	row := make([]string, 0, len(columns))
+	for _, c := range columns {
+		switch c.kind {
+		case columnTimestamp:
+			row = append(row, s.formatTime(metric.Time()))
+		case columnMeasurement:
+			row = append(row, metric.Name())
+		case prefixTag:
+			value, _ := metric.GetTag(c.key)
+			row = append(row, value)
+		case prefixField:
+			value, ok := metric.GetField(c.key)
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, formatValue(value))
+		}
+	}
+	return row
+}
+
+func (s *Serializer) formatTime(t time.Time) string {
+	switch s.timestampFormat {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixNano()/1000000, 10)
+	case "unix_us":
+		return strconv.FormatInt(t.UnixNano()/1000, 10)
+	case "unix_ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	default:
+		return t.In(s.location).Format(s.timestampFormat)
+	}
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
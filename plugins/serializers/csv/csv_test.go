@@ -0,0 +1,120 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage_idle": 91.5, "count": int64(3)},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "b,c", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 42.0, "ok": true},
+			time.Unix(1600000010, 0),
+		),
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	s, err := NewSerializer(&Config{Header: true})
+	require.NoError(t, err)
+
+	var out []byte
+	for _, m := range testMetrics() {
+		buf, err := s.Serialize(m)
+		require.NoError(t, err)
+		out = append(out, buf...)
+	}
+
+	// the columns are taken from the first metric
+	expected := "timestamp,measurement,host,count,usage_idle\n" +
+		"1600000000,cpu,a,3,91.5\n" +
+		"1600000010,cpu,\"b,c\",,42\n"
+	require.Equal(t, expected, string(out))
+}
+
+func TestSerializeBatch(t *testing.T) {
+	s, err := NewSerializer(&Config{Header: true, Separator: ";"})
+	require.NoError(t, err)
+
+	expected := "timestamp;measurement;cpu;host;count;ok;usage_idle\n" +
+		"1600000000;cpu;;a;3;;91.5\n" +
+		"1600000010;cpu;cpu0;b,c;;true;42\n"
+
+	// each batch starts with the header
+	for i := 0; i < 2; i++ {
+		buf, err := s.SerializeBatch(testMetrics())
+		require.NoError(t, err)
+		require.Equal(t, expected, string(buf))
+	}
+}
+
+func TestColumns(t *testing.T) {
+	s, err := NewSerializer(&Config{
+		Header:          true,
+		Columns:         []string{"field.usage_idle", "tag.host", "timestamp"},
+		TimestampFormat: "2006-01-02T15:04:05Z07:00",
+	})
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	expected := "usage_idle,host,timestamp\n" +
+		"91.5,a,2020-09-13T12:26:40Z\n" +
+		"42,\"b,c\",2020-09-13T12:26:50Z\n"
+	require.Equal(t, expected, string(buf))
+}
+
+func TestTimestampFormat(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(1600000000, 123456789),
+	)
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"unix", "1600000000,1\n"},
+		{"unix_ms", "1600000000123,1\n"},
+		{"unix_us", "1600000000123456,1\n"},
+		{"unix_ns", "1600000000123456789,1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			s, err := NewSerializer(&Config{
+				Columns:         []string{"timestamp", "field.value"},
+				TimestampFormat: tt.format,
+			})
+			require.NoError(t, err)
+			buf, err := s.Serialize(m)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(buf))
+		})
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := NewSerializer(&Config{Separator: "::"})
+	require.Error(t, err)
+
+	_, err = NewSerializer(&Config{Columns: []string{"host"}})
+	require.EqualError(t, err, `invalid csv column "host"`)
+
+	_, err = NewSerializer(&Config{Timezone: "Nowhere/Invalid"})
+	require.Error(t, err)
+}
@@ -0,0 +1,531 @@
+package parquet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/xitongsys/parquet-go/common"
+	"github.com/xitongsys/parquet-go/layout"
+	"github.com/xitongsys/parquet-go/marshal"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	defaultTimestampColumn   = "timestamp"
+	defaultMeasurementColumn = "measurement"
+	defaultRowGroupSize      = 128 * 1024 * 1024
+	defaultPageSize          = 8 * 1024
+
+	// flushRows is the number of rows encoded at once, the size of the row
+	// group is checked after each of them.
+	flushRows = 1000
+)
+
+// Column types of the schema.
+const (
+	typeBoolean = "boolean"
+	typeInt64   = "int64"
+	typeUint64  = "uint64"
+	typeDouble  = "double"
+	typeString  = "string"
+)
+
+var compressionCodecs = map[string]parquet.CompressionCodec{
+	"":             parquet.CompressionCodec_SNAPPY,
+	"snappy":       parquet.CompressionCodec_SNAPPY,
+	"gzip":         parquet.CompressionCodec_GZIP,
+	"zstd":         parquet.CompressionCodec_ZSTD,
+	"uncompressed": parquet.CompressionCodec_UNCOMPRESSED,
+}
+
+type Config struct {
+	// TimestampColumn and MeasurementColumn are the names of the columns of
+	// the metric timestamp and name.
+	TimestampColumn   string
+	MeasurementColumn string
+	// Columns is the ordered list of tag and field keys written.  When empty
+	// all tags and fields of the metrics are written.
+	Columns []string
+	// ColumnTypes is the type of the columns, columns without a type are
+	// inferred from the metrics.
+	ColumnTypes map[string]string
+	// RowGroupSize and PageSize are the sizes in bytes of the row groups and
+	// pages.
+	RowGroupSize int64
+	PageSize     int64
+	// Compression is one of "snappy", "gzip", "zstd" or "uncompressed".
+	Compression string
+	// ColumnCompression overrides the compression of individual columns.
+	ColumnCompression map[string]string
+	// DictionaryColumns are glob patterns of the columns written with
+	// dictionary encoding.
+	DictionaryColumns []string
+}
+
+type Serializer struct {
+	timestampColumn   string
+	measurementColumn string
+	columns           []string
+	columnTypes       map[string]string
+	rowGroupSize      int64
+	pageSize          int64
+	compression       parquet.CompressionCodec
+	columnCompression map[string]parquet.CompressionCodec
+	dictionary        filter.Filter
+}
+
+// column is a tag or field column of the file.
+type column struct {
+	key string
+	typ string
+}
+
+func NewSerializer(config *Config) (*Serializer, error) {
+	s := &Serializer{
+		timestampColumn:   config.TimestampColumn,
+		measurementColumn: config.MeasurementColumn,
+		columns:           config.Columns,
+		columnTypes:       config.ColumnTypes,
+		rowGroupSize:      config.RowGroupSize,
+		pageSize:          config.PageSize,
+	}
+	if s.timestampColumn == "" {
+		s.timestampColumn = defaultTimestampColumn
+	}
+	if s.measurementColumn == "" {
+		s.measurementColumn = defaultMeasurementColumn
+	}
+	if s.rowGroupSize <= 0 {
+		s.rowGroupSize = defaultRowGroupSize
+	}
+	if s.pageSize <= 0 {
+		s.pageSize = defaultPageSize
+	}
+
+	codec, ok := compressionCodecs[config.Compression]
+	if !ok {
+		return nil, fmt.Errorf("unknown parquet compression %q", config.Compression)
+	}
+	s.compression = codec
+
+	s.columnCompression = make(map[string]parquet.CompressionCodec, len(config.ColumnCompression))
+	for key, name := range config.ColumnCompression {
+		codec, ok := compressionCodecs[name]
+		if !ok || name == "" {
+			return nil, fmt.Errorf("unknown parquet compression %q for column %q", name, key)
+		}
+		s.columnCompression[key] = codec
+	}
+
+	dictionary, err := filter.Compile(config.DictionaryColumns)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dictionary columns: %v", err)
+	}
+	s.dictionary = dictionary
+
+	for key, typ := range s.columnTypes {
+		switch typ {
+		case typeBoolean, typeInt64, typeUint64, typeDouble, typeString:
+		default:
+			return nil, fmt.Errorf("invalid type %q for column %q", typ, key)
+		}
+	}
+	return s, nil
+}
+
+// Serialize returns a file with a single row.  Files can't be concatenated,
+// use batch serialization to write multiple metrics.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// Joinable reports that files can't be concatenated.
+func (s *Serializer) Joinable(_ bool) bool {
+	return false
+}
+
+// SerializeBatch returns a file with a row for each metric.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	columns := s.schemaColumns(metrics)
+	schema, err := s.schema(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf writeBuffer
+	pw, err := writer.NewParquetWriter(&buf, schema, 1)
+	if err != nil {
+		return nil, err
+	}
+	s.setEncodings(pw.SchemaHandler)
+
+	rows := make([]interface{}, 0, len(metrics))
+	for _, metric := range metrics {
+		row := make([]interface{}, 0, len(columns)+2)
+		row = append(row, metric.Time().UnixNano()/1000, metric.Name())
+		for _, c := range columns {
+			row = append(row, value(metric, c))
+		}
+		rows = append(rows, row)
+	}
+	if err := s.writeRowGroups(pw, rows); err != nil {
+		return nil, err
+	}
+
+	// The rows are written, only the footer is left to write.
+	if err := pw.WriteStop(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// setEncodings enables dictionary encoding of the selected columns, boolean
+// columns are always plain encoded.
+func (s *Serializer) setEncodings(sh *schema.SchemaHandler) {
+	if s.dictionary == nil {
+		return
+	}
+	for i, e := range sh.SchemaElements {
+		if e.GetNumChildren() > 0 || e.GetType() == parquet.Type_BOOLEAN {
+			continue
+		}
+		if s.dictionary.Match(e.GetName()) {
+			sh.Infos[i].Encoding = parquet.Encoding_PLAIN_DICTIONARY
+		}
+	}
+}
+
+// codec returns the compression codec of the column.
+func (s *Serializer) codec(name string) parquet.CompressionCodec {
+	if codec, ok := s.columnCompression[name]; ok {
+		return codec
+	}
+	return s.compression
+}
+
+// writeRowGroups writes the rows in row groups of about the row group size.
+// The parquet writer compresses all columns with the same codec, so the
+// pages and column chunks are built here instead.
+func (s *Serializer) writeRowGroups(pw *writer.ParquetWriter, rows []interface{}) error {
+	pages := make(map[string][]*layout.Page)
+	dicts := make(map[string]*layout.DictRecType)
+	var size, numRows int64
+	for bgn := 0; bgn < len(rows); bgn += flushRows {
+		end := bgn + flushRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		tables, err := marshal.MarshalCSV(rows, bgn, end, pw.SchemaHandler)
+		if err != nil {
+			return err
+		}
+		for path, table := range *tables {
+			codec := s.codec(table.Info.ExName)
+			var p []*layout.Page
+			var n int64
+			if table.Info.Encoding == parquet.Encoding_PLAIN_DICTIONARY {
+				dict, ok := dicts[path]
+				if !ok {
+					dict = layout.NewDictRec(*table.Schema.Type)
+					dicts[path] = dict
+				}
+				p, n = layout.TableToDictDataPages(dict, table, int32(s.pageSize), 32, codec)
+			} else {
+				p, n = layout.TableToDataPages(table, int32(s.pageSize), codec)
+			}
+			pages[path] = append(pages[path], p...)
+			size += n
+		}
+		numRows += int64(end - bgn)
+
+		if size >= s.rowGroupSize || end == len(rows) {
+			if err := s.writeRowGroup(pw, pages, dicts, numRows); err != nil {
+				return err
+			}
+			pages = make(map[string][]*layout.Page)
+			dicts = make(map[string]*layout.DictRecType)
+			size, numRows = 0, 0
+		}
+	}
+	return nil
+}
+
+// writeRowGroup writes the pages of the columns as a row group and adds it
+// to the footer of the file.
+func (s *Serializer) writeRowGroup(
+	pw *writer.ParquetWriter,
+	pages map[string][]*layout.Page,
+	dicts map[string]*layout.DictRecType,
+	numRows int64,
+) error {
+	rowGroup := layout.NewRowGroup()
+	rowGroup.RowGroupHeader.Columns = make([]*parquet.ColumnChunk, 0, len(pages))
+	for i, e := range pw.SchemaHandler.SchemaElements {
+		if e.GetNumChildren() > 0 {
+			continue
+		}
+		path := pw.SchemaHandler.IndexMap[int32(i)]
+		columnPages := pages[path]
+		if len(columnPages) == 0 {
+			continue
+		}
+
+		var chunk *layout.Chunk
+		if dict, ok := dicts[path]; ok {
+			dictPage, _ := layout.DictRecToDictPage(dict, int32(s.pageSize), columnPages[0].CompressType)
+			chunk = layout.PagesToDictChunk(append([]*layout.Page{dictPage}, columnPages...))
+		} else {
+			chunk = layout.PagesToChunk(columnPages)
+		}
+		rowGroup.Chunks = append(rowGroup.Chunks, chunk)
+		rowGroup.RowGroupHeader.TotalByteSize += chunk.ChunkHeader.MetaData.TotalUncompressedSize
+		rowGroup.RowGroupHeader.Columns = append(rowGroup.RowGroupHeader.Columns, chunk.ChunkHeader)
+	}
+	rowGroup.RowGroupHeader.NumRows = numRows
+
+	for _, chunk := range rowGroup.Chunks {
+		chunk.ChunkHeader.MetaData.DataPageOffset = -1
+		chunk.ChunkHeader.FileOffset = pw.Offset
+		for _, page := range chunk.Pages {
+			if page.Header.Type == parquet.PageType_DICTIONARY_PAGE {
+				offset := pw.Offset
+				chunk.ChunkHeader.MetaData.DictionaryPageOffset = &offset
+			} else if chunk.ChunkHeader.MetaData.DataPageOffset <= 0 {
+				chunk.ChunkHeader.MetaData.DataPageOffset = pw.Offset
+			}
+			if _, err := pw.PFile.Write(page.RawData); err != nil {
+				return err
+			}
+			pw.Offset += int64(len(page.RawData))
+		}
+	}
+	pw.Footer.RowGroups = append(pw.Footer.RowGroups, rowGroup.RowGroupHeader)
+	pw.Footer.NumRows += numRows
+	return nil
+}
+
+// schemaColumns returns the tag and field columns with their types.
+// Inferred columns mixing numeric types are doubles, other mixed types are
+// strings.
+func (s *Serializer) schemaColumns(metrics []telegraf.Metric) []column {
+	inferred := make(map[string]string)
+	for _, metric := range metrics {
+		for _, tag := range metric.TagList() {
+			inferred[tag.Key] = mergeType(inferred[tag.Key], typeString)
+		}
+		for _, field := range metric.FieldList() {
+			inferred[field.Key] = mergeType(inferred[field.Key], typeOf(field.Value))
+		}
+	}
+
+	keys := s.columns
+	if len(keys) == 0 {
+		keys = make([]string, 0, len(inferred))
+		for key := range inferred {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+
+	columns := make([]column, 0, len(keys))
+	for _, key := range keys {
+		typ, ok := s.columnTypes[key]
+		if !ok {
+			typ = inferred[key]
+		}
+		if typ == "" {
+			typ = typeString
+		}
+		columns = append(columns, column{key: key, typ: typ})
+	}
+	return columns
+}
+
+func (s *Serializer) schema(columns []column) ([]*parquet.SchemaElement, error) {
+	schema := make([]*parquet.SchemaElement, 0, len(columns)+3)
+	schema = append(schema, &parquet.SchemaElement{
+		Name:           "telegraf",
+		RepetitionType: parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_REQUIRED),
+		NumChildren:    int32Ptr(int32(len(columns) + 2)),
+	})
+	schema = append(schema, element(s.timestampColumn, parquet.Type_INT64, parquet.ConvertedType_TIMESTAMP_MICROS))
+	schema = append(schema, element(s.measurementColumn, parquet.Type_BYTE_ARRAY, parquet.ConvertedType_UTF8))
+
+	for _, c := range columns {
+		switch c.typ {
+		case typeBoolean:
+			schema = append(schema, element(c.key, parquet.Type_BOOLEAN, -1))
+		case typeInt64:
+			schema = append(schema, element(c.key, parquet.Type_INT64, -1))
+		case typeUint64:
+			schema = append(schema, element(c.key, parquet.Type_INT64, parquet.ConvertedType_UINT_64))
+		case typeDouble:
+			schema = append(schema, element(c.key, parquet.Type_DOUBLE, -1))
+		default:
+			schema = append(schema, element(c.key, parquet.Type_BYTE_ARRAY, parquet.ConvertedType_UTF8))
+		}
+	}
+
+	// The writer identifies columns by a variable name derived from the
+	// column name, which must be unique.
+	names := make(map[string]string)
+	for _, e := range schema[1:] {
+		name := common.StringToVariableName(e.Name)
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("columns %q and %q can't be written to the same file", other, e.Name)
+		}
+		names[name] = e.Name
+	}
+	return schema, nil
+}
+
+func element(name string, typ parquet.Type, convertedType parquet.ConvertedType) *parquet.SchemaElement {
+	e := &parquet.SchemaElement{
+		Name:           name,
+		Type:           parquet.TypePtr(typ),
+		RepetitionType: parquet.FieldRepetitionTypePtr(parquet.FieldRepetitionType_OPTIONAL),
+	}
+	if convertedType >= 0 {
+		e.ConvertedType = parquet.ConvertedTypePtr(convertedType)
+	}
+	return e
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return typeBoolean
+	case int64:
+		return typeInt64
+	case uint64:
+		return typeUint64
+	case float64:
+		return typeDouble
+	default:
+		return typeString
+	}
+}
+
+func mergeType(a, b string) string {
+	switch {
+	case a == "" || a == b:
+		return b
+	case isNumeric(a) && isNumeric(b):
+		return typeDouble
+	default:
+		return typeString
+	}
+}
+
+func isNumeric(typ string) bool {
+	return typ == typeInt64 || typ == typeUint64 || typ == typeDouble
+}
+
+// value returns the field or tag of the metric converted to the column type,
+// or nil if it is missing or can't be converted.
+func value(metric telegraf.Metric, c column) interface{} {
+	v, ok := metric.GetField(c.key)
+	if !ok {
+		tag, ok := metric.GetTag(c.key)
+		if !ok {
+			return nil
+		}
+		v = tag
+	}
+
+	switch c.typ {
+	case typeBoolean:
+		switch v := v.(type) {
+		case bool:
+			return v
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		}
+	case typeInt64:
+		switch v := v.(type) {
+		case int64:
+			return v
+		case uint64:
+			return int64(v)
+		case float64:
+			return int64(v)
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return i
+			}
+		}
+	case typeUint64:
+		// Unsigned values are stored in a signed column.
+		switch v := v.(type) {
+		case uint64:
+			return int64(v)
+		case int64:
+			if v >= 0 {
+				return v
+			}
+		case string:
+			if u, err := strconv.ParseUint(v, 10, 64); err == nil {
+				return int64(u)
+			}
+		}
+	case typeDouble:
+		switch v := v.(type) {
+		case float64:
+			return v
+		case int64:
+			return float64(v)
+		case uint64:
+			return float64(v)
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f
+			}
+		}
+	default:
+		switch v := v.(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return fmt.Sprintf("%v", v)
+		}
+	}
+	return nil
+}
+
+// writeBuffer collects the file written by the parquet writer.
+type writeBuffer struct {
+	bytes.Buffer
+}
+
+func (b *writeBuffer) Seek(_ int64, _ int) (int64, error) {
+	return 0, errors.New("not supported")
+}
+
+func (b *writeBuffer) Open(_ string) (source.ParquetFile, error) {
+	return nil, errors.New("not supported")
+}
+
+func (b *writeBuffer) Create(_ string) (source.ParquetFile, error) {
+	return nil, errors.New("not supported")
+}
+
+func (b *writeBuffer) Close() error {
+	return nil
+}
@@ -0,0 +1,254 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/influxdata/telegraf"
+	parser "github.com/influxdata/telegraf/plugins/parsers/parquet"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go/parquet"
+)
+
+func parse(t *testing.T, buf []byte, tagColumns ...string) []telegraf.Metric {
+	p, err := parser.New(&parser.Config{
+		TagColumns:        tagColumns,
+		MeasurementColumn: "measurement",
+		TimestampColumn:   "timestamp",
+	})
+	require.NoError(t, err)
+	metrics, err := p.Parse(buf)
+	require.NoError(t, err)
+	return metrics
+}
+
+// footer decodes the metadata at the end of the file.
+func footer(t *testing.T, buf []byte) *parquet.FileMetaData {
+	size := binary.LittleEndian.Uint32(buf[len(buf)-8:])
+	ts := thrift.NewTDeserializer()
+	ts.Protocol = thrift.NewTCompactProtocolFactory().GetProtocol(ts.Transport)
+	meta := parquet.NewFileMetaData()
+	require.NoError(t, ts.Read(meta, buf[len(buf)-8-int(size):len(buf)-8]))
+	return meta
+}
+
+func TestSerializeBatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage_idle": 91.5, "count": int64(3), "ok": true},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"usage_idle": 42.0, "bytes": uint64(7), "state": "up"},
+			time.Unix(1600000010, 123456000),
+		),
+	}
+
+	s, err := NewSerializer(&Config{})
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, metrics, parse(t, buf, "host"))
+}
+
+func TestTypeInference(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"value": int64(3), "status": int64(1)},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"value": 1.5, "status": "ok"},
+			time.Unix(1600000010, 0),
+		),
+	}
+
+	s, err := NewSerializer(&Config{})
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"value": 3.0, "status": "1"},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"value": 1.5, "status": "ok"},
+			time.Unix(1600000010, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, parse(t, buf))
+}
+
+func TestExplicitSchema(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": int64(91), "dropped": "x"},
+			time.Unix(1600000000, 0),
+		),
+	}
+
+	s, err := NewSerializer(&Config{
+		TimestampColumn:   "time",
+		MeasurementColumn: "name",
+		Columns:           []string{"host", "usage_idle", "missing"},
+		ColumnTypes:       map[string]string{"usage_idle": "double", "missing": "int64"},
+		Compression:       "gzip",
+		RowGroupSize:      1024,
+	})
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	p, err := parser.New(&parser.Config{
+		TagColumns:        []string{"host"},
+		MeasurementColumn: "name",
+		TimestampColumn:   "time",
+	})
+	require.NoError(t, err)
+	actual, err := p.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage_idle": 91.0},
+			time.Unix(1600000000, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := NewSerializer(&Config{Compression: "lzma"})
+	require.EqualError(t, err, `unknown parquet compression "lzma"`)
+
+	_, err = NewSerializer(&Config{ColumnTypes: map[string]string{"value": "float"}})
+	require.EqualError(t, err, `invalid type "float" for column "value"`)
+
+	_, err = NewSerializer(&Config{ColumnCompression: map[string]string{"value": "lzma"}})
+	require.EqualError(t, err, `unknown parquet compression "lzma" for column "value"`)
+
+	_, err = NewSerializer(&Config{DictionaryColumns: []string{"[host"}})
+	require.Error(t, err)
+}
+
+func TestColumnNameConflict(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"Value": 1.0, "value": 2.0},
+		time.Unix(1600000000, 0),
+	)
+
+	s, err := NewSerializer(&Config{})
+	require.NoError(t, err)
+	_, err = s.Serialize(m)
+	require.Error(t, err)
+}
+
+func TestRowGroupSize(t *testing.T) {
+	metrics := make([]telegraf.Metric, 0, 5000)
+	for i := 0; i < cap(metrics); i++ {
+		metrics = append(metrics, testutil.MustMetric(
+			"cpu",
+			map[string]string{},
+			map[string]interface{}{"value": int64(i)},
+			time.Unix(int64(1600000000+i), 0),
+		))
+	}
+
+	s, err := NewSerializer(&Config{RowGroupSize: 4096, PageSize: 1024})
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, metrics, parse(t, buf))
+
+	meta := footer(t, buf)
+	require.Len(t, meta.RowGroups, 5)
+	require.Equal(t, int64(5000), meta.NumRows)
+}
+
+func TestColumnCompression(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage_idle": 91.5},
+			time.Unix(1600000000, 0),
+		),
+	}
+
+	s, err := NewSerializer(&Config{
+		Compression:       "zstd",
+		ColumnCompression: map[string]string{"host": "gzip", "timestamp": "uncompressed"},
+	})
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, metrics, parse(t, buf, "host"))
+
+	codecs := make(map[string]parquet.CompressionCodec)
+	for _, c := range footer(t, buf).RowGroups[0].Columns {
+		codecs[c.MetaData.PathInSchema[0]] = c.MetaData.Codec
+	}
+	require.Equal(t, map[string]parquet.CompressionCodec{
+		"timestamp":   parquet.CompressionCodec_UNCOMPRESSED,
+		"measurement": parquet.CompressionCodec_ZSTD,
+		"host":        parquet.CompressionCodec_GZIP,
+		"usage_idle":  parquet.CompressionCodec_ZSTD,
+	}, codecs)
+}
+
+func TestDictionaryColumns(t *testing.T) {
+	metrics := make([]telegraf.Metric, 0, 3000)
+	for i := 0; i < cap(metrics); i++ {
+		metrics = append(metrics, testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": []string{"a", "b", "c"}[i%3]},
+			map[string]interface{}{"value": int64(i % 7), "ok": i%2 == 0},
+			time.Unix(int64(1600000000+i), 0),
+		))
+	}
+
+	s, err := NewSerializer(&Config{DictionaryColumns: []string{"host", "measurement", "ok"}})
+	require.NoError(t, err)
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, metrics, parse(t, buf, "host"))
+
+	dictionary := make(map[string]bool)
+	for _, c := range footer(t, buf).RowGroups[0].Columns {
+		dictionary[c.MetaData.PathInSchema[0]] = c.MetaData.DictionaryPageOffset != nil
+	}
+	require.Equal(t, map[string]bool{
+		"timestamp":   false,
+		"measurement": true,
+		"host":        true,
+		"ok":          false,
+		"value":       false,
+	}, dictionary)
+}
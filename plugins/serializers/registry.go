@@ -6,14 +6,19 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/avro"
 	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
+	"github.com/influxdata/telegraf/plugins/serializers/csv"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
 	"github.com/influxdata/telegraf/plugins/serializers/nowmetric"
+	"github.com/influxdata/telegraf/plugins/serializers/parquet"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
+	"github.com/influxdata/telegraf/plugins/serializers/template"
 	"github.com/influxdata/telegraf/plugins/serializers/wavefront"
+	"github.com/influxdata/telegraf/plugins/serializers/xlsx"
 )
 
 // SerializerOutput is an interface for output plugins that are able to
@@ -43,6 +48,24 @@ type Serializer interface {
 	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
 }
 
+// Joiner is implemented by serializers whose output can't always be joined
+// into a single file or object by concatenating it, such as formats with a
+// header or a footer.
+type Joiner interface {
+	// Joinable reports whether the data returned by separate calls of
+	// Serialize, or of SerializeBatch if batch is set, can be concatenated.
+	Joinable(batch bool) bool
+}
+
+// CanJoin reports whether the output of the serializer can be concatenated,
+// which is the case for all serializers not implementing Joiner.
+func CanJoin(serializer Serializer, batch bool) bool {
+	if j, ok := serializer.(Joiner); ok {
+		return j.Joinable(batch)
+	}
+	return true
+}
+
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
@@ -52,6 +75,67 @@ type Config struct {
 	// Carbon2 metric format.
 	Carbon2Format string `toml:"carbon2_format"`
 
+	// Field delimiter of the csv format, defaults to a comma.
+	CSVSeparator string `toml:"csv_separator"`
+
+	// Write a header row with the column names; csv format only
+	CSVHeader bool `toml:"csv_header"`
+
+	// Ordered list of columns; csv format only
+	CSVColumns []string `toml:"csv_columns"`
+
+	// Timestamp format and timezone of the csv format
+	CSVTimestampFormat string `toml:"csv_timestamp_format"`
+	CSVTimezone        string `toml:"csv_timezone"`
+
+	// Names of the timestamp and measurement columns; parquet format only
+	ParquetTimestampColumn   string `toml:"parquet_timestamp_column"`
+	ParquetMeasurementColumn string `toml:"parquet_measurement_column"`
+
+	// Ordered list of tag and field columns and their types; parquet
+	// format only
+	ParquetColumns     []string          `toml:"parquet_columns"`
+	ParquetColumnTypes map[string]string `toml:"parquet_column_types"`
+
+	// Row group and page size in bytes and compression codec of the parquet
+	// format
+	ParquetRowGroupSize int64  `toml:"parquet_row_group_size"`
+	ParquetPageSize     int64  `toml:"parquet_page_size"`
+	ParquetCompression  string `toml:"parquet_compression"`
+
+	// Compression codec of individual columns and the columns written with
+	// dictionary encoding; parquet format only
+	ParquetColumnCompression map[string]string `toml:"parquet_column_compression"`
+	ParquetDictionaryColumns []string          `toml:"parquet_dictionary_columns"`
+
+	// Local schema, or the schema registry and subject to fetch or register
+	// the schema; avro format only
+	AvroSchema         string `toml:"avro_schema"`
+	AvroSchemaFile     string `toml:"avro_schema_file"`
+	AvroSchemaRegistry string `toml:"avro_schema_registry"`
+	AvroSchemaSubject  string `toml:"avro_schema_subject"`
+	AvroSchemaRegister bool   `toml:"avro_schema_register"`
+
+	// Encoding and record fields of the metric name and time of the avro
+	// format
+	AvroEncoding         string `toml:"avro_encoding"`
+	AvroMeasurementField string `toml:"avro_measurement_field"`
+	AvroTimestampField   string `toml:"avro_timestamp_field"`
+	AvroTimestampFormat  string `toml:"avro_timestamp_format"`
+	AvroTimezone         string `toml:"avro_timezone"`
+
+	// Sheet layout, the sheet name of the single layout and the ordered list
+	// of tag and field columns; xlsx format only
+	XlsxLayout    string   `toml:"xlsx_layout"`
+	XlsxSheetName string   `toml:"xlsx_sheet_name"`
+	XlsxColumns   []string `toml:"xlsx_columns"`
+
+	// Headers of the timestamp and measurement columns and the timezone of
+	// the xlsx format
+	XlsxTimestampColumn   string `toml:"xlsx_timestamp_column"`
+	XlsxMeasurementColumn string `toml:"xlsx_measurement_column"`
+	XlsxTimezone          string `toml:"xlsx_timezone"`
+
 	// Support tags in graphite protocol
 	GraphiteTagSupport bool `toml:"graphite_tag_support"`
 
@@ -71,10 +155,13 @@ type Config struct {
 	// Prefix to add to all measurements, only supports Graphite
 	Prefix string `toml:"prefix"`
 
-	// Template for converting telegraf metrics into Graphite
-	// only supports Graphite
+	// Template for converting telegraf metrics into Graphite, or the Go
+	// template of each metric of the template format
 	Template string `toml:"template"`
 
+	// Go template of batches; template format only
+	TemplateBatch string `toml:"template_batch"`
+
 	// Templates same Template, but multiple
 	Templates []string `toml:"templates"`
 
@@ -123,6 +210,16 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewNowSerializer()
 	case "carbon2":
 		serializer, err = NewCarbon2Serializer(config.Carbon2Format)
+	case "csv":
+		serializer, err = NewCSVSerializer(config)
+	case "parquet":
+		serializer, err = NewParquetSerializer(config)
+	case "avro":
+		serializer, err = NewAvroSerializer(config)
+	case "template":
+		serializer, err = NewTemplateSerializer(config.Template, config.TemplateBatch)
+	case "xlsx":
+		serializer, err = NewXlsxSerializer(config)
 	case "wavefront":
 		serializer, err = NewWavefrontSerializer(config.Prefix, config.WavefrontUseStrict, config.WavefrontSourceOverride)
 	case "prometheus":
@@ -175,6 +272,60 @@ func NewPrometheusSerializer(config *Config) (Serializer, error) {
 	})
 }
 
+func NewCSVSerializer(config *Config) (Serializer, error) {
+	return csv.NewSerializer(&csv.Config{
+		Separator:       config.CSVSeparator,
+		Header:          config.CSVHeader,
+		Columns:         config.CSVColumns,
+		TimestampFormat: config.CSVTimestampFormat,
+		Timezone:        config.CSVTimezone,
+	})
+}
+
+func NewParquetSerializer(config *Config) (Serializer, error) {
+	return parquet.NewSerializer(&parquet.Config{
+		TimestampColumn:   config.ParquetTimestampColumn,
+		MeasurementColumn: config.ParquetMeasurementColumn,
+		Columns:           config.ParquetColumns,
+		ColumnTypes:       config.ParquetColumnTypes,
+		RowGroupSize:      config.ParquetRowGroupSize,
+		PageSize:          config.ParquetPageSize,
+		Compression:       config.ParquetCompression,
+		ColumnCompression: config.ParquetColumnCompression,
+		DictionaryColumns: config.ParquetDictionaryColumns,
+	})
+}
+
+func NewAvroSerializer(config *Config) (Serializer, error) {
+	return avro.NewSerializer(&avro.Config{
+		Schema:           config.AvroSchema,
+		SchemaFile:       config.AvroSchemaFile,
+		SchemaRegistry:   config.AvroSchemaRegistry,
+		SchemaSubject:    config.AvroSchemaSubject,
+		SchemaRegister:   config.AvroSchemaRegister,
+		Encoding:         config.AvroEncoding,
+		MeasurementField: config.AvroMeasurementField,
+		TimestampField:   config.AvroTimestampField,
+		TimestampFormat:  config.AvroTimestampFormat,
+		Timezone:         config.AvroTimezone,
+	})
+}
+
+func NewTemplateSerializer(metricTemplate, batchTemplate string) (Serializer, error) {
+	return template.NewSerializer(metricTemplate, batchTemplate)
+}
+
+func NewXlsxSerializer(config *Config) (Serializer, error) {
+	return xlsx.NewSerializer(&xlsx.Config{
+		Layout:            config.XlsxLayout,
+		SheetName:         config.XlsxSheetName,
+		Columns:           config.XlsxColumns,
+		TimestampColumn:   config.XlsxTimestampColumn,
+		MeasurementColumn: config.XlsxMeasurementColumn,
+		Timezone:          config.XlsxTimezone,
+	})
+}
+
 func NewWavefrontSerializer(prefix string, useStrict bool, sourceOverride []string) (Serializer, error) {
 	return wavefront.NewSerializer(prefix, useStrict, sourceOverride)
 }
@@ -0,0 +1,81 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Serializer renders metrics with a Go template for each metric, and an
+// optional template for whole batches.
+type Serializer struct {
+	tmpl      *template.Template
+	batchTmpl *template.Template
+}
+
+func NewSerializer(metricTemplate, batchTemplate string) (*Serializer, error) {
+	if metricTemplate == "" && batchTemplate == "" {
+		return nil, fmt.Errorf("template or template_batch must be set")
+	}
+
+	s := &Serializer{}
+	var err error
+	if metricTemplate != "" {
+		s.tmpl, err = template.New("template").Parse(metricTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template: %v", err)
+		}
+	}
+	if batchTemplate != "" {
+		s.batchTmpl, err = template.New("template_batch").Parse(batchTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template_batch: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// Serialize renders the metric template, ending with a newline.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	if s.tmpl == nil {
+		return s.SerializeBatch([]telegraf.Metric{metric})
+	}
+
+	var buf bytes.Buffer
+	if err := s.tmpl.Execute(&buf, &TemplateMetric{metric}); err != nil {
+		return nil, err
+	}
+	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// SerializeBatch renders the batch template with the metrics, or the metric
+// template for each metric if no batch template is set.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	if s.batchTmpl == nil {
+		var batch bytes.Buffer
+		for _, metric := range metrics {
+			buf, err := s.Serialize(metric)
+			if err != nil {
+				return nil, err
+			}
+			batch.Write(buf)
+		}
+		return batch.Bytes(), nil
+	}
+
+	batch := make([]*TemplateMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		batch = append(batch, &TemplateMetric{metric})
+	}
+
+	var buf bytes.Buffer
+	if err := s.batchTmpl.Execute(&buf, batch); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,40 @@
+package template
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// TemplateMetric is the metric passed to the templates.
+type TemplateMetric struct {
+	metric telegraf.Metric
+}
+
+func (m *TemplateMetric) Name() string {
+	return m.metric.Name()
+}
+
+func (m *TemplateMetric) Tag(key string) string {
+	tagString, _ := m.metric.GetTag(key)
+	return tagString
+}
+
+func (m *TemplateMetric) Field(key string) interface{} {
+	field, _ := m.metric.GetField(key)
+	return field
+}
+
+func (m *TemplateMetric) Time() time.Time {
+	return m.metric.Time()
+}
+
+// Tags returns the tags of the metric, ranged over in key order.
+func (m *TemplateMetric) Tags() map[string]string {
+	return m.metric.Tags()
+}
+
+// Fields returns the fields of the metric, ranged over in key order.
+func (m *TemplateMetric) Fields() map[string]interface{} {
+	return m.metric.Fields()
+}
@@ -0,0 +1,89 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5, "usage_user": 3.0},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"used": int64(42)},
+			time.Unix(1600000010, 0),
+		),
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	s, err := NewSerializer(`{{ .Time.Unix }}|{{ .Name }}|{{ .Tag "host" }}{{ range $k, $v := .Fields }}|{{ $k }}={{ $v }}{{ end }}`, "")
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testMetrics()[0])
+	require.NoError(t, err)
+	require.Equal(t, "1600000000|cpu|a|usage_idle=91.5|usage_user=3\n", string(buf))
+
+	buf, err = s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+	require.Equal(t, "1600000000|cpu|a|usage_idle=91.5|usage_user=3\n1600000010|mem|b|used=42\n", string(buf))
+}
+
+func TestSerializeKeepsNewline(t *testing.T) {
+	s, err := NewSerializer("{{ .Name }} {{ .Field \"used\" }}\n", "")
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testMetrics()[1])
+	require.NoError(t, err)
+	require.Equal(t, "mem 42\n", string(buf))
+}
+
+func TestSerializeBatchTemplate(t *testing.T) {
+	s, err := NewSerializer("", `BEGIN {{ len . }}
+{{ range . }}{{ .Name }}{{ range $k, $v := .Tags }};{{ $k }}={{ $v }}{{ end }}
+{{ end }}END
+`)
+	require.NoError(t, err)
+
+	expected := "BEGIN 2\ncpu;cpu=cpu0;host=a\nmem;host=b\nEND\n"
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+	require.Equal(t, expected, string(buf))
+
+	// single metrics use the batch template without a metric template
+	buf, err = s.Serialize(testMetrics()[1])
+	require.NoError(t, err)
+	require.Equal(t, "BEGIN 1\nmem;host=b\nEND\n", string(buf))
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	_, err := NewSerializer("", "")
+	require.Error(t, err)
+
+	_, err = NewSerializer("{{ .Name", "")
+	require.Error(t, err)
+
+	s, err := NewSerializer("{{ .Missing }}", "")
+	require.NoError(t, err)
+	_, err = s.Serialize(testMetrics()[0])
+	require.Error(t, err)
+}
+
+func TestMissingField(t *testing.T) {
+	s, err := NewSerializer(`{{ .Name }};{{ with .Field "missing" }}{{ . }}{{ end }};{{ .Tag "missing" }}`, "")
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testMetrics()[1])
+	require.NoError(t, err)
+	require.Equal(t, "mem;;\n", string(buf))
+}
@@ -0,0 +1,250 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/tealeg/xlsx"
+)
+
+const (
+	layoutMeasurement = "measurement"
+	layoutSingle      = "single"
+
+	defaultSheetName         = "metrics"
+	defaultTimestampColumn   = "timestamp"
+	defaultMeasurementColumn = "measurement"
+
+	// maxSheetName is the maximum length of sheet names allowed by Excel.
+	maxSheetName = 31
+)
+
+type Config struct {
+	// Layout is "measurement" for a sheet per measurement, or "single" for
+	// all metrics in one sheet.
+	Layout string
+	// SheetName is the name of the sheet of the single layout.
+	SheetName string
+	// Columns is the ordered list of tag and field keys written.  When empty
+	// all tags and fields of the metrics of a sheet are written.
+	Columns []string
+	// TimestampColumn and MeasurementColumn are the header of the columns
+	// of the metric time and name, the measurement is only written with the
+	// single layout.
+	TimestampColumn   string
+	MeasurementColumn string
+	// Timezone is the location of the written times.
+	Timezone string
+}
+
+// Serializer writes metrics as Excel workbooks, with a header row and a row
+// for each metric.
+type Serializer struct {
+	layout            string
+	sheetName         string
+	columns           []string
+	timestampColumn   string
+	measurementColumn string
+	location          *time.Location
+}
+
+// sheet collects the metrics written to one sheet.
+type sheet struct {
+	name    string
+	metrics []telegraf.Metric
+}
+
+func NewSerializer(config *Config) (*Serializer, error) {
+	s := &Serializer{
+		layout:            config.Layout,
+		sheetName:         config.SheetName,
+		columns:           config.Columns,
+		timestampColumn:   config.TimestampColumn,
+		measurementColumn: config.MeasurementColumn,
+	}
+	if s.layout == "" {
+		s.layout = layoutMeasurement
+	}
+	if s.layout != layoutMeasurement && s.layout != layoutSingle {
+		return nil, fmt.Errorf("unknown xlsx layout %q", s.layout)
+	}
+	if s.sheetName == "" {
+		s.sheetName = defaultSheetName
+	}
+	if s.sheetName != sheetName(s.sheetName) {
+		return nil, fmt.Errorf("invalid sheet name %q", s.sheetName)
+	}
+	if s.timestampColumn == "" {
+		s.timestampColumn = defaultTimestampColumn
+	}
+	if s.measurementColumn == "" {
+		s.measurementColumn = defaultMeasurementColumn
+	}
+
+	// LoadLocation returns UTC if timezone is the empty string.
+	var err error
+	s.location, err = time.LoadLocation(config.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Serialize returns a workbook with a single row.  Workbooks can't be
+// concatenated, use batch serialization to write multiple metrics.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// Joinable reports that workbooks can't be concatenated.
+func (s *Serializer) Joinable(_ bool) bool {
+	return false
+}
+
+// SerializeBatch returns a workbook with the metrics, the sheets are ordered
+// by their first metric.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var sheets []*sheet
+	byName := make(map[string]*sheet)
+	for _, metric := range metrics {
+		name := s.sheetName
+		if s.layout == layoutMeasurement {
+			name = sheetName(metric.Name())
+		}
+		sh, ok := byName[name]
+		if !ok {
+			sh = &sheet{name: name}
+			byName[name] = sh
+			sheets = append(sheets, sh)
+		}
+		sh.metrics = append(sh.metrics, metric)
+	}
+
+	file := xlsx.NewFile()
+	for _, sh := range sheets {
+		if err := s.writeSheet(file, sh); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := file.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Serializer) writeSheet(file *xlsx.File, sh *sheet) error {
+	xs, err := file.AddSheet(sh.name)
+	if err != nil {
+		return err
+	}
+
+	keys := s.columns
+	if len(keys) == 0 {
+		keys = columnsOf(sh.metrics)
+	}
+
+	header := xs.AddRow()
+	header.AddCell().SetString(s.timestampColumn)
+	if s.layout == layoutSingle {
+		header.AddCell().SetString(s.measurementColumn)
+	}
+	for _, key := range keys {
+		header.AddCell().SetString(key)
+	}
+
+	options := xlsx.DateTimeOptions{
+		Location:        s.location,
+		ExcelTimeFormat: xlsx.DefaultDateTimeFormat,
+	}
+	for _, metric := range sh.metrics {
+		row := xs.AddRow()
+		row.AddCell().SetDateWithOptions(metric.Time(), options)
+		if s.layout == layoutSingle {
+			row.AddCell().SetString(metric.Name())
+		}
+		for _, key := range keys {
+			cell := row.AddCell()
+			if v, ok := metric.GetField(key); ok {
+				setValue(cell, v)
+			} else if v, ok := metric.GetTag(key); ok {
+				cell.SetString(v)
+			}
+		}
+	}
+	return nil
+}
+
+// columnsOf returns the sorted tag keys followed by the sorted field keys of
+// the metrics.
+func columnsOf(metrics []telegraf.Metric) []string {
+	tags := make(map[string]bool)
+	fields := make(map[string]bool)
+	for _, metric := range metrics {
+		for _, tag := range metric.TagList() {
+			tags[tag.Key] = true
+		}
+		for _, field := range metric.FieldList() {
+			fields[field.Key] = true
+		}
+	}
+
+	columns := make([]string, 0, len(tags)+len(fields))
+	columns = append(columns, sortedKeys(tags, nil)...)
+	return append(columns, sortedKeys(fields, tags)...)
+}
+
+func sortedKeys(keys map[string]bool, exclude map[string]bool) []string {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		if !exclude[key] {
+			sorted = append(sorted, key)
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func setValue(cell *xlsx.Cell, v interface{}) {
+	switch v := v.(type) {
+	case bool:
+		cell.SetBool(v)
+	case string:
+		cell.SetString(v)
+	case int64:
+		cell.SetInt64(v)
+	case uint64:
+		if v <= math.MaxInt64 {
+			cell.SetInt64(int64(v))
+		} else {
+			cell.SetString(strconv.FormatUint(v, 10))
+		}
+	case float64:
+		cell.SetFloat(v)
+	default:
+		cell.SetString(fmt.Sprint(v))
+	}
+}
+
+// sheetName replaces the characters not allowed in sheet names and cuts the
+// name to the maximum length.
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '\\', '/', '?', '*', '[', ']':
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > maxSheetName {
+		name = string(runes[:maxSheetName])
+	}
+	return name
+}
@@ -0,0 +1,141 @@
+package xlsx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	parser "github.com/influxdata/telegraf/plugins/parsers/xlsx"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/tealeg/xlsx"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage_idle": 91.5, "count": int64(3)},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"used": uint64(42), "ok": true},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 42.0, "state": "up"},
+			time.Unix(1600000010, 0),
+		),
+	}
+}
+
+// sheetRows returns the cell values of the rows of each sheet.
+func sheetRows(t *testing.T, buf []byte) map[string][][]string {
+	file, err := xlsx.OpenBinary(buf)
+	require.NoError(t, err)
+
+	sheets := make(map[string][][]string)
+	for _, sheet := range file.Sheets {
+		var rows [][]string
+		for _, row := range sheet.Rows {
+			var values []string
+			for _, cell := range row.Cells {
+				values = append(values, cell.Value)
+			}
+			rows = append(rows, values)
+		}
+		sheets[sheet.Name] = rows
+	}
+	return sheets
+}
+
+func TestSheetPerMeasurement(t *testing.T) {
+	s, err := NewSerializer(&Config{})
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	file, err := xlsx.OpenBinary(buf)
+	require.NoError(t, err)
+	require.Len(t, file.Sheets, 2)
+	require.Equal(t, "cpu", file.Sheets[0].Name)
+	require.Equal(t, "mem", file.Sheets[1].Name)
+
+	rows := sheetRows(t, buf)
+	require.Equal(t, []string{"timestamp", "cpu", "host", "count", "state", "usage_idle"}, rows["cpu"][0])
+	require.Equal(t, []string{"a", "3", "", "91.5"}, rows["cpu"][1][2:])
+	require.Equal(t, []string{"cpu0", "b", "", "up", "42"}, rows["cpu"][2][1:])
+	require.Equal(t, []string{"timestamp", "host", "ok", "used"}, rows["mem"][0])
+	require.Equal(t, []string{"a", "1", "42"}, rows["mem"][1][1:])
+
+	// the workbook can be read with the xlsx parser
+	p, err := parser.New(&parser.Config{
+		MetricName:      "xlsx",
+		Sheets:          []string{"cpu"},
+		HeaderRow:       1,
+		TagColumns:      []string{"host", "cpu"},
+		TimestampColumn: "timestamp",
+	})
+	require.NoError(t, err)
+	metrics, err := p.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"xlsx",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage_idle": 91.5, "count": int64(3)},
+			time.Unix(1600000000, 0),
+		),
+		testutil.MustMetric(
+			"xlsx",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": int64(42), "state": "up"},
+			time.Unix(1600000010, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics, testutil.IgnoreTime())
+	for i, m := range metrics {
+		require.True(t, expected[i].Time().Equal(m.Time()))
+	}
+}
+
+func TestSingleSheet(t *testing.T) {
+	s, err := NewSerializer(&Config{
+		Layout:            "single",
+		SheetName:         "report",
+		Columns:           []string{"host", "usage_idle"},
+		MeasurementColumn: "name",
+		TimestampColumn:   "time",
+	})
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	rows := sheetRows(t, buf)
+	require.Len(t, rows, 1)
+	require.Equal(t, []string{"time", "name", "host", "usage_idle"}, rows["report"][0])
+	require.Equal(t, []string{"cpu", "a", "91.5"}, rows["report"][1][1:])
+	require.Equal(t, []string{"mem", "a", ""}, rows["report"][2][1:])
+	require.Equal(t, []string{"cpu", "b", "42"}, rows["report"][3][1:])
+}
+
+func TestSheetName(t *testing.T) {
+	require.Equal(t, "a_b_c", sheetName("a/b:c"))
+	require.Equal(t, "abcdefghijklmnopqrstuvwxyz01234", sheetName("abcdefghijklmnopqrstuvwxyz0123456789"))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := NewSerializer(&Config{Layout: "grid"})
+	require.EqualError(t, err, `unknown xlsx layout "grid"`)
+
+	_, err = NewSerializer(&Config{Layout: "single", SheetName: "a/b"})
+	require.EqualError(t, err, `invalid sheet name "a/b"`)
+}